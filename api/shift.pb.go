@@ -52,7 +52,7 @@ func (x StorageKind) String() string {
 	return proto.EnumName(StorageKind_name, int32(x))
 }
 func (StorageKind) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{0}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{0}
 }
 
 type RegisterReq struct {
@@ -67,7 +67,7 @@ func (m *RegisterReq) Reset()         { *m = RegisterReq{} }
 func (m *RegisterReq) String() string { return proto.CompactTextString(m) }
 func (*RegisterReq) ProtoMessage()    {}
 func (*RegisterReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{0}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{0}
 }
 func (m *RegisterReq) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterReq.Unmarshal(m, b)
@@ -112,7 +112,7 @@ func (m *RegisterRes) Reset()         { *m = RegisterRes{} }
 func (m *RegisterRes) String() string { return proto.CompactTextString(m) }
 func (*RegisterRes) ProtoMessage()    {}
 func (*RegisterRes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{1}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{1}
 }
 func (m *RegisterRes) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterRes.Unmarshal(m, b)
@@ -159,7 +159,7 @@ func (m *UpdateBuildStatusReq) Reset()         { *m = UpdateBuildStatusReq{} }
 func (m *UpdateBuildStatusReq) String() string { return proto.CompactTextString(m) }
 func (*UpdateBuildStatusReq) ProtoMessage()    {}
 func (*UpdateBuildStatusReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{2}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{2}
 }
 func (m *UpdateBuildStatusReq) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateBuildStatusReq.Unmarshal(m, b)
@@ -259,7 +259,7 @@ func (m *UpdateBuildStatusRes) Reset()         { *m = UpdateBuildStatusRes{} }
 func (m *UpdateBuildStatusRes) String() string { return proto.CompactTextString(m) }
 func (*UpdateBuildStatusRes) ProtoMessage()    {}
 func (*UpdateBuildStatusRes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{3}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{3}
 }
 func (m *UpdateBuildStatusRes) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateBuildStatusRes.Unmarshal(m, b)
@@ -291,7 +291,7 @@ func (m *GetProjectReq) Reset()         { *m = GetProjectReq{} }
 func (m *GetProjectReq) String() string { return proto.CompactTextString(m) }
 func (*GetProjectReq) ProtoMessage()    {}
 func (*GetProjectReq) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{4}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{4}
 }
 func (m *GetProjectReq) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetProjectReq.Unmarshal(m, b)
@@ -348,7 +348,7 @@ func (m *GetProjectRes) Reset()         { *m = GetProjectRes{} }
 func (m *GetProjectRes) String() string { return proto.CompactTextString(m) }
 func (*GetProjectRes) ProtoMessage()    {}
 func (*GetProjectRes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{5}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{5}
 }
 func (m *GetProjectRes) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetProjectRes.Unmarshal(m, b)
@@ -473,7 +473,7 @@ func (m *MinioStorage) Reset()         { *m = MinioStorage{} }
 func (m *MinioStorage) String() string { return proto.CompactTextString(m) }
 func (*MinioStorage) ProtoMessage()    {}
 func (*MinioStorage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{6}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{6}
 }
 func (m *MinioStorage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MinioStorage.Unmarshal(m, b)
@@ -531,7 +531,7 @@ func (m *NFSStorage) Reset()         { *m = NFSStorage{} }
 func (m *NFSStorage) String() string { return proto.CompactTextString(m) }
 func (*NFSStorage) ProtoMessage()    {}
 func (*NFSStorage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{7}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{7}
 }
 func (m *NFSStorage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NFSStorage.Unmarshal(m, b)
@@ -563,7 +563,7 @@ func (m *Storage) Reset()         { *m = Storage{} }
 func (m *Storage) String() string { return proto.CompactTextString(m) }
 func (*Storage) ProtoMessage()    {}
 func (*Storage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_shift_019ec61578ad1f36, []int{8}
+	return fileDescriptor_shift_dab264dc211e9d19, []int{8}
 }
 func (m *Storage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Storage.Unmarshal(m, b)
@@ -597,6 +597,330 @@ func (m *Storage) GetMinio() *MinioStorage {
 	return nil
 }
 
+type TriggerBuildReq struct {
+	BuildId              string   `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	SubBuildId           string   `protobuf:"bytes,2,opt,name=sub_build_id,json=subBuildId,proto3" json:"sub_build_id,omitempty"`
+	Node                 string   `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	Repository           string   `protobuf:"bytes,4,opt,name=repository,proto3" json:"repository,omitempty"`
+	Branch               string   `protobuf:"bytes,5,opt,name=branch,proto3" json:"branch,omitempty"`
+	Pipeline             string   `protobuf:"bytes,6,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
+	Parameters           []string `protobuf:"bytes,7,rep,name=parameters,proto3" json:"parameters,omitempty"`
+	Wait                 bool     `protobuf:"varint,8,opt,name=wait,proto3" json:"wait,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TriggerBuildReq) Reset()         { *m = TriggerBuildReq{} }
+func (m *TriggerBuildReq) String() string { return proto.CompactTextString(m) }
+func (*TriggerBuildReq) ProtoMessage()    {}
+func (*TriggerBuildReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{9}
+}
+func (m *TriggerBuildReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TriggerBuildReq.Unmarshal(m, b)
+}
+func (m *TriggerBuildReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TriggerBuildReq.Marshal(b, m, deterministic)
+}
+func (dst *TriggerBuildReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TriggerBuildReq.Merge(dst, src)
+}
+func (m *TriggerBuildReq) XXX_Size() int {
+	return xxx_messageInfo_TriggerBuildReq.Size(m)
+}
+func (m *TriggerBuildReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_TriggerBuildReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TriggerBuildReq proto.InternalMessageInfo
+
+func (m *TriggerBuildReq) GetBuildId() string {
+	if m != nil {
+		return m.BuildId
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetSubBuildId() string {
+	if m != nil {
+		return m.SubBuildId
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetRepository() string {
+	if m != nil {
+		return m.Repository
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetBranch() string {
+	if m != nil {
+		return m.Branch
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetPipeline() string {
+	if m != nil {
+		return m.Pipeline
+	}
+	return ""
+}
+
+func (m *TriggerBuildReq) GetParameters() []string {
+	if m != nil {
+		return m.Parameters
+	}
+	return nil
+}
+
+func (m *TriggerBuildReq) GetWait() bool {
+	if m != nil {
+		return m.Wait
+	}
+	return false
+}
+
+type TriggerBuildRes struct {
+	BuildId              string   `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	RepositoryId         string   `protobuf:"bytes,2,opt,name=repository_id,json=repositoryId,proto3" json:"repository_id,omitempty"`
+	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TriggerBuildRes) Reset()         { *m = TriggerBuildRes{} }
+func (m *TriggerBuildRes) String() string { return proto.CompactTextString(m) }
+func (*TriggerBuildRes) ProtoMessage()    {}
+func (*TriggerBuildRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{10}
+}
+func (m *TriggerBuildRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TriggerBuildRes.Unmarshal(m, b)
+}
+func (m *TriggerBuildRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TriggerBuildRes.Marshal(b, m, deterministic)
+}
+func (dst *TriggerBuildRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TriggerBuildRes.Merge(dst, src)
+}
+func (m *TriggerBuildRes) XXX_Size() int {
+	return xxx_messageInfo_TriggerBuildRes.Size(m)
+}
+func (m *TriggerBuildRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_TriggerBuildRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TriggerBuildRes proto.InternalMessageInfo
+
+func (m *TriggerBuildRes) GetBuildId() string {
+	if m != nil {
+		return m.BuildId
+	}
+	return ""
+}
+
+func (m *TriggerBuildRes) GetRepositoryId() string {
+	if m != nil {
+		return m.RepositoryId
+	}
+	return ""
+}
+
+func (m *TriggerBuildRes) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+type GetBuildStatusReq struct {
+	BuildId              string   `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetBuildStatusReq) Reset()         { *m = GetBuildStatusReq{} }
+func (m *GetBuildStatusReq) String() string { return proto.CompactTextString(m) }
+func (*GetBuildStatusReq) ProtoMessage()    {}
+func (*GetBuildStatusReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{11}
+}
+func (m *GetBuildStatusReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetBuildStatusReq.Unmarshal(m, b)
+}
+func (m *GetBuildStatusReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetBuildStatusReq.Marshal(b, m, deterministic)
+}
+func (dst *GetBuildStatusReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetBuildStatusReq.Merge(dst, src)
+}
+func (m *GetBuildStatusReq) XXX_Size() int {
+	return xxx_messageInfo_GetBuildStatusReq.Size(m)
+}
+func (m *GetBuildStatusReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetBuildStatusReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetBuildStatusReq proto.InternalMessageInfo
+
+func (m *GetBuildStatusReq) GetBuildId() string {
+	if m != nil {
+		return m.BuildId
+	}
+	return ""
+}
+
+type GetBuildStatusRes struct {
+	Status               string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetBuildStatusRes) Reset()         { *m = GetBuildStatusRes{} }
+func (m *GetBuildStatusRes) String() string { return proto.CompactTextString(m) }
+func (*GetBuildStatusRes) ProtoMessage()    {}
+func (*GetBuildStatusRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{12}
+}
+func (m *GetBuildStatusRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetBuildStatusRes.Unmarshal(m, b)
+}
+func (m *GetBuildStatusRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetBuildStatusRes.Marshal(b, m, deterministic)
+}
+func (dst *GetBuildStatusRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetBuildStatusRes.Merge(dst, src)
+}
+func (m *GetBuildStatusRes) XXX_Size() int {
+	return xxx_messageInfo_GetBuildStatusRes.Size(m)
+}
+func (m *GetBuildStatusRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetBuildStatusRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetBuildStatusRes proto.InternalMessageInfo
+
+func (m *GetBuildStatusRes) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *GetBuildStatusRes) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type HeartbeatReq struct {
+	BuildId              string   `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	SubBuildId           string   `protobuf:"bytes,2,opt,name=sub_build_id,json=subBuildId,proto3" json:"sub_build_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeartbeatReq) Reset()         { *m = HeartbeatReq{} }
+func (m *HeartbeatReq) String() string { return proto.CompactTextString(m) }
+func (*HeartbeatReq) ProtoMessage()    {}
+func (*HeartbeatReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{13}
+}
+func (m *HeartbeatReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeartbeatReq.Unmarshal(m, b)
+}
+func (m *HeartbeatReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeartbeatReq.Marshal(b, m, deterministic)
+}
+func (dst *HeartbeatReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeartbeatReq.Merge(dst, src)
+}
+func (m *HeartbeatReq) XXX_Size() int {
+	return xxx_messageInfo_HeartbeatReq.Size(m)
+}
+func (m *HeartbeatReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeartbeatReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeartbeatReq proto.InternalMessageInfo
+
+func (m *HeartbeatReq) GetBuildId() string {
+	if m != nil {
+		return m.BuildId
+	}
+	return ""
+}
+
+func (m *HeartbeatReq) GetSubBuildId() string {
+	if m != nil {
+		return m.SubBuildId
+	}
+	return ""
+}
+
+type HeartbeatRes struct {
+	Stop                 bool     `protobuf:"varint,1,opt,name=stop,proto3" json:"stop,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeartbeatRes) Reset()         { *m = HeartbeatRes{} }
+func (m *HeartbeatRes) String() string { return proto.CompactTextString(m) }
+func (*HeartbeatRes) ProtoMessage()    {}
+func (*HeartbeatRes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_shift_dab264dc211e9d19, []int{14}
+}
+func (m *HeartbeatRes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeartbeatRes.Unmarshal(m, b)
+}
+func (m *HeartbeatRes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeartbeatRes.Marshal(b, m, deterministic)
+}
+func (dst *HeartbeatRes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeartbeatRes.Merge(dst, src)
+}
+func (m *HeartbeatRes) XXX_Size() int {
+	return xxx_messageInfo_HeartbeatRes.Size(m)
+}
+func (m *HeartbeatRes) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeartbeatRes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeartbeatRes proto.InternalMessageInfo
+
+func (m *HeartbeatRes) GetStop() bool {
+	if m != nil {
+		return m.Stop
+	}
+	return false
+}
+
+func (m *HeartbeatRes) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*RegisterReq)(nil), "api.RegisterReq")
 	proto.RegisterType((*RegisterRes)(nil), "api.RegisterRes")
@@ -607,6 +931,12 @@ func init() {
 	proto.RegisterType((*MinioStorage)(nil), "api.MinioStorage")
 	proto.RegisterType((*NFSStorage)(nil), "api.NFSStorage")
 	proto.RegisterType((*Storage)(nil), "api.Storage")
+	proto.RegisterType((*TriggerBuildReq)(nil), "api.TriggerBuildReq")
+	proto.RegisterType((*TriggerBuildRes)(nil), "api.TriggerBuildRes")
+	proto.RegisterType((*GetBuildStatusReq)(nil), "api.GetBuildStatusReq")
+	proto.RegisterType((*GetBuildStatusRes)(nil), "api.GetBuildStatusRes")
+	proto.RegisterType((*HeartbeatReq)(nil), "api.HeartbeatReq")
+	proto.RegisterType((*HeartbeatRes)(nil), "api.HeartbeatRes")
 	proto.RegisterEnum("api.StorageKind", StorageKind_name, StorageKind_value)
 }
 
@@ -625,6 +955,9 @@ type ShiftClient interface {
 	Register(ctx context.Context, in *RegisterReq, opts ...grpc.CallOption) (*RegisterRes, error)
 	GetProject(ctx context.Context, in *GetProjectReq, opts ...grpc.CallOption) (*GetProjectRes, error)
 	UpdateBuildStatus(ctx context.Context, in *UpdateBuildStatusReq, opts ...grpc.CallOption) (*UpdateBuildStatusRes, error)
+	TriggerBuild(ctx context.Context, in *TriggerBuildReq, opts ...grpc.CallOption) (*TriggerBuildRes, error)
+	GetBuildStatus(ctx context.Context, in *GetBuildStatusReq, opts ...grpc.CallOption) (*GetBuildStatusRes, error)
+	Heartbeat(ctx context.Context, in *HeartbeatReq, opts ...grpc.CallOption) (*HeartbeatRes, error)
 }
 
 type shiftClient struct {
@@ -662,11 +995,41 @@ func (c *shiftClient) UpdateBuildStatus(ctx context.Context, in *UpdateBuildStat
 	return out, nil
 }
 
+func (c *shiftClient) TriggerBuild(ctx context.Context, in *TriggerBuildReq, opts ...grpc.CallOption) (*TriggerBuildRes, error) {
+	out := new(TriggerBuildRes)
+	err := c.cc.Invoke(ctx, "/api.Shift/TriggerBuild", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shiftClient) GetBuildStatus(ctx context.Context, in *GetBuildStatusReq, opts ...grpc.CallOption) (*GetBuildStatusRes, error) {
+	out := new(GetBuildStatusRes)
+	err := c.cc.Invoke(ctx, "/api.Shift/GetBuildStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shiftClient) Heartbeat(ctx context.Context, in *HeartbeatReq, opts ...grpc.CallOption) (*HeartbeatRes, error) {
+	out := new(HeartbeatRes)
+	err := c.cc.Invoke(ctx, "/api.Shift/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShiftServer is the server API for Shift service.
 type ShiftServer interface {
 	Register(context.Context, *RegisterReq) (*RegisterRes, error)
 	GetProject(context.Context, *GetProjectReq) (*GetProjectRes, error)
 	UpdateBuildStatus(context.Context, *UpdateBuildStatusReq) (*UpdateBuildStatusRes, error)
+	TriggerBuild(context.Context, *TriggerBuildReq) (*TriggerBuildRes, error)
+	GetBuildStatus(context.Context, *GetBuildStatusReq) (*GetBuildStatusRes, error)
+	Heartbeat(context.Context, *HeartbeatReq) (*HeartbeatRes, error)
 }
 
 func RegisterShiftServer(s *grpc.Server, srv ShiftServer) {
@@ -727,6 +1090,60 @@ func _Shift_UpdateBuildStatus_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shift_TriggerBuild_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerBuildReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShiftServer).TriggerBuild(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Shift/TriggerBuild",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShiftServer).TriggerBuild(ctx, req.(*TriggerBuildReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shift_GetBuildStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBuildStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShiftServer).GetBuildStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Shift/GetBuildStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShiftServer).GetBuildStatus(ctx, req.(*GetBuildStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shift_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShiftServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Shift/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShiftServer).Heartbeat(ctx, req.(*HeartbeatReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Shift_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.Shift",
 	HandlerType: (*ShiftServer)(nil),
@@ -743,58 +1160,82 @@ var _Shift_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateBuildStatus",
 			Handler:    _Shift_UpdateBuildStatus_Handler,
 		},
+		{
+			MethodName: "TriggerBuild",
+			Handler:    _Shift_TriggerBuild_Handler,
+		},
+		{
+			MethodName: "GetBuildStatus",
+			Handler:    _Shift_GetBuildStatus_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Shift_Heartbeat_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/shift.proto",
 }
 
-func init() { proto.RegisterFile("api/shift.proto", fileDescriptor_shift_019ec61578ad1f36) }
-
-var fileDescriptor_shift_019ec61578ad1f36 = []byte{
-	// 716 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xdd, 0x6e, 0xd3, 0x4a,
-	0x10, 0x6e, 0xfe, 0x9d, 0xb1, 0x7b, 0x4e, 0xba, 0xea, 0xe9, 0x71, 0xc3, 0x8f, 0x82, 0x41, 0x50,
-	0x81, 0x28, 0x28, 0x95, 0xb8, 0xa7, 0x88, 0x96, 0x28, 0x22, 0x14, 0x47, 0x15, 0xdc, 0x45, 0x1b,
-	0x7b, 0x9b, 0x2c, 0x71, 0xbc, 0x66, 0x77, 0x5d, 0x29, 0x5c, 0xf2, 0x0a, 0x3c, 0x06, 0xef, 0xc0,
-	0xb3, 0xa1, 0xfd, 0x49, 0xe2, 0xd0, 0xa0, 0xde, 0x79, 0xbe, 0x6f, 0x76, 0xfc, 0xcd, 0x37, 0xb3,
-	0x0b, 0xff, 0xe2, 0x8c, 0xbe, 0x10, 0x53, 0x7a, 0x25, 0x8f, 0x33, 0xce, 0x24, 0x43, 0x15, 0x9c,
-	0xd1, 0xe0, 0x1d, 0xb8, 0x21, 0x99, 0x50, 0x21, 0x09, 0x0f, 0xc9, 0x57, 0x74, 0x08, 0xce, 0x38,
-	0xa7, 0x49, 0x3c, 0xa2, 0xb1, 0x5f, 0xea, 0x94, 0x8e, 0x9a, 0x61, 0x43, 0xc7, 0xbd, 0x18, 0xdd,
-	0x07, 0xc8, 0x38, 0xbd, 0xc6, 0x92, 0xcc, 0xc8, 0xc2, 0x2f, 0x6b, 0xb2, 0x80, 0x04, 0xcf, 0x8b,
-	0x95, 0x84, 0x4a, 0xe7, 0x36, 0x24, 0xa6, 0x96, 0x13, 0x16, 0x90, 0xe0, 0x67, 0x19, 0xf6, 0x2f,
-	0xb3, 0x18, 0x4b, 0x72, 0xaa, 0x7e, 0x30, 0x94, 0x58, 0xe6, 0xe2, 0x16, 0x09, 0x1d, 0xf0, 0x44,
-	0x3e, 0x1e, 0xad, 0x68, 0x2b, 0x42, 0xe4, 0xe3, 0x53, 0x9b, 0xf1, 0x10, 0x76, 0x39, 0xc9, 0x98,
-	0xa0, 0x92, 0xf1, 0x85, 0x4a, 0xa9, 0xe8, 0x14, 0x6f, 0x0d, 0xf6, 0x62, 0xf4, 0x3f, 0x34, 0x24,
-	0xc1, 0x73, 0x45, 0x57, 0x35, 0x5d, 0x57, 0x61, 0x2f, 0x46, 0x07, 0x50, 0x1f, 0x73, 0x9c, 0x46,
-	0x53, 0xbf, 0x66, 0x70, 0x13, 0xa1, 0x7d, 0xa8, 0x4d, 0x38, 0xce, 0xa6, 0x7e, 0x5d, 0xc3, 0x26,
-	0x50, 0xd9, 0x42, 0xab, 0xf6, 0x1b, 0x26, 0xdb, 0x44, 0xaa, 0xf3, 0x68, 0x4a, 0xa2, 0x59, 0xc6,
-	0x68, 0x2a, 0x7d, 0xc7, 0x68, 0x5c, 0x23, 0xea, 0x1c, 0x27, 0x58, 0xb0, 0xd4, 0x6f, 0x9a, 0x73,
-	0x26, 0x42, 0x6d, 0x70, 0xe2, 0x9c, 0x63, 0x49, 0x59, 0xea, 0x83, 0x66, 0x56, 0x71, 0x70, 0xb0,
-	0xd5, 0x2c, 0x11, 0x7c, 0x82, 0xdd, 0x73, 0x22, 0x2f, 0x38, 0xfb, 0x42, 0x22, 0x79, 0x8b, 0x7b,
-	0xcf, 0x60, 0x8f, 0xa6, 0x51, 0x92, 0xc7, 0x64, 0xa4, 0xd7, 0xe0, 0x8a, 0x26, 0x44, 0x5b, 0xe8,
-	0x84, 0x2d, 0x4b, 0x0c, 0x97, 0x78, 0xf0, 0xa3, 0xb2, 0x59, 0x59, 0xa0, 0x07, 0xe0, 0x45, 0x2c,
-	0x95, 0x98, 0xa6, 0x84, 0xaf, 0xab, 0xbb, 0x2b, 0x6c, 0x9b, 0xfb, 0xe5, 0x2d, 0xee, 0xff, 0x07,
-	0xf5, 0xeb, 0x48, 0xac, 0x67, 0x53, 0xbb, 0x8e, 0xc4, 0x86, 0xf7, 0xd5, 0x0d, 0xef, 0x11, 0x54,
-	0x53, 0x3c, 0x27, 0x76, 0x22, 0xfa, 0x1b, 0xdd, 0x81, 0x66, 0x94, 0xb0, 0x94, 0x8c, 0x72, 0x9e,
-	0xd8, 0x99, 0x38, 0x1a, 0xb8, 0xe4, 0x89, 0xb2, 0x31, 0xc1, 0xe9, 0x24, 0xc7, 0x13, 0x62, 0x07,
-	0xb3, 0x8a, 0x51, 0x07, 0x5c, 0x1c, 0x45, 0x44, 0x08, 0xc9, 0x66, 0x24, 0xb5, 0xb3, 0x29, 0x42,
-	0xba, 0x34, 0x9b, 0xcf, 0xa9, 0x54, 0x02, 0x9b, 0xb6, 0xb4, 0x06, 0x7a, 0xb1, 0xb2, 0x40, 0x48,
-	0xc6, 0xf1, 0x84, 0x8c, 0x32, 0x2c, 0xa7, 0x76, 0x4a, 0xae, 0xc5, 0x2e, 0xb0, 0x34, 0x4b, 0xc1,
-	0x72, 0x1e, 0x11, 0xdf, 0xb5, 0x4b, 0xa1, 0x23, 0x74, 0x17, 0x9a, 0x6b, 0xd3, 0x3d, 0x4d, 0xad,
-	0x01, 0xf4, 0x18, 0x1a, 0xb6, 0x88, 0xbf, 0xdb, 0x29, 0x1d, 0xb9, 0x5d, 0xef, 0x18, 0x67, 0xf4,
-	0x78, 0x68, 0xb0, 0x70, 0x49, 0x06, 0xdf, 0x4b, 0xe0, 0xbd, 0xa7, 0x29, 0x65, 0x96, 0x51, 0xee,
-	0x4c, 0x99, 0x90, 0x76, 0x18, 0xfa, 0x5b, 0x35, 0x19, 0x11, 0x2e, 0xe9, 0x15, 0x8d, 0xb0, 0x24,
-	0x76, 0x06, 0x45, 0x08, 0xdd, 0x03, 0x30, 0x3d, 0x8f, 0xd4, 0x55, 0x36, 0x63, 0x68, 0x1a, 0xa4,
-	0x4f, 0x16, 0x8a, 0x16, 0x24, 0xe2, 0x44, 0x6a, 0xba, 0x6a, 0xc5, 0x6a, 0xa4, 0x4f, 0x16, 0x81,
-	0x07, 0x30, 0x38, 0x1b, 0x5a, 0x05, 0xc1, 0x67, 0x68, 0x2c, 0xc5, 0x3c, 0x82, 0xea, 0x8c, 0xa6,
-	0x66, 0x33, 0xfe, 0xe9, 0xb6, 0x8a, 0x2d, 0xf4, 0x69, 0x1a, 0x87, 0x9a, 0x45, 0x4f, 0xa0, 0x36,
-	0x57, 0x2d, 0x68, 0x61, 0x6e, 0x77, 0x4f, 0xa7, 0x15, 0x9b, 0x0a, 0x0d, 0xff, 0xf4, 0x23, 0xb8,
-	0x85, 0xd3, 0xc8, 0x81, 0xea, 0xe0, 0xc3, 0xe0, 0x6d, 0x6b, 0x07, 0x35, 0xa1, 0xa6, 0xf3, 0x5b,
-	0x25, 0xe4, 0x81, 0xf3, 0x7a, 0x8e, 0xbf, 0xb1, 0x74, 0x78, 0xd2, 0x2a, 0xa3, 0x03, 0x40, 0xe7,
-	0x8c, 0x4d, 0x12, 0xf2, 0x26, 0x61, 0x79, 0x6c, 0x0f, 0xb7, 0x2a, 0xa8, 0x01, 0x95, 0xc1, 0xd9,
-	0xb0, 0x55, 0xed, 0xfe, 0x2a, 0x41, 0x4d, 0xef, 0x38, 0x7a, 0x09, 0xce, 0xf2, 0xb5, 0x42, 0x46,
-	0x69, 0xe1, 0x19, 0x6c, 0xff, 0x89, 0x88, 0x60, 0x07, 0xbd, 0x02, 0x58, 0x5f, 0x08, 0x84, 0x74,
-	0xc6, 0xc6, 0xdd, 0x6b, 0xdf, 0xc4, 0xd4, 0xb9, 0x3e, 0xec, 0xdd, 0xb8, 0xba, 0xe8, 0x50, 0xa7,
-	0x6e, 0x7b, 0xff, 0xda, 0x7f, 0xa5, 0x44, 0xb0, 0x33, 0xae, 0xeb, 0xa7, 0xfb, 0xe4, 0x77, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0xfc, 0x2a, 0xcc, 0x64, 0xcd, 0x05, 0x00, 0x00,
+func init() { proto.RegisterFile("api/shift.proto", fileDescriptor_shift_dab264dc211e9d19) }
+
+var fileDescriptor_shift_dab264dc211e9d19 = []byte{
+	// 906 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4b, 0x6f, 0x23, 0x45,
+	0x10, 0x8e, 0xdf, 0xe3, 0xf2, 0x64, 0xd7, 0x69, 0x85, 0x30, 0x6b, 0x1e, 0x0a, 0x03, 0x82, 0x15,
+	0x88, 0x80, 0x12, 0x89, 0x03, 0xe2, 0x42, 0x56, 0x6c, 0x36, 0xb2, 0x08, 0xcb, 0x98, 0x15, 0xdc,
+	0xac, 0xf6, 0x4c, 0xc5, 0x6e, 0x32, 0x9e, 0x1e, 0xba, 0x7b, 0x82, 0xc2, 0x91, 0xbf, 0xc0, 0xcf,
+	0xe0, 0x9f, 0x71, 0xe7, 0x8e, 0xfa, 0x61, 0x4f, 0x3b, 0xf6, 0xb2, 0x2b, 0xed, 0xad, 0xeb, 0xab,
+	0xea, 0x72, 0x3d, 0xbe, 0xf9, 0xda, 0xf0, 0x90, 0x96, 0xec, 0x0b, 0xb9, 0x60, 0xd7, 0xea, 0xa4,
+	0x14, 0x5c, 0x71, 0xd2, 0xa2, 0x25, 0x8b, 0x9f, 0xc1, 0x20, 0xc1, 0x39, 0x93, 0x0a, 0x45, 0x82,
+	0xbf, 0x91, 0x47, 0x10, 0xcc, 0x2a, 0x96, 0x67, 0x53, 0x96, 0x45, 0x8d, 0xe3, 0xc6, 0xe3, 0x7e,
+	0xd2, 0x33, 0xf6, 0x65, 0x46, 0xde, 0x07, 0x28, 0x05, 0xbb, 0xa5, 0x0a, 0x6f, 0xf0, 0x2e, 0x6a,
+	0x1a, 0xa7, 0x87, 0xc4, 0x9f, 0xfb, 0x99, 0xa4, 0x0e, 0x17, 0xce, 0x44, 0x9b, 0x2b, 0x48, 0x3c,
+	0x24, 0xfe, 0xbb, 0x09, 0x87, 0x2f, 0xca, 0x8c, 0x2a, 0x3c, 0xd7, 0x3f, 0x30, 0x51, 0x54, 0x55,
+	0xf2, 0x15, 0x25, 0x1c, 0x43, 0x28, 0xab, 0xd9, 0x74, 0xed, 0x76, 0x45, 0xc8, 0x6a, 0x76, 0xee,
+	0x22, 0x3e, 0x84, 0x7d, 0x81, 0x25, 0x97, 0x4c, 0x71, 0x71, 0xa7, 0x43, 0x5a, 0x26, 0x24, 0xac,
+	0xc1, 0xcb, 0x8c, 0xbc, 0x0d, 0x3d, 0x85, 0x74, 0xa9, 0xdd, 0x6d, 0xe3, 0xee, 0x6a, 0xf3, 0x32,
+	0x23, 0x47, 0xd0, 0x9d, 0x09, 0x5a, 0xa4, 0x8b, 0xa8, 0x63, 0x71, 0x6b, 0x91, 0x43, 0xe8, 0xcc,
+	0x05, 0x2d, 0x17, 0x51, 0xd7, 0xc0, 0xd6, 0xd0, 0xd1, 0xd2, 0x54, 0x1d, 0xf5, 0x6c, 0xb4, 0xb5,
+	0x74, 0xe7, 0xe9, 0x02, 0xd3, 0x9b, 0x92, 0xb3, 0x42, 0x45, 0x81, 0xad, 0xb1, 0x46, 0xf4, 0x3d,
+	0x81, 0x54, 0xf2, 0x22, 0xea, 0xdb, 0x7b, 0xd6, 0x22, 0x23, 0x08, 0xb2, 0x4a, 0x50, 0xc5, 0x78,
+	0x11, 0x81, 0xf1, 0xac, 0xed, 0xf8, 0x68, 0xe7, 0xb0, 0x64, 0xfc, 0x33, 0xec, 0x5f, 0xa0, 0x7a,
+	0x2e, 0xf8, 0xaf, 0x98, 0xaa, 0x57, 0x4c, 0xef, 0x33, 0x38, 0x60, 0x45, 0x9a, 0x57, 0x19, 0x4e,
+	0x0d, 0x0d, 0xae, 0x59, 0x8e, 0x66, 0x84, 0x41, 0x32, 0x74, 0x8e, 0xc9, 0x0a, 0x8f, 0xff, 0x6a,
+	0x6d, 0x66, 0x96, 0xe4, 0x03, 0x08, 0x53, 0x5e, 0x28, 0xca, 0x0a, 0x14, 0x75, 0xf6, 0xc1, 0x1a,
+	0xdb, 0x35, 0xfd, 0xe6, 0x8e, 0xe9, 0xbf, 0x05, 0xdd, 0xdb, 0x54, 0xd6, 0xbb, 0xe9, 0xdc, 0xa6,
+	0x72, 0x63, 0xf6, 0xed, 0x8d, 0xd9, 0x13, 0x68, 0x17, 0x74, 0x89, 0x6e, 0x23, 0xe6, 0x4c, 0xde,
+	0x81, 0x7e, 0x9a, 0xf3, 0x02, 0xa7, 0x95, 0xc8, 0xdd, 0x4e, 0x02, 0x03, 0xbc, 0x10, 0xb9, 0x1e,
+	0x63, 0x4e, 0x8b, 0x79, 0x45, 0xe7, 0xe8, 0x16, 0xb3, 0xb6, 0xc9, 0x31, 0x0c, 0x68, 0x9a, 0xa2,
+	0x94, 0x8a, 0xdf, 0x60, 0xe1, 0x76, 0xe3, 0x43, 0x26, 0x35, 0x5f, 0x2e, 0x99, 0xd2, 0x05, 0xf6,
+	0x5d, 0x6a, 0x03, 0x5c, 0x66, 0x7a, 0x04, 0x52, 0x71, 0x41, 0xe7, 0x38, 0x2d, 0xa9, 0x5a, 0xb8,
+	0x2d, 0x0d, 0x1c, 0xf6, 0x9c, 0x2a, 0x4b, 0x0a, 0x5e, 0x89, 0x14, 0xa3, 0x81, 0x23, 0x85, 0xb1,
+	0xc8, 0xbb, 0xd0, 0xaf, 0x87, 0x1e, 0x1a, 0x57, 0x0d, 0x90, 0x8f, 0xa1, 0xe7, 0x92, 0x44, 0xfb,
+	0xc7, 0x8d, 0xc7, 0x83, 0xd3, 0xf0, 0x84, 0x96, 0xec, 0x64, 0x62, 0xb1, 0x64, 0xe5, 0x8c, 0xff,
+	0x6c, 0x40, 0xf8, 0x3d, 0x2b, 0x18, 0x77, 0x1e, 0x3d, 0x9d, 0x05, 0x97, 0xca, 0x2d, 0xc3, 0x9c,
+	0x75, 0x93, 0x29, 0x0a, 0xc5, 0xae, 0x59, 0x4a, 0x15, 0xba, 0x1d, 0xf8, 0x10, 0x79, 0x0f, 0xc0,
+	0xf6, 0x3c, 0xd5, 0x9f, 0xb2, 0x5d, 0x43, 0xdf, 0x22, 0x63, 0xbc, 0xd3, 0x6e, 0x89, 0xa9, 0x40,
+	0x65, 0xdc, 0x6d, 0x57, 0xac, 0x41, 0xc6, 0x78, 0x17, 0x87, 0x00, 0x57, 0x4f, 0x27, 0xae, 0x82,
+	0xf8, 0x17, 0xe8, 0xad, 0x8a, 0xf9, 0x08, 0xda, 0x37, 0xac, 0xb0, 0xcc, 0x78, 0x70, 0x3a, 0xf4,
+	0x5b, 0x18, 0xb3, 0x22, 0x4b, 0x8c, 0x97, 0x7c, 0x02, 0x9d, 0xa5, 0x6e, 0xc1, 0x14, 0x36, 0x38,
+	0x3d, 0x30, 0x61, 0x7e, 0x53, 0x89, 0xf5, 0xc7, 0xff, 0x34, 0xe0, 0xe1, 0x4f, 0x82, 0xcd, 0xe7,
+	0x28, 0x0c, 0xeb, 0xdf, 0x58, 0x1c, 0x34, 0x95, 0x78, 0x86, 0xae, 0x61, 0x73, 0xb6, 0x32, 0xb5,
+	0x62, 0xa7, 0xeb, 0xd5, 0x43, 0x5e, 0x2a, 0x09, 0x23, 0x08, 0x4a, 0x56, 0x62, 0xce, 0x0a, 0x5c,
+	0x31, 0x70, 0x65, 0x1b, 0xa5, 0xa4, 0x82, 0x2e, 0x51, 0xa1, 0xd0, 0xe2, 0xd0, 0x32, 0x4a, 0xb9,
+	0x46, 0x74, 0x1d, 0xbf, 0x53, 0x66, 0xa5, 0x21, 0x48, 0xcc, 0x39, 0x66, 0xf7, 0x7b, 0x95, 0xff,
+	0xd7, 0xeb, 0x6b, 0x7d, 0x68, 0xb5, 0x3e, 0xb5, 0x7c, 0x7d, 0x8a, 0x4f, 0xe0, 0xe0, 0x02, 0xd5,
+	0x6b, 0xab, 0x6e, 0xfc, 0x64, 0x3b, 0x5e, 0x7a, 0xc9, 0x1b, 0x1b, 0xe2, 0x57, 0x8b, 0x5b, 0xd3,
+	0x17, 0xb7, 0x78, 0x0c, 0xe1, 0x33, 0xa4, 0x42, 0xcd, 0x90, 0xaa, 0x37, 0x5d, 0x64, 0xfc, 0xf5,
+	0x46, 0x32, 0x33, 0x50, 0xa9, 0x78, 0xe9, 0x5e, 0x19, 0x73, 0xf6, 0x0a, 0x6c, 0xfa, 0x05, 0x7e,
+	0xfa, 0x23, 0x0c, 0x3c, 0x4e, 0x92, 0x00, 0xda, 0x57, 0x3f, 0x5c, 0x7d, 0x37, 0xdc, 0x23, 0x7d,
+	0xe8, 0x18, 0x16, 0x0e, 0x1b, 0x24, 0x84, 0xe0, 0xdb, 0x25, 0xfd, 0x83, 0x17, 0x93, 0xb3, 0x61,
+	0x93, 0x1c, 0x01, 0xb9, 0xe0, 0x7c, 0x9e, 0xe3, 0x93, 0x9c, 0x57, 0x99, 0xbb, 0x3c, 0x6c, 0x91,
+	0x1e, 0xb4, 0xae, 0x9e, 0x4e, 0x86, 0xed, 0xd3, 0x7f, 0x9b, 0xd0, 0x31, 0xca, 0x49, 0xbe, 0x84,
+	0x60, 0xf5, 0x06, 0x12, 0xcb, 0x7f, 0xef, 0x71, 0x1d, 0xdd, 0x47, 0x64, 0xbc, 0x47, 0xbe, 0x02,
+	0xa8, 0x65, 0x96, 0x10, 0x13, 0xb1, 0xa1, 0xe8, 0xa3, 0x6d, 0x4c, 0xdf, 0x1b, 0xc3, 0xc1, 0xd6,
+	0x83, 0x40, 0x1e, 0x99, 0xd0, 0x5d, 0xaf, 0xea, 0xe8, 0xa5, 0x2e, 0x9d, 0xec, 0x1b, 0x08, 0x7d,
+	0xf2, 0x91, 0x43, 0x13, 0x7c, 0xef, 0xdb, 0x1b, 0xed, 0x42, 0xf5, 0xed, 0x73, 0x78, 0xb0, 0xc9,
+	0x0f, 0x72, 0xb4, 0x2a, 0xf9, 0x5e, 0x11, 0xbb, 0x71, 0x9d, 0xe3, 0x0c, 0xfa, 0xeb, 0x8d, 0x12,
+	0x2b, 0x09, 0x3e, 0x5d, 0x46, 0x5b, 0x90, 0x8c, 0xf7, 0x66, 0x5d, 0xf3, 0x3f, 0xe6, 0xec, 0xbf,
+	0x01, 0x00, 0x86, 0xe2, 0x41, 0x42, 0xda, 0x08, 0x00, 0x00,
 }
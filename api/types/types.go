@@ -5,6 +5,7 @@ package types
 
 import (
 	"encoding/base64"
+	"strings"
 	"time"
 
 	"gopkg.in/mgo.v2/bson"
@@ -54,6 +55,13 @@ type VCSSysConf struct {
 	Secret      string `bson:"secret,omitempty" json:"secret"`
 	CallbackURL string `bson:"callback_url,omitempty" json:"callback_url"`
 	HookURL     string `bson:"hook_url,omitempty" json:"hook_url"`
+	HookSecret  string `bson:"hook_secret,omitempty" json:"-"`
+
+	// kind of the provider such as github, gitlab etc, the name is
+	// used when not set. The api & web url points to self-hosted systems.
+	Provider string `bson:"provider,omitempty" json:"provider"`
+	APIURL   string `bson:"api_url,omitempty" json:"api_url"`
+	WebURL   string `bson:"web_url,omitempty" json:"web_url"`
 }
 
 // Team ..
@@ -63,6 +71,9 @@ type Team struct {
 	Display    string        `bson:"display,omitempty"`
 	Accounts   []VCS         `bson:"accounts"`
 	KubeConfig KubeConfig    `json:"-" bson:"kube_config"`
+
+	// build history kept for the repositories of the team
+	Retention *Retention `bson:"retention,omitempty"`
 }
 
 // User ..
@@ -95,6 +106,7 @@ type VCS struct {
 	RefreshToken string    `json:"refresh_token" bson:"refresh_token,omitempty"`
 	TokenExpiry  time.Time `json:"token_expiry" bson:"token_expiry,omitempty"`
 	SecretID     string    `json:"-" bson:"secret_id"`
+	SyncedAt     time.Time `json:"synced_at" bson:"synced_at,omitempty"`
 }
 
 // Repository ..
@@ -114,6 +126,104 @@ type Repository struct {
 	Identifier    string        `json:"-" bson:"identifier"`
 	Source        string        `json:"source" bson:"source"`
 	Team          string        `json:"-" bson:"team"`
+	HookEnabled   bool          `json:"hook_enabled" bson:"hook_enabled,omitempty"`
+
+	// plain git repositories, not linked to any vcs account
+	Auth         string    `json:"auth" bson:"auth,omitempty"`
+	PublicKey    string    `json:"public_key" bson:"public_key,omitempty"`
+	HostKeys     string    `json:"host_keys" bson:"host_keys,omitempty"`
+	SecretID     string    `json:"-" bson:"secret_id,omitempty"`
+	PollInterval int       `json:"poll_interval" bson:"poll_interval,omitempty"`
+	PolledAt     time.Time `json:"polled_at" bson:"polled_at,omitempty"`
+	Heads        []Head    `json:"-" bson:"heads,omitempty"`
+
+	// monorepo, pipelines built on the changes of their paths
+	Pipelines []Pipeline `json:"pipelines" bson:"pipelines,omitempty"`
+
+	// reconciled with the vcs or the git server in background
+	SyncStatus string    `json:"sync_status" bson:"sync_status,omitempty"`
+	SyncedAt   time.Time `json:"synced_at" bson:"synced_at,omitempty"`
+
+	// concurrent builds of the repository, unlimited when zero,
+	// and the default priority of its builds
+	MaxConcurrentBuilds int `json:"max_concurrent_builds" bson:"max_concurrent_builds,omitempty"`
+	Priority            int `json:"priority" bson:"priority,omitempty"`
+
+	AutoCancel AutoCancel `json:"auto_cancel" bson:"auto_cancel,omitempty"`
+
+	// build history kept, overrides the retention of the team
+	Retention *Retention `json:"retention" bson:"retention,omitempty"`
+
+	// number of the last build, incremented for every build
+	BuildNumber int `json:"-" bson:"build_number,omitempty"`
+
+	// token given to view the status badge of the private repository
+	BadgeToken string `json:"-" bson:"badge_token,omitempty"`
+
+	// blocks allowed to fail without failing the build, by their
+	// description, such as the ones found to be flaky
+	Quarantine []string `json:"quarantine" bson:"quarantine,omitempty"`
+}
+
+// AutoCancel ..
+// Cancels the older builds of a branch when a new commit is pushed to it,
+// the default branch is exempted unless it's configured too.
+type AutoCancel struct {
+	Enabled       bool `json:"enabled" bson:"enabled"`
+	DefaultBranch bool `json:"default_branch" bson:"default_branch,omitempty"`
+}
+
+// Pipeline ..
+// Builds a part of the repository (monorepo) through its own Shiftfile,
+// triggered when the changed files match the include globs and none of
+// the exclude globs. No include globs matches all the files. The shiftfile
+// may be a glob (services/*/Shiftfile), which discovers a pipeline for each
+// Shiftfile found, named by its directory and triggered by its changes.
+type Pipeline struct {
+	Name      string   `json:"name" bson:"name"`
+	Shiftfile string   `json:"shiftfile" bson:"shiftfile"`
+	Include   []string `json:"include" bson:"include,omitempty"`
+	Exclude   []string `json:"exclude" bson:"exclude,omitempty"`
+}
+
+// Head ..
+// Commit that the branch of the plain git repository points to
+type Head struct {
+	Branch   string `json:"branch" bson:"branch"`
+	CommitID string `json:"commit_id" bson:"commit_id"`
+}
+
+// Authentication of the plain git repositories
+const (
+	RepoAuthNone   = "none"
+	RepoAuthSSHKey = "ssh_key"
+	RepoAuthBasic  = "basic"
+)
+
+// Sync status of the repositories
+const (
+	RepoSyncOK         = "ok"
+	RepoSyncNotFound   = "not_found"
+	RepoSyncAccessLost = "access_lost"
+)
+
+// Plain ..
+// True if the repository is a plain git repository that is
+// registered by url, the changes are detected through polling.
+func (r Repository) Plain() bool {
+	return r.Auth != ""
+}
+
+// Owner ..
+// Returns the account that owns the repository on the version control system
+func (r Repository) Owner() string {
+
+	// identifier is in the form of <source>/<owner>
+	idx := strings.Index(r.Identifier, "/")
+	if idx == -1 {
+		return ""
+	}
+	return r.Identifier[idx+1:]
 }
 
 type VCSList struct {
@@ -190,7 +300,105 @@ type Build struct {
 	StoragePath       string        `json:"-" bson:"storage_path"`
 	Privatekey        string        `json:"-" bson:"private_key,omitempty"`
 	Source            string        `json:"source" bson:"source"`
+	CommitID          string        `json:"commit_id" bson:"commit_id,omitempty"`
 	SubBuilds         []SubBuild    `json:"sub_builds" bson:"sub_builds,omitempty"`
+	Pipeline          string        `json:"pipeline" bson:"pipeline,omitempty"`
+	Shiftfile         string        `json:"shiftfile" bson:"shiftfile,omitempty"`
+	Directives        Directives    `json:"directives" bson:"directives,omitempty"`
+
+	// sequential number of the build in the repository, such as #512
+	Number int `json:"number" bson:"number,omitempty"`
+
+	// queued builds of a higher priority are launched first
+	Priority int `json:"priority" bson:"priority,omitempty"`
+
+	// concurrency group of the build given in the Shiftfile, and the
+	// group the queued build is waiting for, held by another build
+	Concurrency string `json:"concurrency" bson:"concurrency,omitempty"`
+	HeldBy      string `json:"held_by" bson:"held_by,omitempty"`
+
+	// the build of a newer commit that cancelled this build
+	SupersededBy string `json:"superseded_by" bson:"superseded_by,omitempty"`
+
+	// shiftfile resolved when the build was launched, a rerun is built
+	// with the same shiftfile even if it's changed on the branch since
+	ResolvedShiftfile string `json:"-" bson:"resolved_shiftfile,omitempty"`
+
+	// the build this build reruns, resumed from the node with the workspace
+	// restored from the snapshots of the build that ran the nodes before
+	RerunOf    string `json:"rerun_of" bson:"rerun_of,omitempty"`
+	FromNode   string `json:"from_node" bson:"from_node,omitempty"`
+	SnapshotOf string `json:"-" bson:"snapshot_of,omitempty"`
+
+	// the build that triggered this build through a trigger block, and the
+	// builds triggered by this build, with the parameters given to the build
+	Upstream   *BuildLink        `json:"upstream" bson:"upstream,omitempty"`
+	Downstream []BuildLink       `json:"downstream" bson:"downstream,omitempty"`
+	Parameters map[string]string `json:"parameters" bson:"parameters,omitempty"`
+
+	// the downstream build the build waits for, the limits and the
+	// concurrency group held by the build are released meanwhile
+	WaitingFor string `json:"waiting_for" bson:"waiting_for,omitempty"`
+
+	// the finished build is counted in the daily rollups of the analytics
+	RolledUp bool `json:"-" bson:"rolled_up,omitempty"`
+
+	// computed for the queued builds, not persisted
+	QueuePosition  int       `json:"queue_position" bson:"-"`
+	EstimatedStart time.Time `json:"estimated_start" bson:"-"`
+}
+
+// Directives ..
+// Instructions to the build given through the commit message or
+// the pull request labels, such as [skip ci]
+type Directives struct {
+	Skip         bool     `json:"skip" bson:"skip,omitempty"`
+	RebuildCache bool     `json:"rebuild_cache" bson:"rebuild_cache,omitempty"`
+	Only         []string `json:"only" bson:"only,omitempty"`
+	Debug        bool     `json:"debug" bson:"debug,omitempty"`
+}
+
+// BuildLink ..
+// Links the builds of a pipeline across the repositories, the upstream
+// build triggers the downstream build from one of its blocks
+type BuildLink struct {
+	BuildID      string `json:"build_id" bson:"build_id"`
+	RepositoryID string `json:"repository_id" bson:"repository_id"`
+	Branch       string `json:"branch" bson:"branch,omitempty"`
+	Pipeline     string `json:"pipeline" bson:"pipeline,omitempty"`
+
+	// the trigger block of the upstream build, and whether
+	// it waits for the downstream build to finish
+	Node string `json:"node" bson:"node,omitempty"`
+	Wait bool   `json:"wait" bson:"wait,omitempty"`
+}
+
+// PipelineGraph ..
+// The builds linked through the trigger blocks, starting from the build
+// that isn't triggered by any other build
+type PipelineGraph struct {
+	Nodes []PipelineNode `json:"nodes"`
+	Edges []PipelineEdge `json:"edges"`
+}
+
+// PipelineNode ..
+type PipelineNode struct {
+	BuildID      string `json:"build_id"`
+	Number       int    `json:"number"`
+	RepositoryID string `json:"repository_id"`
+	Repository   string `json:"repository"`
+	Branch       string `json:"branch"`
+	Pipeline     string `json:"pipeline"`
+	Status       string `json:"status"`
+	TriggeredBy  string `json:"triggered_by"`
+}
+
+// PipelineEdge ..
+type PipelineEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Node string `json:"node"`
+	Wait bool   `json:"wait"`
 }
 
 type SubBuild struct {
@@ -203,6 +411,74 @@ type SubBuild struct {
 	StartedAt time.Time `json:"started_at" bson:"started_at,omitempty"`
 	EndedAt   time.Time `json:"ended_at" bson:"ended_at,omitempty"`
 	Metadata  *Metadata `json:"-" bson:"metadata,omitempty"`
+
+	// when the container was launched, and the last time the worker
+	// reported alive, the build is stuck when the heartbeats are missed
+	LaunchedAt  time.Time `json:"launched_at" bson:"launched_at,omitempty"`
+	HeartbeatAt time.Time `json:"heartbeat_at" bson:"heartbeat_at,omitempty"`
+
+	// seconds the sub build is expected to take, from the recent runs of
+	// its nodes, the percentage done and when it's expected to end are
+	// computed from the graph when it's read
+	Estimate float64   `json:"estimate" bson:"estimate,omitempty"`
+	Progress int       `json:"progress" bson:"-"`
+	ETA      time.Time `json:"eta" bson:"-"`
+
+	// incremented on every update, the update of a sub build
+	// changed since it was read is refused
+	Version int `json:"-" bson:"version,omitempty"`
+}
+
+// HookDelivery ..
+// Records a webhook delivery, so the redelivery of same event is ignored
+type HookDelivery struct {
+	ID         string    `json:"id" bson:"_id"`
+	Provider   string    `json:"provider" bson:"provider"`
+	Event      string    `json:"event" bson:"event"`
+	ReceivedAt time.Time `json:"received_at" bson:"received_at"`
+}
+
+// ConcurrencyLock ..
+// Held by the build running in the concurrency group
+type ConcurrencyLock struct {
+	ID         string    `json:"-" bson:"_id"`
+	Team       string    `json:"team" bson:"team"`
+	Group      string    `json:"group" bson:"group"`
+	BuildID    string    `json:"build_id" bson:"build_id"`
+	AcquiredAt time.Time `json:"acquired_at" bson:"acquired_at"`
+}
+
+// BuildSlot ..
+// Builds holding the slots of a limit, such as the concurrent builds
+// of a team, the holders never exceed the limit.
+type BuildSlot struct {
+	Key     string   `json:"key" bson:"_id"`
+	Holders []string `json:"holders" bson:"holders"`
+}
+
+// QueuedBuild ..
+// A build waiting in the queue to be launched, leased by a launcher
+// until the container is created
+type QueuedBuild struct {
+	ID           bson.ObjectId `json:"id" bson:"_id"`
+	Team         string        `json:"team" bson:"team"`
+	RepositoryID string        `json:"repository_id" bson:"repository_id"`
+	EngineID     string        `json:"container_engine_id" bson:"container_engine_id"`
+	Priority     int           `json:"priority" bson:"priority"`
+	EnqueuedAt   time.Time     `json:"enqueued_at" bson:"enqueued_at"`
+	VisibleAt    time.Time     `json:"visible_at" bson:"visible_at"`
+	LeasedBy     string        `json:"leased_by" bson:"leased_by,omitempty"`
+	Attempts     int           `json:"attempts" bson:"attempts"`
+}
+
+// ActiveBuilds ..
+// Number of the builds having a container, of a team and repository
+// on the container engine
+type ActiveBuilds struct {
+	Team         string `json:"team" bson:"team"`
+	RepositoryID string `json:"repository_id" bson:"repository_id"`
+	EngineID     string `json:"container_engine_id" bson:"container_engine_id"`
+	Count        int    `json:"count" bson:"count"`
 }
 
 type BuildContainer struct {
@@ -248,6 +524,11 @@ type Container struct {
 	Duration        string          `json:"duration" bson:"duration"`
 	Kind            string          `json:"kind" bson:"kind"`
 	Status          ContainerStatus `json:"status" bson:"status"`
+
+	// team of the container engine, and the reason the
+	// container was removed by the garbage collector
+	Team   string `json:"team" bson:"team,omitempty"`
+	Reason string `json:"reason" bson:"reason,omitempty"`
 }
 
 type ContainerList struct {
@@ -290,6 +571,9 @@ type ContainerEngine struct {
 	Team         string        `json:"team" bson:"team"`
 	Version      string        `json:"version" bson:"version,omitempty"`
 	KubeFile     KubeConfig    `json:"kube_config" bson:"kube_config,omitempty"`
+
+	// concurrent builds run on the engine, unlimited when zero
+	MaxConcurrentBuilds int `json:"max_concurrent_builds" bson:"max_concurrent_builds,omitempty"`
 }
 
 type ContainerEngineList struct {
@@ -379,6 +663,9 @@ type Default struct {
 	ContainerEngineID string            `json:"container_engine_id" bson:"container_engine_id,omitempty"`
 	StorageID         string            `json:"storage_id" bson:"storage_id,omitempty"`
 	Languages         map[string]string `json:"languages" bson:"languages,omitempty"`
+
+	// concurrent builds of the team, unlimited when zero
+	MaxConcurrentBuilds int `json:"max_concurrent_builds" bson:"max_concurrent_builds,omitempty"`
 }
 
 type KubeConfig []byte
@@ -435,6 +722,170 @@ type ShiftfileList struct {
 	Count int         `json:"count"`
 }
 
+// Schedule ..
+// Triggers the builds of a repository branch periodically, at the times
+// given by the cron expression in the time zone.
+type Schedule struct {
+	ID           bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	Team         string        `json:"team" bson:"team"`
+	RepositoryID string        `json:"repository_id" bson:"repository_id"`
+	Branch       string        `json:"branch" bson:"branch,omitempty"`
+	Cron         string        `json:"cron" bson:"cron"`
+	Timezone     string        `json:"timezone" bson:"timezone,omitempty"`
+	Enabled      bool          `json:"enabled" bson:"enabled"`
+
+	// how the builds are triggered, the parameters are set as their environment
+	Pipeline   string            `json:"pipeline" bson:"pipeline,omitempty"`
+	Priority   int               `json:"priority" bson:"priority,omitempty"`
+	Directives Directives        `json:"directives" bson:"directives,omitempty"`
+	Parameters map[string]string `json:"parameters" bson:"parameters,omitempty"`
+
+	// the run is claimed by moving the next run, so it fires once across the servers
+	LastRunAt   time.Time `json:"last_run_at" bson:"last_run_at,omitempty"`
+	NextRunAt   time.Time `json:"next_run_at" bson:"next_run_at,omitempty"`
+	LastBuildID string    `json:"last_build_id" bson:"last_build_id,omitempty"`
+	LastError   string    `json:"last_error" bson:"last_error,omitempty"`
+
+	CreatedBy string    `json:"created_by" bson:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type ScheduleList struct {
+	Nodes []Schedule `json:"nodes"`
+	Count int        `json:"count"`
+}
+
+// Retention ..
+// Expires the build history, the builds beyond the latest count or older
+// than the days are removed along with their logs and snapshots, unlimited
+// when zero. The last successful build of each branch is always kept.
+type Retention struct {
+	KeepBuilds int `json:"keep_builds" bson:"keep_builds,omitempty"`
+	KeepDays   int `json:"keep_days" bson:"keep_days,omitempty"`
+}
+
+// ExpiredBuild ..
+// A build removed by the retention, and the reason it's expired
+type ExpiredBuild struct {
+	BuildID      string    `json:"build_id"`
+	RepositoryID string    `json:"repository_id"`
+	Branch       string    `json:"branch"`
+	Pipeline     string    `json:"pipeline"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	Reason       string    `json:"reason"`
+}
+
+// RetentionReport ..
+// The builds and the caches that are removed by the retention on its next
+// run, reported without removing them
+type RetentionReport struct {
+	Builds []ExpiredBuild `json:"builds"`
+	Caches []string       `json:"caches"`
+	Count  int            `json:"count"`
+}
+
+// BuildStat ..
+// Daily rollup of the finished builds of a branch, by the day the builds
+// are triggered
+type BuildStat struct {
+	ID           bson.ObjectId `json:"-" bson:"_id,omitempty"`
+	Team         string        `json:"team" bson:"team"`
+	RepositoryID string        `json:"repository_id" bson:"repository_id"`
+	Branch       string        `json:"branch" bson:"branch"`
+	Day          time.Time     `json:"day" bson:"day"`
+	Builds       int           `json:"builds" bson:"builds"`
+	Succeeded    int           `json:"succeeded" bson:"succeeded"`
+	Failed       int           `json:"failed" bson:"failed"`
+	Cancelled    int           `json:"cancelled" bson:"cancelled"`
+	Stuck        int           `json:"stuck" bson:"stuck"`
+}
+
+// BuildTime ..
+// The duration and the queue wait of a finished build in seconds, kept by
+// the build for the percentiles to be computed over any number of days
+type BuildTime struct {
+	ID           bson.ObjectId `json:"-" bson:"_id"`
+	Team         string        `json:"team" bson:"team"`
+	RepositoryID string        `json:"repository_id" bson:"repository_id"`
+	Branch       string        `json:"branch" bson:"branch"`
+	Day          time.Time     `json:"day" bson:"day"`
+	Duration     float64       `json:"duration" bson:"duration,omitempty"`
+	Wait         float64       `json:"wait" bson:"wait,omitempty"`
+}
+
+// NodeStat ..
+// Daily rollup of the runs of a node of the builds of a branch, the
+// skipped and the reused nodes aren't counted
+type NodeStat struct {
+	ID           bson.ObjectId `json:"-" bson:"_id,omitempty"`
+	Team         string        `json:"team" bson:"team"`
+	RepositoryID string        `json:"repository_id" bson:"repository_id"`
+	Branch       string        `json:"branch" bson:"branch"`
+	Day          time.Time     `json:"day" bson:"day"`
+	Node         string        `json:"node" bson:"node"`
+	Runs         int           `json:"runs" bson:"runs"`
+	Failures     int           `json:"failures" bson:"failures"`
+	Duration     float64       `json:"duration" bson:"duration"`
+	MaxDuration  float64       `json:"max_duration" bson:"max_duration"`
+}
+
+// FlakyNode ..
+// A node that both failed and passed on the same commit. The score is the
+// ratio of the reruns on the same commit that flipped the outcome of the node.
+type FlakyNode struct {
+	Node         string    `json:"node"`
+	Runs         int       `json:"runs"`
+	Failures     int       `json:"failures"`
+	Reruns       int       `json:"reruns"`
+	Flips        int       `json:"flips"`
+	Commits      int       `json:"commits"`
+	Score        float64   `json:"score"`
+	LastBuildID  string    `json:"last_build_id"`
+	LastFlakedAt time.Time `json:"last_flaked_at"`
+	Quarantined  bool      `json:"quarantined"`
+}
+
+// Analytics ..
+// Metrics of the builds of a team, repository or branch over the days,
+// the durations are in seconds
+type Analytics struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Builds       int             `json:"builds"`
+	SuccessRate  float64         `json:"success_rate"`
+	DurationP50  float64         `json:"duration_p50"`
+	DurationP95  float64         `json:"duration_p95"`
+	WaitP50      float64         `json:"wait_p50"`
+	WaitP95      float64         `json:"wait_p95"`
+	Series       []AnalyticsDay  `json:"series"`
+	SlowestNodes []NodeAnalytics `json:"slowest_nodes"`
+	FailingNodes []NodeAnalytics `json:"failing_nodes"`
+}
+
+// AnalyticsDay ..
+// The builds of a day, the cancelled builds aren't counted in the success rate
+type AnalyticsDay struct {
+	Day         time.Time `json:"day" bson:"_id"`
+	Builds      int       `json:"builds" bson:"builds"`
+	Succeeded   int       `json:"succeeded" bson:"succeeded"`
+	Failed      int       `json:"failed" bson:"failed"`
+	Cancelled   int       `json:"cancelled" bson:"cancelled"`
+	Stuck       int       `json:"stuck" bson:"stuck"`
+	SuccessRate float64   `json:"success_rate" bson:"-"`
+}
+
+// NodeAnalytics ..
+// The runs of a node over the days
+type NodeAnalytics struct {
+	Node            string  `json:"node" bson:"_id"`
+	Runs            int     `json:"runs" bson:"runs"`
+	Failures        int     `json:"failures" bson:"failures"`
+	FailureRate     float64 `json:"failure_rate" bson:"failure_rate"`
+	AverageDuration float64 `json:"average_duration" bson:"average_duration"`
+	MaxDuration     float64 `json:"max_duration" bson:"max_duration"`
+}
+
 type Metadata struct {
 
 	// general
@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+// colors of the badges
+const (
+	Green  = "#4c1"
+	Red    = "#e05d44"
+	Yellow = "#dfb317"
+	Orange = "#fe7d37"
+	Grey   = "#9f9f9f"
+)
+
+// approximate width of the characters of Verdana 11px, the badges
+// are rendered without measuring the text
+const (
+	charWidth = 7
+	padding   = 10
+)
+
+// Render ..
+// Renders a flat badge of the label on the left and the message on the
+// right, such as "build | passing 2m10s", the message is filled with color
+func Render(label, message, color string) []byte {
+
+	lw := textWidth(label)
+	mw := textWidth(message)
+	w := lw + mw
+
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, w, label, message)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, message)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, w)
+	b.WriteString(`<g clip-path="url(#r)">`)
+	fmt.Fprintf(&b, `<rect width="%d" height="20" fill="#555"/>`, lw)
+	fmt.Fprintf(&b, `<rect x="%d" width="%d" height="20" fill="%s"/>`, lw, mw, html.EscapeString(color))
+	fmt.Fprintf(&b, `<rect width="%d" height="20" fill="url(#s)"/>`, w)
+	b.WriteString(`</g>`)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	writeText(&b, lw/2, label)
+	writeText(&b, lw+mw/2, message)
+	b.WriteString(`</g></svg>`)
+
+	return b.Bytes()
+}
+
+// writeText ..
+// Writes the text centered at x, with the shadow underneath
+func writeText(b *bytes.Buffer, x int, text string) {
+	fmt.Fprintf(b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text>`, x, text)
+	fmt.Fprintf(b, `<text x="%d" y="14">%s</text>`, x, text)
+}
+
+func textWidth(s string) int {
+	return len([]rune(s))*charWidth + padding
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package badge
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+
+	svg := string(Render("build", "passing 2m10s", Green))
+
+	if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>") {
+		t.Fatalf("Expected an svg document, got %s", svg)
+	}
+
+	for _, s := range []string{"<title>build: passing 2m10s</title>", `fill="#4c1"`, `width="146"`} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("Expected the badge to contain %s, got %s", s, svg)
+		}
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+
+	svg := Render("build", "<failed & stuck>", Red)
+
+	var doc struct{}
+	err := xml.Unmarshal(svg, &doc)
+	if err != nil {
+		t.Fatalf("Expected a well formed svg, got %v: %s", err, svg)
+	}
+
+	if !strings.Contains(string(svg), "&lt;failed &amp; stuck&gt;") {
+		t.Errorf("Expected the message to be escaped, got %s", svg)
+	}
+}
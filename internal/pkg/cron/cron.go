@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule ..
+// A parsed cron expression of five fields (minute, hour, day of the month,
+// month and day of the week), each held as the set of values it fires at.
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// a day fires on either of the day fields, unless one of them is *
+	domStar bool
+	dowStar bool
+}
+
+type bounds struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	macros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+
+	// a schedule that doesn't fire within (such as 30th of February) never fires
+	searchLimit = 5
+)
+
+// Parse ..
+// Parses the standard cron expression, such as "30 2 * * 1-5". The fields
+// take *, lists (1,15), ranges (1-5), steps (*/10, 0-30/5) and the names
+// of the months and the days (jan, mon). The macros @yearly, @monthly,
+// @weekly, @daily and @hourly are accepted too.
+func Parse(expr string) (Schedule, error) {
+
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("Invalid cron expression '%s', expected 5 fields but found %d", expr, len(fields))
+	}
+
+	var s Schedule
+	var err error
+
+	s.minute, err = parseField(fields[0], minutes)
+	if err == nil {
+		s.hour, err = parseField(fields[1], hours)
+	}
+
+	if err == nil {
+		s.dom, err = parseField(fields[2], doms)
+	}
+
+	if err == nil {
+		s.month, err = parseField(fields[3], months)
+	}
+
+	if err == nil {
+		s.dow, err = parseField(fields[4], dows)
+	}
+
+	if err != nil {
+		return Schedule{}, fmt.Errorf("Invalid cron expression '%s': %v", expr, err)
+	}
+
+	// sunday is either 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		var from, to int
+		switch {
+		case part == "*" || part == "?":
+			from, to = b.min, b.max
+		case strings.Contains(part, "-"):
+
+			i := strings.Index(part, "-")
+
+			var err error
+			from, err = value(part[:i], b)
+			if err == nil {
+				to, err = value(part[i+1:], b)
+			}
+
+			if err != nil {
+				return 0, err
+			}
+		default:
+
+			var err error
+			from, err = value(part, b)
+			if err != nil {
+				return 0, err
+			}
+
+			to = from
+			if step > 1 {
+				to = b.max
+			}
+		}
+
+		if from > to {
+			return 0, fmt.Errorf("invalid range '%s'", part)
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return set, nil
+}
+
+func value(s string, b bounds) (int, error) {
+
+	if n, ok := b.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+
+	if n < b.min || n > b.max {
+		return 0, fmt.Errorf("value %d is out of range %d-%d", n, b.min, b.max)
+	}
+	return n, nil
+}
+
+// Next ..
+// Returns the time the schedule fires next, after the given time and in
+// its location. Returns zero time if the schedule never fires.
+func (s Schedule) Next(t time.Time) time.Time {
+
+	loc := t.Location()
+
+	// fires at the start of a minute, after the given time
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(searchLimit, 0, 0)
+
+	for t.Before(limit) {
+
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+
+	from := time.Date(2018, time.December, 28, 10, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"* * * * *", from, time.Date(2018, time.December, 28, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", from, time.Date(2018, time.December, 28, 10, 30, 0, 0, time.UTC)},
+		{"0 2 * * *", from, time.Date(2018, time.December, 29, 2, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2018, time.December, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", from, time.Date(2018, time.December, 28, 11, 0, 0, 0, time.UTC)},
+		{"30 1 * * mon-fri", from, time.Date(2018, time.December, 31, 1, 30, 0, 0, time.UTC)},
+		{"0 0 1 jan *", from, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", from, time.Date(2018, time.December, 30, 12, 0, 0, 0, time.UTC)},
+		{"5,10 10 28 12 *", from, time.Date(2019, time.December, 28, 10, 5, 0, 0, time.UTC)},
+
+		// either of the days, when both are restricted
+		{"0 0 1 * fri", from, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * fri", time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, time.January, 4, 0, 0, 0, 0, time.UTC)},
+
+		// never fires
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+
+	for _, test := range tests {
+
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse '%s': %v", test.expr, err)
+		}
+
+		next := s.Next(test.from)
+		if !next.Equal(test.expected) {
+			t.Errorf("Next of '%s': expected %v, got %v", test.expr, test.expected, next)
+		}
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("Time zone database is not available: %v", err)
+	}
+
+	s, err := Parse("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2018, time.December, 28, 10, 0, 0, 0, time.UTC)
+	next := s.Next(from.In(loc))
+
+	expected := time.Date(2018, time.December, 28, 20, 30, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next.UTC())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected '%s' to be invalid", expr)
+		}
+	}
+}
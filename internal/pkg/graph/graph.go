@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -58,6 +59,11 @@ var (
 	StatusUnknown    = "U"
 	StatusNotStarted = "N"
 	StatusCancelled  = "C"
+	StatusSkipped    = "K"
+	StatusReused     = "P"
+
+	// the node failed, but it's allowed to fail without failing the build
+	StatusAllowedFailure = "A"
 )
 
 // N ...
@@ -75,6 +81,9 @@ type N struct {
 	EndedAt     time.Time `json:"ended_at,omitempty"`
 	Duration    string    `json:"duration,omitempty"`
 
+	// seconds the node is expected to take, from its recent runs
+	Estimate float64 `json:"estimate,omitempty"`
+
 	ID string `json:"id,omitempty"`
 
 	Parallel bool
@@ -166,6 +175,7 @@ func (i *N) MarshalJSON() ([]byte, error) {
 		StartedAt   time.Time `json:"started_at,omitempty"`
 		EndedAt     time.Time `json:"ended_at,omitempty"`
 		Duration    string    `json:"duration,omitempty"`
+		Estimate    float64   `json:"estimate,omitempty"`
 		ID          string    `json:"id"`
 	}{
 		Name:        i.Name,
@@ -175,10 +185,61 @@ func (i *N) MarshalJSON() ([]byte, error) {
 		EndedAt:     i.EndedAt,
 		Message:     msg,
 		Duration:    i.Duration,
+		Estimate:    i.Estimate,
 		ID:          i.ID,
 	})
 }
 
+// UnmarshalJSON ..
+// Deserialize the N (node) from json format
+func (i *N) UnmarshalJSON(data []byte) error {
+
+	// the node without its methods, decoded as is
+	type node N
+	err := json.Unmarshal(data, (*node)(i))
+	if err != nil {
+		return err
+	}
+
+	// the message is always encoded by MarshalJSON
+	msg, err := base64.StdEncoding.DecodeString(i.Message)
+	if err != nil {
+		return fmt.Errorf("Failed to decode the message of node %s: %v", i.Name, err)
+	}
+	i.Message = string(msg)
+	return nil
+}
+
+// Key ..
+// Returns the name the node is known by across the builds, the description
+// of the block, as many blocks are run by the same plugin
+func (i *N) Key() string {
+
+	if i.Description != "" {
+		return i.Description
+	}
+	return i.Name
+}
+
+// SameKey ..
+// True if the name given by the user, such as a quarantined block, is the
+// key of a node. The case and the surrounding spaces are ignored.
+func SameKey(name, key string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), key)
+}
+
+// Decode ..
+// Parses the checkpoints of the graph from its json format
+func Decode(gph string) ([]*Checkpoint, error) {
+
+	var cps []*Checkpoint
+	err := json.Unmarshal([]byte(gph), &cps)
+	if err != nil {
+		return nil, err
+	}
+	return cps, nil
+}
+
 // Checkpoint ..
 // Each and every hop of the build during execution.
 type Checkpoint struct {
@@ -468,6 +529,27 @@ func (g *Graph) Checkpoints() []*Checkpoint {
 	return g.checkpoints
 }
 
+// CheckpointOf ..
+// Finds the checkpoint the node is run at, either as the node of the
+// checkpoint or as one of its parallel edges. Returns the index of the
+// checkpoint and the node, or -1 if the node isn't in the graph.
+func (g *Graph) CheckpointOf(id string) (int, *N) {
+
+	for i, c := range g.checkpoints {
+
+		if c.Node.ID == id {
+			return i, c.Node
+		}
+
+		for _, e := range c.Edges {
+			if e.ID == id {
+				return i, e
+			}
+		}
+	}
+	return -1, nil
+}
+
 // JSON ...
 // Return graph in json format
 func (g *Graph) JSON() (string, error) {
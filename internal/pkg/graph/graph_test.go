@@ -268,9 +268,67 @@ func TestGraph(t *testing.T) {
 	fmt.Println(graph.JSON())
 }
 
+func TestCheckpointOf(t *testing.T) {
+
+	f, err := parser.AST([]byte(file3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := Construct(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   string
+		idx  int
+		name string
+	}{
+		{"3", 3, "shell"},
+		{"4", 4, "FANOUT-echogroup"},
+		{"4.2", 4, "shell"},
+		{"6", 6, "shell"},
+		{"8", 8, "END"},
+		{"9", -1, ""},
+	}
+
+	for _, test := range tests {
+
+		idx, n := graph.CheckpointOf(test.id)
+		if idx != test.idx {
+			t.Errorf("Checkpoint of %s: expected %d, got %d", test.id, test.idx, idx)
+		}
+
+		if n != nil && (n.ID != test.id || n.Name != test.name) {
+			t.Errorf("Node of %s: expected %s, got %s (%s)", test.id, test.name, n.Name, n.ID)
+		}
+	}
+}
+
 func assertString(t *testing.T, expected string, actual string) {
 
 	if !strings.EqualFold(expected, actual) {
 		t.Fatalf("Expected %s, got %s", expected, actual)
 	}
 }
+
+func TestSameKey(t *testing.T) {
+
+	tests := []struct {
+		name string
+		key  string
+		same bool
+	}{
+		{"test", "test", true},
+		{" Unit Tests ", "unit tests", true},
+		{"test", "tests", false},
+		{"", "test", false},
+	}
+
+	for _, tt := range tests {
+		if s := SameKey(tt.name, tt.key); s != tt.same {
+			t.Errorf("SameKey(%q, %q) = %v, expected %v", tt.name, tt.key, s, tt.same)
+		}
+	}
+}
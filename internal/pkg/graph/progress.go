@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package graph
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Encode ..
+// Returns the checkpoints of the graph in json format
+func Encode(cps []*Checkpoint) (string, error) {
+
+	data, err := json.Marshal(cps)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Progress ..
+// Returns how long the checkpoints are expected to take from the estimates
+// of their nodes, and how much of it is done by now. The parallel nodes of
+// a checkpoint take as long as the longest of them, the running nodes are
+// done as much as they ran up to their estimates, and the skipped or reused
+// nodes take no time.
+func Progress(cps []*Checkpoint, now time.Time) (total, done time.Duration) {
+
+	for _, cp := range cps {
+
+		ns := cp.Edges
+		if len(ns) == 0 {
+			ns = []*N{cp.Node}
+		}
+
+		var cpTotal, cpDone time.Duration
+		for _, n := range ns {
+
+			if n == nil {
+				continue
+			}
+
+			est, d := n.progress(now)
+			if est > cpTotal {
+				cpTotal = est
+			}
+
+			if d > cpDone {
+				cpDone = d
+			}
+		}
+
+		total += cpTotal
+		done += cpDone
+	}
+	return total, done
+}
+
+// progress ..
+// Returns the estimate of the node and how much of it is done by now
+func (i *N) progress(now time.Time) (time.Duration, time.Duration) {
+
+	est := time.Duration(i.Estimate * float64(time.Second))
+
+	switch i.Status {
+	case StatusSkipped, StatusReused:
+		return 0, 0
+	case StatusSuccess, StatusFailed, StatusAllowedFailure, StatusCancelled:
+		return est, est
+	case StatusRunning:
+		if i.StartedAt.IsZero() || !now.After(i.StartedAt) {
+			return est, 0
+		}
+
+		ran := now.Sub(i.StartedAt)
+		if ran > est {
+			ran = est
+		}
+		return est, ran
+	}
+	return est, 0
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package graph
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+
+	started := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	n := &N{Name: "shell", Description: "the build", Status: StatusFailed, Message: "exit status 1", StartedAt: started, Estimate: 12.5, ID: "3"}
+	gph, err := Encode([]*Checkpoint{{Node: n}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cps, err := Decode(gph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cps) != 1 || cps[0].Node == nil {
+		t.Fatalf("Expected one checkpoint, got %s", gph)
+	}
+
+	got := cps[0].Node
+	if got.Message != n.Message {
+		t.Errorf("Message: expected %q, got %q", n.Message, got.Message)
+	}
+
+	if got.Estimate != n.Estimate || got.ID != n.ID || !got.StartedAt.Equal(started) {
+		t.Errorf("Expected %+v, got %+v", n, got)
+	}
+
+	if got.Key() != "the build" {
+		t.Errorf("Key: expected the description, got %s", got.Key())
+	}
+
+	_, err = Decode(`[{"node":{"name":"shell","message":"exit status 1"}}]`)
+	if err == nil {
+		t.Error("Expected the message not encoded to fail")
+	}
+}
+
+func TestProgress(t *testing.T) {
+
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	cps := []*Checkpoint{
+		{Node: &N{Name: START, Status: StatusSuccess}},
+		{Node: &N{Name: "checkout", Status: StatusSuccess, Estimate: 10}},
+		{Node: &N{Name: "FANOUT-tests", Status: StatusRunning}, Edges: []*N{
+			{Name: "unit", Status: StatusSuccess, Estimate: 20},
+			{Name: "integration", Status: StatusRunning, Estimate: 60, StartedAt: now.Add(-30 * time.Second)},
+		}},
+		{Node: &N{Name: "lint", Status: StatusSkipped, Estimate: 15}},
+		{Node: &N{Name: "package", Status: StatusNotStarted, Estimate: 30}},
+		{Node: &N{Name: "deploy", Status: StatusRunning, Estimate: 5, StartedAt: now.Add(-time.Minute)}},
+	}
+
+	total, done := Progress(cps, now)
+	if total != 105*time.Second {
+		t.Errorf("Total: expected 1m45s, got %v", total)
+	}
+
+	// the running nodes are done up to their estimates
+	if done != 45*time.Second {
+		t.Errorf("Done: expected 45s, got %v", done)
+	}
+
+	total, done = Progress(nil, now)
+	if total != 0 || done != 0 {
+		t.Errorf("Expected nothing to be done without checkpoints, got %v of %v", done, total)
+	}
+}
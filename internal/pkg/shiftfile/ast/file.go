@@ -152,6 +152,46 @@ func (f *File) CacheDirectories() []string {
 	return directories
 }
 
+// Concurrency ..
+// Returns the concurrency group of the build, given through the hint on the
+// file or on any of its blocks. The builds of a group run one at a time,
+// unless the hint is CANCEL_IN_PROGRESS, that cancels the build in progress.
+func (f *File) Concurrency() (string, bool) {
+
+	for _, item := range items(f.Node) {
+
+		switch item.Kind {
+		case scope.Hin:
+			if group, cancel, ok := concurrency(item); ok {
+				return group, cancel
+			}
+		case scope.Blk:
+			for _, n := range item.Value.(*Block).Node {
+				if group, cancel, ok := concurrency(n.(*NodeItem)); ok {
+					return group, cancel
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func concurrency(n *NodeItem) (string, bool, bool) {
+
+	h, ok := n.Value.(*Hint)
+	if !ok {
+		return "", false, false
+	}
+
+	switch strings.ToUpper(h.Operation) {
+	case keys.HINT_CONCURRENCY:
+		return h.Value, false, true
+	case keys.HINT_CANCEL_IN_PROGRESS:
+		return h.Value, true, true
+	}
+	return "", false, false
+}
+
 func (f *File) HasMoreBlocks() bool {
 	return f.currentBlock < f.BlockCount
 }
@@ -10,6 +10,11 @@ var (
 	HINT       = "hint"
 	PROPERTIES = "properties"
 
+	// builds of the same concurrency group run one at a time,
+	// or cancel the build in progress
+	HINT_CONCURRENCY        = "CONCURRENCY"
+	HINT_CANCEL_IN_PROGRESS = "CANCEL_IN_PROGRESS"
+
 	BLOCK_NUMBER = "BlockNumber"
 
 	COMMAND = "command"
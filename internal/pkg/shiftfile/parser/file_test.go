@@ -174,6 +174,36 @@ func testBlock(f *ast.File, t *testing.T) {
 	}
 }
 
+func TestConcurrency(t *testing.T) {
+
+	tests := []struct {
+		filename string
+		group    string
+		cancel   bool
+	}{
+		{"concurrency.shift", "deploy-prod", false},
+		{"concurrency2.shift", "deploy-staging", true},
+		{"file.shift", "", false},
+	}
+
+	for _, test := range tests {
+
+		buf, e := ioutil.ReadFile(filepath.Join("./testfiles", test.filename))
+		if e != nil {
+			t.Fatalf("err: %s", e)
+		}
+
+		f, err := New(buf).Parse()
+		if err != nil {
+			t.Fatalf("Failed %v", err)
+		}
+
+		group, cancel := f.Concurrency()
+		assertString(t, test.group, group)
+		assertEqual(t, test.cancel, cancel)
+	}
+}
+
 func assertEqual(t *testing.T, expected interface{}, actual interface{}) {
 
 	if !reflect.DeepEqual(expected, actual) {
@@ -194,3 +224,28 @@ func assertInt(t *testing.T, expected int, actual int) {
 		t.Fatalf("Expected %d, got %d", expected, actual)
 	}
 }
+
+func TestTriggerBlock(t *testing.T) {
+
+	buf, e := ioutil.ReadFile(filepath.Join("./testfiles", "trigger.shift"))
+	if e != nil {
+		t.Fatalf("err: %s", e)
+	}
+
+	f, err := New(buf).Parse()
+	if err != nil {
+		t.Fatalf("Failed %v", err)
+	}
+
+	var blk map[string]interface{}
+	for f.HasMoreBlocks() {
+		blk = f.NextBlock()
+	}
+
+	assertString(t, "elasticshift/trigger", blk[keys.NAME].(string))
+	assertString(t, "Building the payments service", blk[keys.DESC].(string))
+	assertString(t, "acme/payments", blk["repo"].(string))
+	assertString(t, "develop", blk["branch"].(string))
+	assertEqual(t, []string{"LIB_VERSION=1.2.0", "DEPLOY=false"}, blk["params"])
+	assertString(t, "true", blk["wait"].(string))
+}
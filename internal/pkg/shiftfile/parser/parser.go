@@ -19,7 +19,7 @@ import (
 var errEofToken = errors.New("EOF token found")
 
 var (
-	validHints = []string{"PARALLEL", "TIMEOUT"}
+	validHints = []string{"PARALLEL", "TIMEOUT", "CONCURRENCY", "CANCEL_IN_PROGRESS"}
 )
 
 type Parser struct {
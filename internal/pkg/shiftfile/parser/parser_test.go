@@ -64,6 +64,14 @@ func TestParser(t *testing.T) {
 			"cache.shift",
 			false,
 		},
+		{
+			"concurrency.shift",
+			false,
+		},
+		{
+			"concurrency2.shift",
+			false,
+		},
 	}
 
 	testfileDir := "./testfiles"
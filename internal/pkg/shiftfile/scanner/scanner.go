@@ -125,7 +125,7 @@ func (s *Scanner) Scan() token.Token {
 
 	switch ch := s.ch; {
 	case isLetter(ch):
-		tok.Type, tok.Text = s.scanIdentifier()
+		tok.Type, tok.Text = s.scanIdentifier(s.token.Type == token.HINT_DEL)
 		s.lastIdentifier = tok.Type
 	case isDigit(ch):
 		tok.Type, tok.Text = s.scanNumber(false)
@@ -420,10 +420,12 @@ func digitVal(ch rune) int {
 }
 
 // Scan the identifier by reading next character in look until reach whitespace or endline
-func (s *Scanner) scanIdentifier() (token.Type, string) {
+// The value of a hint, the identifier following the hint delimiter, may have
+// hyphens within, ex: CONCURRENCY:deploy-prod
+func (s *Scanner) scanIdentifier(hintValue bool) (token.Type, string) {
 
 	ofs := s.pos.Offset - 1
-	for isLetter(s.ch) || isDigit(s.ch) {
+	for isLetter(s.ch) || isDigit(s.ch) || (hintValue && s.ch == '-') {
 		s.next()
 	}
 
@@ -55,6 +55,12 @@ var tokens = map[string][]tokenPair{
 			{Line: 1, Text: ":"},
 			{Line: 1, Text: "testinghint"},
 		}},
+		{"// CONCURRENCY:deploy-prod", nil, []Expected{
+			{Line: 1, Text: "//"},
+			{Line: 1, Text: "CONCURRENCY"},
+			{Line: 1, Text: ":"},
+			{Line: 1, Text: "deploy-prod"},
+		}},
 		{"/* PARALLEL:testinghint */", nil, []Expected{
 			{Line: 1, Text: "/*"},
 			{Line: 1, Text: "PARALLEL"},
@@ -496,6 +502,48 @@ func TestList(t *testing.T) {
 	testTokenTypes(t, token.LBRACK, tokens["list"])
 }
 
+// the hyphen is taken within the identifier only in the hint value,
+// elsewhere it starts the command as it did before
+func TestHyphen(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"a-b\n", []token.Token{
+			{Type: token.IDENTIFIER, Text: "a"},
+			{Type: token.COMMAND, Text: "b"},
+		}},
+		{"image a-b\n", []token.Token{
+			{Type: token.IDENTIFIER, Text: "image"},
+			{Type: token.IDENTIFIER, Text: "a"},
+			{Type: token.COMMAND, Text: "b"},
+		}},
+		{"// CONCURRENCY:deploy-prod\n- ./deploy.sh\n", []token.Token{
+			{Type: token.HINT, Text: "//"},
+			{Type: token.IDENTIFIER, Text: "CONCURRENCY"},
+			{Type: token.HINT_DEL, Text: ":"},
+			{Type: token.IDENTIFIER, Text: "deploy-prod"},
+			{Type: token.COMMAND, Text: "./deploy.sh"},
+		}},
+	}
+
+	for _, tt := range tests {
+
+		out := alltokens(tt.input)
+		if len(out) != len(tt.expected)+1 || out[len(out)-1].Type != token.EOF {
+			t.Errorf("%q: expected %d tokens, got %q", tt.input, len(tt.expected), out)
+			continue
+		}
+
+		for i, tok := range tt.expected {
+			if out[i].Type != tok.Type || out[i].Text != tok.Text {
+				t.Errorf("%q: expected %s %q at %d, got %s %q", tt.input, tok.Type, tok.Text, i, out[i].Type, out[i].Text)
+			}
+		}
+	}
+}
+
 func alltokens(input string) []token.Token {
 
 	buf := new(bytes.Buffer)
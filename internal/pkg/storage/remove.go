@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package storage
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+// RemoveBuild ..
+// Removes the logs, the archives and the snapshots of all the sub builds
+// of the build given through the metadata. Returns the number of objects removed.
+func (s *ShiftStorage) RemoveBuild(m *types.StorageMetadata) (int, error) {
+
+	if m.Path == "" || m.BuildID == "" {
+		return 0, fmt.Errorf("Storage path and the build are required to remove the build")
+	}
+
+	// the path of the sub build, without the sub build
+	bm := *m
+	bm.SubBuildID = ""
+
+	var count int
+	for _, dir := range []string{logDir, archiveDir, snapshotDir} {
+
+		n, err := s.stor.RemoveObjects(s.bucketName, GetPath(&bm, dir), true)
+		count += n
+		if err != nil {
+			return count, fmt.Errorf("Failed to remove the %s of build %s: %v", dir, m.BuildID, err)
+		}
+	}
+	return count, nil
+}
+
+// RemoveCache ..
+// Removes the cache of the branch given through the metadata,
+// the caches of the nested paths (such as the pipelines) are left as is
+func (s *ShiftStorage) RemoveCache(m *types.StorageMetadata) (int, error) {
+
+	if m.Path == "" {
+		return 0, fmt.Errorf("Storage path is required to remove the cache")
+	}
+	return s.stor.RemoveObjects(s.bucketName, GetCachePath(m, cacheDir)+objectSeparator, false)
+}
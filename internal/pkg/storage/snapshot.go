@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package storage
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+// PutSnapshot ..
+// Stores the snapshot of the workspace taken at the checkpoint
+func (s *ShiftStorage) PutSnapshot(checkpoint, path string) error {
+	return s.PutSnapshotWithMetadata(checkpoint, path, s.metadata)
+}
+
+// PutSnapshotWithMetadata ..
+func (s *ShiftStorage) PutSnapshotWithMetadata(checkpoint, path string, m *types.StorageMetadata) error {
+
+	objectName := GetObjectName(m, checkpoint, snapshotDir)
+	_, err := s.stor.PutFObject(s.bucketName, objectName, path, snapshotContentType)
+	return err
+}
+
+// GetSnapshotWithMetadata ..
+// Fetches the snapshot of the workspace taken at the checkpoint
+// of the build given through the metadata
+func (s *ShiftStorage) GetSnapshotWithMetadata(checkpoint, path string, m *types.StorageMetadata) error {
+
+	objectName := GetObjectName(m, checkpoint, snapshotDir)
+	err := s.stor.GetFObject(s.bucketName, objectName, path)
+	if err != nil && err.Error() == errKeyDoesNotExist {
+		return fmt.Errorf("No snapshot found at checkpoint %s of build %s", checkpoint, m.BuildID)
+	}
+	return err
+}
@@ -19,13 +19,15 @@ var (
 	defaultBucketName = "elasticshift"
 
 	// specific dirs
-	logDir     = "logs"
-	cacheDir   = "cache"
-	archiveDir = "archive"
+	logDir      = "logs"
+	cacheDir    = "cache"
+	archiveDir  = "archive"
+	snapshotDir = "snapshot"
 
 	// content type
-	logContentType   = "text/plain"
-	cacheContentType = "application/octet-stream"
+	logContentType      = "text/plain"
+	cacheContentType    = "application/octet-stream"
+	snapshotContentType = "application/octet-stream"
 
 	objectSeparator = "/"
 )
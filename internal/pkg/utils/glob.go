@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchGlob ..
+// Matches the slash separated path against the glob pattern, where
+// * matches within a path segment, ** matches across the segments
+// and ? matches a single character other than slash.
+// Ex: services/**/*.go matches services/api/main.go
+func MatchGlob(pattern, path string) bool {
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// IsGlob ..
+// True if the path is a glob pattern rather than a plain path
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?")
+}
+
+func globToRegexp(pattern string) string {
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			// zero or more directories
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package utils
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+
+	tests := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"services/api/**", "services/api/main.go", true},
+		{"services/api/**", "services/api/pkg/handler/handler.go", true},
+		{"services/api/**", "services/apigw/main.go", false},
+		{"services/*/Shiftfile", "services/web/Shiftfile", true},
+		{"services/*/Shiftfile", "services/web/nested/Shiftfile", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/guide/setup.md", true},
+		{"**/*.md", "docs/guide/setup.mdx", false},
+		{"docs/v?/index.html", "docs/v1/index.html", true},
+		{"docs/v?/index.html", "docs/v10/index.html", false},
+		{"go.mod", "go.mod", true},
+		{"go.mod", "libs/go.mod", false},
+		{"libs/[a]/x", "libs/[a]/x", true},
+	}
+
+	for _, tt := range tests {
+		if MatchGlob(tt.pattern, tt.path) != tt.match {
+			t.Errorf("MatchGlob(%s, %s) expected to be %v", tt.pattern, tt.path, tt.match)
+		}
+	}
+}
+
+func TestIsGlob(t *testing.T) {
+
+	if !IsGlob("services/*/Shiftfile") || !IsGlob("docs/v?/index.html") {
+		t.Error("expected the patterns to be globs")
+	}
+
+	if IsGlob("services/api/Shiftfile") || IsGlob("libs/[a]/x") {
+		t.Error("expected the paths not to be globs")
+	}
+}
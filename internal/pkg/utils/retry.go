@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package utils
+
+import "time"
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent ..
+// Wraps the error to let Retry know that retrying wouldn't help
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// Retry ..
+// Executes the func until it succeeds or the attempts are exhausted,
+// the wait between the attempts doubles starting from the backoff.
+func Retry(attempts int, backoff time.Duration, fn func() error) error {
+
+	var err error
+	for i := 0; i < attempts; i++ {
+
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff = backoff * 2
+		}
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		if p, ok := err.(permanentError); ok {
+			return p.err
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+
+	var calls int
+	err := Retry(3, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success after 3 calls, got %d calls: %v", calls, err)
+	}
+
+	calls = 0
+	err = Retry(3, time.Millisecond, func() error {
+		calls++
+		return errors.New("temporary")
+	})
+
+	if err == nil || calls != 3 {
+		t.Fatalf("expected failure after 3 calls, got %d calls", calls)
+	}
+
+	calls = 0
+	err = Retry(3, time.Millisecond, func() error {
+		calls++
+		return Permanent(errors.New("bad request"))
+	})
+
+	if err == nil || err.Error() != "bad request" || calls != 1 {
+		t.Fatalf("expected permanent failure after 1 call, got %d calls: %v", calls, err)
+	}
+}
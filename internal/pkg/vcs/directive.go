@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package vcs
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+// Directives given in the commit message within brackets, or as the pull
+// request labels (brackets are optional). Ex: [skip ci], [ci rebuild-cache],
+// [ci debug], [ci only: Running unit tests, Building the image]. The comma
+// within the node name is escaped by backslash. Ex: [ci only: Lint\, vet]
+const (
+	DirectiveRebuildCache = "rebuild-cache"
+	DirectiveDebug        = "debug"
+	DirectiveOnly         = "only"
+	DirectiveSkip         = "skip"
+)
+
+var directivePattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// ParseDirectives ..
+// Parses the build directives from the head commit message and the labels
+func ParseDirectives(message string, labels []string) types.Directives {
+
+	d := types.Directives{}
+	for _, m := range directivePattern.FindAllStringSubmatch(message, -1) {
+		parseDirective(m[1], &d)
+	}
+
+	for _, l := range labels {
+		parseDirective(strings.Trim(strings.TrimSpace(l), "[]"), &d)
+	}
+	return d
+}
+
+func parseDirective(text string, d *types.Directives) {
+
+	text = strings.TrimSpace(text)
+	lower := strings.ToLower(text)
+
+	switch lower {
+	case "skip ci", "ci skip", "no ci", "skip build":
+		d.Skip = true
+		return
+	}
+
+	// ci <directive> or ci:<directive>
+	if !strings.HasPrefix(lower, "ci ") && !strings.HasPrefix(lower, "ci:") {
+		return
+	}
+	text = strings.TrimSpace(text[3:])
+	lower = strings.ToLower(text)
+
+	switch {
+	case lower == DirectiveSkip:
+		d.Skip = true
+	case lower == DirectiveRebuildCache:
+		d.RebuildCache = true
+	case lower == DirectiveDebug:
+		d.Debug = true
+	case lower == DirectiveOnly || strings.HasPrefix(lower, DirectiveOnly+":") || strings.HasPrefix(lower, DirectiveOnly+" "):
+
+		names := strings.TrimPrefix(strings.TrimSpace(text[len(DirectiveOnly):]), ":")
+		for _, name := range splitNames(names) {
+			if name = strings.TrimSpace(name); name != "" {
+				d.Only = append(d.Only, name)
+			}
+		}
+	}
+}
+
+// splitNames ..
+// Splits the comma separated names, except the escaped commas
+func splitNames(text string) []string {
+
+	var names []string
+	var name strings.Builder
+	for i := 0; i < len(text); i++ {
+
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && text[i+1] == ',':
+			name.WriteByte(',')
+			i++
+		case text[i] == ',':
+			names = append(names, name.String())
+			name.Reset()
+		default:
+			name.WriteByte(text[i])
+		}
+	}
+	return append(names, name.String())
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestParseDirectives(t *testing.T) {
+
+	tests := []struct {
+		message string
+		labels  []string
+		expect  types.Directives
+	}{
+		{"Fix typo in README [skip ci]", nil, types.Directives{Skip: true}},
+		{"Fix typo\n\n[CI SKIP]", nil, types.Directives{Skip: true}},
+		{"Bump gradle [ci rebuild-cache] [ci debug]", nil, types.Directives{RebuildCache: true, Debug: true}},
+		{"Run tests [ci only: Running unit tests, Building the image ]", nil, types.Directives{Only: []string{"Running unit tests", "Building the image"}}},
+		{"Run lint [ci only Lint]", nil, types.Directives{Only: []string{"Lint"}}},
+		{"Run lint [ci only: Lint\\, vet, Build]", nil, types.Directives{Only: []string{"Lint, vet", "Build"}}},
+		{"Unknown [ci onlyfoo] [ci only-lint]", nil, types.Directives{}},
+		{"Fix [JIRA-1234] the login", nil, types.Directives{}},
+		{"No directive", []string{"ci:rebuild-cache", "[skip ci]", "bug"}, types.Directives{Skip: true, RebuildCache: true}},
+	}
+
+	for _, tt := range tests {
+		if d := ParseDirectives(tt.message, tt.labels); !reflect.DeepEqual(d, tt.expect) {
+			t.Errorf("ParseDirectives(%q, %v) = %+v, expected %+v", tt.message, tt.labels, d, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const refHeadsPrefix = "refs/heads/"
+
+// abbreviated or full commit id
+var commitID = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+var (
+	// time allowed to fetch the shiftfile through git
+	gitFetchTimeout = 2 * time.Minute
+
+	// time allowed to list the remote references
+	gitListTimeout = 30 * time.Second
+)
+
+// host keys of the public git servers, as published by them, the
+// self-hosted ones are given through the credentials in the same format
+var knownHosts = []string{
+	"github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+	"github.com ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBEmKSENjQEezOmxkZMy7opKgwFB9nkt5YRrYMjNuG5N87uRgg6CLrbo5wAdT/y6v0mKV0U2w0WZ2YB/++Tpockg=",
+	"gitlab.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAfuCHKVTjquxvt6CM6tdG4SLp1Btn/nOeHHE5UOzRdf",
+	"gitlab.com ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBFSMqzJeV9rUzU4kWitGjeR4PWSa29SPqJ1fVkhtj3Hw9xjLVXVYrU9QlYWrOLXBpQ6KWjbjTDTdDkoohFzgbEY=",
+	"bitbucket.org ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIIazEu89wgQZ4bqs3d63QSMzYVa0MuJ2e2gKTKqu+UUO",
+	"bitbucket.org ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBPIQmuzMBuKdWeF4+a2sjSSpBK0iqitSQ+5BM9KhpexuGt20JpTVM7u5BDZngncgrqDMbWdxMWWOGtZ9UgbqgZE=",
+}
+
+// messages of git, when the remote rejects the credentials
+var accessDenied = []string{
+	"Authentication failed",
+	"HTTP Basic: Access denied",
+	"Permission denied (publickey",
+	"could not read Username",
+	"The requested URL returned error: 401",
+	"The requested URL returned error: 403",
+}
+
+// accessError ..
+// The git remote rejected the credentials
+type accessError struct {
+	err error
+}
+
+func (e accessError) Error() string {
+	return e.err.Error()
+}
+
+// AccessDenied ..
+// True if the git remote rejected the credentials, as opposed
+// to being unreachable or not responding in time
+func AccessDenied(err error) bool {
+
+	_, ok := err.(accessError)
+	return ok
+}
+
+// denied ..
+// True if the git failure is caused by the rejected credentials
+func denied(err error) bool {
+
+	for _, m := range accessDenied {
+		if strings.Contains(err.Error(), m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials ..
+// Authenticates the git remote, either through the ssh key
+// or the username and token (basic auth) for http remotes.
+// The host keys (known_hosts format) verify the self-hosted
+// git server the ssh key is used against.
+type Credentials struct {
+	Username   string
+	Token      string
+	SSHKey     string
+	KnownHosts string
+}
+
+// KnownHost ..
+// True if the host key of the git server is published, so that
+// the host keys are not required to access it through ssh
+func KnownHost(host string) bool {
+
+	for _, h := range knownHosts {
+		if strings.HasPrefix(h, host+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// session ..
+// Returns the git options and the environment that authenticate the
+// commands, the cleanup removes the ssh key written for the session.
+func (c Credentials) session() ([]string, []string, func(), error) {
+
+	var opts, env []string
+	cleanup := func() {}
+
+	if c.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Token))
+		opts = append(opts, "-c", "http.extraHeader=Authorization: Basic "+auth)
+	}
+
+	if c.SSHKey != "" {
+
+		dir, err := ioutil.TempDir("", "sshkey")
+		if err != nil {
+			return nil, nil, cleanup, fmt.Errorf("Failed to create temporary directory: %v", err)
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+
+		key := filepath.Join(dir, "id")
+		err = ioutil.WriteFile(key, []byte(c.SSHKey), 0600)
+		if err != nil {
+			cleanup()
+			return nil, nil, func() {}, fmt.Errorf("Failed to write the ssh key: %v", err)
+		}
+
+		// the key is used only against the hosts known by their keys
+		hosts := filepath.Join(dir, "known_hosts")
+		err = ioutil.WriteFile(hosts, []byte(strings.Join(knownHosts, "\n")+"\n"+c.KnownHosts+"\n"), 0600)
+		if err != nil {
+			cleanup()
+			return nil, nil, func() {}, fmt.Errorf("Failed to write the known hosts: %v", err)
+		}
+
+		ssh := "ssh -i " + key + " -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile=" + hosts
+		env = append(env, "GIT_SSH_COMMAND="+ssh)
+	}
+
+	return opts, env, cleanup, nil
+}
+
+// LsRemote ..
+// Lists the branches of the remote along with the commit id of their heads,
+// the default branch is the one the HEAD of the remote points to.
+func LsRemote(uri string, c Credentials) (map[string]string, string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitListTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return nil, "", err
+	}
+	defer cleanup()
+
+	out, err := git(ctx, "", env, append(opts, "ls-remote", "--symref", "--", uri, "HEAD", refHeadsPrefix+"*")...)
+	if err != nil {
+
+		err = fmt.Errorf("Failed to list the references of %s: %v", uri, err)
+		if denied(err) {
+			return nil, "", accessError{err}
+		}
+		return nil, "", err
+	}
+
+	heads, defaultBranch := parseLsRemote(out)
+	return heads, defaultBranch, nil
+}
+
+// parseLsRemote ..
+// Parses the output of ls-remote, which is in the form of
+// ref: refs/heads/master	HEAD
+// <commit id>	refs/heads/<branch>
+func parseLsRemote(out []byte) (map[string]string, string) {
+
+	heads := make(map[string]string)
+	var defaultBranch string
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+
+			if len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+				defaultBranch = strings.TrimPrefix(fields[1], refHeadsPrefix)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(fields[1], refHeadsPrefix) {
+			heads[strings.TrimPrefix(fields[1], refHeadsPrefix)] = fields[0]
+		}
+	}
+	return heads, defaultBranch
+}
+
+// GetFileFromGit ..
+// Fetches only the file out of any git remote, the clone is shallow and
+// excludes the file contents, so that git downloads just the requested
+// file when it's read from the fetched commit.
+func GetFileFromGit(uri, branch, path string, c Credentials) ([]byte, error) {
+
+	dir, err := ioutil.TempDir("", "shiftfile")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitFetchTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args := append(opts, "clone", "--quiet", "--depth", "1", "--no-checkout", "--filter=blob:none")
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, "--", uri, dir)
+
+	_, err = git(ctx, "", env, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch %s: %v", uri, err)
+	}
+
+	// credentials are required to download the blob on demand
+	f, err := git(ctx, dir, env, append(opts, "show", "HEAD:"+path)...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s from %s: %v", path, uri, err)
+	}
+	return f, nil
+}
+
+// ChangedFiles ..
+// Lists the files changed between the base and the head commit of the
+// branch. Only the commits and trees of the branch are fetched, the
+// file contents are not required to compare the commits.
+func ChangedFiles(uri, branch, base, head string, c Credentials) ([]string, error) {
+
+	// the commits come from the webhooks, they must not be taken for an option
+	if !commitID.MatchString(base) || !commitID.MatchString(head) {
+		return nil, fmt.Errorf("Invalid commit id to compare, %q or %q", base, head)
+	}
+
+	dir, err := ioutil.TempDir("", "changes")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitFetchTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	_, err = git(ctx, dir, env, "init", "--quiet", "--bare")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize the repository: %v", err)
+	}
+
+	refspec := "+" + refHeadsPrefix + branch + ":" + refHeadsPrefix + branch
+	_, err = git(ctx, dir, env, append(opts, "fetch", "--quiet", "--no-tags", "--filter=blob:none", "--", uri, refspec)...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch %s: %v", uri, err)
+	}
+
+	// rename detection would download the contents
+	out, err := git(ctx, dir, env, "diff", "--name-only", "--no-renames", "--end-of-options", base, head, "--")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to compare %s with %s: %v", base, head, err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(string(out), "\n") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// CommitMessage ..
+// Returns the message of the head commit of the branch, only the head
+// commit is fetched and without the file contents.
+func CommitMessage(uri, branch string, c Credentials) (string, error) {
+
+	dir, err := ioutil.TempDir("", "message")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitFetchTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	err = fetchHead(ctx, dir, env, opts, uri, branch)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := git(ctx, dir, env, "log", "-1", "--format=%B", "FETCH_HEAD")
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the commit message: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// HeadCommit ..
+// Returns the id of the head commit of the branch, only the head commit
+// is fetched and without the file contents.
+func HeadCommit(uri, branch string, c Credentials) (string, error) {
+
+	dir, err := ioutil.TempDir("", "head")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitFetchTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	err = fetchHead(ctx, dir, env, opts, uri, branch)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := git(ctx, dir, env, "log", "-1", "--format=%H", "FETCH_HEAD")
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the head commit: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ListFiles ..
+// Lists the files at the head commit of the branch, only the head
+// commit and its trees are fetched, without the file contents.
+func ListFiles(uri, branch string, c Credentials) ([]string, error) {
+
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitFetchTimeout)
+	defer cancel()
+
+	opts, env, cleanup, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	err = fetchHead(ctx, dir, env, opts, uri, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := git(ctx, dir, env, "ls-tree", "-r", "--name-only", "FETCH_HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the files: %v", err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(string(out), "\n") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// fetchHead ..
+// Initializes a bare repository in the directory and fetches only the
+// head commit of the branch into it, without the file contents.
+func fetchHead(ctx context.Context, dir string, env, opts []string, uri, branch string) error {
+
+	_, err := git(ctx, dir, env, "init", "--quiet", "--bare")
+	if err != nil {
+		return fmt.Errorf("Failed to initialize the repository: %v", err)
+	}
+
+	_, err = git(ctx, dir, env, append(opts, "fetch", "--quiet", "--no-tags", "--depth", "1", "--filter=blob:none", "--", uri, refHeadsPrefix+branch)...)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch %s: %v", uri, err)
+	}
+	return nil
+}
+
+// git ..
+// Runs the git command, the urls and the refspecs are passed after "--"
+// so that they're never taken for an option.
+func git(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLsRemote(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, SHIFTFILE), shiftfile, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	run("init", "--quiet")
+	run("checkout", "--quiet", "-b", "trunk")
+	run("add", SHIFTFILE)
+	run("-c", "user.name=shift", "-c", "user.email=shift@localhost", "commit", "--quiet", "-m", "Add shiftfile")
+	run("branch", "feature")
+	head := run("rev-parse", "HEAD")
+
+	heads, defaultBranch, err := LsRemote("file://"+dir, Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultBranch != "trunk" {
+		t.Fatalf("unexpected default branch %s", defaultBranch)
+	}
+
+	if len(heads) != 2 || heads["trunk"] != head || heads["feature"] != head {
+		t.Fatalf("unexpected heads %v", heads)
+	}
+
+	_, _, err = LsRemote("file://"+filepath.Join(dir, "unknown"), Credentials{})
+	if err == nil || AccessDenied(err) {
+		t.Fatalf("expected failure for unknown remote, got %v", err)
+	}
+}
+
+func TestDenied(t *testing.T) {
+
+	tests := []struct {
+		msg    string
+		denied bool
+	}{
+		{"exit status 128: remote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/infra/tools.git/'", true},
+		{"exit status 128: remote: HTTP Basic: Access denied\nfatal: Authentication failed for 'https://gitlab.com/infra/tools.git/'", true},
+		{"exit status 128: git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.", true},
+		{"exit status 128: fatal: could not read Username for 'https://github.com': terminal prompts disabled", true},
+		{"exit status 128: fatal: unable to access 'https://git.local/infra/tools.git/': The requested URL returned error: 403", true},
+		{"exit status 128: fatal: unable to access 'https://git.local/infra/tools.git/': The requested URL returned error: 502", false},
+		{"exit status 128: fatal: unable to access 'https://git.local/infra/tools.git/': Could not resolve host: git.local", false},
+		{"exit status 128: Host key verification failed.\nfatal: Could not read from remote repository.", false},
+		{"signal: killed: ", false},
+	}
+
+	for _, tt := range tests {
+		if got := denied(errors.New(tt.msg)); got != tt.denied {
+			t.Errorf("denied(%q) = %v, expected %v", tt.msg, got, tt.denied)
+		}
+	}
+}
+
+func TestCredentialsSession(t *testing.T) {
+
+	opts, env, cleanup, err := Credentials{Username: "shift", Token: "t0k3n"}.session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+
+	// shift:t0k3n
+	if len(opts) != 2 || opts[1] != "http.extraHeader=Authorization: Basic c2hpZnQ6dDBrM24=" || len(env) != 0 {
+		t.Fatalf("unexpected session %v %v", opts, env)
+	}
+
+	opts, env, cleanup, err = Credentials{SSHKey: "private"}.session()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opts) != 0 || len(env) != 1 || !strings.HasPrefix(env[0], "GIT_SSH_COMMAND=ssh -i ") {
+		t.Fatalf("unexpected session %v %v", opts, env)
+	}
+
+	key := strings.Fields(env[0])[2]
+	if b, err := ioutil.ReadFile(key); err != nil || string(b) != "private" {
+		t.Fatalf("expected the key to be written to %s: %v", key, err)
+	}
+
+	if !strings.Contains(env[0], "StrictHostKeyChecking=yes") {
+		t.Fatalf("expected the host keys to be checked %v", env)
+	}
+
+	cleanup()
+	if _, err := os.Stat(key); !os.IsNotExist(err) {
+		t.Fatal("expected the key to be removed")
+	}
+
+	opts, env, cleanup, err = Credentials{SSHKey: "private", KnownHosts: "git.example.com ssh-ed25519 AAAA"}.session()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := strings.TrimPrefix(strings.Fields(env[0])[len(strings.Fields(env[0]))-1], "UserKnownHostsFile=")
+	b, err := ioutil.ReadFile(hosts)
+	if err != nil || !strings.Contains(string(b), "github.com ssh-ed25519 ") || !strings.Contains(string(b), "git.example.com ssh-ed25519 AAAA") {
+		t.Fatalf("expected the known hosts to be written to %s: %q %v", hosts, b, err)
+	}
+
+	if !KnownHost("github.com") || !KnownHost("bitbucket.org") || KnownHost("git.example.com") || KnownHost("github") {
+		t.Fatal("unexpected known hosts")
+	}
+
+	cleanup()
+	if _, err := os.Stat(hosts); !os.IsNotExist(err) {
+		t.Fatal("expected the known hosts to be removed")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	var rev int
+	commit := func(files ...string) string {
+		rev++
+		for _, f := range files {
+			os.MkdirAll(filepath.Join(dir, filepath.Dir(f)), 0755)
+			ioutil.WriteFile(filepath.Join(dir, f), []byte(fmt.Sprintf("rev %d", rev)), 0644)
+			run("add", f)
+		}
+		run("-c", "user.name=shift", "-c", "user.email=shift@localhost", "commit", "--quiet", "-m", "Change")
+		return run("rev-parse", "HEAD")
+	}
+
+	run("init", "--quiet")
+	run("checkout", "--quiet", "-b", "main")
+	base := commit("services/api/main.go", "services/web/index.js")
+	commit("services/api/main.go")
+	head := commit("docs/README.md")
+
+	files, err := ChangedFiles("file://"+dir, "main", base, head, Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 || files[0] != "docs/README.md" || files[1] != "services/api/main.go" {
+		t.Fatalf("unexpected changed files %v", files)
+	}
+
+	_, err = ChangedFiles("file://"+dir, "main", "0000000000000000000000000000000000000001", head, Credentials{})
+	if err == nil {
+		t.Fatal("expected failure for unknown base commit")
+	}
+
+	_, err = ChangedFiles("file://"+dir, "main", "--output=/tmp/changes", head, Credentials{})
+	if err == nil {
+		t.Fatal("expected failure for the base taken for an option")
+	}
+
+	msg, err := CommitMessage("file://"+dir, "main", Credentials{})
+	if err != nil || msg != "Change" {
+		t.Fatalf("unexpected commit message %q: %v", msg, err)
+	}
+
+	id, err := HeadCommit("file://"+dir, "main", Credentials{})
+	if err != nil || id != head {
+		t.Fatalf("unexpected head commit %s: %v", id, err)
+	}
+
+	files, err = ListFiles("file://"+dir, "main", Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 3 || files[0] != "docs/README.md" || files[1] != "services/api/main.go" || files[2] != "services/web/index.js" {
+		t.Fatalf("unexpected files %v", files)
+	}
+}
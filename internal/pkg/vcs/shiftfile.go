@@ -6,6 +6,9 @@ package vcs
 import (
 	"encoding/base64"
 	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
@@ -16,49 +19,132 @@ var (
 	GITHUB_DOT_COM    = "github.com"
 	GITLAB_DOT_COM    = "gitlab.com"
 	BITBUCKET_DOT_ORG = "bitbucket.org"
+	GITEA_DOT_COM     = "gitea.com"
+	CODEBERG_DOT_ORG  = "codeberg.org"
 )
 
+// Shiftfile name expected at the root of the repository
+const SHIFTFILE = "Shiftfile"
+
 var (
-	// githubComUrl = "https://api.github.com/repos/nshahm/hybrid.test.runner/contents/Shiftfile?ref=master"
-	githubComUrl = providers.GithubBaseURL + "/repos/:account/:repo/contents/Shiftfile"
-)
+	// https://api.github.com/repos/nshahm/hybrid.test.runner/contents/Shiftfile?ref=master
+	githubComPath = "/repos/:account/:repo/contents/:path"
 
-func GetSource(provider string) string {
+	// https://gitlab.com/api/v4/projects/nshahm%2Fhybrid.test.runner/repository/files/Shiftfile?ref=master
+	gitlabComPath = "/projects/:id/repository/files/:path"
 
-	source := ""
-	if provider == providers.GithubProviderName {
-		source = GITHUB_DOT_COM
-	} else if provider == providers.GitlabProviderName {
-		source = GITLAB_DOT_COM
-	} else if provider == providers.BitbucketProviderName {
-		source = BITBUCKET_DOT_ORG
-	}
+	// https://api.bitbucket.org/2.0/repositories/nshahm/hybrid.test.runner/src/master/Shiftfile
+	bitbucketOrgPath = "/repositories/:account/:repo/src/:ref/:path"
+)
 
-	// TODO must add for enterprise versions.
-	return source
+// Endpoint ..
+// Describes the version control system hosting the repository, the
+// provider and its urls are resolved by source for the public hosts.
+type Endpoint struct {
+	Source   string
+	Provider string
+	APIURL   string
+	WebURL   string
 }
 
-func GetShiftFile(source, url, branch string) ([]byte, error) {
+// resolve ..
+// Fills the provider and api url of the public hosts
+func (e Endpoint) resolve() Endpoint {
 
-	switch source {
+	if e.Provider != "" {
+		return e
+	}
 
+	switch e.Source {
 	case GITHUB_DOT_COM:
-		return getShiftfileFromGithub(url, branch)
+		e.Provider, e.APIURL, e.WebURL = providers.GithubProviderName, providers.GithubBaseURL, providers.GithubWebURL
 	case GITLAB_DOT_COM:
+		e.Provider, e.APIURL, e.WebURL = providers.GitlabProviderName, providers.GitlabBaseURLV4, providers.GitlabWebURL
 	case BITBUCKET_DOT_ORG:
+		e.Provider, e.APIURL, e.WebURL = providers.BitbucketProviderName, providers.BitbucketBaseURLV2, providers.BitbucketWebURL
+	case GITEA_DOT_COM:
+		e.Provider, e.APIURL, e.WebURL = providers.GiteaProviderName, providers.GiteaBaseURL, providers.GiteaWebURL
+	case CODEBERG_DOT_ORG:
+		e.Provider, e.APIURL, e.WebURL = providers.ForgejoProviderName, providers.ForgejoWebURL+providers.GiteaAPIPath, providers.ForgejoWebURL
+	}
+	return e
+}
+
+// GetShiftFile ..
+// Fetches the Shiftfile at the root of the repository at the given branch
+func GetShiftFile(e Endpoint, url, branch, token string) ([]byte, error) {
+	return GetFile(e, url, branch, token, SHIFTFILE)
+}
+
+// GetFile ..
+// Fetches the file (path relative to the repository root) at the given
+// branch, the token of the linked account is used to access private
+// repositories. Remotes other than the known providers are fetched through git.
+func GetFile(e Endpoint, url, branch, token, path string) ([]byte, error) {
+
+	if e.Source == "" {
+		e.Source, _, _ = ParseGitUrl(url)
+	}
+	e = e.resolve()
+
+	switch e.Provider {
+
+	case providers.GithubProviderName, providers.GiteaProviderName, providers.ForgejoProviderName:
+		// gitea serves github compatible contents api
+		return getFileFromGithub(e, url, branch, token, path)
+	case providers.GitlabProviderName:
+		return getFileFromGitlab(e, url, branch, token, path)
+	case providers.BitbucketProviderName:
+		return getFileFromBitbucket(e, url, branch, token, path)
+	}
+	return GetFileFromGit(url, branch, path, TokenCredentials(e, url, token))
+}
+
+func getFileFromGithub(e Endpoint, url, branch, token, path string) ([]byte, error) {
+
+	_, account, repo := e.parse(url)
+
+	r := dispatch.NewGetRequestMaker(e.APIURL + githubComPath)
+
+	r.Header("Accept", dispatch.JSON)
+	if token != "" {
+		r.Header("Authorization", "token "+token)
+	}
+
+	r.PathParams(account, repo, path)
+
+	r.QueryParam("ref", branch)
+
+	result := struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}{}
+
+	err := checkResponse(r, r.Scan(&result).Dispatch(), path)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("Url not supported")
+
+	decoded, err := base64.StdEncoding.DecodeString(result.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
 }
 
-func getShiftfileFromGithub(url, branch string) ([]byte, error) {
+func getFileFromGitlab(e Endpoint, uri, branch, token, path string) ([]byte, error) {
 
-	_, account, repo := parseGitUrl(url)
+	_, account, repo := e.parse(uri)
 
-	r := dispatch.NewGetRequestMaker(githubComUrl)
+	r := dispatch.NewGetRequestMaker(e.APIURL + gitlabComPath)
 
 	r.Header("Accept", dispatch.JSON)
+	if token != "" {
+		r.Header("Authorization", "Bearer "+token)
+	}
 
-	r.PathParams(account, repo)
+	r.PathParams(url.PathEscape(account+"/"+repo), url.PathEscape(path))
 
 	r.QueryParam("ref", branch)
 
@@ -67,7 +153,7 @@ func getShiftfileFromGithub(url, branch string) ([]byte, error) {
 		Encoding string `json:"encoding"`
 	}{}
 
-	err := r.Scan(&result).Dispatch()
+	err := checkResponse(r, r.Scan(&result).Dispatch(), path)
 	if err != nil {
 		return nil, err
 	}
@@ -80,34 +166,144 @@ func getShiftfileFromGithub(url, branch string) ([]byte, error) {
 	return decoded, nil
 }
 
-func parseGitUrl(uri string) (string, string, string) {
+func getFileFromBitbucket(e Endpoint, url, branch, token, path string) ([]byte, error) {
+
+	_, account, repo := e.parse(url)
+
+	r := dispatch.NewGetRequestMaker(e.APIURL + bitbucketOrgPath)
+
+	if token != "" {
+		r.Header("Authorization", "Bearer "+token)
+	}
+
+	r.PathParams(account, repo, branch, path)
+
+	var content []byte
+	err := checkResponse(r, r.Scan(&content).Dispatch(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// TokenCredentials ..
+// Returns the credentials to authenticate http remotes with the token,
+// the username expected along with oauth token differs by the provider.
+func TokenCredentials(e Endpoint, uri, token string) Credentials {
+
+	if token == "" || !strings.HasPrefix(uri, "http") {
+		return Credentials{}
+	}
+
+	username := "oauth2"
+	switch e.Provider {
+	case providers.GithubProviderName:
+		username = "x-access-token"
+	case providers.BitbucketProviderName:
+		username = "x-token-auth"
+	}
+	return Credentials{Username: username, Token: token}
+}
+
+// checkResponse ..
+// Converts the unsuccessful response to error
+func checkResponse(r *dispatch.RequestMaker, err error, path string) error {
+
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("No %s found in the repository", path)
+	}
+
+	if r.StatusCode() >= http.StatusBadRequest {
+		return fmt.Errorf("Failed to fetch %s, status %d", path, r.StatusCode())
+	}
+	return nil
+}
+
+// parse ..
+// Parses the clone url, the path the web url of the self-hosted
+// system is served under is not considered as account.
+func (e Endpoint) parse(uri string) (string, string, string) {
+
+	if e.WebURL != "" && strings.HasPrefix(uri, e.WebURL+"/") {
+
+		source, _, _ := ParseGitUrl(e.WebURL)
+		_, account, repoName := splitRepoPath(strings.TrimPrefix(uri, e.WebURL))
+		return source, account, repoName
+	}
+	return ParseGitUrl(uri)
+}
+
+// ParseGitUrl ..
+// Parses the clone url to the source (host), account and repository name.
+// The account holds the nested groups if any (gitlab subgroups).
+func ParseGitUrl(uri string) (string, string, string) {
 
 	// parse uri and identify the VCS
 	// git@github.com:nshahm/hybrid.test.runner.git
-	protoGit := strings.HasPrefix(uri, "git@")
-	protoHttps := strings.HasPrefix(uri, "http")
+	// https://github.com/nshahm/hybrid.test.runner.git
+	// ssh://git@git.example.com:7999/project/repo.git
+	if strings.Contains(uri, "://") {
 
-	eIdx := strings.LastIndex(uri, "/")
-	var sIdx int
-	var source, account, repoName string
-	if protoGit {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", "", ""
+		}
+		return splitRepoPath(u.Hostname() + "/" + strings.TrimPrefix(u.Path, "/"))
+	}
 
-		sIdx = strings.Index(uri, "@")
-		val := uri[sIdx+1 : eIdx]
-		valArr := strings.Split(val, ":")
-		source = valArr[0]
-		account = valArr[1]
-		repoName = uri[eIdx+1:]
+	// scp like syntax
+	sIdx := strings.Index(uri, "@")
+	eIdx := strings.Index(uri, ":")
+	if eIdx < sIdx {
+		return "", "", ""
+	}
+	return splitRepoPath(uri[sIdx+1:eIdx] + "/" + uri[eIdx+1:])
+}
 
-	} else if protoHttps {
+// scp like uri, user@host:path
+var scpUrl = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*@[A-Za-z0-9][A-Za-z0-9.-]*:[^\s-][^\s]*$`)
 
-		valArr := strings.Split(uri, "/")
-		source = valArr[2]
-		account = valArr[3]
-		repoName = valArr[4]
+// ValidGitUrl ..
+// True if the clone url is either https, ssh or the scp like syntax. The
+// url is passed to git, so the ones that git may read as an option or as
+// another transport (ext::, file://) are refused.
+func ValidGitUrl(uri string) bool {
+
+	if uri == "" || strings.HasPrefix(uri, "-") || strings.ContainsAny(uri, " \t\r\n") {
+		return false
+	}
+
+	if strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "ssh://") {
+
+		u, err := url.Parse(uri)
+		if err != nil {
+			return false
+		}
+
+		host := u.Hostname()
+		return host != "" && !strings.HasPrefix(host, "-") && strings.TrimPrefix(u.Path, "/") != ""
 	}
 
-	repoName = strings.TrimRight(repoName, ".git")
+	return scpUrl.MatchString(uri)
+}
+
+// splitRepoPath ..
+// Splits <source>/<account>/<repository> path
+func splitRepoPath(path string) (string, string, string) {
+
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
 
+	var source, account, repoName string
+	parts := strings.Split(path, "/")
+	source = parts[0]
+	if len(parts) > 1 {
+		repoName = parts[len(parts)-1]
+		account = strings.Join(parts[1:len(parts)-1], "/")
+	}
 	return source, account, repoName
 }
@@ -4,18 +4,214 @@ Copyright 2018 The Elasticshift Authors.
 package vcs
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
+var shiftfile = []byte(`VERSION "1.0"`)
+
 func TestShiftfileFromGithub(t *testing.T) {
 
 	url := "https://github.com/nshahm/hybrid.test.runner.git"
 	branch := "master"
-	data, err := GetShiftFile(GITHUB_DOT_COM, url, branch)
+	data, err := GetShiftFile(Endpoint{Source: GITHUB_DOT_COM}, url, branch, "")
 	if err != nil {
 		fmt.Println(err)
 		t.Fail()
 	}
 	fmt.Println(string(data[:]))
 }
+
+func TestShiftfileFromGitlab(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.EscapedPath() != "/projects/nshahm%2Fhybrid.test.runner/repository/files/Shiftfile" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+
+		if r.URL.Query().Get("ref") != "master" || r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprintf(w, `{"encoding":"base64","content":"%s"}`, base64.StdEncoding.EncodeToString(shiftfile))
+	}))
+	defer srv.Close()
+
+	// self-managed gitlab served under a path
+	e := Endpoint{Source: "git.example.com", Provider: "gitlab", APIURL: srv.URL, WebURL: "https://git.example.com/gitlab"}
+
+	data, err := GetShiftFile(e, "https://git.example.com/gitlab/nshahm/hybrid.test.runner.git", "master", "t0k3n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(shiftfile) {
+		t.Fatalf("unexpected shiftfile %s", data)
+	}
+
+	_, err = GetShiftFile(e, "https://git.example.com/gitlab/nshahm/hybrid.test.runner.git", "master", "")
+	if err == nil {
+		t.Fatal("expected failure without token")
+	}
+}
+
+func TestShiftfileFromBitbucket(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path != "/repositories/nshahm/hybrid.test.runner/src/develop/Shiftfile" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write(shiftfile)
+	}))
+	defer srv.Close()
+
+	e := Endpoint{Provider: "bitbucket", APIURL: srv.URL}
+
+	data, err := GetShiftFile(e, "https://bitbucket.org/nshahm/hybrid.test.runner.git", "develop", "t0k3n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(shiftfile) {
+		t.Fatalf("unexpected shiftfile %s", data)
+	}
+}
+
+func TestShiftfileFromGitea(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path != "/api/v1/repos/infra/tools/contents/Shiftfile" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if r.URL.Query().Get("ref") != "main" || r.Header.Get("Authorization") != "token t0k3n" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprintf(w, `{"type":"file","encoding":"base64","content":"%s"}`, base64.StdEncoding.EncodeToString(shiftfile))
+	}))
+	defer srv.Close()
+
+	e := Endpoint{Provider: "gitea", APIURL: srv.URL + "/api/v1", WebURL: "https://git.example.com"}
+
+	data, err := GetShiftFile(e, "https://git.example.com/infra/tools.git", "main", "t0k3n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(shiftfile) {
+		t.Fatalf("unexpected shiftfile %s", data)
+	}
+}
+
+func TestShiftfileFromGit(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, SHIFTFILE), shiftfile, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"checkout", "--quiet", "-b", "release"},
+		{"add", SHIFTFILE},
+		{"-c", "user.name=shift", "-c", "user.email=shift@localhost", "commit", "--quiet", "-m", "Add shiftfile"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v %s", args, err, out)
+		}
+	}
+
+	data, err := GetShiftFile(Endpoint{}, "file://"+dir, "release", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(shiftfile) {
+		t.Fatalf("unexpected shiftfile %s", data)
+	}
+
+	_, err = GetShiftFile(Endpoint{}, "file://"+dir, "unknown", "")
+	if err == nil {
+		t.Fatal("expected failure for unknown branch")
+	}
+}
+
+func TestParseGitUrl(t *testing.T) {
+
+	tests := []struct {
+		uri, source, account, repo string
+	}{
+		{"git@github.com:nshahm/hybrid.test.runner.git", "github.com", "nshahm", "hybrid.test.runner"},
+		{"https://bitbucket.org/nshahm/digit.git", "bitbucket.org", "nshahm", "digit"},
+		{"https://gitlab.example.com:8443/group/subgroup/repo.git", "gitlab.example.com", "group/subgroup", "repo"},
+		{"ssh://git@git.example.com:7999/project/repo.git", "git.example.com", "project", "repo"},
+		{"https://git.example.com/repo.git", "git.example.com", "", "repo"},
+	}
+
+	for _, tt := range tests {
+
+		source, account, repo := ParseGitUrl(tt.uri)
+		if source != tt.source || account != tt.account || repo != tt.repo {
+			t.Errorf("ParseGitUrl(%s) = %s, %s, %s", tt.uri, source, account, repo)
+		}
+	}
+}
+
+func TestValidGitUrl(t *testing.T) {
+
+	tests := []struct {
+		uri   string
+		valid bool
+	}{
+		{"git@github.com:nshahm/hybrid.test.runner.git", true},
+		{"https://bitbucket.org/nshahm/digit.git", true},
+		{"https://gitlab.example.com:8443/group/subgroup/repo.git", true},
+		{"ssh://git@git.example.com:7999/project/repo.git", true},
+		{"--upload-pack=touch /tmp/pwned;:a/b", false},
+		{"-oProxyCommand=x@host:a/b", false},
+		{"ssh://-oProxyCommand=x/a/b", false},
+		{"git@host:-a/b", false},
+		{"ext::sh -c touch% /tmp/pwned", false},
+		{"file:///tmp/repo", false},
+		{"http://git.example.com/repo.git", false},
+		{"https://", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+
+		if valid := ValidGitUrl(tt.uri); valid != tt.valid {
+			t.Errorf("ValidGitUrl(%s) = %v, want %v", tt.uri, valid, tt.valid)
+		}
+	}
+}
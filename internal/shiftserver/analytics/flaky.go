@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+)
+
+// the runs of a node on the same inputs, the same commit
+// built by the same pipeline and the same image
+type inputKey struct {
+	commit   string
+	pipeline string
+	subBuild string
+	node     string
+}
+
+type outcome struct {
+	failed  bool
+	buildID string
+	at      time.Time
+}
+
+// Flaky ..
+// Returns the nodes that both failed and passed on the same commit, either
+// passed on a rerun after failing, or alternated between the two, from the
+// flakiest. The builds of the repository are given from the oldest, the
+// builds in progress are left out.
+func Flaky(builds []types.Build, quarantine []string) []types.FlakyNode {
+
+	runs := map[inputKey][]outcome{}
+	var keys []inputKey
+	for _, b := range builds {
+
+		switch build.Status(b) {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+			continue
+		}
+
+		for _, sb := range b.SubBuilds {
+
+			if sb.Graph == "" {
+				continue
+			}
+
+			cps, err := graph.Decode(sb.Graph)
+			if err != nil {
+				continue
+			}
+
+			for _, cp := range cps {
+
+				ns := append([]*graph.N{cp.Node}, cp.Edges...)
+				for _, n := range ns {
+
+					if n == nil || !counted(n) {
+						continue
+					}
+
+					key := inputKey{b.CommitID, b.Pipeline, sb.ID, n.Key()}
+					if _, ok := runs[key]; !ok {
+						keys = append(keys, key)
+					}
+
+					at := n.EndedAt
+					if at.IsZero() {
+						at = b.ID.Time()
+					}
+					runs[key] = append(runs[key], outcome{Failed(n), b.ID.Hex(), at})
+				}
+			}
+		}
+	}
+
+	nodes := map[string]*types.FlakyNode{}
+	flakyCommits := map[string]map[string]bool{}
+	var names []string
+	for _, key := range keys {
+
+		fn, ok := nodes[key.node]
+		if !ok {
+			fn = &types.FlakyNode{Node: key.node, Quarantined: quarantined(quarantine, key.node)}
+			nodes[key.node] = fn
+			names = append(names, key.node)
+		}
+
+		var flipped bool
+		seq := runs[key]
+		for i, o := range seq {
+
+			fn.Runs++
+			if o.failed {
+				fn.Failures++
+			}
+
+			if i == 0 {
+				continue
+			}
+			fn.Reruns++
+
+			if o.failed != seq[i-1].failed {
+
+				fn.Flips++
+				flipped = true
+
+				if o.at.After(fn.LastFlakedAt) {
+					fn.LastFlakedAt = o.at
+					fn.LastBuildID = o.buildID
+				}
+			}
+		}
+
+		if flipped && !flakyCommits[key.node][key.commit] {
+
+			if flakyCommits[key.node] == nil {
+				flakyCommits[key.node] = map[string]bool{}
+			}
+			flakyCommits[key.node][key.commit] = true
+			fn.Commits++
+		}
+	}
+
+	var result []types.FlakyNode
+	for _, name := range names {
+
+		fn := nodes[name]
+		if fn.Flips == 0 {
+			continue
+		}
+
+		fn.Score = float64(fn.Flips) / float64(fn.Reruns)
+		result = append(result, *fn)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Flips > result[j].Flips
+	})
+	return result
+}
+
+// quarantined ..
+// True if the node is quarantined, matched the way the worker matches
+// the blocks allowed to fail
+func quarantined(quarantine []string, node string) bool {
+
+	for _, q := range quarantine {
+		if graph.SameKey(q, node) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestFlaky(t *testing.T) {
+
+	start := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	// the builds of the commit, each ran the nodes with the given outcomes
+	minute := 0
+	build := func(commit, pipeline, status string, outcomes map[string]string) types.Build {
+
+		minute++
+		at := start.Add(time.Duration(minute) * time.Minute)
+
+		var cps []*graph.Checkpoint
+		for _, desc := range []string{"compile", "test", "lint"} {
+			if s, ok := outcomes[desc]; ok {
+				cps = append(cps, &graph.Checkpoint{Node: &graph.N{Name: "shell", Description: desc, Status: s, EndedAt: at}})
+			}
+		}
+
+		return types.Build{
+			ID:        bson.NewObjectIdWithTime(at),
+			CommitID:  commit,
+			Pipeline:  pipeline,
+			SubBuilds: []types.SubBuild{{ID: "1", Status: status, Graph: encode(t, cps...)}},
+		}
+	}
+
+	s, f := graph.StatusSuccess, graph.StatusFailed
+	failed, success := types.BuildStatusFailed, types.BuildStatusSuccess
+
+	builds := []types.Build{
+
+		// passed on the rerun
+		build("c1", "", failed, map[string]string{"compile": s, "test": f}),
+		build("c1", "", success, map[string]string{"compile": s, "test": s}),
+
+		// alternated
+		build("c2", "", success, map[string]string{"compile": s, "test": s, "lint": f}),
+		build("c2", "", failed, map[string]string{"compile": s, "test": f, "lint": f}),
+		build("c2", "", success, map[string]string{"compile": s, "test": s, "lint": s}),
+
+		// another pipeline doesn't build the same inputs
+		build("c3", "", failed, map[string]string{"compile": f}),
+		build("c3", "deploy", success, map[string]string{"compile": s}),
+
+		// the build in progress is left out
+		build("c3", "", types.BuildStatusRunning, map[string]string{"compile": s}),
+	}
+	last := builds[4]
+
+	flaky := Flaky(builds, []string{" TEST "})
+	if len(flaky) != 2 {
+		t.Fatalf("expected 2 flaky nodes, got %+v", flaky)
+	}
+
+	test := flaky[0]
+	if test.Node != "test" || test.Runs != 5 || test.Failures != 2 || test.Reruns != 3 || test.Flips != 3 || test.Commits != 2 || test.Score != 1 {
+		t.Errorf("unexpected flakiness of the test %+v", test)
+	}
+
+	if test.LastBuildID != last.ID.Hex() || !test.LastFlakedAt.Equal(last.ID.Time()) {
+		t.Errorf("expected the test to flake last on %s, got %s", last.ID.Hex(), test.LastBuildID)
+	}
+
+	if !test.Quarantined {
+		t.Error("expected the test to be quarantined, the case is ignored")
+	}
+
+	lint := flaky[1]
+	if lint.Node != "lint" || lint.Runs != 3 || lint.Reruns != 2 || lint.Flips != 1 || lint.Commits != 1 || lint.Score != 0.5 || lint.Quarantined {
+		t.Errorf("unexpected flakiness of the lint %+v", lint)
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/team"
+	"github.com/graphql-go/graphql"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	defaultDays  = 30
+	maxDays      = 365
+	defaultNodes = 10
+)
+
+var (
+	errInvalidDays         = fmt.Errorf("Days must be between 1 and %d", maxDays)
+	errInvalidNodes        = errors.New("Number of nodes must be positive")
+	errInvalidRepositoryID = errors.New("Repository ID is invalid")
+	errInvalidNode         = errors.New("Node must be the description of a block, without commas")
+)
+
+// Resolver ...
+type Resolver interface {
+	FetchAnalytics(params graphql.ResolveParams) (interface{}, error)
+	FetchFlakyNodes(params graphql.ResolveParams) (interface{}, error)
+	QuarantineNode(params graphql.ResolveParams) (interface{}, error)
+	UnquarantineNode(params graphql.ResolveParams) (interface{}, error)
+}
+
+type resolver struct {
+	store           store.Build
+	repositoryStore store.Repository
+	buildStatStore  store.BuildStat
+	buildTimeStore  store.BuildTime
+	nodeStatStore   store.NodeStat
+	logger          *logrus.Entry
+	Ctx             context.Context
+}
+
+// NewResolver ...
+func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift) (Resolver, error) {
+
+	r := &resolver{
+		store:           s.Build,
+		repositoryStore: s.Repository,
+		buildStatStore:  s.BuildStat,
+		buildTimeStore:  s.BuildTime,
+		nodeStatStore:   s.NodeStat,
+		logger:          loggr.GetLogger("graphql/analytics"),
+		Ctx:             ctx,
+	}
+	return r, nil
+}
+
+// FetchAnalytics ..
+// Computes the metrics of the builds of the team, the repository or the
+// branch, over the days up to today, from the daily rollups
+func (r *resolver) FetchAnalytics(params graphql.ResolveParams) (interface{}, error) {
+
+	teamName, _ := params.Args["team"].(string)
+	if teamName == "" {
+		return nil, team.ErrTeamNameIsEmpty
+	}
+
+	days, err := daysArg(params)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, ok := params.Args["nodes"].(int)
+	if !ok {
+		limit = defaultNodes
+	}
+
+	if limit <= 0 {
+		return nil, errInvalidNodes
+	}
+
+	to := Day(time.Now()).Add(24 * time.Hour)
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+
+	q := bson.M{"team": teamName, "day": bson.M{"$gte": from, "$lt": to}}
+	if id, _ := params.Args["repository_id"].(string); id != "" {
+		q["repository_id"] = id
+	}
+
+	if branch, _ := params.Args["branch"].(string); branch != "" {
+		q["branch"] = branch
+	}
+
+	res := types.Analytics{From: from, To: to}
+
+	rolled, err := r.buildStatStore.FetchDays(q)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the builds by day: %v", err)
+	}
+
+	// the days without builds are in the series too
+	byDay := map[time.Time]types.AnalyticsDay{}
+	for _, d := range rolled {
+		byDay[d.Day.UTC()] = d
+	}
+
+	var total types.AnalyticsDay
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+
+		d := byDay[day]
+		d.Day = day
+		d.SuccessRate = successRate(d)
+		res.Series = append(res.Series, d)
+
+		total.Builds += d.Builds
+		total.Succeeded += d.Succeeded
+		total.Failed += d.Failed
+		total.Cancelled += d.Cancelled
+		total.Stuck += d.Stuck
+	}
+	res.Builds = total.Builds
+	res.SuccessRate = successRate(total)
+
+	res.DurationP50, res.DurationP95, err = r.percentiles(q, "duration")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the build durations: %v", err)
+	}
+
+	res.WaitP50, res.WaitP95, err = r.percentiles(q, "wait")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the queue waits: %v", err)
+	}
+
+	res.SlowestNodes, err = r.nodeStatStore.FetchNodes(q, "average_duration", limit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the slowest nodes: %v", err)
+	}
+
+	res.FailingNodes, err = r.nodeStatStore.FetchNodes(q, "failures", limit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the most failing nodes: %v", err)
+	}
+
+	return res, nil
+}
+
+// FetchFlakyNodes ..
+// Returns the nodes of the repository that both failed and passed on the
+// same commit over the days up to today, from the flakiest
+func (r *resolver) FetchFlakyNodes(params graphql.ResolveParams) (interface{}, error) {
+
+	repo, err := r.repository(params)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := daysArg(params)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	builds, err := r.store.FetchCommitBuilds(repo.ID.Hex(), since)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the builds of the repository: %v", err)
+	}
+
+	return Flaky(builds, repo.Quarantine), nil
+}
+
+// QuarantineNode ..
+// Allows the block of the repository to fail without failing the builds,
+// the block is still run and its failures are still counted. Returns the
+// blocks quarantined in the repository.
+func (r *resolver) QuarantineNode(params graphql.ResolveParams) (interface{}, error) {
+
+	repo, node, err := r.quarantineArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// the block is matched ignoring the case, as the worker matches it
+	if quarantined(repo.Quarantine, node) {
+		return repo.Quarantine, nil
+	}
+
+	err = r.repositoryStore.AddQuarantine(repo.ID, node)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to quarantine the node: %v", err)
+	}
+	return append(repo.Quarantine, node), nil
+}
+
+// UnquarantineNode ..
+// Fails the builds of the repository again when the block fails.
+// Returns the blocks quarantined in the repository.
+func (r *resolver) UnquarantineNode(params graphql.ResolveParams) (interface{}, error) {
+
+	repo, node, err := r.quarantineArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, q := range repo.Quarantine {
+
+		if !graph.SameKey(q, node) {
+			result = append(result, q)
+			continue
+		}
+
+		err = r.repositoryStore.RemoveQuarantine(repo.ID, q)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to remove the node from the quarantine: %v", err)
+		}
+	}
+	return result, nil
+}
+
+func (r *resolver) quarantineArgs(params graphql.ResolveParams) (types.Repository, string, error) {
+
+	repo, err := r.repository(params)
+	if err != nil {
+		return repo, "", err
+	}
+
+	// the quarantined blocks are passed on to the worker separated by commas
+	node, _ := params.Args["node"].(string)
+	node = strings.TrimSpace(node)
+	if node == "" || strings.Contains(node, ",") {
+		return repo, "", errInvalidNode
+	}
+	return repo, node, nil
+}
+
+// repository ..
+// Returns the repository of the team
+func (r *resolver) repository(params graphql.ResolveParams) (types.Repository, error) {
+
+	teamName, _ := params.Args["team"].(string)
+	if teamName == "" {
+		return types.Repository{}, team.ErrTeamNameIsEmpty
+	}
+
+	id, _ := params.Args["repository_id"].(string)
+	if !bson.IsObjectIdHex(id) {
+		return types.Repository{}, errInvalidRepositoryID
+	}
+
+	repo, err := r.repositoryStore.GetRepositoryByID(id)
+	if err != nil || repo.Team != teamName {
+		return types.Repository{}, errInvalidRepositoryID
+	}
+	return repo, nil
+}
+
+func daysArg(params graphql.ResolveParams) (int, error) {
+
+	days, ok := params.Args["days"].(int)
+	if !ok {
+		days = defaultDays
+	}
+
+	if days <= 0 || days > maxDays {
+		return 0, errInvalidDays
+	}
+	return days, nil
+}
+
+// successRate ..
+// Ratio of the succeeded builds to the finished builds, the cancelled
+// builds are neither succeeded nor failed
+func successRate(d types.AnalyticsDay) float64 {
+
+	n := d.Builds - d.Cancelled
+	if n <= 0 {
+		return 0
+	}
+	return float64(d.Succeeded) / float64(n)
+}
+
+// percentiles ..
+// Returns the median and the 95th percentile of the time of the builds
+// matching the query, such as the duration
+func (r *resolver) percentiles(q bson.M, field string) (float64, float64, error) {
+
+	n, err := r.buildTimeStore.CountValues(q, field)
+	if err != nil || n == 0 {
+		return 0, 0, err
+	}
+
+	p50, err := r.buildTimeStore.FetchValueAt(q, field, percentile(n, 50))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	p95, err := r.buildTimeStore.FetchValueAt(q, field, percentile(n, 95))
+	return p50, p95, err
+}
+
+// percentile ..
+// Returns the index of the nearest rank percentile of the n sorted values
+func percentile(n int, p float64) int {
+
+	i := int(math.Ceil(p/100*float64(n))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
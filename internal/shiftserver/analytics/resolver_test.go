@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestSuccessRate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		day  types.AnalyticsDay
+		rate float64
+	}{
+		{"no builds", types.AnalyticsDay{}, 0},
+		{"all succeeded", types.AnalyticsDay{Builds: 2, Succeeded: 2}, 1},
+		{"half succeeded", types.AnalyticsDay{Builds: 4, Succeeded: 2, Failed: 1, Stuck: 1}, 0.5},
+		{"cancelled left out", types.AnalyticsDay{Builds: 4, Succeeded: 1, Failed: 1, Cancelled: 2}, 0.5},
+		{"all cancelled", types.AnalyticsDay{Builds: 2, Cancelled: 2}, 0},
+	}
+
+	for _, tt := range tests {
+		if r := successRate(tt.day); r != tt.rate {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.rate, r)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+
+	tests := []struct {
+		n     int
+		p     float64
+		index int
+	}{
+		{0, 50, 0},
+		{1, 50, 0},
+		{1, 95, 0},
+		{2, 50, 0},
+		{2, 95, 1},
+		{10, 50, 4},
+		{10, 95, 9},
+		{100, 95, 94},
+		{101, 95, 95},
+	}
+
+	for _, tt := range tests {
+		if i := percentile(tt.n, tt.p); i != tt.index {
+			t.Errorf("percentile(%d, %v) = %d, expected %d", tt.n, tt.p, i, tt.index)
+		}
+	}
+}
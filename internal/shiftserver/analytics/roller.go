@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+
+	// interval the finished builds are rolled up
+	rollTick = 5 * time.Minute
+
+	// builds rolled up at once, the rest are rolled up on the next run
+	rollBatch = 500
+)
+
+// Roller ..
+// Adds the finished builds to the daily rollups the analytics are computed
+// from. Each build is claimed before it's added, so that it's counted once
+// even when many servers are rolling up.
+type Roller struct {
+	store          store.Build
+	buildStatStore store.BuildStat
+	buildTimeStore store.BuildTime
+	nodeStatStore  store.NodeStat
+	logger         *logrus.Entry
+}
+
+// NewRoller ..
+func NewRoller(loggr logger.Loggr, s store.Shift) *Roller {
+
+	return &Roller{
+		store:          s.Build,
+		buildStatStore: s.BuildStat,
+		buildTimeStore: s.BuildTime,
+		nodeStatStore:  s.NodeStat,
+		logger:         loggr.GetLogger("analytics/roller"),
+	}
+}
+
+// Run ..
+// Rolls up the finished builds periodically, until the context is done
+func (rl *Roller) Run(ctx context.Context) {
+
+	t := time.NewTicker(rollTick)
+	defer t.Stop()
+
+	for {
+
+		// the builds finished before the server started are rolled up
+		// right away, a batch per tick afterwards
+		for rl.roll() == rollBatch {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// roll ..
+// Rolls up a batch of the finished builds, returns the number rolled up
+func (rl *Roller) roll() int {
+
+	builds, err := rl.store.FetchUnrolledBuilds(rollBatch)
+	if err != nil {
+		rl.logger.Errorf("Failed to fetch the finished builds to roll up: %v", err)
+		return 0
+	}
+
+	for _, b := range builds {
+
+		claimed, err := rl.store.ClaimRollup(b.ID)
+		if err != nil {
+			rl.logger.Errorf("Failed to claim the build %s for the rollup: %v", b.ID.Hex(), err)
+			return 0
+		}
+
+		if !claimed {
+			continue
+		}
+
+		st, bt, nodes := Rollup(b)
+
+		// the times are kept once by the build, so they're recorded
+		// before the counts
+		if bt.Duration > 0 || bt.Wait > 0 {
+			err = rl.buildTimeStore.Record(bt)
+		}
+
+		if err == nil {
+			err = rl.buildStatStore.Record(st)
+		}
+
+		// the build is rolled up again on the next run
+		if err != nil {
+
+			rl.logger.Warnf("Failed to roll up the build %s: %v", b.ID.Hex(), err)
+
+			err = rl.store.ReleaseRollup(b.ID)
+			if err != nil {
+				rl.logger.Errorf("Failed to release the build %s for the rollup: %v", b.ID.Hex(), err)
+			}
+			return 0
+		}
+
+		for _, n := range nodes {
+
+			err = rl.nodeStatStore.Record(n)
+			if err != nil {
+				rl.logger.Warnf("Failed to roll up the node %s of build %s: %v", n.Node, b.ID.Hex(), err)
+			}
+		}
+	}
+	return len(builds)
+}
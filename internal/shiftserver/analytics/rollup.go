@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+)
+
+// Day ..
+// Returns the day the rollups of the time are kept under
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Rollup ..
+// Returns the counts of the finished build, and of the runs of its nodes,
+// to be added to the rollups of the day the build is triggered, and the
+// times of the build
+func Rollup(b types.Build) (types.BuildStat, types.BuildTime, []types.NodeStat) {
+
+	created := b.ID.Time()
+
+	st := types.BuildStat{
+		Team:         b.Team,
+		RepositoryID: b.RepositoryID,
+		Branch:       b.Branch,
+		Day:          Day(created),
+		Builds:       1,
+	}
+
+	switch build.Status(b) {
+	case types.BuildStatusSuccess:
+		st.Succeeded = 1
+	case types.BuildStatusFailed:
+		st.Failed = 1
+	case types.BuildStatusCancel:
+		st.Cancelled = 1
+	case types.BuildStatusStuck:
+		st.Stuck = 1
+	}
+
+	bt := types.BuildTime{
+		ID:           b.ID,
+		Team:         st.Team,
+		RepositoryID: st.RepositoryID,
+		Branch:       st.Branch,
+		Day:          st.Day,
+	}
+
+	if d := build.Duration(b); d > 0 {
+		bt.Duration = d.Seconds()
+	}
+
+	if w := wait(b, created); w > 0 {
+		bt.Wait = w.Seconds()
+	}
+
+	nodes := map[string]*types.NodeStat{}
+	var names []string
+	for _, sb := range b.SubBuilds {
+
+		if sb.Graph == "" {
+			continue
+		}
+
+		cps, err := graph.Decode(sb.Graph)
+		if err != nil {
+			continue
+		}
+
+		for _, cp := range cps {
+
+			ns := append([]*graph.N{cp.Node}, cp.Edges...)
+			for _, n := range ns {
+
+				if n == nil || !counted(n) {
+					continue
+				}
+
+				name := n.Key()
+				ndst, ok := nodes[name]
+				if !ok {
+					ndst = &types.NodeStat{
+						Team:         st.Team,
+						RepositoryID: st.RepositoryID,
+						Branch:       st.Branch,
+						Day:          st.Day,
+						Node:         name,
+					}
+					nodes[name] = ndst
+					names = append(names, name)
+				}
+
+				ndst.Runs++
+				if Failed(n) {
+					ndst.Failures++
+				}
+
+				if !n.StartedAt.IsZero() && n.EndedAt.After(n.StartedAt) {
+
+					d := n.EndedAt.Sub(n.StartedAt).Seconds()
+					ndst.Duration += d
+					if d > ndst.MaxDuration {
+						ndst.MaxDuration = d
+					}
+				}
+			}
+		}
+	}
+
+	var result []types.NodeStat
+	for _, name := range names {
+		result = append(result, *nodes[name])
+	}
+	return st, bt, result
+}
+
+// wait ..
+// Returns the time the build waited in the queue, until the first of its
+// sub builds is launched
+func wait(b types.Build, created time.Time) time.Duration {
+
+	var start time.Time
+	for _, sb := range b.SubBuilds {
+
+		t := sb.LaunchedAt
+		if t.IsZero() {
+			t = sb.StartedAt
+		}
+
+		if !t.IsZero() && (start.IsZero() || t.Before(start)) {
+			start = t
+		}
+	}
+
+	if start.IsZero() || !start.After(created) {
+		return 0
+	}
+	return start.Sub(created)
+}
+
+// counted ..
+// The nodes that ran are counted, the nodes that only join the
+// graph, and the skipped or reused nodes are not
+func counted(n *graph.N) bool {
+
+	switch n.Name {
+	case graph.START, graph.END:
+		return false
+	}
+
+	if strings.HasPrefix(n.Name, graph.FANOUT) || strings.HasPrefix(n.Name, graph.FANIN) {
+		return false
+	}
+	return n.Status == graph.StatusSuccess || Failed(n)
+}
+
+// Failed ..
+// True if the node failed, even if it's allowed to fail
+func Failed(n *graph.N) bool {
+	return n.Status == graph.StatusFailed || n.Status == graph.StatusAllowedFailure
+}
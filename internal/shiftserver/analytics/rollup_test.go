@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func encode(t *testing.T, cps ...*graph.Checkpoint) string {
+
+	gph, err := graph.Encode(cps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gph
+}
+
+func TestRollup(t *testing.T) {
+
+	created := time.Date(2018, 6, 1, 23, 50, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return created.Add(d) }
+
+	node := func(name, desc, status string, from, to time.Duration) *graph.N {
+		return &graph.N{Name: name, Description: desc, Status: status, StartedAt: at(from), EndedAt: at(to)}
+	}
+
+	gph := encode(t,
+		&graph.Checkpoint{Node: &graph.N{Name: graph.START, Status: graph.StatusSuccess}},
+		&graph.Checkpoint{Node: node("shell", "compile", graph.StatusSuccess, 2*time.Minute, 4*time.Minute)},
+		&graph.Checkpoint{Node: &graph.N{Name: graph.FANOUT + "_1", Status: graph.StatusSuccess}, Edges: []*graph.N{
+			node("shell", "test", graph.StatusFailed, 4*time.Minute, 7*time.Minute),
+			node("shell", "lint", graph.StatusAllowedFailure, 4*time.Minute, 5*time.Minute),
+			node("shell", "deploy", graph.StatusSkipped, 0, 0),
+		}},
+		&graph.Checkpoint{Node: node("shell", "compile", graph.StatusSuccess, 7*time.Minute, 8*time.Minute)},
+		&graph.Checkpoint{Node: &graph.N{Name: graph.END, Status: graph.StatusFailed}},
+	)
+
+	b := types.Build{
+		ID:           bson.NewObjectIdWithTime(created),
+		Team:         "team",
+		RepositoryID: "r1",
+		Branch:       "master",
+		SubBuilds: []types.SubBuild{{
+			ID:         "1",
+			Status:     types.BuildStatusFailed,
+			Graph:      gph,
+			LaunchedAt: at(time.Minute),
+			StartedAt:  at(2 * time.Minute),
+			EndedAt:    at(8 * time.Minute),
+		}},
+	}
+
+	st, bt, nodes := Rollup(b)
+
+	day := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	if !st.Day.Equal(day) || st.Builds != 1 || st.Failed != 1 || st.Succeeded != 0 {
+		t.Errorf("unexpected rollup of the build %+v", st)
+	}
+
+	if bt.ID != b.ID || !bt.Day.Equal(day) || bt.Duration != 360 || bt.Wait != 60 {
+		t.Errorf("unexpected times of the build %+v", bt)
+	}
+
+	expected := []types.NodeStat{
+		{Node: "compile", Runs: 2, Duration: 180, MaxDuration: 120},
+		{Node: "test", Runs: 1, Failures: 1, Duration: 180, MaxDuration: 180},
+		{Node: "lint", Runs: 1, Failures: 1, Duration: 60, MaxDuration: 60},
+	}
+
+	if len(nodes) != len(expected) {
+		t.Fatalf("expected %d nodes, got %+v", len(expected), nodes)
+	}
+
+	for i, e := range expected {
+
+		n := nodes[i]
+		if n.Node != e.Node || n.Runs != e.Runs || n.Failures != e.Failures || n.Duration != e.Duration || n.MaxDuration != e.MaxDuration {
+			t.Errorf("expected the node %+v, got %+v", e, n)
+		}
+
+		if n.Team != "team" || n.RepositoryID != "r1" || n.Branch != "master" || !n.Day.Equal(day) {
+			t.Errorf("expected the node %s rolled up under the branch and the day, got %+v", e.Node, n)
+		}
+	}
+}
+
+func TestRollupStatus(t *testing.T) {
+
+	tests := []struct {
+		status string
+		st     types.BuildStat
+	}{
+		{types.BuildStatusSuccess, types.BuildStat{Builds: 1, Succeeded: 1}},
+		{types.BuildStatusFailed, types.BuildStat{Builds: 1, Failed: 1}},
+		{types.BuildStatusCancel, types.BuildStat{Builds: 1, Cancelled: 1}},
+		{types.BuildStatusStuck, types.BuildStat{Builds: 1, Stuck: 1}},
+	}
+
+	for _, tt := range tests {
+
+		b := types.Build{ID: bson.NewObjectId(), SubBuilds: []types.SubBuild{{ID: "1", Status: tt.status}}}
+
+		st, bt, nodes := Rollup(b)
+		if st.Builds != tt.st.Builds || st.Succeeded != tt.st.Succeeded || st.Failed != tt.st.Failed ||
+			st.Cancelled != tt.st.Cancelled || st.Stuck != tt.st.Stuck {
+			t.Errorf("%s: expected %+v, got %+v", tt.status, tt.st, st)
+		}
+
+		// the build that never ran has no times and no nodes
+		if bt.Duration != 0 || bt.Wait != 0 || len(nodes) != 0 {
+			t.Errorf("%s: unexpected times %+v and nodes %+v", tt.status, bt, nodes)
+		}
+	}
+}
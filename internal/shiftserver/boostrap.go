@@ -3,7 +3,73 @@ Copyright 2017 The Elasticshift Authors.
 */
 package shiftserver
 
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/internal/shiftserver/analytics"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/repository"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/retention"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/schedule"
+)
+
 func (s Server) bootstrap() error {
 
+	// the builds are numbered before their numbers are indexed
+	numbered, err := build.NumberBuilds(s.Shift)
+	if err != nil {
+		return err
+	}
+
+	if numbered > 0 {
+		s.Logger.Infof("Numbered %d builds", numbered)
+	}
+
+	// indexes the builds are looked up by
+	err = s.Shift.Build.EnsureIndexes()
+	if err != nil {
+		return fmt.Errorf("Failed to create the indexes of the builds: %v", err)
+	}
+
+	// index the queued builds are scheduled by
+	err = s.Shift.BuildQueue.EnsureIndexes()
+	if err != nil {
+		return fmt.Errorf("Failed to create the indexes of the build queue: %v", err)
+	}
+
+	// indexes the rollups of the analytics are recorded by
+	err = s.Shift.BuildStat.EnsureIndexes()
+	if err == nil {
+		err = s.Shift.BuildTime.EnsureIndexes()
+	}
+
+	if err == nil {
+		err = s.Shift.NodeStat.EnsureIndexes()
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to create the indexes of the analytics: %v", err)
+	}
+
+	// detects the changes on plain git repositories
+	p := repository.NewPoller(s.Loggr, s.Shift, s.Vault, s.Resolver)
+	go p.Run(s.Ctx)
+
+	// refreshes the tokens and syncs the repositories of the linked accounts
+	rc := repository.NewReconciler(s.Loggr, s.Shift, s.Providers, s.Vault)
+	go rc.Run(s.Ctx)
+
+	// triggers the builds of the schedules that are due
+	sc := schedule.NewScheduler(s.Loggr, s.Shift, s.Resolver)
+	go sc.Run(s.Ctx)
+
+	// removes the builds expired by the retention
+	cl := retention.NewCleaner(s.Loggr, s.Shift)
+	go cl.Run(s.Ctx)
+
+	// rolls up the finished builds for the analytics
+	rl := analytics.NewRoller(s.Loggr, s.Shift)
+	go rl.Run(s.Ctx)
+
 	return nil
 }
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/badge"
+	"github.com/gorilla/mux"
+)
+
+// seconds the badges are cached by the browsers and the proxies, they're
+// revalidated with the etag afterwards
+const badgeMaxAge = 60
+
+// Badge ..
+// Renders the status of the latest build of the branch as an svg badge,
+// the default branch of the repository when it's not given. The badges of
+// the private repositories are rendered only with the token of the repository.
+func (s service) Badge(w http.ResponseWriter, r *http.Request) {
+
+	q := r.URL.Query()
+
+	repo, err := s.repository(mux.Vars(r)["team"], mux.Vars(r)["repo"])
+	if err != nil {
+		writeBadge(w, r, http.StatusNotFound, "not found", badge.Grey, false)
+		return
+	}
+
+	// a private repository without a token is reported as not found,
+	// so its existence isn't revealed
+	token := q.Get("token")
+	if repo.Private && (repo.BadgeToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(repo.BadgeToken)) != 1) {
+		writeBadge(w, r, http.StatusNotFound, "not found", badge.Grey, false)
+		return
+	}
+
+	branch := q.Get("branch")
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+
+	builds, err := s.buildStore.FetchBuild(repo.Team, repo.ID.Hex(), branch, q.Get("pipeline"), "", nil, 1)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch the latest build of %s for the badge: %v", repo.Name, err)
+		writeBadge(w, r, http.StatusInternalServerError, "error", badge.Grey, repo.Private)
+		return
+	}
+
+	if len(builds) == 0 {
+		writeBadge(w, r, http.StatusOK, "no builds", badge.Grey, repo.Private)
+		return
+	}
+
+	msg, color := badgeStatus(builds[0])
+	writeBadge(w, r, http.StatusOK, msg, color, repo.Private)
+}
+
+// badgeStatus ..
+// Returns the message and the color of the badge of the build, the
+// finished builds are followed by how long they took
+func badgeStatus(b types.Build) (string, string) {
+
+	var msg, color string
+	switch Status(b) {
+	case types.BuildStatusSuccess:
+		msg, color = "passing", badge.Green
+	case types.BuildStatusFailed:
+		msg, color = "failing", badge.Red
+	case types.BuildStatusPreparing, types.BuildStatusRunning:
+		return "running", badge.Yellow
+	case types.BuildStatusWaiting:
+		return "queued", badge.Grey
+	case types.BuildStatusStuck:
+		msg, color = "stuck", badge.Orange
+	case types.BuildStatusCancel:
+		msg, color = "cancelled", badge.Grey
+	default:
+		return "unknown", badge.Grey
+	}
+
+	if d := Duration(b).Round(time.Second); d > 0 {
+		msg = msg + " " + d.String()
+	}
+	return msg, color
+}
+
+func writeBadge(w http.ResponseWriter, r *http.Request, code int, msg, color string, private bool) {
+
+	svg := badge.Render("build", msg, color)
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(svg))
+
+	cache := "public"
+	if private {
+		cache = "private"
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "image/svg+xml")
+	h.Set("Cache-Control", fmt.Sprintf("%s, max-age=%d, must-revalidate", cache, badgeMaxAge))
+	h.Set("ETag", etag)
+
+	if code == http.StatusOK && etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(code)
+	w.Write(svg)
+}
+
+// etagMatch ..
+// Tells whether the If-None-Match header contains the etag
+func etagMatch(header, etag string) bool {
+
+	for _, t := range strings.Split(header, ",") {
+
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Cancel ..
+// Cancels the sub builds that are not finished yet, the launched containers
+// are deleted and the build is taken off the queue. The concurrency group
+// and the branch held by the build are passed on to the next build.
+func (r *resolver) Cancel(b types.Build, reason string) error {
+
+	buildID := b.ID.Hex()
+
+	var engine integration.ContainerEngineInterface
+	var active bool
+	for _, sb := range b.SubBuilds {
+
+		switch sb.Status {
+		case types.BuildStatusCancel, types.BuildStatusFailed, types.BuildStatusSuccess, types.BuildStatusStuck:
+			continue
+		case types.BuildStatusPreparing, types.BuildStatusRunning:
+			active = true
+		}
+
+		if sb.Metadata != nil && sb.Metadata.ContainerID != "" {
+
+			var err error
+			if engine == nil {
+				engine, err = r.GetContainerEngine(b.Team)
+			}
+
+			if err == nil {
+				err = engine.DeleteContainer(buildID + "-" + sb.ID)
+			}
+
+			if err != nil {
+				r.logger.Warnf("Failed to delete the container of build %s-%s: %v", buildID, sb.ID, err)
+			}
+		}
+
+		// the sub build finished meanwhile is left as it is
+		err := r.finishSubBuild("cancel", buildID, sb, types.BuildStatusCancel, reason)
+		if err != nil && err != ErrTransitionRejected {
+			return err
+		}
+	}
+
+	err := r.queueStore.Remove(b.ID)
+	if err != nil && err.Error() != "not found" {
+		r.logger.Errorf("Failed to remove the build %s from the queue: %v", buildID, err)
+	}
+
+	r.releaseGroup(b)
+	r.releaseSlots(buildID)
+
+	r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+
+	// the waiting build of the branch is kicked off
+	if active {
+		r.TriggerNextIfAny(buildID, b.Team, b.RepositoryID, b.Branch)
+	}
+
+	return nil
+}
+
+// supersedes ..
+// A push supersedes the older builds of the branch, when the repository is
+// configured to auto cancel, except on the default branch unless configured.
+func supersedes(repo types.Repository, branch string) bool {
+
+	if !repo.AutoCancel.Enabled {
+		return false
+	}
+	return branch != repo.DefaultBranch || repo.AutoCancel.DefaultBranch
+}
+
+// cancelSuperseded ..
+// Cancels the waiting and running builds of the branch (and the pipeline)
+// superseded by the saved build, referencing the build that superseded them.
+func (r *resolver) cancelSuperseded(by types.Build) {
+
+	builds, err := r.store.FetchBuild(by.Team, by.RepositoryID, by.Branch, by.Pipeline, "", []string{types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning}, 0)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch the builds superseded by build %s: %v", by.ID.Hex(), err)
+		return
+	}
+
+	reason := fmt.Sprintf("Superseded by build %s", by.ID.Hex())
+	for _, b := range superseded(builds, by) {
+
+		err = r.store.UpdateId(b.ID, bson.M{"$set": bson.M{"superseded_by": by.ID.Hex()}})
+		if err != nil {
+			r.logger.Errorf("Failed to mark the build %s as superseded: %v", b.ID.Hex(), err)
+		}
+
+		b.SupersededBy = by.ID.Hex()
+		err = r.Cancel(b, reason)
+		if err != nil {
+			r.logger.Errorf("Failed to cancel the superseded build %s: %v", b.ID.Hex(), err)
+		}
+	}
+}
+
+// superseded ..
+// Returns the builds superseded by the build, the ones building the same
+// commit are left to finish. The waiting builds come first, so none of them
+// is kicked off when the running build is cancelled.
+func superseded(builds []types.Build, by types.Build) []types.Build {
+
+	var result []types.Build
+	for _, b := range builds {
+		if b.ID != by.ID && b.CommitID != by.CommitID {
+			result = append(result, b)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return waiting(result[i]) && !waiting(result[j])
+	})
+	return result
+}
+
+func waiting(b types.Build) bool {
+	return len(b.SubBuilds) > 0 && b.SubBuilds[0].Status == types.BuildStatusWaiting
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSupersedes(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		autoCancel types.AutoCancel
+		branch     string
+		supersedes bool
+	}{
+		{"disabled", types.AutoCancel{}, "feature", false},
+		{"feature branch", types.AutoCancel{Enabled: true}, "feature", true},
+		{"default branch", types.AutoCancel{Enabled: true}, "master", false},
+		{"default branch configured", types.AutoCancel{Enabled: true, DefaultBranch: true}, "master", true},
+		{"default branch only", types.AutoCancel{DefaultBranch: true}, "master", false},
+	}
+
+	for _, tt := range tests {
+		repo := types.Repository{DefaultBranch: "master", AutoCancel: tt.autoCancel}
+		if s := supersedes(repo, tt.branch); s != tt.supersedes {
+			t.Errorf("%s: expected supersedes to be %v", tt.name, tt.supersedes)
+		}
+	}
+}
+
+func TestSuperseded(t *testing.T) {
+
+	build := func(commitID, status string) types.Build {
+		return types.Build{
+			ID:        bson.NewObjectId(),
+			CommitID:  commitID,
+			SubBuilds: []types.SubBuild{{ID: "1", Status: status}},
+		}
+	}
+
+	by := build("c3", types.BuildStatusWaiting)
+	running := build("c1", types.BuildStatusRunning)
+	waiting := build("c2", types.BuildStatusWaiting)
+	sameCommit := build("c3", types.BuildStatusRunning)
+
+	tests := []struct {
+		name       string
+		builds     []types.Build
+		superseded []types.Build
+	}{
+		{"none", nil, nil},
+		{"itself", []types.Build{by}, nil},
+		{"same commit", []types.Build{sameCommit, by}, nil},
+		{"waiting first", []types.Build{running, waiting, by}, []types.Build{waiting, running}},
+		{"older commits", []types.Build{sameCommit, running, by}, []types.Build{running}},
+	}
+
+	for _, tt := range tests {
+
+		s := superseded(tt.builds, by)
+		if len(s) != len(tt.superseded) {
+			t.Errorf("%s: expected %d superseded builds, got %d", tt.name, len(tt.superseded), len(s))
+			continue
+		}
+
+		for i := range s {
+			if s[i].ID != tt.superseded[i].ID {
+				t.Errorf("%s: expected build %s at %d, got %s", tt.name, tt.superseded[i].ID.Hex(), i, s[i].ID.Hex())
+			}
+		}
+	}
+}
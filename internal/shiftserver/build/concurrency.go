@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
+)
+
+var (
+	// interval a build held by its concurrency group looks for the group again
+	groupRetryDelay = 15 * time.Second
+)
+
+// acquireGroup ..
+// Locks the concurrency group of the team for the build. The build waits while the group
+// is held by another build, or cancels it when the group is configured to
+// cancel the build in progress. The group held by a build that has finished
+// without releasing it is taken over.
+func (r *resolver) acquireGroup(b types.Build, group string, cancel bool) (bool, error) {
+
+	buildID := b.ID.Hex()
+
+	lock, acquired, err := r.concurrencyStore.Acquire(b.Team, group, buildID)
+	if err != nil {
+		return false, err
+	}
+
+	if !acquired {
+
+		holder, err := r.store.FetchBuildByID(lock.BuildID)
+		if err != nil && err.Error() != "not found" {
+			return false, err
+		}
+
+		switch {
+		case err == nil && inProgress(holder) && cancel:
+
+			r.logger.Infof("Cancelling the build %s in progress of concurrency group %s, superseded by build %s", lock.BuildID, group, buildID)
+			err = r.Cancel(holder, fmt.Sprintf("Cancelled by the build %s of concurrency group %s", buildID, group))
+			if err == nil {
+				_, acquired, err = r.concurrencyStore.Acquire(b.Team, group, buildID)
+			}
+		case err != nil || !inProgress(holder):
+			acquired, err = r.concurrencyStore.Takeover(b.Team, group, lock.BuildID, buildID)
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	heldBy := group
+	if acquired {
+		heldBy = ""
+	}
+
+	// waiting builds show the group holding them
+	if b.Concurrency != group || b.HeldBy != heldBy {
+
+		err = r.store.UpdateConcurrency(b.ID, group, heldBy)
+		if err != nil {
+			r.logger.Errorf("Failed to update the concurrency group of build %s: %v", buildID, err)
+		}
+		r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+	}
+
+	return acquired, nil
+}
+
+// releaseGroup ..
+// Releases the concurrency group held by the build
+func (r *resolver) releaseGroup(b types.Build) {
+
+	if b.Concurrency == "" || b.HeldBy != "" {
+		return
+	}
+
+	err := r.concurrencyStore.Release(b.Team, b.Concurrency, b.ID.Hex())
+	if err != nil {
+		r.logger.Errorf("Failed to release the concurrency group %s of build %s: %v", b.Concurrency, b.ID.Hex(), err)
+	}
+}
+
+// inProgress ..
+// A build is in progress until all of its sub builds are finished
+func inProgress(b types.Build) bool {
+
+	for _, sb := range b.SubBuilds {
+		switch sb.Status {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/graphql-go/graphql"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// builds of a pipeline triggered one from another, beyond which
+// the trigger is refused as it's likely to be a loop
+const maxPipelineDepth = 10
+
+// DownstreamOptions ..
+// Holds the information given by the trigger block of the upstream build
+type DownstreamOptions struct {
+
+	// repository of the upstream team, as "name" or "owner/name"
+	Repository string
+	Branch     string
+	Pipeline   string
+	Parameters map[string]string
+
+	// the trigger block, and whether it waits for the build to finish
+	Node string
+	Wait bool
+}
+
+// TriggerDownstream ..
+// Triggers the build of another repository of the team, from a block of
+// the upstream build. Both the builds are linked, to be shown as a pipeline.
+func (r *resolver) TriggerDownstream(upstream types.Build, opts DownstreamOptions) (types.Build, error) {
+
+	repo, err := findRepository(r.repositoryStore, upstream.Team, opts.Repository)
+	if err != nil {
+		return types.Build{}, err
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+
+	err = r.checkLoop(upstream, repo.ID.Hex(), branch, opts.Pipeline)
+	if err != nil {
+		return types.Build{}, err
+	}
+
+	t := TriggerOptions{}
+	t.Repository = repo
+	t.Branch = branch
+	t.Pipeline = opts.Pipeline
+	t.Parameters = opts.Parameters
+	t.TriggeredBy = fmt.Sprintf("build %s", upstream.ID.Hex())
+	t.Upstream = &types.BuildLink{
+		BuildID:      upstream.ID.Hex(),
+		RepositoryID: upstream.RepositoryID,
+		Branch:       upstream.Branch,
+		Pipeline:     upstream.Pipeline,
+		Node:         opts.Node,
+		Wait:         opts.Wait,
+	}
+
+	b, err := r.Trigger(t)
+	if err != nil || !opts.Wait {
+		return b, err
+	}
+
+	r.waitFor(upstream, b)
+	return b, nil
+}
+
+// waitFor ..
+// The upstream build waiting for the build releases its limits and its
+// concurrency group, the build may need them to be launched otherwise. It
+// goes on without them once the build is finished, as it can't be held.
+func (r *resolver) waitFor(upstream, b types.Build) {
+
+	err := r.store.SetWaitingFor(upstream.ID, b.ID.Hex())
+	if err != nil {
+		r.logger.Errorf("Failed to set the build %s waiting for the build %s: %v", upstream.ID.Hex(), b.ID.Hex(), err)
+	}
+
+	r.releaseGroup(upstream)
+	r.releaseSlots(upstream.ID.Hex())
+}
+
+// WaitOver ..
+// Ends the wait of the upstream build for the finished build
+func (r *resolver) WaitOver(b types.Build) {
+
+	if b.Upstream == nil || !b.Upstream.Wait || !bson.IsObjectIdHex(b.Upstream.BuildID) {
+		return
+	}
+
+	err := r.store.ClearWaitingFor(bson.ObjectIdHex(b.Upstream.BuildID), b.ID.Hex())
+	if err != nil {
+		r.logger.Errorf("Failed to end the wait of the build %s for the build %s: %v", b.Upstream.BuildID, b.ID.Hex(), err)
+	}
+}
+
+// ParseParameters ..
+// Parses the parameters given as NAME=VALUE to the downstream build.
+// The names of the environment set by the server are refused.
+func ParseParameters(args []string) (map[string]string, error) {
+
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+
+		kv := strings.SplitN(arg, "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if name == "" {
+			return nil, fmt.Errorf("Invalid parameter '%s', expected NAME=VALUE", arg)
+		}
+
+		if ReservedParameter(name) {
+			return nil, fmt.Errorf("Parameter '%s' is reserved for the environment set by the server", name)
+		}
+
+		if len(kv) == 2 {
+			params[name] = kv[1]
+		} else {
+			params[name] = ""
+		}
+	}
+	return params, nil
+}
+
+// ReservedParameter ..
+// The environment set by the server can't be given as a parameter
+func ReservedParameter(name string) bool {
+	return strings.HasPrefix(name, "SHIFT_") || name == "WORKER_PORT"
+}
+
+// findRepository ..
+// Finds the repository of the team by "name", or by "owner/name"
+// when the team has the repositories of the same name
+func findRepository(s store.Repository, team, name string) (types.Repository, error) {
+
+	var owner string
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		owner, name = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return types.Repository{}, fmt.Errorf("Repository to trigger cannot be empty")
+	}
+
+	repos, err := s.GetRepositoryByName(team, name)
+	if err != nil {
+		return types.Repository{}, fmt.Errorf("Failed to fetch the repository '%s': %v", name, err)
+	}
+
+	var found []types.Repository
+	for _, repo := range repos {
+
+		// identifier is "source/account"
+		if owner == "" || strings.HasSuffix(strings.ToLower(repo.Identifier), "/"+strings.ToLower(owner)) {
+			found = append(found, repo)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return types.Repository{}, fmt.Errorf("Repository '%s' is not found in the team", joinOwner(owner, name))
+	case 1:
+		return found[0], nil
+	}
+	return types.Repository{}, fmt.Errorf("More than one repository named '%s' is found in the team, qualify it as 'owner/%s'", name, name)
+}
+
+func joinOwner(owner, name string) string {
+
+	if owner == "" {
+		return name
+	}
+	return owner + "/" + name
+}
+
+// checkLoop ..
+// Refuses to trigger a build of the branch (and the pipeline) that is
+// already part of the pipeline, as the builds would trigger each other
+func (r *resolver) checkLoop(upstream types.Build, repositoryID, branch, pipeline string) error {
+
+	b := upstream
+	for depth := 1; ; depth++ {
+
+		if b.RepositoryID == repositoryID && b.Branch == branch && b.Pipeline == pipeline {
+			return fmt.Errorf("Build %s of the same branch is part of the pipeline already, the builds would trigger each other", b.ID.Hex())
+		}
+
+		if b.Upstream == nil {
+			return nil
+		}
+
+		if depth >= maxPipelineDepth {
+			return fmt.Errorf("The pipeline is more than %d builds deep", maxPipelineDepth)
+		}
+
+		up, err := r.store.FetchBuildByID(b.Upstream.BuildID)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch the upstream build %s: %v", b.Upstream.BuildID, err)
+		}
+		b = up
+	}
+}
+
+// Status ..
+// Returns the status of the build, from the status of its sub builds.
+// A build is in progress until all of its sub builds are finished, and
+// succeeds only when all of them succeed.
+func Status(b types.Build) string {
+
+	if len(b.SubBuilds) == 0 {
+		return types.BuildStatusWaiting
+	}
+
+	status := types.BuildStatusSuccess
+	for _, sb := range b.SubBuilds {
+
+		switch sb.Status {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+			return sb.Status
+		case types.BuildStatusSuccess:
+		default:
+			if status == types.BuildStatusSuccess {
+				status = sb.Status
+			}
+		}
+	}
+	return status
+}
+
+// Duration ..
+// Returns the time from the start of the first sub build to the end of
+// the last one, zero when any of them never started or ended
+func Duration(b types.Build) time.Duration {
+
+	var start, end time.Time
+	for _, sb := range b.SubBuilds {
+
+		if sb.StartedAt.IsZero() || sb.EndedAt.IsZero() {
+			return 0
+		}
+
+		if start.IsZero() || sb.StartedAt.Before(start) {
+			start = sb.StartedAt
+		}
+
+		if sb.EndedAt.After(end) {
+			end = sb.EndedAt
+		}
+	}
+
+	if start.IsZero() || !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// FetchPipelineGraph ..
+// Returns the builds linked to the given build through the trigger blocks,
+// from the build that started the pipeline
+func (r *resolver) FetchPipelineGraph(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["build_id"].(string)
+	if !bson.IsObjectIdHex(id) {
+		return nil, errIDCantBeEmpty
+	}
+
+	b, err := r.store.FetchBuildByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the build: %v", err)
+	}
+
+	// the build started the pipeline
+	for depth := 1; b.Upstream != nil && depth < maxPipelineDepth; depth++ {
+
+		up, err := r.store.FetchBuildByID(b.Upstream.BuildID)
+		if err != nil {
+			break
+		}
+		b = up
+	}
+
+	g := types.PipelineGraph{}
+	names := map[string]string{}
+	seen := map[string]bool{b.ID.Hex(): true}
+
+	builds := []types.Build{b}
+	for len(builds) > 0 {
+
+		b := builds[0]
+		builds = builds[1:]
+
+		g.Nodes = append(g.Nodes, r.pipelineNode(b, names))
+
+		for _, d := range b.Downstream {
+
+			g.Edges = append(g.Edges, types.PipelineEdge{From: b.ID.Hex(), To: d.BuildID, Node: d.Node, Wait: d.Wait})
+
+			if seen[d.BuildID] || !bson.IsObjectIdHex(d.BuildID) {
+				continue
+			}
+			seen[d.BuildID] = true
+
+			db, err := r.store.FetchBuildByID(d.BuildID)
+			if err != nil {
+				r.logger.Warnf("Failed to fetch the downstream build %s: %v", d.BuildID, err)
+				continue
+			}
+			builds = append(builds, db)
+		}
+	}
+
+	return g, nil
+}
+
+func (r *resolver) pipelineNode(b types.Build, names map[string]string) types.PipelineNode {
+
+	name, ok := names[b.RepositoryID]
+	if !ok {
+
+		repo, err := r.repositoryStore.GetRepositoryByID(b.RepositoryID)
+		if err == nil {
+			name = repo.Name
+		}
+		names[b.RepositoryID] = name
+	}
+
+	return types.PipelineNode{
+		BuildID:      b.ID.Hex(),
+		Number:       b.Number,
+		RepositoryID: b.RepositoryID,
+		Repository:   name,
+		Branch:       b.Branch,
+		Pipeline:     b.Pipeline,
+		Status:       Status(b),
+		TriggeredBy:  b.TriggeredBy,
+	}
+}
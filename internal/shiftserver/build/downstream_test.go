@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestParseParameters(t *testing.T) {
+
+	tests := []struct {
+		args   []string
+		params map[string]string
+		err    bool
+	}{
+		{nil, map[string]string{}, false},
+		{[]string{"LIB_VERSION=1.2.0", "DEPLOY=false"}, map[string]string{"LIB_VERSION": "1.2.0", "DEPLOY": "false"}, false},
+		{[]string{" NAME =a=b", "EMPTY"}, map[string]string{"NAME": "a=b", "EMPTY": ""}, false},
+		{[]string{"=value"}, nil, true},
+		{[]string{"SHIFT_COMMIT=abc"}, nil, true},
+		{[]string{"WORKER_PORT=80"}, nil, true},
+	}
+
+	for _, tt := range tests {
+
+		params, err := ParseParameters(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseParameters(%v): unexpected error %v", tt.args, err)
+			continue
+		}
+
+		if len(params) != len(tt.params) {
+			t.Errorf("ParseParameters(%v) = %v, expected %v", tt.args, params, tt.params)
+			continue
+		}
+
+		for name, value := range tt.params {
+			if v, ok := params[name]; !ok || v != value {
+				t.Errorf("ParseParameters(%v) = %v, expected %v", tt.args, params, tt.params)
+			}
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+
+	sbs := func(status ...string) []types.SubBuild {
+
+		var s []types.SubBuild
+		for _, st := range status {
+			s = append(s, types.SubBuild{Status: st})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		subBuilds []types.SubBuild
+		status    string
+	}{
+		{"no sub build", nil, types.BuildStatusWaiting},
+		{"succeeded", sbs(types.BuildStatusSuccess, types.BuildStatusSuccess), types.BuildStatusSuccess},
+		{"in progress", sbs(types.BuildStatusFailed, types.BuildStatusRunning), types.BuildStatusRunning},
+		{"first failure", sbs(types.BuildStatusSuccess, types.BuildStatusCancel, types.BuildStatusFailed), types.BuildStatusCancel},
+	}
+
+	for _, tt := range tests {
+		if s := Status(types.Build{SubBuilds: tt.subBuilds}); s != tt.status {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.status, s)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+
+	start := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	sb := func(from, to time.Duration) types.SubBuild {
+		return types.SubBuild{StartedAt: start.Add(from), EndedAt: start.Add(to)}
+	}
+
+	tests := []struct {
+		name      string
+		subBuilds []types.SubBuild
+		duration  time.Duration
+	}{
+		{"no sub build", nil, 0},
+		{"one", []types.SubBuild{sb(0, time.Minute)}, time.Minute},
+		{"overlapping", []types.SubBuild{sb(time.Minute, 3*time.Minute), sb(0, 2*time.Minute)}, 3 * time.Minute},
+		{"not ended", []types.SubBuild{sb(0, time.Minute), {StartedAt: start}}, 0},
+	}
+
+	for _, tt := range tests {
+		if d := Duration(types.Build{SubBuilds: tt.subBuilds}); d != tt.duration {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.duration, d)
+		}
+	}
+}
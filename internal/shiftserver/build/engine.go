@@ -4,13 +4,18 @@ Copyright 2018 The Elasticshift Authors.
 package build
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/ast"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
 	itypes "github.com/elasticshift/elasticshift/internal/shiftserver/integration/types"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
@@ -126,242 +131,266 @@ func (r *resolver) GetContainerEngine(team string) (integration.ContainerEngineI
 	return integration.NewContainerEngine(r.loggr, i, stor)
 }
 
-func (r *resolver) ContainerLauncher() {
+// launch ..
+// Launches the containers of the build, one for each of the images
+func (r *resolver) launch(b types.Build, sf *ast.File, repoFile bool) {
 
-	defer r.recoverErrorIfAny()
+	// start the container
+	// TODO select the default orchestration, by config
+	// opts := &docker.ClientOptions{}
+	// opts.Host = docker.DefaultHost
+	// opts.Ctx = r.Ctx
 
-	// Restrict the concurrent execution and queue the build
-	// if it is for same branch.
-	// TODO handle panic
-	for b := range r.BuildQueue {
+	// cli, err := docker.NewClient(opts)
+	// if err != nil {
+	// 	r.SLog(b.ID, fmt.Sprintf("Failed to connect to docker daemon: %v", err))
+	// }
+	buildID := b.ID.Hex()
 
-		go func(b types.Build) {
+	var subBuildID int
+	subBuildID = 1
 
-			// start the container
-			// TODO select the default orchestration, by config
-			// opts := &docker.ClientOptions{}
-			// opts.Host = docker.DefaultHost
-			// opts.Ctx = r.Ctx
+	// Identify the default orchestration based integration
+	// such as docker swarm or kubernetes etc
+	engine, err := r.GetContainerEngine(b.Team)
+	if err != nil {
+		//udpate the build log and set the status to failed
+		r.logger.Errorf("Failed to connect container engine: %v", err)
+
+		sb := types.SubBuild{}
+		sb.Image = sf.ImageNames()[0]
+		sb.ID = strconv.Itoa(subBuildID)
+		sb.Status = types.BuildStatusFailed
+		sb.Reason = fmt.Sprintf("Failed to launch container: %v", err)
+
+		err := r.updateSubBuild("launch", buildID, sb)
+		if err != nil {
+			r.logger.Errorf("Error when updating the build status: %v", err)
+		}
+
+		r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+		return
+	}
 
-			// cli, err := docker.NewClient(opts)
-			// if err != nil {
-			// 	r.SLog(b.ID, fmt.Sprintf("Failed to connect to docker daemon: %v", err))
-			// }
-			buildID := b.ID.Hex()
+	// the blocks allowed to fail are the ones quarantined when launched
+	var quarantine []string
+	repo, err := r.repositoryStore.GetRepositoryByID(b.RepositoryID)
+	if err != nil {
+		r.logger.Warnf("Failed to fetch the quarantined blocks of repository %s: %v", b.RepositoryID, err)
+	} else {
+		quarantine = repo.Quarantine
+	}
 
-			var subBuildID int
-			subBuildID = 1
+	var subBuildExist = true
 
-			sf, repoFile, err := r.GetShiftfile(b)
+	for _, imgName := range sf.ImageNames() {
 
-			if err != nil {
-				//r.SLog(b.ID, fmt.Sprintf("Unable to find the build image from Shiftfile", b.CloneURL))
-				sb := types.SubBuild{}
-				sb.Image = sf.ImageNames()[0]
-				sb.ID = strconv.Itoa(subBuildID)
-				sb.Status = types.BuildStatusFailed
-				sb.Reason = fmt.Sprintf("Failed to find container image name: %s", err.Error())
-
-				err := r.store.UpdateSubBuild(buildID, sb)
-				if err != nil {
-					r.logger.Errorf("Error when updating the build status: %v", err)
-				}
-
-				r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
-				return
+		fmt.Println("Image name: " + imgName)
+		subBuildIDStr := strconv.Itoa(subBuildID)
+
+		sb := types.SubBuild{ID: subBuildIDStr, Image: imgName}
+
+		// the sub builds of the other images are launched right away
+		if !subBuildExist {
+			sb.Status = types.BuildStatusPreparing
+		}
+
+		g, err := graph.Construct(sf)
+		if err != nil {
+			sb.Status = types.BuildStatusFailed
+			sb.Reason = fmt.Sprintf("Failed when constructing execution graph: %v", err)
+
+			var err error
+			if subBuildExist {
+				err = r.updateSubBuild("launch", buildID, sb)
+			} else {
+				err = r.store.SaveSubBuild(buildID, &sb)
+				subBuildExist = true
 			}
 
-			// Identify the default orchestration based integration
-			// such as docker swarm or kubernetes etc
-			engine, err := r.GetContainerEngine(b.Team)
 			if err != nil {
-				//udpate the build log and set the status to failed
-				r.logger.Errorf("Failed to connect container engine: %v", err)
-
-				sb := types.SubBuild{}
-				sb.Image = sf.ImageNames()[0]
-				sb.ID = strconv.Itoa(subBuildID)
-				sb.Status = types.BuildStatusFailed
-				sb.Reason = fmt.Sprintf("Failed to launch container: %v", err)
-
-				err := r.store.UpdateSubBuild(buildID, sb)
-				if err != nil {
-					r.logger.Errorf("Error when updating the build status: %v", err)
-				}
-
-				r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
-				return
+				r.logger.Errorf("Error when updating the build status: %v", err)
 			}
 
-			var subBuildExist = true
-
-			for _, imgName := range sf.ImageNames() {
-
-				fmt.Println("Image name: " + imgName)
-				subBuildIDStr := strconv.Itoa(subBuildID)
-
-				sb := types.SubBuild{ID: subBuildIDStr, Image: imgName}
-
-				g, err := graph.Construct(sf)
-				if err != nil {
-					sb.Status = types.BuildStatusFailed
-					sb.Reason = fmt.Sprintf("Failed when constructing execution graph: %v", err)
-
-					var err error
-					if subBuildExist {
-						err = r.store.UpdateSubBuild(buildID, sb)
-					} else {
-						err = r.store.SaveSubBuild(buildID, &sb)
-						subBuildExist = true
-					}
-
-					if err != nil {
-						r.logger.Errorf("Error when updating the build status: %v", err)
-					}
-
-					r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
-					return
-				}
-
-				sb.Graph, _ = g.JSON()
-				sb.Image = imgName
-
-				if subBuildExist {
-					err = r.store.UpdateSubBuild(buildID, sb)
-				} else {
-					err = r.store.SaveSubBuild(buildID, &sb)
-					subBuildExist = true
-				}
-
-				if err != nil {
-					r.logger.Errorf("Error when updating the sub build: %v", err)
-				}
-
-				// find the system storage
-				// storage, err := r.sysconfStore.GetDefaultStorage()
-				// if err != nil {
-				// 	r.SLog(b.ID, "Failed to fetch the default storage: "+err.Error())
-				// 	return
-				// }
-
-				// err = utils.Mkdir(filepath.Join(storage.Path, "code", b.Team))
-				// if err != nil {
-				// 	r.SLog(b.ID, "Unable to create directory for cloning the project:"+err.Error())
-				// }
-
-				shiftHost := os.Getenv("SHIFT_HOST")
-				if shiftHost == "" {
-					shiftHost = "127.0.0.1"
-				}
-
-				// env := []string{
-				// 	"SHIFT_HOST=shiftserver",
-				// 	"SHIFT_PORT=5051",
-				// 	"SHIFT_LOGGER=" + LogType_File,
-				// 	"SHIFT_BUILDID=" + b.ID.Hex(),
-				// 	"SHIFT_TIMEOUT=120m",
-				// 	"WORKER_PORT=" + "6060",
-				// }
-
-				// filepath.Join(storage.Path, b.Team, DIR_CODE)
-
-				// hc := &container.HostConfig{}
-				// hc.Binds = []string{
-				// 	filepath.Join(storage.Path, b.Team, DIR_CODE) + ":" + VOL_CODE,
-				// 	filepath.Join(storage.Path, b.Team, DIR_LOGS) + ":" + VOL_LOGS,
-				// 	filepath.Join(storage.Path, DIR_PLUGINS) + ":" + VOL_PLUGINS,
-				// 	filepath.Join(storage.Path, DIR_WORKER) + ":" + VOL_SHIFT,
-				// }
-
-				// workerPort, _ := nat.NewPort("tcp", "6060")
-				// serverPort, _ := nat.NewPort("tcp", "5051")
-
-				// exposedPorts := map[nat.Port]struct{}{
-				// 	serverPort: struct{}{},
-				// 	workerPort: struct{}{},
-				// }
-
-				// c := &container.Config{
-				// 	Image:        imgName,
-				// 	Entrypoint:   strslice.StrSlice{"./shift/worker"},
-				// 	Env:          env,
-				// 	AttachStdout: true,
-				// 	ExposedPorts: exposedPorts,
-				// }
-
-				envs := []itypes.Env{
-					// itypes.Env{"SHIFT_HOST", "shahlab2.duckdns.org"},
-					itypes.Env{"SHIFT_HOST", shiftHost},
-					itypes.Env{"SHIFT_PORT", "9101"},
-					itypes.Env{"SHIFT_BUILDID", b.ID.Hex()},
-					itypes.Env{"SHIFT_SUBBUILDID", strconv.Itoa(subBuildID)},
-					itypes.Env{"SHIFT_TEAMID", b.Team},
-					itypes.Env{"SHIFT_TIMEOUT", "120m"},
-					itypes.Env{"WORKER_PORT", "9200"},
-					itypes.Env{"SHIFT_LOG_LEVEL", "info"},
-					itypes.Env{"SHIFT_LOG_FORMAT", "json"},
-					itypes.Env{"SHIFT_REPOFILE", strconv.FormatBool(repoFile)},
-				}
-
-				opts := &itypes.CreateContainerOptions{}
-				opts.Image = imgName
-				// opts.Command = "curl http://shahlab2.duckdns.org:9000/downloads/worker.sh | bash"
-				opts.Command = defaultStarupScript
-				opts.Environment = envs
-				opts.BuildID = b.ID.Hex()
-				opts.SubBuildID = subBuildIDStr
-				opts.FailureFunc = r.UpdateBuildStatusAsFailed
-				opts.UpdateMetadata = r.UpdateBuildMetadata
-				// opts.VolumeMounts = []itypes.Volume{{"localvol", "/opt/elasticshift"}}
-
-				res, err := engine.CreateContainer(opts)
-				if err != nil {
-					r.logger.Errorf("Create container failed: %v", err)
-					sb.Status = types.BuildStatusFailed
-					sb.Reason = err.Error()
-
-					var err error
-					if subBuildExist {
-						err = r.store.UpdateSubBuild(buildID, sb)
-					} else {
-						err = r.store.SaveSubBuild(buildID, &sb)
-						subBuildExist = true
-					}
-
-					if err != nil {
-						r.logger.Errorf("Error when updating the build status: %v", err)
-					}
-
-					r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
-					return
-				}
-
-				fmt.Println("Container ID =", res.UID)
-				if sb.Metadata == nil {
-					sb.Metadata = &types.Metadata{}
-				}
-				sb.Metadata.ContainerID = res.UID
-
-				if subBuildExist {
-					err = r.store.UpdateSubBuild(buildID, sb)
-				} else {
-					err = r.store.SaveSubBuild(buildID, &sb)
-					subBuildExist = true
-				}
-				if err != nil {
-					r.logger.Errorln("Failed to update the container id: ", res.UID)
-				}
-
-				subBuildID = subBuildID + 1
-				subBuildExist = false
+			r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+			return
+		}
+
+		sb.Graph, _ = g.JSON()
+		sb.Image = imgName
+
+		if subBuildExist {
+			err = r.updateSubBuild("launch", buildID, sb)
+		} else {
+			err = r.store.SaveSubBuild(buildID, &sb)
+			subBuildExist = true
+		}
+
+		if err != nil {
+			r.logger.Errorf("Error when updating the sub build: %v", err)
+		}
+
+		// find the system storage
+		// storage, err := r.sysconfStore.GetDefaultStorage()
+		// if err != nil {
+		// 	r.SLog(b.ID, "Failed to fetch the default storage: "+err.Error())
+		// 	return
+		// }
+
+		// err = utils.Mkdir(filepath.Join(storage.Path, "code", b.Team))
+		// if err != nil {
+		// 	r.SLog(b.ID, "Unable to create directory for cloning the project:"+err.Error())
+		// }
+
+		shiftHost := os.Getenv("SHIFT_HOST")
+		if shiftHost == "" {
+			shiftHost = "127.0.0.1"
+		}
+
+		// env := []string{
+		// 	"SHIFT_HOST=shiftserver",
+		// 	"SHIFT_PORT=5051",
+		// 	"SHIFT_LOGGER=" + LogType_File,
+		// 	"SHIFT_BUILDID=" + b.ID.Hex(),
+		// 	"SHIFT_TIMEOUT=120m",
+		// 	"WORKER_PORT=" + "6060",
+		// }
+
+		// filepath.Join(storage.Path, b.Team, DIR_CODE)
+
+		// hc := &container.HostConfig{}
+		// hc.Binds = []string{
+		// 	filepath.Join(storage.Path, b.Team, DIR_CODE) + ":" + VOL_CODE,
+		// 	filepath.Join(storage.Path, b.Team, DIR_LOGS) + ":" + VOL_LOGS,
+		// 	filepath.Join(storage.Path, DIR_PLUGINS) + ":" + VOL_PLUGINS,
+		// 	filepath.Join(storage.Path, DIR_WORKER) + ":" + VOL_SHIFT,
+		// }
+
+		// workerPort, _ := nat.NewPort("tcp", "6060")
+		// serverPort, _ := nat.NewPort("tcp", "5051")
+
+		// exposedPorts := map[nat.Port]struct{}{
+		// 	serverPort: struct{}{},
+		// 	workerPort: struct{}{},
+		// }
+
+		// c := &container.Config{
+		// 	Image:        imgName,
+		// 	Entrypoint:   strslice.StrSlice{"./shift/worker"},
+		// 	Env:          env,
+		// 	AttachStdout: true,
+		// 	ExposedPorts: exposedPorts,
+		// }
+
+		// directives given through the commit message or the labels
+		logLevel := "info"
+		if b.Directives.Debug {
+			logLevel = "debug"
+		}
+
+		envs := []itypes.Env{
+			// itypes.Env{"SHIFT_HOST", "shahlab2.duckdns.org"},
+			itypes.Env{"SHIFT_HOST", shiftHost},
+			itypes.Env{"SHIFT_PORT", "9101"},
+			itypes.Env{"SHIFT_BUILDID", b.ID.Hex()},
+			itypes.Env{"SHIFT_SUBBUILDID", strconv.Itoa(subBuildID)},
+			itypes.Env{"SHIFT_TEAMID", b.Team},
+			itypes.Env{"SHIFT_TIMEOUT", fmt.Sprintf("%dm", int(buildTimeout.Minutes()))},
+			itypes.Env{"WORKER_PORT", "9200"},
+			itypes.Env{"SHIFT_LOG_LEVEL", logLevel},
+			itypes.Env{"SHIFT_LOG_FORMAT", "json"},
+			itypes.Env{"SHIFT_REPOFILE", strconv.FormatBool(repoFile)},
+		}
+
+		// the commit built, the worker checks it out once the source is
+		// cloned, so that a rerun builds the same commit after the branch moved
+		if b.CommitID != "" {
+			envs = append(envs, itypes.Env{"SHIFT_COMMIT", b.CommitID})
+		}
+
+		if b.Directives.RebuildCache {
+			envs = append(envs, itypes.Env{"SHIFT_REBUILD_CACHE", "true"})
+		}
+
+		// json array, as the node names may contain commas
+		if len(b.Directives.Only) > 0 {
+			only, _ := json.Marshal(b.Directives.Only)
+			envs = append(envs, itypes.Env{"SHIFT_ONLY", string(only)})
+		}
+
+		// the flaky blocks quarantined in the repository
+		if len(quarantine) > 0 {
+			envs = append(envs, itypes.Env{"SHIFT_ALLOW_FAILURE", strings.Join(quarantine, ",")})
+		}
+
+		// the rerun resumes from the node, with the workspace restored
+		if b.FromNode != "" {
+			envs = append(envs, itypes.Env{"SHIFT_FROM_NODE", b.FromNode})
+			envs = append(envs, itypes.Env{"SHIFT_SNAPSHOT_BUILDID", b.SnapshotOf})
+		}
+
+		// parameters given by the upstream build, the ones
+		// that would override the worker's are left out
+		envs = append(envs, parameterEnvs(b.Parameters)...)
+
+		opts := &itypes.CreateContainerOptions{}
+		opts.Image = imgName
+		// opts.Command = "curl http://shahlab2.duckdns.org:9000/downloads/worker.sh | bash"
+		opts.Command = defaultStarupScript
+		opts.Environment = envs
+		opts.BuildID = b.ID.Hex()
+		opts.SubBuildID = subBuildIDStr
+		opts.FailureFunc = r.UpdateBuildStatusAsFailed
+		opts.UpdateMetadata = r.UpdateBuildMetadata
+		// opts.VolumeMounts = []itypes.Volume{{"localvol", "/opt/elasticshift"}}
+
+		res, err := engine.CreateContainer(opts)
+		if err != nil {
+			r.logger.Errorf("Create container failed: %v", err)
+			sb.Status = types.BuildStatusFailed
+			sb.Reason = err.Error()
+
+			var err error
+			if subBuildExist {
+				err = r.updateSubBuild("launch", buildID, sb)
+			} else {
+				err = r.store.SaveSubBuild(buildID, &sb)
+				subBuildExist = true
 			}
 
-			// err = cli.StartContainer(containerID)
-			// if err != nil {
-			// 	r.logger.Errorln("Failed to start the container: %v", err)
-			// }
-		}(b)
+			if err != nil {
+				r.logger.Errorf("Error when updating the build status: %v", err)
+			}
+
+			r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+			return
+		}
+
+		fmt.Println("Container ID =", res.UID)
+		if sb.Metadata == nil {
+			sb.Metadata = &types.Metadata{}
+		}
+		sb.Metadata.ContainerID = res.UID
+		sb.LaunchedAt = time.Now()
+
+		if subBuildExist {
+			err = r.updateSubBuild("launch", buildID, sb)
+		} else {
+			err = r.store.SaveSubBuild(buildID, &sb)
+			subBuildExist = true
+		}
+		if err != nil {
+			r.logger.Errorln("Failed to update the container id: ", res.UID)
+		}
+
+		subBuildID = subBuildID + 1
+		subBuildExist = false
 	}
+
+	// err = cli.StartContainer(containerID)
+	// if err != nil {
+	// 	r.logger.Errorln("Failed to start the container: %v", err)
+	// }
 }
 
 func (r *resolver) UpdateBuildMetadata(kind int, id, subid, podname string) {
@@ -377,7 +406,7 @@ func (r *resolver) UpdateBuildMetadata(kind int, id, subid, podname string) {
 	b.Metadata.Kind = kind
 	b.Metadata.PodName = podname
 
-	err = r.store.UpdateSubBuild(id, b)
+	err = r.updateSubBuild("metadata", id, types.SubBuild{ID: subid, Metadata: b.Metadata})
 	if err != nil {
 		r.logger.Errorf("Failed to update the build with metadata: %v", err)
 	}
@@ -389,3 +418,22 @@ func (r *resolver) recoverErrorIfAny() {
 		fmt.Printf("recovered : %v", err)
 	}
 }
+
+// parameterEnvs ..
+// Returns the parameters of the build as the environment, in the order of the names
+func parameterEnvs(params map[string]string) []itypes.Env {
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if name != "" && !ReservedParameter(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	envs := make([]itypes.Env, 0, len(names))
+	for _, name := range names {
+		envs = append(envs, itypes.Env{name, params[name]})
+	}
+	return envs
+}
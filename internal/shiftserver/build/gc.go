@@ -0,0 +1,225 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
+	itypes "github.com/elasticshift/elasticshift/internal/shiftserver/integration/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// the containers of the finished builds are left for the duration,
+	// so the logs can be looked into, and the containers just launched
+	// aren't mistaken for the ones of the unknown builds
+	gcGrace = 30 * time.Minute
+
+	// interval the containers on the engines are reconciled with the builds
+	gcTick = 10 * time.Minute
+
+	// job the runs of the garbage collection are claimed by
+	gcJob = "gc"
+)
+
+// collectGarbage ..
+// Removes the containers (and pods) left behind on the container engines
+// by the builds that are finished, or no longer exist.
+func (r *resolver) collectGarbage() {
+
+	t := time.NewTicker(gcTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.Ctx.Done():
+			return
+		case <-t.C:
+
+			now := time.Now()
+
+			// the engines are shared by the servers, one of them collects
+			// at a time. The claim falls short of the tick, so the server
+			// claiming the run ticks in time for the next one.
+			claimed, err := r.jobStore.ClaimRun(gcJob, now.Add(gcTick-gcTick/10), now)
+			if err != nil {
+				r.logger.Errorf("Failed to claim the garbage collection: %v", err)
+				continue
+			}
+
+			if claimed {
+				r.collect(now)
+			}
+		}
+	}
+}
+
+func (r *resolver) collect(now time.Time) {
+
+	var engines []types.ContainerEngine
+	err := r.integrationStore.FindAll(bson.M{"internal_type": integration.INT_ContainerEngine}, &engines)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch the container engines: %v", err)
+		return
+	}
+
+	// builds are looked up once, as the engines may be shared by the teams
+	builds := map[string]*types.Build{}
+	for _, i := range engines {
+
+		// storage is required only to launch the containers
+		ce, err := integration.NewContainerEngine(r.loggr, i, types.Storage{})
+		if err != nil {
+			r.logger.Warnf("Failed to connect to the container engine %s: %v", i.Name, err)
+			continue
+		}
+
+		r.collectEngine(i, ce, builds, now)
+	}
+}
+
+func (r *resolver) collectEngine(i types.ContainerEngine, ce integration.ContainerEngineInterface, builds map[string]*types.Build, now time.Time) {
+
+	containers, err := ce.ListContainers()
+	if err != nil {
+		r.logger.Warnf("Failed to list the containers of the engine %s: %v", i.Name, err)
+		return
+	}
+
+	for _, c := range containers {
+
+		b, reason, err := r.orphaned(c, builds, now)
+		if err != nil {
+			r.logger.Warnf("Failed to check the container %s: %v", c.Name, err)
+			continue
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		err = ce.DeleteContainer(c.Name)
+		if err != nil {
+			r.logger.Warnf("Failed to remove the container %s: %v", c.Name, err)
+			continue
+		}
+
+		r.logger.Infof("Removed the container %s from the engine %s: %s", c.Name, i.Name, reason)
+
+		rec := types.Container{}
+		rec.ContainerID = c.UID
+		rec.OrchestrationID = i.ID.Hex()
+		rec.Image = c.Image
+		rec.StartedAt = c.CreatedAt
+		rec.StoppedAt = now
+		rec.Duration = now.Sub(c.CreatedAt).Round(time.Second).String()
+		rec.Kind = engineKind(i.Kind)
+		rec.Status = types.CS_STOPPED
+		rec.Team = i.Team
+		rec.Reason = reason
+
+		if b != nil {
+			rec.BuildID = b.ID.Hex()
+			rec.RepositoryID = b.RepositoryID
+			rec.VcsID = b.VcsID
+		}
+
+		err = r.containerStore.Save(&rec)
+		if err != nil {
+			r.logger.Errorf("Failed to record the removal of the container %s: %v", c.Name, err)
+		}
+	}
+}
+
+// orphaned ..
+// Returns the reason the container is removed, when the build it runs is
+// finished beyond the grace period, or the build isn't known at all.
+// The reason is empty when the container is left as is.
+func (r *resolver) orphaned(c itypes.ContainerInfo, builds map[string]*types.Build, now time.Time) (*types.Build, string, error) {
+
+	if now.Sub(c.CreatedAt) < gcGrace {
+		return nil, "", nil
+	}
+
+	// the container not named after a build is left as is, it may be
+	// launched by other means
+	buildID, subBuildID, ok := containerBuild(c)
+	if !ok {
+		return nil, "", nil
+	}
+
+	b, ok := builds[buildID]
+	if !ok {
+
+		fb, err := r.store.FetchBuildByID(buildID)
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, "", err
+		}
+
+		if err == nil {
+			b = &fb
+		}
+		builds[buildID] = b
+	}
+
+	return b, orphanReason(b, buildID, subBuildID, now), nil
+}
+
+// containerBuild ..
+// Returns the build and the sub build of the container, labelled as
+// <buildid>-<subbuildid>, or named so when launched before it's labelled
+func containerBuild(c itypes.ContainerInfo) (string, string, bool) {
+
+	name := c.Labels[integration.KW_BUILDID]
+	if name == "" {
+		name = c.Name
+	}
+	return integration.BuildContainer(name)
+}
+
+// orphanReason ..
+// Returns the reason the container of the sub build is removed, empty
+// when the sub build is in progress or finished within the grace period
+func orphanReason(b *types.Build, buildID, subBuildID string, now time.Time) string {
+
+	if b == nil {
+		return fmt.Sprintf("The build %s doesn't exist", buildID)
+	}
+
+	for _, sb := range b.SubBuilds {
+
+		if sb.ID != subBuildID {
+			continue
+		}
+
+		switch sb.Status {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+			return ""
+		}
+
+		if !sb.EndedAt.IsZero() && now.Sub(sb.EndedAt) < gcGrace {
+			return ""
+		}
+		return fmt.Sprintf("The build %s-%s is finished as %s", buildID, subBuildID, sb.Status)
+	}
+
+	return fmt.Sprintf("The build %s has no sub build %s", buildID, subBuildID)
+}
+
+func engineKind(kind int) string {
+
+	switch kind {
+	case integration.Kubernetes:
+		return "kubernetes"
+	case integration.Docker:
+		return "docker"
+	case integration.DCOS:
+		return "dcos"
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
+	itypes "github.com/elasticshift/elasticshift/internal/shiftserver/integration/types"
+)
+
+func TestContainerBuild(t *testing.T) {
+
+	id := "5b0d1a2f3c4d5e6f7a8b9c0d"
+	labelled := map[string]string{integration.KW_CREATEDBY: integration.DefaultContext, integration.KW_BUILDID: id + "-1"}
+
+	tests := []struct {
+		name       string
+		c          itypes.ContainerInfo
+		subBuildID string
+		ok         bool
+	}{
+		{"labelled", itypes.ContainerInfo{Name: "shift-pod-x1", Labels: labelled}, "1", true},
+		{"unlabelled", itypes.ContainerInfo{Name: id + "-2"}, "2", true},
+		{"label not parsed", itypes.ContainerInfo{Name: id + "-1", Labels: map[string]string{integration.KW_BUILDID: "build-1"}}, "", false},
+		{"name not parsed", itypes.ContainerInfo{Name: "nginx"}, "", false},
+		{"no sub build", itypes.ContainerInfo{Name: id + "-"}, "", false},
+		{"not an id", itypes.ContainerInfo{Name: "5b0d1a2f3c4d5e6f7a8b9c0z-1"}, "", false},
+	}
+
+	for _, tt := range tests {
+
+		buildID, subBuildID, ok := containerBuild(tt.c)
+		if ok != tt.ok || subBuildID != tt.subBuildID || (ok && buildID != id) {
+			t.Errorf("%s: unexpected build %q, sub build %q, %v", tt.name, buildID, subBuildID, ok)
+		}
+	}
+}
+
+func TestOrphanReason(t *testing.T) {
+
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	build := func(status string, endedAt time.Time) *types.Build {
+		return &types.Build{SubBuilds: []types.SubBuild{{ID: "1", Status: status, EndedAt: endedAt}}}
+	}
+
+	tests := []struct {
+		name       string
+		b          *types.Build
+		subBuildID string
+		orphaned   bool
+	}{
+		{"unknown build", nil, "1", true},
+		{"unknown sub build", build(types.BuildStatusRunning, time.Time{}), "2", true},
+		{"running", build(types.BuildStatusRunning, time.Time{}), "1", false},
+		{"preparing", build(types.BuildStatusPreparing, time.Time{}), "1", false},
+		{"just finished", build(types.BuildStatusFailed, now.Add(-time.Minute)), "1", false},
+		{"finished", build(types.BuildStatusSuccess, now.Add(-gcGrace-time.Minute)), "1", true},
+		{"finished unknown time", build(types.BuildStatusStuck, time.Time{}), "1", true},
+	}
+
+	for _, tt := range tests {
+		if reason := orphanReason(tt.b, "b1", tt.subBuildID, now); (reason != "") != tt.orphaned {
+			t.Errorf("%s: expected orphaned to be %v, got reason %q", tt.name, tt.orphaned, reason)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// builds numbered at a time
+const numberBatch = 500
+
+// NumberBuilds ..
+// Numbers the builds triggered before the builds were numbered, from the
+// oldest, so the number is unique in the repository before it's indexed.
+// The builds of a repository that no longer exists are numbered after its
+// last numbered build, no build of the repository is triggered anymore.
+func NumberBuilds(s store.Shift) (int, error) {
+
+	// the last number of the repositories that no longer exist
+	orphans := map[string]int{}
+
+	numbered := 0
+	for {
+
+		builds, err := s.Build.FetchUnnumberedBuilds(numberBatch)
+		if err != nil {
+			return numbered, fmt.Errorf("Failed to fetch the builds to number: %v", err)
+		}
+
+		if len(builds) == 0 {
+			return numbered, nil
+		}
+
+		for _, b := range builds {
+
+			number, err := nextNumber(s, orphans, b.RepositoryID)
+			if err == nil {
+				err = s.Build.SetNumber(b.ID, number)
+			}
+
+			if err != nil {
+				return numbered, fmt.Errorf("Failed to number the build %s: %v", b.ID.Hex(), err)
+			}
+			numbered++
+		}
+	}
+}
+
+func nextNumber(s store.Shift, orphans map[string]int, repositoryID string) (int, error) {
+
+	last, ok := orphans[repositoryID]
+	if !ok {
+
+		if bson.IsObjectIdHex(repositoryID) {
+
+			number, err := s.Repository.NextBuildNumber(bson.ObjectIdHex(repositoryID))
+			if err != mgo.ErrNotFound {
+				return number, err
+			}
+		}
+
+		var err error
+		last, err = s.Build.FetchLastBuildNumber(repositoryID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	orphans[repositoryID] = last + 1
+	return last + 1, nil
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
+)
+
+// number of the commits the discovered shiftfiles are cached for
+const discoveryCacheSize = 256
+
+// discoveryCache ..
+// Caches the files listed to discover the shiftfiles by the commit pushed,
+// so that the redelivered hooks and the polls don't list them again. The
+// oldest commit is evicted first.
+type discoveryCache struct {
+	mu    sync.Mutex
+	files map[string][]string
+	keys  []string
+}
+
+func newDiscoveryCache() *discoveryCache {
+	return &discoveryCache{files: make(map[string][]string)}
+}
+
+func (c *discoveryCache) get(key string) ([]string, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, ok := c.files[key]
+	return files, ok
+}
+
+func (c *discoveryCache) put(key string, files []string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.files[key]; ok {
+		return
+	}
+
+	if len(c.keys) >= discoveryCacheSize {
+		delete(c.files, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+
+	c.files[key] = files
+	c.keys = append(c.keys, key)
+}
+
+// TriggerChanged ..
+// Triggers the builds for a push to the repository, unless the commit
+// directs to skip. When the repository is configured with pipelines, only
+// the pipelines whose paths have changed since their last successful
+// build are triggered. A pipeline failed to trigger doesn't stop the
+// others, the builds triggered are returned along with the error.
+func (r *resolver) TriggerChanged(opts TriggerOptions) ([]types.Build, error) {
+
+	repo := opts.Repository
+	if vcs.ParseDirectives(opts.Message, opts.Labels).Skip {
+		r.logger.Infof("Skipping the build of repository %s on commit %s, as directed by the commit", repo.ID.Hex(), opts.CommitID)
+		return nil, nil
+	}
+
+	if len(repo.Pipelines) == 0 || opts.Pipeline != "" {
+
+		b, err := r.Trigger(opts)
+		if err != nil {
+			return nil, err
+		}
+		return []types.Build{b}, nil
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+
+	// the pipelines of the discovered shiftfiles are triggered as the others
+	pipelines, err := r.discoverPipelines(repo, branch, opts.CommitID)
+	if err != nil {
+		return nil, err
+	}
+	repo.Pipelines = pipelines
+
+	// changed files are computed once for every distinct base commit
+	changes := make(map[string][]string)
+
+	var builds []types.Build
+	var errs []string
+	for _, p := range repo.Pipelines {
+
+		if opts.CommitID != "" {
+
+			files, ok := r.changedFiles(repo, branch, p.Name, opts.CommitID, changes)
+			if ok && !pipelineAffected(p, files) {
+				r.logger.Infof("Skipping pipeline %s of repository %s, no changes in its paths", p.Name, repo.ID.Hex())
+				continue
+			}
+		}
+
+		popts := opts
+		popts.Repository = repo
+		popts.Branch = branch
+		popts.Pipeline = p.Name
+
+		b, err := r.Trigger(popts)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("pipeline %s: %v", p.Name, err))
+			continue
+		}
+		builds = append(builds, b)
+	}
+
+	if len(errs) > 0 {
+		return builds, fmt.Errorf("Failed to trigger %d of %d pipelines, %s", len(errs), len(errs)+len(builds), strings.Join(errs, "; "))
+	}
+	return builds, nil
+}
+
+// changedFiles ..
+// Lists the files changed between the last successful build of the pipeline
+// (on the branch, or else on the default branch) and the commit. It returns
+// false when the changes can't be determined, so the pipeline is built.
+func (r *resolver) changedFiles(repo types.Repository, branch, pipeline, commitID string, changes map[string][]string) ([]string, bool) {
+
+	repositoryID := repo.ID.Hex()
+
+	last, err := r.store.FetchLastSuccessfulBuild(repositoryID, branch, pipeline)
+	if err != nil && err.Error() == "not found" && branch != repo.DefaultBranch {
+		last, err = r.store.FetchLastSuccessfulBuild(repositoryID, repo.DefaultBranch, pipeline)
+	}
+
+	if err != nil {
+		if err.Error() != "not found" {
+			r.logger.Errorf("Failed to fetch the last successful build of pipeline %s: %v", pipeline, err)
+		}
+		return nil, false
+	}
+
+	if files, ok := changes[last.CommitID]; ok {
+		return files, files != nil
+	}
+
+	c, err := r.gitCredentials(repo)
+	if err == nil {
+		changes[last.CommitID], err = vcs.ChangedFiles(repo.CloneURL, branch, last.CommitID, commitID, c)
+	}
+
+	if err != nil {
+		r.logger.Warnf("Failed to list the changed files of repository %s: %v", repositoryID, err)
+		changes[last.CommitID] = nil
+		return nil, false
+	}
+
+	return changes[last.CommitID], true
+}
+
+// gitCredentials ..
+// Resolves the credentials to access the repository through git
+func (r *resolver) gitCredentials(repo types.Repository) (vcs.Credentials, error) {
+
+	if repo.Plain() {
+		return secret.GitCredentials(r.vault, repo)
+	}
+
+	e, token, err := r.vcsAccess(repo.Team, repo.VcsID, repo.Source)
+	if err != nil {
+		return vcs.Credentials{}, err
+	}
+	return vcs.TokenCredentials(e, repo.CloneURL, token), nil
+}
+
+// pipelineAffected ..
+// A pipeline is affected when any of the files matches its include
+// paths (all files, when not set) and none of its exclude paths.
+func pipelineAffected(p types.Pipeline, files []string) bool {
+
+	for _, f := range files {
+
+		if len(p.Include) > 0 && !matchAny(p.Include, f) {
+			continue
+		}
+
+		if matchAny(p.Exclude, f) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func matchAny(patterns []string, path string) bool {
+
+	for _, pattern := range patterns {
+		if utils.MatchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverPipelines ..
+// Returns the pipelines of the repository, where the pipeline whose
+// shiftfile is a glob is replaced by a pipeline for each of the shiftfiles
+// found at the head of the branch. The files are listed once per commit.
+func (r *resolver) discoverPipelines(repo types.Repository, branch, commitID string) ([]types.Pipeline, error) {
+
+	var globs bool
+	for _, p := range repo.Pipelines {
+		globs = globs || utils.IsGlob(p.Shiftfile)
+	}
+
+	if !globs {
+		return repo.Pipelines, nil
+	}
+
+	key := repo.ID.Hex() + "/" + commitID
+	if files, ok := r.discovered.get(key); ok && commitID != "" {
+		return expandPipelines(repo.Pipelines, files), nil
+	}
+
+	c, err := r.gitCredentials(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := vcs.ListFiles(repo.CloneURL, branch, c)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to discover the shiftfiles of repository %s: %v", repo.ID.Hex(), err)
+	}
+
+	if commitID != "" {
+		r.discovered.put(key, files)
+	}
+	return expandPipelines(repo.Pipelines, files), nil
+}
+
+// expandPipelines ..
+// Replaces the pipelines whose shiftfile is a glob by a pipeline for each of
+// the matching files, named by the directory of the shiftfile. Unless the
+// include paths are given, the discovered pipeline is triggered by the
+// changes in its directory. The configured pipelines take precedence over
+// the discovered ones of the same name.
+func expandPipelines(pipelines []types.Pipeline, files []string) []types.Pipeline {
+
+	names := make(map[string]bool)
+	for _, p := range pipelines {
+		if !utils.IsGlob(p.Shiftfile) {
+			names[p.Name] = true
+		}
+	}
+
+	var result []types.Pipeline
+	for _, p := range pipelines {
+
+		if !utils.IsGlob(p.Shiftfile) {
+			result = append(result, p)
+			continue
+		}
+
+		for _, f := range files {
+
+			if !utils.MatchGlob(p.Shiftfile, f) {
+				continue
+			}
+
+			d := types.Pipeline{Name: path.Dir(f), Shiftfile: f, Include: p.Include, Exclude: p.Exclude}
+
+			// the shiftfile at the root builds the repository as a whole
+			if d.Name == "." {
+				d.Name = p.Name
+			} else if len(d.Include) == 0 {
+				d.Include = []string{d.Name + "/**"}
+			}
+
+			if names[d.Name] {
+				continue
+			}
+			names[d.Name] = true
+
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func findPipeline(pipelines []types.Pipeline, name string) (types.Pipeline, bool) {
+
+	for _, p := range pipelines {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return types.Pipeline{}, false
+}
+
+func pipelineNames(pipelines []types.Pipeline) []string {
+
+	names := make([]string, 0, len(pipelines))
+	for _, p := range pipelines {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"sort"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+)
+
+// recent builds of the branch the nodes are estimated from
+const estimateSamples = 10
+
+// Estimate ..
+// Annotates the nodes of the graph reported by the worker with how long
+// they're expected to take, and the sub build with its estimate. The nodes
+// are estimated from their recent successful runs on the branch when the
+// worker reports the graph first, the estimates are carried over from the
+// previous graph on the later reports.
+func (r *resolver) Estimate(buildID string, sb *types.SubBuild, prevGraph string, first bool) {
+
+	if sb.Graph == "" {
+		return
+	}
+
+	cps, err := graph.Decode(sb.Graph)
+	if err != nil {
+		r.logger.Warnf("Failed to decode the graph of build %s/%s: %v", buildID, sb.ID, err)
+		return
+	}
+
+	var estimates map[string]float64
+	if first {
+		estimates = r.nodeEstimates(buildID, sb.ID)
+	} else {
+		estimates = graphEstimates(prevGraph)
+	}
+
+	if len(estimates) == 0 {
+		return
+	}
+
+	for _, cp := range cps {
+		for _, n := range append([]*graph.N{cp.Node}, cp.Edges...) {
+			if n != nil {
+				n.Estimate = estimates[n.Key()]
+			}
+		}
+	}
+
+	gph, err := graph.Encode(cps)
+	if err != nil {
+		r.logger.Warnf("Failed to encode the graph of build %s/%s: %v", buildID, sb.ID, err)
+		return
+	}
+	sb.Graph = gph
+
+	total, _ := graph.Progress(cps, time.Now())
+	sb.Estimate = total.Seconds()
+}
+
+// Progress ..
+// Returns the percentage of the sub build done by now and when it's
+// expected to end, from the estimates of the nodes of its graph. It's
+// computed when the sub build is read, as the running nodes progress
+// between the reports of the worker. A finished sub build is done when
+// it ended.
+func Progress(sb types.SubBuild, now time.Time) (int, time.Time) {
+
+	if Finished(sb.Status) {
+		return 100, sb.EndedAt
+	}
+
+	if sb.Graph == "" {
+		return 0, time.Time{}
+	}
+
+	cps, err := graph.Decode(sb.Graph)
+	if err != nil {
+		return 0, time.Time{}
+	}
+
+	total, done := graph.Progress(cps, now)
+	if total <= 0 {
+		return 0, time.Time{}
+	}
+
+	// the sub build is done only when the worker says so,
+	// even if it's taking longer than expected
+	progress := int(done * 100 / total)
+	if progress > 99 {
+		progress = 99
+	}
+	return progress, now.Add(total - done)
+}
+
+// nodeEstimates ..
+// Returns the median seconds of the recent successful runs of the nodes of
+// the sub build on the branch, by the nodes
+func (r *resolver) nodeEstimates(buildID, subBuildID string) map[string]float64 {
+
+	b, err := r.store.FetchBuildByID(buildID)
+	if err != nil {
+		r.logger.Warnf("Failed to fetch the build %s to estimate: %v", buildID, err)
+		return nil
+	}
+
+	builds, err := r.store.FetchRecentGraphs(b.RepositoryID, b.Branch, b.Pipeline, estimateSamples)
+	if err != nil {
+		r.logger.Warnf("Failed to fetch the recent builds of branch %s to estimate: %v", b.Branch, err)
+		return nil
+	}
+
+	samples := map[string][]float64{}
+	for _, prev := range builds {
+		for _, sb := range prev.SubBuilds {
+
+			if sb.ID != subBuildID || sb.Graph == "" {
+				continue
+			}
+
+			cps, err := graph.Decode(sb.Graph)
+			if err != nil {
+				continue
+			}
+
+			for _, cp := range cps {
+				for _, n := range append([]*graph.N{cp.Node}, cp.Edges...) {
+
+					if n == nil || n.Status != graph.StatusSuccess || n.StartedAt.IsZero() || !n.EndedAt.After(n.StartedAt) {
+						continue
+					}
+					samples[n.Key()] = append(samples[n.Key()], n.EndedAt.Sub(n.StartedAt).Seconds())
+				}
+			}
+		}
+	}
+
+	estimates := map[string]float64{}
+	for key, s := range samples {
+		estimates[key] = median(s)
+	}
+	return estimates
+}
+
+// graphEstimates ..
+// Returns the estimates the nodes of the graph are annotated with
+func graphEstimates(gph string) map[string]float64 {
+
+	if gph == "" {
+		return nil
+	}
+
+	cps, err := graph.Decode(gph)
+	if err != nil {
+		return nil
+	}
+
+	estimates := map[string]float64{}
+	for _, cp := range cps {
+		for _, n := range append([]*graph.N{cp.Node}, cp.Edges...) {
+			if n != nil && n.Estimate > 0 {
+				estimates[n.Key()] = n.Estimate
+			}
+		}
+	}
+	return estimates
+}
+
+func median(values []float64) float64 {
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	s := append([]float64(nil), values...)
+	sort.Float64s(s)
+
+	mid := len(s) / 2
+	if len(s)%2 == 0 {
+		return (s[mid-1] + s[mid]) / 2
+	}
+	return s[mid]
+}
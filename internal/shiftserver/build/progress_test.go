@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+)
+
+func TestProgress(t *testing.T) {
+
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	encode := func(cps ...*graph.Checkpoint) string {
+
+		gph, err := graph.Encode(cps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gph
+	}
+
+	running := encode(
+		&graph.Checkpoint{Node: &graph.N{Name: "checkout", Status: graph.StatusSuccess, Estimate: 30}},
+		&graph.Checkpoint{Node: &graph.N{Name: "test", Status: graph.StatusRunning, Estimate: 60, StartedAt: now.Add(-30 * time.Second)}},
+		&graph.Checkpoint{Node: &graph.N{Name: "deploy", Status: graph.StatusNotStarted, Estimate: 30}},
+	)
+
+	overdue := encode(
+		&graph.Checkpoint{Node: &graph.N{Name: "test", Status: graph.StatusRunning, Estimate: 60, StartedAt: now.Add(-time.Hour)}},
+	)
+
+	ended := now.Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		sb       types.SubBuild
+		progress int
+		eta      time.Time
+	}{
+		{"finished", types.SubBuild{Status: types.BuildStatusFailed, Graph: running, EndedAt: ended}, 100, ended},
+		{"no graph", types.SubBuild{Status: types.BuildStatusRunning}, 0, time.Time{}},
+		{"not estimated", types.SubBuild{Status: types.BuildStatusRunning, Graph: encode(&graph.Checkpoint{Node: &graph.N{Name: "test", Status: graph.StatusRunning}})}, 0, time.Time{}},
+		{"running", types.SubBuild{Status: types.BuildStatusRunning, Graph: running}, 50, now.Add(time.Minute)},
+		{"overdue", types.SubBuild{Status: types.BuildStatusRunning, Graph: overdue}, 99, now},
+	}
+
+	for _, tt := range tests {
+
+		progress, eta := Progress(tt.sb, now)
+		if progress != tt.progress || !eta.Equal(tt.eta) {
+			t.Errorf("%s: expected %d%% until %v, got %d%% until %v", tt.name, tt.progress, tt.eta, progress, eta)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// number of launchers run by a server, each launches a build at a time
+	launchers = 4
+
+	// a queued build is hidden from the other launchers for the lease,
+	// the launcher extends it until the containers are created
+	leaseTimeout = 2 * time.Minute
+
+	// interval the launchers look for the queued builds, when not notified
+	queuePoll = 5 * time.Second
+
+	// delay before a build is retried, when it couldn't be read
+	retryDelay = 30 * time.Second
+
+	// a build that couldn't be launched after the attempts is failed
+	maxLaunchAttempts = 5
+)
+
+// queue ..
+// Persists the build in the queue, to be launched by any of the servers
+func (r *resolver) queue(b types.Build) error {
+
+	now := time.Now()
+	err := r.queueStore.Enqueue(types.QueuedBuild{
+		ID:           b.ID,
+		Team:         b.Team,
+		RepositoryID: b.RepositoryID,
+		EngineID:     b.ContainerEngineID,
+		Priority:     b.Priority,
+		EnqueuedAt:   now,
+		VisibleAt:    now,
+	})
+
+	// wake up a launcher of this server
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+
+	return err
+}
+
+// ContainerLauncher ..
+// Leases the queued builds and launches their containers,
+// until the context is done.
+func (r *resolver) ContainerLauncher(owner string) {
+
+	t := time.NewTicker(queuePoll)
+	defer t.Stop()
+
+	for {
+
+		for r.launchNext(owner) {
+		}
+
+		select {
+		case <-r.Ctx.Done():
+			return
+		case <-r.notify:
+		case <-t.C:
+		}
+	}
+}
+
+// launchNext ..
+// Leases the next queued build in the schedule and launches it.
+// Returns false when there's nothing to launch.
+func (r *resolver) launchNext(owner string) bool {
+
+	q, err := r.leaseNext(owner)
+	if err == mgo.ErrNotFound {
+		return false
+	}
+
+	if err != nil {
+		r.logger.Errorf("Failed to lease a build from the queue: %v", err)
+		return false
+	}
+
+	// a panic leaves the build in the queue, it's retried once the lease expires
+	defer r.recoverErrorIfAny()
+
+	done := make(chan struct{})
+	defer close(done)
+	go r.keepLease(q.ID, owner, done)
+
+	buildID := q.ID.Hex()
+	b, err := r.store.FetchBuildByID(buildID)
+	if err != nil && err.Error() != "not found" {
+
+		r.logger.Errorf("Failed to fetch the queued build %s: %v", buildID, err)
+		r.releaseSlots(buildID)
+
+		err = r.queueStore.Release(q.ID, owner, retryDelay)
+		if err != nil {
+			r.logger.Errorf("Failed to release the build %s: %v", buildID, err)
+		}
+		return true
+	}
+
+	if err == nil && launchable(b) {
+
+		if exhausted(q) {
+
+			r.logger.Errorf("Giving up the build %s after %d attempts to launch", buildID, q.Attempts-1)
+			r.UpdateBuildStatusAsFailed(buildID, "1", fmt.Sprintf("Failed to launch the build after %d attempts", q.Attempts-1), time.Now())
+			r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+			r.releaseSlots(buildID)
+		} else if !r.prepare(q, owner, b) {
+			return true
+		}
+	} else if err != nil || !inProgress(b) {
+
+		// the slots are held by the launched build until it finishes
+		r.releaseSlots(buildID)
+	}
+
+	// removed, cancelled or launched already
+	err = r.queueStore.Ack(q.ID, owner)
+	if err != nil {
+		r.logger.Errorf("Failed to remove the build %s from the queue: %v", buildID, err)
+	}
+	return true
+}
+
+// prepare ..
+// Launches the build once its concurrency group is acquired, otherwise the
+// build is put back in the queue. Returns false if the build is put back.
+func (r *resolver) prepare(q types.QueuedBuild, owner string, b types.Build) bool {
+
+	buildID := b.ID.Hex()
+
+	sf, repoFile, err := r.GetShiftfile(b)
+	if err == nil && sf == nil {
+		err = fmt.Errorf("Failed to parse the shiftfile")
+	}
+
+	if err != nil {
+
+		r.UpdateBuildStatusAsFailed(buildID, "1", fmt.Sprintf("Failed to find container image name: %s", err.Error()), time.Now())
+		r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+		r.releaseSlots(buildID)
+		return true
+	}
+
+	if group, cancel := sf.Concurrency(); group != "" {
+
+		acquired, err := r.acquireGroup(b, group, cancel)
+		if err != nil {
+			r.logger.Errorf("Failed to acquire the concurrency group %s for build %s: %v", group, buildID, err)
+		}
+
+		if !acquired {
+
+			// the slots are left for the other builds while waiting for the group
+			r.releaseSlots(buildID)
+
+			err = r.queueStore.Postpone(q.ID, owner, groupRetryDelay)
+			if err != nil {
+				r.logger.Errorf("Failed to put back the build %s in the queue: %v", buildID, err)
+			}
+			return false
+		}
+	}
+
+	r.launch(b, sf, repoFile)
+	return true
+}
+
+// exhausted ..
+// True when the build is leased more than the attempts to launch,
+// the lease being put back to wait for the limits isn't counted
+func exhausted(q types.QueuedBuild) bool {
+	return q.Attempts > maxLaunchAttempts
+}
+
+// keepLease ..
+// Extends the lease of the build until done
+func (r *resolver) keepLease(id bson.ObjectId, owner string, done chan struct{}) {
+
+	t := time.NewTicker(leaseTimeout / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			err := r.queueStore.Extend(id, owner, leaseTimeout)
+			if err != nil {
+				r.logger.Warnf("Failed to extend the lease of build %s: %v", id.Hex(), err)
+			}
+		}
+	}
+}
+
+// launchable ..
+// A build is launched when it's still preparing and
+// none of its containers are created.
+func launchable(b types.Build) bool {
+
+	if len(b.SubBuilds) == 0 || b.SubBuilds[0].Status != types.BuildStatusPreparing {
+		return false
+	}
+
+	for _, sb := range b.SubBuilds {
+		if sb.Metadata != nil && sb.Metadata.ContainerID != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// recoverQueue ..
+// Queues the builds left behind by a server that was stopped or crashed.
+// The preparing builds are queued again (it's a no-op if they're queued
+// already), and the oldest waiting build of a branch is promoted when
+// nothing else is building it.
+func (r *resolver) recoverQueue() {
+
+	builds, err := r.store.FetchBuildsByStatus(types.BuildStatusPreparing, types.BuildStatusRunning, types.BuildStatusWaiting)
+	if err != nil {
+		r.logger.Errorf("Failed to recover the build queue: %v", err)
+		return
+	}
+
+	busy := make(map[string]bool)
+	var waiting []types.Build
+	for _, b := range builds {
+
+		switch b.SubBuilds[0].Status {
+		case types.BuildStatusPreparing:
+
+			busy[queueKey(b)] = true
+			err = r.queue(b)
+			if err != nil {
+				r.logger.Errorf("Failed to queue the build %s: %v", b.ID.Hex(), err)
+			}
+		case types.BuildStatusRunning:
+			busy[queueKey(b)] = true
+		case types.BuildStatusWaiting:
+			waiting = append(waiting, b)
+		}
+	}
+
+	for _, b := range waiting {
+
+		key := queueKey(b)
+		if busy[key] {
+			continue
+		}
+		busy[key] = true
+
+		// other servers may recover the same build
+		promoted, err := r.store.TransitSubBuild(b.ID.Hex(), "1", types.BuildStatusWaiting, types.BuildStatusPreparing)
+		if err != nil {
+			r.logger.Errorf("Failed to promote the waiting build %s: %v", b.ID.Hex(), err)
+			continue
+		}
+
+		if promoted {
+			r.pushToQueue(b)
+		}
+	}
+}
+
+// queueKey ..
+// The builds of a pipeline on the same branch wait for each other
+func queueKey(b types.Build) string {
+	return b.Team + "/" + b.RepositoryID + "/" + b.Branch + "/" + b.Pipeline
+}
+
+// launcherID ..
+// Identifies the launchers of this server as the owner of the leases
+func launcherID() string {
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "shiftserver"
+	}
+	return host + "-" + bson.NewObjectId().Hex()
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestExhausted(t *testing.T) {
+
+	tests := []struct {
+		attempts  int
+		exhausted bool
+	}{
+		{1, false},
+		{maxLaunchAttempts, false},
+
+		// the lease of the last attempt gives up the build
+		{maxLaunchAttempts + 1, true},
+	}
+
+	for _, tt := range tests {
+		if e := exhausted(types.QueuedBuild{Attempts: tt.attempts}); e != tt.exhausted {
+			t.Errorf("exhausted(%d) = %v, expected %v", tt.attempts, e, tt.exhausted)
+		}
+	}
+}
+
+func TestLaunchable(t *testing.T) {
+
+	sb := func(status, containerID string) types.SubBuild {
+
+		s := types.SubBuild{ID: "1", Status: status}
+		if containerID != "" {
+			s.Metadata = &types.Metadata{ContainerID: containerID}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		subBuilds  []types.SubBuild
+		launchable bool
+	}{
+		{"preparing", []types.SubBuild{sb(types.BuildStatusPreparing, "")}, true},
+		{"container created", []types.SubBuild{sb(types.BuildStatusPreparing, "c1")}, false},
+		{"cancelled", []types.SubBuild{sb(types.BuildStatusCancel, "")}, false},
+		{"running", []types.SubBuild{sb(types.BuildStatusRunning, "c1")}, false},
+		{"no sub build", nil, false},
+	}
+
+	for _, tt := range tests {
+		if l := launchable(types.Build{SubBuilds: tt.subBuilds}); l != tt.launchable {
+			t.Errorf("%s: expected launchable to be %v", tt.name, tt.launchable)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+)
+
+var (
+	// the longest a build runs, the worker times out the build by itself
+	buildTimeout = 120 * time.Minute
+
+	// a build running beyond the timeout is failed by the server, in case
+	// the worker didn't, the grace lets the worker report it first
+	timeoutGrace = 10 * time.Minute
+
+	// the worker reports alive every 30 seconds, a build is stuck
+	// when the worker missed the heartbeats for the duration
+	heartbeatTimeout = 5 * time.Minute
+
+	// a launched container pulls the image before the worker starts, a
+	// build is stuck when the worker didn't report alive for the duration
+	startupTimeout = 30 * time.Minute
+
+	// interval the reaper looks for the builds that are stuck or timed out
+	reapTick = time.Minute
+)
+
+// reap ..
+// Marks the builds as stuck when their workers stop reporting alive, or
+// as failed when they run beyond the timeout, so the branch and the
+// concurrency group held by them are passed on to the next build.
+func (r *resolver) reap() {
+
+	t := time.NewTicker(reapTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.Ctx.Done():
+			return
+		case <-t.C:
+			r.reapBuilds(time.Now())
+		}
+	}
+}
+
+func (r *resolver) reapBuilds(now time.Time) {
+
+	builds, err := r.store.FetchBuildsByStatus(types.BuildStatusPreparing, types.BuildStatusRunning)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch the builds in progress: %v", err)
+		return
+	}
+
+	for _, b := range builds {
+		r.reapBuild(b, now)
+	}
+}
+
+func (r *resolver) reapBuild(b types.Build, now time.Time) {
+
+	buildID := b.ID.Hex()
+
+	var engine integration.ContainerEngineInterface
+	var reaped bool
+	for _, sb := range b.SubBuilds {
+
+		status, reason := expired(sb, now)
+		if status == "" {
+			continue
+		}
+
+		// the sub build is updated only at the version it's found expired,
+		// another server may have reaped it, or the worker reported meanwhile
+		err := r.updateSubBuild("reaper", buildID, types.SubBuild{
+			ID:      sb.ID,
+			Status:  status,
+			Reason:  reason,
+			EndedAt: now,
+			Version: sb.Version,
+		})
+		if err == store.ErrConflict || err == ErrTransitionRejected {
+			continue
+		}
+
+		if err != nil {
+			r.logger.Errorf("Failed to mark the build %s-%s as %s: %v", buildID, sb.ID, status, err)
+			continue
+		}
+
+		r.logger.Warnf("Build %s-%s is marked as %s: %s", buildID, sb.ID, status, reason)
+		reaped = true
+
+		if sb.Metadata == nil || sb.Metadata.ContainerID == "" {
+			continue
+		}
+
+		if engine == nil {
+			engine, err = r.GetContainerEngine(b.Team)
+		}
+
+		if err == nil {
+			err = engine.DeleteContainer(buildID + "-" + sb.ID)
+		}
+
+		if err != nil {
+			r.logger.Warnf("Failed to delete the container of build %s-%s: %v", buildID, sb.ID, err)
+		}
+	}
+
+	if !reaped {
+		return
+	}
+
+	r.ps.Publish(pubsub.SubscribeBuildUpdate, buildID)
+
+	// the waiting build of the branch is kicked off
+	r.TriggerNextIfAny(buildID, b.Team, b.RepositoryID, b.Branch)
+}
+
+// expired ..
+// Returns the status the sub build is moved to and the reason, when its
+// worker stopped reporting alive or it ran beyond the timeout. The builds
+// waiting in the queue are left alone, until their containers are launched.
+func expired(sb types.SubBuild, now time.Time) (string, string) {
+
+	switch {
+	case sb.Status == types.BuildStatusRunning:
+	case sb.Status == types.BuildStatusPreparing && !sb.LaunchedAt.IsZero():
+	default:
+		return "", ""
+	}
+
+	started := sb.StartedAt
+	if started.IsZero() {
+		started = sb.LaunchedAt
+	}
+
+	// nothing is known of when the sub build started, such as the builds
+	// launched before the launch was recorded, it's left to the worker
+	if started.IsZero() && sb.HeartbeatAt.IsZero() {
+		return "", ""
+	}
+
+	if !started.IsZero() && now.Sub(started) > buildTimeout+timeoutGrace {
+		return types.BuildStatusFailed, fmt.Sprintf("The build is timed out, after running for more than %s", buildTimeout)
+	}
+
+	// the heartbeats are expected once the worker reported alive
+	last := latest(sb.HeartbeatAt, sb.StartedAt)
+	if last.IsZero() {
+
+		if now.Sub(sb.LaunchedAt) > startupTimeout {
+			return types.BuildStatusStuck, fmt.Sprintf("The worker didn't start within %s of the container launched at %s", startupTimeout, sb.LaunchedAt.Format(time.RFC3339))
+		}
+		return "", ""
+	}
+
+	if now.Sub(last) > heartbeatTimeout {
+
+		if sb.HeartbeatAt.IsZero() {
+			return types.BuildStatusStuck, fmt.Sprintf("The worker didn't report alive since the build started at %s", last.Format(time.RFC3339))
+		}
+		return types.BuildStatusStuck, fmt.Sprintf("The worker stopped reporting alive, the last heartbeat was at %s", last.Format(time.RFC3339))
+	}
+
+	return "", ""
+}
+
+func latest(times ...time.Time) time.Time {
+
+	var t time.Time
+	for _, v := range times {
+		if v.After(t) {
+			t = v
+		}
+	}
+	return t
+}
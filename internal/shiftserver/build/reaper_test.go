@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestExpired(t *testing.T) {
+
+	now := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time {
+		return now.Add(-d)
+	}
+
+	tests := []struct {
+		name   string
+		sb     types.SubBuild
+		status string
+	}{
+		{"waiting", types.SubBuild{Status: types.BuildStatusWaiting}, ""},
+		{"queued", types.SubBuild{Status: types.BuildStatusPreparing}, ""},
+		{"finished", types.SubBuild{Status: types.BuildStatusSuccess, StartedAt: ago(3 * time.Hour)}, ""},
+		{"nothing recorded", types.SubBuild{Status: types.BuildStatusRunning}, ""},
+		{"launched", types.SubBuild{Status: types.BuildStatusPreparing, LaunchedAt: ago(time.Minute)}, ""},
+		{"not started", types.SubBuild{Status: types.BuildStatusPreparing, LaunchedAt: ago(startupTimeout + time.Minute)}, types.BuildStatusStuck},
+		{"reporting", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(time.Hour), HeartbeatAt: ago(time.Minute)}, ""},
+		{"just started", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(time.Minute)}, ""},
+		{"never reported", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(heartbeatTimeout + time.Minute)}, types.BuildStatusStuck},
+		{"stopped reporting", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(time.Hour), HeartbeatAt: ago(heartbeatTimeout + time.Minute)}, types.BuildStatusStuck},
+		{"heartbeat only", types.SubBuild{Status: types.BuildStatusRunning, HeartbeatAt: ago(heartbeatTimeout + time.Minute)}, types.BuildStatusStuck},
+		{"timed out", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(buildTimeout + timeoutGrace + time.Minute), HeartbeatAt: ago(time.Minute)}, types.BuildStatusFailed},
+		{"in grace", types.SubBuild{Status: types.BuildStatusRunning, StartedAt: ago(buildTimeout + time.Minute), HeartbeatAt: ago(time.Minute)}, ""},
+		{"timed out since launch", types.SubBuild{Status: types.BuildStatusPreparing, LaunchedAt: ago(buildTimeout + timeoutGrace + time.Minute)}, types.BuildStatusFailed},
+	}
+
+	for _, tt := range tests {
+
+		status, reason := expired(tt.sb, now)
+		if status != tt.status {
+			t.Errorf("%s: expected %q, got %q (%s)", tt.name, tt.status, status, reason)
+		}
+
+		if status != "" && reason == "" {
+			t.Errorf("%s: expected the reason of %s", tt.name, status)
+		}
+	}
+}
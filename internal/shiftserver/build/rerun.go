@@ -0,0 +1,258 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/parser"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/graphql-go/graphql"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// RerunBuild ..
+// Reruns the build from the start, or from the given node
+func (r *resolver) RerunBuild(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errIDCantBeEmpty
+	}
+	fromNode, _ := params.Args["from_node"].(string)
+
+	prev, err := r.store.FetchBuildByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the build: %v", err)
+	}
+
+	return r.Rerun(prev, fromNode, "Anonymous") //TODO fill in with logged-in user
+}
+
+// Rerun ..
+// Creates a new build of the same commit, with the parameters and the
+// shiftfile of the build rerun. When resumed from a node, the nodes before
+// are reused and the workspace is restored from the snapshot taken at the
+// checkpoint before the node. It's resumed only while the branch is at the
+// commit of the build, as the nodes after may fetch the branch again.
+func (r *resolver) Rerun(prev types.Build, fromNode, triggeredBy string) (types.Build, error) {
+
+	prevID := prev.ID.Hex()
+	if inProgress(prev) {
+		return types.Build{}, fmt.Errorf("Build %s is still in progress", prevID)
+	}
+
+	def, err := r.defaultStore.FindByReferenceId(prev.Team)
+	if err != nil {
+		return types.Build{}, err
+	}
+
+	if def.ContainerEngineID == "" {
+		return types.Build{}, errors.New("No default container engine found, please configure it.")
+	}
+
+	b := types.Build{}
+	b.ID = bson.NewObjectId()
+	b.RepositoryID = prev.RepositoryID
+	b.ContainerEngineID = def.ContainerEngineID
+	b.VcsID = prev.VcsID
+	b.TriggeredBy = triggeredBy
+	b.Team = prev.Team
+	b.Branch = prev.Branch
+	b.CommitID = prev.CommitID
+	b.StorageID = def.StorageID
+	b.StoragePath = prev.StoragePath
+	b.CloneURL = prev.CloneURL
+	b.Language = prev.Language
+	b.Source = prev.Source
+	b.Pipeline = prev.Pipeline
+	b.Shiftfile = prev.Shiftfile
+	b.ResolvedShiftfile = prev.ResolvedShiftfile
+	b.Directives = prev.Directives
+	b.Priority = prev.Priority
+	b.Parameters = prev.Parameters
+	b.RerunOf = prevID
+
+	if fromNode != "" {
+
+		err = r.headUnmoved(prev)
+		if err != nil {
+			return types.Build{}, err
+		}
+
+		b.SnapshotOf, err = r.snapshotOf(prev, fromNode)
+		if err != nil {
+			return types.Build{}, err
+		}
+		b.FromNode = fromNode
+	}
+
+	status, err := r.initialStatus(b.Team, b.RepositoryID, b.Branch, b.Pipeline)
+	if err != nil {
+		return types.Build{}, err
+	}
+
+	sb := types.SubBuild{
+		ID:     "1",
+		Graph:  defaultGraph,
+		Status: status,
+	}
+	b.SubBuilds = []types.SubBuild{sb}
+
+	b.Number, err = r.repositoryStore.NextBuildNumber(bson.ObjectIdHex(b.RepositoryID))
+	if err != nil {
+		return types.Build{}, fmt.Errorf("Failed to number the build: %v", err)
+	}
+
+	err = r.store.Save(&b)
+	if err != nil {
+		return types.Build{}, fmt.Errorf("Failed to save build details: %v", err)
+	}
+
+	if sb.Status == types.BuildStatusPreparing {
+		r.pushToQueue(b)
+	}
+
+	return b, nil
+}
+
+// snapshotOf ..
+// Finds the build that ran the checkpoint before the node, whose snapshot
+// restores the workspace. It's the build rerun, unless the build itself was
+// resumed from a node at or after the node, then it's looked up further. The
+// snapshot is taken only when the build fails, so it's resumed from the node
+// it failed at.
+func (r *resolver) snapshotOf(prev types.Build, fromNode string) (string, error) {
+
+	if prev.ResolvedShiftfile == "" {
+		return "", fmt.Errorf("The shiftfile of build %s is not recorded, it can be rerun only from the start", prev.ID.Hex())
+	}
+
+	sf, err := parser.AST([]byte(prev.ResolvedShiftfile))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse the shiftfile of build %s: %v", prev.ID.Hex(), err)
+	}
+
+	g, err := graph.Construct(sf)
+	if err != nil {
+		return "", fmt.Errorf("Failed to construct the graph of build %s: %v", prev.ID.Hex(), err)
+	}
+
+	idx, n := g.CheckpointOf(fromNode)
+	if idx < 0 {
+		return "", fmt.Errorf("Node %s is not found in build %s", fromNode, prev.ID.Hex())
+	}
+
+	if !resumable(n) {
+		return "", fmt.Errorf("The build cannot be resumed from %s, it's run by the system", n.Name)
+	}
+
+	src := prev
+	for src.FromNode != "" {
+
+		from, _ := g.CheckpointOf(src.FromNode)
+		if idx > from {
+			break
+		}
+
+		holder, err := r.store.FetchBuildByID(src.SnapshotOf)
+		if err != nil {
+			return "", fmt.Errorf("Failed to fetch the build %s holding the snapshot: %v", src.SnapshotOf, err)
+		}
+		src = holder
+	}
+
+	// the workspace is snapshotted only before the checkpoint the build failed at
+	if failedAt(src) != idx {
+		return "", fmt.Errorf("Build %s can be resumed only from the node it failed at", prev.ID.Hex())
+	}
+
+	return src.ID.Hex(), nil
+}
+
+// failedAt ..
+// Index of the checkpoint the build failed at, -1 unless its graph shows a failure
+func failedAt(b types.Build) int {
+
+	if len(b.SubBuilds) == 0 {
+		return -1
+	}
+
+	cps, err := graph.Decode(b.SubBuilds[0].Graph)
+	if err != nil {
+		return -1
+	}
+
+	for i, cp := range cps {
+		if cp.Node != nil && cp.Node.Status == graph.StatusFailed {
+			return i
+		}
+	}
+	return -1
+}
+
+// headUnmoved ..
+// Fails unless the head of the branch is still the commit of the build, the
+// workspace restored would be of a commit other than the one the nodes from
+// the resumed node check out otherwise.
+func (r *resolver) headUnmoved(prev types.Build) error {
+
+	if prev.CommitID == "" {
+		return fmt.Errorf("The commit of build %s is not recorded, it can be rerun only from the start", prev.ID.Hex())
+	}
+
+	repo, err := r.repositoryStore.GetRepositoryByID(prev.RepositoryID)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	c, err := r.gitCredentials(repo)
+	if err != nil {
+		return err
+	}
+
+	head, err := vcs.HeadCommit(repo.CloneURL, prev.Branch, c)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch the head commit of branch %s: %v", prev.Branch, err)
+	}
+
+	if head != prev.CommitID {
+		return fmt.Errorf("Branch %s has moved from %s to %s since build %s, it can be rerun only from the start", prev.Branch, prev.CommitID, head, prev.ID.Hex())
+	}
+	return nil
+}
+
+// headCommit ..
+// Returns the head commit of the branch, empty when it can't be fetched and
+// the build checks out the head of the branch when it's launched.
+func (r *resolver) headCommit(repo types.Repository, branch string) string {
+
+	c, err := r.gitCredentials(repo)
+	if err != nil {
+		r.logger.Warnf("Failed to pin the build of repository %s to the head of branch %s: %v", repo.ID.Hex(), branch, err)
+		return ""
+	}
+
+	head, err := vcs.HeadCommit(repo.CloneURL, branch, c)
+	if err != nil {
+		r.logger.Warnf("Failed to pin the build of repository %s to the head of branch %s: %v", repo.ID.Hex(), branch, err)
+		return ""
+	}
+	return head
+}
+
+// resumable ..
+// A build is resumed from the blocks of the shiftfile, or from a fan out
+func resumable(n *graph.N) bool {
+
+	switch n.Name {
+	case graph.START, graph.END, graph.ENV, graph.RESTORE_CACHE, graph.SAVE_CACHE:
+		return false
+	}
+	return !strings.HasPrefix(n.Name, graph.FANIN)
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestFailedAt(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		graph string
+		at    int
+	}{
+		{"not reported", "", -1},
+		{"succeeded", `[{"node":{"name":"START","status":"S","id":"1"}},{"node":{"name":"shell","status":"S","id":"2"}}]`, -1},
+		{"failed", `[{"node":{"name":"START","status":"S","id":"1"}},{"node":{"name":"shell","status":"F","id":"2"}},{"node":{"name":"END","status":"N","id":"3"}}]`, 1},
+		{"fanout failed", `[{"node":{"name":"START","status":"S","id":"1"}},{"node":{"name":"FANOUT:test","status":"F","id":"2"},"edges":[{"name":"shell","status":"S","id":"3"},{"name":"shell","status":"F","id":"4"}]}]`, 1},
+	}
+
+	for _, tt := range tests {
+
+		b := types.Build{SubBuilds: []types.SubBuild{{ID: "1", Graph: tt.graph}}}
+		if at := failedAt(b); at != tt.at {
+			t.Errorf("%s: expected the build failed at %d, got %d", tt.name, tt.at, at)
+		}
+	}
+}
@@ -17,8 +17,11 @@ import (
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
 	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/ast"
 	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/parser"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
 	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -53,11 +56,50 @@ type Resolver interface {
 	TriggerBuild(params graphql.ResolveParams) (interface{}, error)
 	FetchBuild(params graphql.ResolveParams) (interface{}, error)
 	CancelBuild(params graphql.ResolveParams) (interface{}, error)
+	RerunBuild(params graphql.ResolveParams) (interface{}, error)
 	FetchBuildByID(params graphql.ResolveParams) (interface{}, error)
 
 	SLog(id interface{}, log string) error
 	Log(id interface{}, log types.Log) error
 	TriggerNextIfAny(prevBuildID, teamID, repositoryID, branch string)
+	Trigger(opts TriggerOptions) (types.Build, error)
+	TriggerDownstream(upstream types.Build, opts DownstreamOptions) (types.Build, error)
+	WaitOver(b types.Build)
+	FetchPipelineGraph(params graphql.ResolveParams) (interface{}, error)
+	TriggerChanged(opts TriggerOptions) ([]types.Build, error)
+	Shiftfile(b types.Build) ([]byte, error)
+	Estimate(buildID string, sb *types.SubBuild, prevGraph string, first bool)
+}
+
+// TriggerOptions ..
+// Holds the information required to trigger a build
+type TriggerOptions struct {
+	Repository  types.Repository
+	Branch      string
+	CommitID    string
+	TriggeredBy string
+
+	// name of the repository pipeline to build, required when
+	// the repository is configured with pipelines
+	Pipeline string
+
+	// head commit message and the pull request labels,
+	// parsed for the build directives such as [skip ci]
+	Message string
+	Labels  []string
+
+	// launch priority among the queued builds, defaults
+	// to the priority of the repository when zero
+	Priority int
+
+	// directives given along the trigger (such as by a schedule),
+	// in addition to the ones parsed from the message and labels
+	Directives types.Directives
+
+	// the build triggered the build from its trigger block, and
+	// the parameters given, set as the environment of the build
+	Upstream   *types.BuildLink
+	Parameters map[string]string
 }
 
 type resolver struct {
@@ -68,15 +110,25 @@ type resolver struct {
 	integrationStore store.Integration
 	defaultStore     store.Defaults
 	shiftfileStore   store.Shiftfile
+	queueStore       store.BuildQueue
+	concurrencyStore store.Concurrency
+	slotStore        store.BuildSlot
+	containerStore   store.Container
+	jobStore         store.Job
 	logger           *logrus.Entry
 	loggr            logger.Loggr
 	Ctx              context.Context
-	BuildQueue       chan types.Build
+	notify           chan struct{}
 	ps               pubsub.Engine
+	providers        providers.Providers
+	vault            secret.Vault
+
+	// files of the repositories listed to discover the shiftfiles
+	discovered *discoveryCache
 }
 
 // NewResolver ...
-func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift, ps pubsub.Engine) (Resolver, error) {
+func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift, ps pubsub.Engine, providers providers.Providers, vault secret.Vault) (Resolver, error) {
 
 	r := &resolver{
 		store:            s.Build,
@@ -86,15 +138,34 @@ func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift, ps pubs
 		integrationStore: s.Integration,
 		defaultStore:     s.Defaults,
 		shiftfileStore:   s.Shiftfile,
+		queueStore:       s.BuildQueue,
+		concurrencyStore: s.Concurrency,
+		slotStore:        s.BuildSlot,
+		containerStore:   s.Container,
+		jobStore:         s.Job,
 		logger:           loggr.GetLogger("graphql/build"),
 		loggr:            loggr,
 		Ctx:              ctx,
-		BuildQueue:       make(chan types.Build),
+		notify:           make(chan struct{}, 1),
 		ps:               ps,
+		providers:        providers,
+		vault:            vault,
+		discovered:       newDiscoveryCache(),
+	}
+
+	// Launch the background processes to launch container after build trigger,
+	// the builds queued before the server (re)started are recovered.
+	owner := launcherID()
+	for i := 0; i < launchers; i++ {
+		go r.ContainerLauncher(fmt.Sprintf("%s-%d", owner, i))
 	}
+	go r.recoverQueue()
+
+	// the builds whose workers died, or ran beyond the timeout, are reaped
+	go r.reap()
 
-	// Launch a background process to launch container after build trigger.
-	go r.ContainerLauncher()
+	// the containers left behind by the finished builds are removed
+	go r.collectGarbage()
 
 	return r, nil
 }
@@ -112,42 +183,106 @@ func (r *resolver) TriggerBuild(params graphql.ResolveParams) (interface{}, erro
 	}
 
 	branch, _ := params.Args["branch"].(string)
+	pipeline, _ := params.Args["pipeline"].(string)
+	priority, _ := params.Args["priority"].(int)
+
+	if pipeline == "" && len(repo.Pipelines) > 0 {
+		return nil, fmt.Errorf("Pipeline is required to trigger the build, must be one of %s", strings.Join(pipelineNames(repo.Pipelines), ", "))
+	}
+
+	opts := TriggerOptions{}
+	opts.Repository = repo
+	opts.Branch = branch
+	opts.Pipeline = pipeline
+	opts.Priority = priority
+	opts.TriggeredBy = "Anonymous" //TODO fill in with logged-in user
+
+	return r.Trigger(opts)
+}
+
+// Trigger ..
+// Creates a new build for the repository and queue it to launch,
+// the build waits if there is an another build running on the same branch.
+func (r *resolver) Trigger(opts TriggerOptions) (types.Build, error) {
+
+	repo := opts.Repository
+	repositoryID := repo.ID.Hex()
+
+	branch := opts.Branch
 	if branch == "" {
 		branch = repo.DefaultBranch
 	}
 
+	var pipeline types.Pipeline
+	if opts.Pipeline != "" {
+
+		p, ok := findPipeline(repo.Pipelines, opts.Pipeline)
+
+		// the pipeline of a discovered shiftfile
+		if !ok || utils.IsGlob(p.Shiftfile) {
+
+			pipelines, err := r.discoverPipelines(repo, branch, opts.CommitID)
+			if err != nil {
+				return types.Build{}, err
+			}
+
+			p, ok = findPipeline(pipelines, opts.Pipeline)
+			if !ok {
+				return types.Build{}, fmt.Errorf("Pipeline '%s' is not configured for the repository, must be one of %s", opts.Pipeline, strings.Join(pipelineNames(pipelines), ", "))
+			}
+		}
+		pipeline = p
+	}
+
 	// Check if default container engine is set
 	def, err := r.defaultStore.FindByReferenceId(repo.Team)
 	if err != nil {
-		return nil, err
+		return types.Build{}, err
 	}
 
 	if def.ContainerEngineID == "" {
-		return nil, errors.New("No default container engine found, please configure it.")
+		return types.Build{}, errors.New("No default container engine found, please configure it.")
 	}
 
-	status := types.BuildStatusPreparing
-	rb, err := r.store.FetchBuild(repo.Team, repositoryID, branch, "", []string{types.BuildStatusPreparing, types.BuildStatusRunning})
-	if err != nil {
-		return nil, fmt.Errorf("Failed to validate if there are any build running: %v", err)
-	}
+	id := bson.NewObjectId()
 
-	if len(rb) > 0 {
-		status = types.BuildStatusWaiting
+	status, err := r.initialStatus(repo.Team, repositoryID, branch, pipeline.Name)
+	if err != nil {
+		return types.Build{}, err
 	}
 
 	b := types.Build{}
-	b.ID = bson.NewObjectId()
+	b.ID = id
 	b.RepositoryID = repositoryID
 	b.ContainerEngineID = def.ContainerEngineID
 	b.VcsID = repo.VcsID
-	b.TriggeredBy = "Anonymous" //TODO fill in with logged-in user
+	b.TriggeredBy = opts.TriggeredBy
 	b.Team = repo.Team
 	b.Branch = branch
+	b.CommitID = opts.CommitID
 	b.StorageID = def.StorageID
 	b.CloneURL = repo.CloneURL
+
+	// the build triggered without a commit is pinned to the head of the
+	// branch, so that its rerun builds the same commit
+	if b.CommitID == "" {
+		b.CommitID = r.headCommit(repo, branch)
+	}
+
 	b.Language = repo.Language
 	b.Source = repo.Source
+	b.Pipeline = pipeline.Name
+	b.Shiftfile = pipeline.Shiftfile
+	b.Directives = vcs.ParseDirectives(opts.Message, opts.Labels)
+	b.Directives.RebuildCache = b.Directives.RebuildCache || opts.Directives.RebuildCache
+	b.Directives.Debug = b.Directives.Debug || opts.Directives.Debug
+	b.Directives.Only = append(b.Directives.Only, opts.Directives.Only...)
+	b.Priority = repo.Priority
+	if opts.Priority != 0 {
+		b.Priority = opts.Priority
+	}
+	b.Upstream = opts.Upstream
+	b.Parameters = opts.Parameters
 	sb := types.SubBuild{
 		ID:     "1",
 		Graph:  defaultGraph,
@@ -160,11 +295,43 @@ func (r *resolver) TriggerBuild(params graphql.ResolveParams) (interface{}, erro
 	// <cache>/team-id/vcs-id/repository-id/branch-name/build-id/reports
 	// <cache>/team-id/vcs-id/repository-id/branch-name/build-id/archive.zip
 	// cache must be mounted as /elasticshift to containers
-	b.StoragePath = filepath.Join(repo.Team, repo.Identifier, repo.Name, branch)
+	// the pipelines of a repository are kept apart:
+	// <cache>/team-id/vcs-id/repository-id/branch-name/pipeline-name/build-id/log
+	b.StoragePath = filepath.Join(repo.Team, repo.Identifier, repo.Name, branch, pipeline.Name)
+
+	b.Number, err = r.repositoryStore.NextBuildNumber(repo.ID)
+	if err != nil {
+		return types.Build{}, fmt.Errorf("Failed to number the build: %v", err)
+	}
 
 	err = r.store.Save(&b)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to save build details: %v", err)
+		return types.Build{}, fmt.Errorf("Failed to save build details: %v", err)
+	}
+
+	// the upstream build is linked to the build it triggered
+	if b.Upstream != nil {
+
+		link := types.BuildLink{
+			BuildID:      id.Hex(),
+			RepositoryID: repositoryID,
+			Branch:       branch,
+			Pipeline:     pipeline.Name,
+			Node:         b.Upstream.Node,
+			Wait:         b.Upstream.Wait,
+		}
+
+		err = r.store.AddDownstream(bson.ObjectIdHex(b.Upstream.BuildID), link)
+		if err != nil {
+			r.logger.Errorf("Failed to link the build %s to the upstream build %s: %v", id.Hex(), b.Upstream.BuildID, err)
+		}
+		r.ps.Publish(pubsub.SubscribeBuildUpdate, b.Upstream.BuildID)
+	}
+
+	// the older builds of the branch are superseded by the pushed commit,
+	// the build waiting for them is kicked off once they're cancelled
+	if opts.CommitID != "" && supersedes(repo, branch) {
+		r.cancelSuperseded(b)
 	}
 
 	if sb.Status == types.BuildStatusPreparing {
@@ -174,6 +341,21 @@ func (r *resolver) TriggerBuild(params graphql.ResolveParams) (interface{}, erro
 	return b, err
 }
 
+// initialStatus ..
+// A build waits while another build of the branch (and the pipeline) is running
+func (r *resolver) initialStatus(team, repositoryID, branch, pipeline string) (string, error) {
+
+	rb, err := r.store.FetchBuild(team, repositoryID, branch, pipeline, "", []string{types.BuildStatusPreparing, types.BuildStatusRunning}, 0)
+	if err != nil {
+		return "", fmt.Errorf("Failed to validate if there are any build running: %v", err)
+	}
+
+	if len(rb) > 0 {
+		return types.BuildStatusWaiting, nil
+	}
+	return types.BuildStatusPreparing, nil
+}
+
 func (r *resolver) TriggerNextIfAny(prevBuildID, teamID, repositoryID, branch string) {
 
 	// check if current build is completed.
@@ -185,8 +367,24 @@ func (r *resolver) TriggerNextIfAny(prevBuildID, teamID, repositoryID, branch st
 		"sub_builds.status": types.BuildStatusWaiting,
 	}
 
+	// only the builds of the same pipeline wait for each other
+	prev, err := r.store.FetchBuildByID(prevBuildID)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch the previous build %s: %v", prevBuildID, err)
+		return
+	}
+
+	// the concurrency group and the slots held by the build are passed on
+	r.releaseGroup(prev)
+	r.releaseSlots(prevBuildID)
+
+	if prev.Pipeline != "" {
+		query["pipeline"] = prev.Pipeline
+	} else {
+		query["pipeline"] = bson.M{"$exists": false}
+	}
+
 	var b types.Build
-	var err error
 	r.store.Execute(func(c *mgo.Collection) {
 		err = c.Find(query).Sort("-sub_builds.started_at").Limit(1).One(&b)
 	})
@@ -199,11 +397,17 @@ func (r *resolver) TriggerNextIfAny(prevBuildID, teamID, repositoryID, branch st
 
 		if b.ID != "" {
 
-			// update the status to preparing
-			r.store.UpdateSubBuild(b.ID.Hex(), types.SubBuild{ID: "0", Status: types.BuildStatusPreparing})
+			// update the status to preparing, unless another server did
+			promoted, err := r.store.TransitSubBuild(b.ID.Hex(), "1", types.BuildStatusWaiting, types.BuildStatusPreparing)
+			if err != nil {
+				r.logger.Errorf("Failed to prepare the next build %s: %v", b.ID.Hex(), err)
+				return
+			}
 
 			// post to build queue
-			r.pushToQueue(b)
+			if promoted {
+				r.pushToQueue(b)
+			}
 		}
 	}
 }
@@ -213,8 +417,12 @@ func (r *resolver) pushToQueue(b types.Build) {
 	// publish to topic to push build updates to subscribers
 	r.ps.Publish(pubsub.SubscribeBuildUpdate, b.ID.Hex())
 
-	// Pass the build data to builder
-	r.BuildQueue <- b
+	// Pass the build data to builder, the build left in preparing
+	// is queued again when the server restarts, if it fails.
+	err := r.queue(b)
+	if err != nil {
+		r.logger.Errorf("Failed to queue the build %s: %v", b.ID.Hex(), err)
+	}
 }
 
 func (r *resolver) UpdateBuildStatusAsFailed(id, subid, reason string, endedAt time.Time) {
@@ -225,16 +433,19 @@ func (r *resolver) UpdateBuildStatus(id, subid, reason, status string, endedAt t
 
 	// should be the container startup log, if startup failed
 	// should be the err log if build failed.
-	b, err := r.store.FetchSubBuild(id, subid)
-	if err != nil {
-		// TODO handler error
-	}
+	var err error
+	if Finished(status) {
 
-	b.Reason = reason
-	b.Status = status
-	b.EndedAt = time.Now()
+		// the sub build finished meanwhile is left as it is
+		var sb types.SubBuild
+		sb, err = r.store.FetchSubBuild(id, subid)
+		if err == nil {
+			err = r.finishSubBuild("container", id, sb, status, reason)
+		}
+	} else {
+		err = r.updateSubBuild("container", id, types.SubBuild{ID: subid, Reason: reason, Status: status})
+	}
 
-	err = r.store.UpdateSubBuild(id, b)
 	if err != nil {
 		r.logger.Errorf("failed to update build status: %v", err)
 	}
@@ -257,7 +468,9 @@ func (r *resolver) FetchBuild(params graphql.ResolveParams) (interface{}, error)
 	team, _ := params.Args["team"].(string)
 	repository_id, _ := params.Args["repository_id"].(string)
 	branch, _ := params.Args["branch"].(string)
+	pipeline, _ := params.Args["pipeline"].(string)
 	id, _ := params.Args["id"].(string)
+	number, _ := params.Args["number"].(int)
 	statusParam, _ := params.Args["status"].(int)
 
 	statusArr := []string{}
@@ -285,11 +498,31 @@ func (r *resolver) FetchBuild(params graphql.ResolveParams) (interface{}, error)
 	}
 
 	result := types.BuildList{}
-	res, err := r.store.FetchBuild(team, repository_id, branch, id, statusArr)
+
+	// the build is looked up by its number in the repository
+	if number > 0 && id == "" {
+
+		b, err := r.store.FetchBuildByNumber(repository_id, number)
+		if err == mgo.ErrNotFound {
+			return result, nil
+		}
+
+		if err != nil {
+			return result, fmt.Errorf("Failed to fetch the build #%d : %v", number, err)
+		}
+		id = b.ID.Hex()
+	}
+
+	res, err := r.store.FetchBuild(team, repository_id, branch, pipeline, id, statusArr, 0)
 	if err != nil {
 		return result, fmt.Errorf("Failed to fetch the build : %v", err)
 	}
 
+	err = r.estimateQueue(res)
+	if err != nil {
+		r.logger.Warnf("Failed to estimate the queued builds: %v", err)
+	}
+
 	result.Nodes = res
 	result.Count = len(res)
 
@@ -303,24 +536,14 @@ func (r *resolver) CancelBuild(params graphql.ResolveParams) (interface{}, error
 		return nil, fmt.Errorf("Failed to cancel the build : %v", err)
 	}
 
-	b := res.(types.Build)
+	b, _ := res.(types.Build)
 	if b.ID == "" {
 		return nil, fmt.Errorf("Build id not found")
 	}
 
-	for _, sb := range b.SubBuilds {
-
-		if types.BuildStatusCancel == sb.Status || types.BuildStatusFailed == sb.Status || types.BuildStatusSuccess == sb.Status {
-			//fmt.Sprintf("Cancelling the build is not possible, because it seems that it was already %s", sb.Status), nil
-		} else {
-
-			// TODO trigger the cancel build, only if the current status is RUNNING | WAITING | STUCK
-
-			err = r.store.UpdateBuildStatus(b.ID, types.BuildStatusCancel)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to cancel the build: %v", err)
-			}
-		}
+	err = r.Cancel(b, "Cancelled by the user")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to cancel the build: %v", err)
 	}
 
 	return nil, nil
@@ -342,9 +565,19 @@ func (r *resolver) FetchBuildByID(params graphql.ResolveParams) (interface{}, er
 
 func (r *resolver) GetShiftfile(b types.Build) (*ast.File, bool, error) {
 
+	// a rerun is built with the shiftfile of the build rerun
+	if b.ResolvedShiftfile != "" {
+
+		sf, err := parser.AST([]byte(b.ResolvedShiftfile))
+		if err != nil {
+			r.SLog(b.ID, fmt.Sprintf("Failed to parse shift file: %v", err))
+		}
+		return sf, false, nil
+	}
+
 	var repoFile bool
 	repoFile = true
-	f, err := r.repoImageName(b)
+	f, err := r.Shiftfile(b)
 	if err != nil && f == nil {
 
 		repoFile = false
@@ -372,22 +605,70 @@ func (r *resolver) GetShiftfile(b types.Build) (*ast.File, bool, error) {
 	sf, err := parser.AST([]byte(f))
 	if err != nil {
 		r.SLog(b.ID, fmt.Sprintf("Failed to parse shift file: %v", err))
+		return sf, repoFile, nil
+	}
+
+	// pinned, so the build is rerun with the same shiftfile
+	err = r.store.UpdateId(b.ID, bson.M{"$set": bson.M{"resolved_shiftfile": string(f)}})
+	if err != nil {
+		r.logger.Warnf("Failed to record the shiftfile of build %s: %v", b.ID.Hex(), err)
 	}
 
 	return sf, repoFile, nil
 }
 
-func (r *resolver) repoImageName(b types.Build) ([]byte, error) {
+// Shiftfile ..
+// Fetches the shiftfile of the build from the repository, which is the
+// shiftfile of the pipeline when the build is triggered for a pipeline.
+func (r *resolver) Shiftfile(b types.Build) ([]byte, error) {
+
+	path := b.Shiftfile
+	if path == "" {
+		path = vcs.SHIFTFILE
+	}
+
+	// plain git repositories are not linked to any vcs account
+	if b.VcsID == "" {
+
+		repo, err := r.repositoryStore.GetRepositoryByID(b.RepositoryID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+		}
+
+		c, err := secret.GitCredentials(r.vault, repo)
+		if err != nil {
+			return nil, err
+		}
 
-	var source string
-	if strings.Contains(b.CloneURL, vcs.GITHUB_DOT_COM) {
-		source = vcs.GITHUB_DOT_COM
+		return vcs.GetFileFromGit(b.CloneURL, b.Branch, path, c)
 	}
 
-	f, err := vcs.GetShiftFile(source, b.CloneURL, b.Branch)
+	e, token, err := r.vcsAccess(b.Team, b.VcsID, b.Source)
 	if err != nil {
 		return nil, err
 	}
 
-	return f, nil
+	return vcs.GetFile(e, b.CloneURL, b.Branch, token, path)
+}
+
+// vcsAccess ..
+// Resolves the api endpoint and the (refreshed) token of the vcs account
+func (r *resolver) vcsAccess(team, vcsID, source string) (vcs.Endpoint, string, error) {
+
+	account, err := r.teamStore.GetVCSByID(team, vcsID)
+	if err != nil {
+		return vcs.Endpoint{}, "", fmt.Errorf("Failed to fetch the vcs account linked to the repository: %v", err)
+	}
+
+	token, err := r.providers.GetToken(team, account)
+	if err != nil {
+		return vcs.Endpoint{}, "", fmt.Errorf("Failed to fetch/refresh the token for account %s: %v", account.Name, err)
+	}
+
+	conf, err := r.providers.Conf(account.Kind)
+	if err != nil {
+		return vcs.Endpoint{}, "", err
+	}
+
+	return vcs.Endpoint{Source: source, Provider: conf.Provider, APIURL: conf.APIURL, WebURL: conf.WebURL}, token, nil
 }
@@ -0,0 +1,467 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"sort"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var (
+	// expected duration of a build, until the repository has a successful build
+	defaultBuildDuration = 10 * time.Minute
+
+	// successful builds of the repository, averaged to estimate the duration
+	durationSamples = 10
+)
+
+// usage ..
+// Builds running or being launched, per team, repository and container engine
+type usage struct {
+	teams        map[string]int
+	repositories map[string]int
+	engines      map[string]int
+}
+
+func (u usage) add(team, repositoryID, engineID string, n int) {
+	u.teams[team] += n
+	u.repositories[repositoryID] += n
+	u.engines[engineID] += n
+}
+
+// usage ..
+// Counts the builds having a container, and the queued builds
+// leased by a launcher at the moment.
+func (r *resolver) usage(queued []types.QueuedBuild) (usage, error) {
+
+	u := usage{
+		teams:        make(map[string]int),
+		repositories: make(map[string]int),
+		engines:      make(map[string]int),
+	}
+
+	active, err := r.store.CountActiveBuilds()
+	if err != nil {
+		return u, err
+	}
+
+	for _, a := range active {
+		u.add(a.Team, a.RepositoryID, a.EngineID, a.Count)
+	}
+
+	now := time.Now()
+	for _, q := range queued {
+		if leased(q, now) {
+			u.add(q.Team, q.RepositoryID, q.EngineID, 1)
+		}
+	}
+	return u, nil
+}
+
+func leased(q types.QueuedBuild, now time.Time) bool {
+	return q.LeasedBy != "" && q.VisibleAt.After(now)
+}
+
+// limits ..
+// Concurrent builds allowed per team, repository and container engine,
+// unlimited when zero. The limits are read once for a scheduling round.
+type limits struct {
+	r            *resolver
+	teams        map[string]int
+	repositories map[string]int
+	engines      map[string]int
+}
+
+func (r *resolver) newLimits() *limits {
+
+	return &limits{
+		r:            r,
+		teams:        make(map[string]int),
+		repositories: make(map[string]int),
+		engines:      make(map[string]int),
+	}
+}
+
+func (l *limits) team(name string) int {
+
+	if n, ok := l.teams[name]; ok {
+		return n
+	}
+
+	def, err := l.r.defaultStore.FindByReferenceId(name)
+	if err != nil && err.Error() != "not found" {
+		l.r.logger.Errorf("Failed to fetch the build limit of team %s: %v", name, err)
+	}
+
+	l.teams[name] = def.MaxConcurrentBuilds
+	return def.MaxConcurrentBuilds
+}
+
+func (l *limits) repository(id string) int {
+
+	if n, ok := l.repositories[id]; ok {
+		return n
+	}
+
+	repo, err := l.r.repositoryStore.GetRepositoryByID(id)
+	if err != nil && err.Error() != "not found" {
+		l.r.logger.Errorf("Failed to fetch the build limit of repository %s: %v", id, err)
+	}
+
+	l.repositories[id] = repo.MaxConcurrentBuilds
+	return repo.MaxConcurrentBuilds
+}
+
+func (l *limits) engine(id string) int {
+
+	if n, ok := l.engines[id]; ok {
+		return n
+	}
+
+	var ce types.ContainerEngine
+	if id != "" {
+
+		err := l.r.integrationStore.FindByID(id, &ce)
+		if err != nil && err.Error() != "not found" {
+			l.r.logger.Errorf("Failed to fetch the build limit of container engine %s: %v", id, err)
+		}
+	}
+
+	l.engines[id] = ce.MaxConcurrentBuilds
+	return ce.MaxConcurrentBuilds
+}
+
+// allow ..
+// A queued build is launched when none of its limits is reached
+func (l *limits) allow(q types.QueuedBuild, u usage) bool {
+
+	return under(l.team(q.Team), u.teams[q.Team]) &&
+		under(l.repository(q.RepositoryID), u.repositories[q.RepositoryID]) &&
+		under(l.engine(q.EngineID), u.engines[q.EngineID])
+}
+
+func under(limit, running int) bool {
+	return limit == 0 || running < limit
+}
+
+// slot ..
+// A limit of the queued build, identified by the key of its slots
+type slot struct {
+	key   string
+	limit int
+}
+
+// slots ..
+// Returns the limits the queued build is subject to, the unlimited are left out
+func (l *limits) slots(q types.QueuedBuild) []slot {
+
+	var s []slot
+	if n := l.team(q.Team); n > 0 {
+		s = append(s, slot{"team/" + q.Team, n})
+	}
+
+	if n := l.repository(q.RepositoryID); n > 0 {
+		s = append(s, slot{"repository/" + q.RepositoryID, n})
+	}
+
+	if n := l.engine(q.EngineID); n > 0 {
+		s = append(s, slot{"engine/" + q.EngineID, n})
+	}
+	return s
+}
+
+// acquireSlots ..
+// Takes a slot of each limit of the queued build, which is atomic across the
+// launchers of all the servers. The slots held by the builds finished without
+// releasing them are freed on the way. Returns false, holding none of the
+// slots, when any of the limits is reached.
+func (r *resolver) acquireSlots(q types.QueuedBuild, l *limits) (bool, error) {
+
+	buildID := q.ID.Hex()
+	for _, s := range l.slots(q) {
+
+		acquired, err := r.slotStore.Acquire(s.key, s.limit, buildID)
+		if err == nil && !acquired && r.reclaimSlots(s.key) {
+			acquired, err = r.slotStore.Acquire(s.key, s.limit, buildID)
+		}
+
+		if err != nil || !acquired {
+			r.releaseSlots(buildID)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// reclaimSlots ..
+// Frees the slots held by the builds that are no longer in progress,
+// returns true if any of the slots is freed.
+func (r *resolver) reclaimSlots(key string) bool {
+
+	holders, err := r.slotStore.FetchHolders(key)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch the builds holding %s: %v", key, err)
+		return false
+	}
+
+	var finished []string
+	for _, id := range holders {
+
+		b, err := r.store.FetchBuildByID(id)
+		if err != nil && err.Error() != "not found" {
+			r.logger.Errorf("Failed to fetch the build %s holding %s: %v", id, key, err)
+			continue
+		}
+
+		if err != nil || !inProgress(b) {
+			finished = append(finished, id)
+		}
+	}
+
+	if len(finished) == 0 {
+		return false
+	}
+
+	err = r.slotStore.RemoveHolders(key, finished)
+	if err != nil {
+		r.logger.Errorf("Failed to free the slots of %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// releaseSlots ..
+// Frees the slots of the limits held by the build
+func (r *resolver) releaseSlots(buildID string) {
+
+	err := r.slotStore.Release(buildID)
+	if err != nil {
+		r.logger.Errorf("Failed to release the slots of build %s: %v", buildID, err)
+	}
+}
+
+// schedule ..
+// Orders the queued builds by fair share between the teams, the next build
+// is taken from the team running the fewest builds, so a team queueing a
+// batch of builds doesn't starve the others. The builds of a team are taken
+// by the priority, and then the oldest first.
+func schedule(queued []types.QueuedBuild, u usage) []types.QueuedBuild {
+
+	pending := make([]types.QueuedBuild, len(queued))
+	copy(pending, queued)
+
+	sort.SliceStable(pending, func(i, j int) bool {
+
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority > pending[j].Priority
+		}
+		return pending[i].EnqueuedAt.Before(pending[j].EnqueuedAt)
+	})
+
+	shares := make(map[string]int, len(u.teams))
+	for team, n := range u.teams {
+		shares[team] = n
+	}
+
+	order := make([]types.QueuedBuild, 0, len(pending))
+	for len(pending) > 0 {
+
+		next := 0
+		for i, q := range pending {
+			if shares[q.Team] < shares[pending[next].Team] {
+				next = i
+			}
+		}
+
+		q := pending[next]
+		shares[q.Team]++
+		order = append(order, q)
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return order
+}
+
+// leaseNext ..
+// Leases the next build to launch in the schedule, that isn't held by
+// its limits. The usage read is only a snapshot, so the build is launched
+// once it takes the slots of its limits. Returns mgo.ErrNotFound when
+// there's nothing to launch.
+func (r *resolver) leaseNext(owner string) (types.QueuedBuild, error) {
+
+	now := time.Now()
+	queued, err := r.queueStore.FetchLeasable(now)
+	if err != nil {
+		return types.QueuedBuild{}, err
+	}
+
+	u, err := r.usage(queued)
+	if err != nil {
+		return types.QueuedBuild{}, err
+	}
+
+	visible := queued[:0]
+	for _, q := range queued {
+		if !q.VisibleAt.After(now) {
+			visible = append(visible, q)
+		}
+	}
+
+	l := r.newLimits()
+	for _, q := range schedule(visible, u) {
+
+		if !l.allow(q, u) {
+			continue
+		}
+
+		lq, err := r.queueStore.Lease(q.ID, owner, leaseTimeout)
+		if err == mgo.ErrNotFound {
+			// leased by another launcher meanwhile
+			continue
+		}
+
+		if err != nil {
+			return lq, err
+		}
+
+		acquired, err := r.acquireSlots(lq, l)
+		if err != nil {
+			r.logger.Errorf("Failed to take the slots of build %s: %v", lq.ID.Hex(), err)
+		}
+
+		if acquired {
+			return lq, nil
+		}
+
+		// the limit is reached by the other launchers meanwhile
+		err = r.queueStore.Postpone(lq.ID, owner, 0)
+		if err != nil {
+			r.logger.Errorf("Failed to put back the build %s in the queue: %v", lq.ID.Hex(), err)
+		}
+	}
+
+	return types.QueuedBuild{}, mgo.ErrNotFound
+}
+
+// estimateQueue ..
+// Fills the position in the queue and the estimated start of the queued builds
+func (r *resolver) estimateQueue(builds []types.Build) error {
+
+	queued, err := r.queueStore.FetchQueued()
+	if err != nil || len(queued) == 0 {
+		return err
+	}
+
+	u, err := r.usage(queued)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	waiting := make([]types.QueuedBuild, 0, len(queued))
+	for _, q := range queued {
+		if !leased(q, now) {
+			waiting = append(waiting, q)
+		}
+	}
+	order := schedule(waiting, u)
+
+	positions := make(map[string]int, len(order))
+	for i, q := range order {
+		positions[q.ID.Hex()] = i
+	}
+
+	l := r.newLimits()
+	durations := make(map[string]time.Duration)
+	for i, b := range builds {
+
+		pos, ok := positions[b.ID.Hex()]
+		if !ok {
+			continue
+		}
+
+		builds[i].QueuePosition = pos + 1
+		builds[i].EstimatedStart = now
+
+		// the builds ahead sharing the tightest limit have to finish first
+		q := order[pos]
+		limit, running, same := l.tightest(q, u)
+		if limit == 0 {
+			continue
+		}
+
+		ahead := 0
+		for _, o := range order[:pos] {
+			if same(o) {
+				ahead++
+			}
+		}
+
+		if running+ahead < limit {
+			continue
+		}
+
+		d, ok := durations[b.RepositoryID]
+		if !ok {
+			d = r.expectedDuration(b.RepositoryID)
+			durations[b.RepositoryID] = d
+		}
+
+		waves := (running+ahead-limit)/limit + 1
+		builds[i].EstimatedStart = now.Add(time.Duration(waves) * d)
+	}
+
+	return nil
+}
+
+// tightest ..
+// Returns the lowest limit of the queued build, with the builds running within
+// the limit and a matcher of the builds sharing it. Zero limit if unlimited.
+func (l *limits) tightest(q types.QueuedBuild, u usage) (int, int, func(types.QueuedBuild) bool) {
+
+	limit, running := 0, 0
+	var same func(types.QueuedBuild) bool
+
+	pick := func(n, active int, match func(types.QueuedBuild) bool) {
+		if n > 0 && (limit == 0 || n < limit) {
+			limit, running, same = n, active, match
+		}
+	}
+
+	pick(l.team(q.Team), u.teams[q.Team], func(o types.QueuedBuild) bool { return o.Team == q.Team })
+	pick(l.repository(q.RepositoryID), u.repositories[q.RepositoryID], func(o types.QueuedBuild) bool { return o.RepositoryID == q.RepositoryID })
+	pick(l.engine(q.EngineID), u.engines[q.EngineID], func(o types.QueuedBuild) bool { return o.EngineID == q.EngineID })
+
+	return limit, running, same
+}
+
+// expectedDuration ..
+// Averages the duration of the recent successful builds of the repository
+func (r *resolver) expectedDuration(repositoryID string) time.Duration {
+
+	builds, err := r.store.FetchRecentBuilds(repositoryID, types.BuildStatusSuccess, durationSamples)
+	if err != nil {
+		r.logger.Warnf("Failed to fetch the recent builds of repository %s: %v", repositoryID, err)
+		return defaultBuildDuration
+	}
+
+	var total time.Duration
+	var count int
+	for _, b := range builds {
+		for _, sb := range b.SubBuilds {
+			if !sb.StartedAt.IsZero() && sb.EndedAt.After(sb.StartedAt) {
+				total += sb.EndedAt.Sub(sb.StartedAt)
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return defaultBuildDuration
+	}
+	return total / time.Duration(count)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func newUsage(teams, repositories, engines map[string]int) usage {
+
+	u := usage{teams: teams, repositories: repositories, engines: engines}
+	if u.teams == nil {
+		u.teams = make(map[string]int)
+	}
+
+	if u.repositories == nil {
+		u.repositories = make(map[string]int)
+	}
+
+	if u.engines == nil {
+		u.engines = make(map[string]int)
+	}
+	return u
+}
+
+func TestSchedule(t *testing.T) {
+
+	now := time.Now()
+	queued := func(name, team string, priority int, age time.Duration) types.QueuedBuild {
+		return types.QueuedBuild{ID: bson.ObjectId(name), Team: team, Priority: priority, EnqueuedAt: now.Add(-age)}
+	}
+
+	tests := []struct {
+		name    string
+		queued  []types.QueuedBuild
+		running map[string]int
+		order   []string
+	}{
+		{
+			name:   "oldest first",
+			queued: []types.QueuedBuild{queued("a2", "a", 0, time.Minute), queued("a1", "a", 0, time.Hour)},
+			order:  []string{"a1", "a2"},
+		},
+		{
+			name:   "priority before age",
+			queued: []types.QueuedBuild{queued("a1", "a", 0, time.Hour), queued("a2", "a", 5, time.Minute)},
+			order:  []string{"a2", "a1"},
+		},
+		{
+			name: "batch of a team doesn't starve the others",
+			queued: []types.QueuedBuild{
+				queued("a1", "a", 0, 4*time.Hour),
+				queued("a2", "a", 0, 3*time.Hour),
+				queued("a3", "a", 0, 2*time.Hour),
+				queued("b1", "b", 0, time.Minute),
+				queued("c1", "c", 0, time.Second),
+			},
+			order: []string{"a1", "b1", "c1", "a2", "a3"},
+		},
+		{
+			name: "team running the fewest goes first",
+			queued: []types.QueuedBuild{
+				queued("a1", "a", 0, time.Hour),
+				queued("b1", "b", 0, time.Minute),
+			},
+			running: map[string]int{"a": 2},
+			order:   []string{"b1", "a1"},
+		},
+		{
+			name: "priority within the fair share",
+			queued: []types.QueuedBuild{
+				queued("a1", "a", 0, time.Hour),
+				queued("a2", "a", 9, time.Minute),
+				queued("b1", "b", 0, time.Hour),
+			},
+			order: []string{"a2", "b1", "a1"},
+		},
+		{
+			name:  "empty queue",
+			order: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+
+		order := schedule(tt.queued, newUsage(tt.running, nil, nil))
+		if len(order) != len(tt.order) {
+			t.Errorf("%s: expected %d builds, got %d", tt.name, len(tt.order), len(order))
+			continue
+		}
+
+		for i, q := range order {
+			if string(q.ID) != tt.order[i] {
+				t.Errorf("%s: expected %s at %d, got %s", tt.name, tt.order[i], i, string(q.ID))
+			}
+		}
+	}
+}
+
+func TestLimitsAllow(t *testing.T) {
+
+	// the limits are cached, so the stores aren't read
+	l := &limits{
+		teams:        map[string]int{"a": 2, "b": 0},
+		repositories: map[string]int{"r1": 1, "r2": 0},
+		engines:      map[string]int{"e1": 3, "e2": 0},
+	}
+
+	q := func(team, repo, engine string) types.QueuedBuild {
+		return types.QueuedBuild{Team: team, RepositoryID: repo, EngineID: engine}
+	}
+
+	tests := []struct {
+		name  string
+		q     types.QueuedBuild
+		u     usage
+		allow bool
+	}{
+		{"nothing running", q("a", "r1", "e1"), newUsage(nil, nil, nil), true},
+		{"under the team limit", q("a", "r2", "e2"), newUsage(map[string]int{"a": 1}, nil, nil), true},
+		{"team limit reached", q("a", "r2", "e2"), newUsage(map[string]int{"a": 2}, nil, nil), false},
+		{"repository limit reached", q("b", "r1", "e2"), newUsage(nil, map[string]int{"r1": 1}, nil), false},
+		{"engine limit reached", q("b", "r2", "e1"), newUsage(nil, nil, map[string]int{"e1": 3}), false},
+		{"unlimited", q("b", "r2", "e2"), newUsage(map[string]int{"b": 50}, map[string]int{"r2": 50}, map[string]int{"e2": 50}), true},
+		{"limits of the others", q("b", "r2", "e2"), newUsage(map[string]int{"a": 2}, map[string]int{"r1": 1}, map[string]int{"e1": 3}), true},
+	}
+
+	for _, tt := range tests {
+		if allow := l.allow(tt.q, tt.u); allow != tt.allow {
+			t.Errorf("%s: expected allow to be %v", tt.name, tt.allow)
+		}
+	}
+
+	s := l.slots(q("a", "r1", "e2"))
+	if len(s) != 2 || s[0] != (slot{"team/a", 2}) || s[1] != (slot{"repository/r1", 1}) {
+		t.Errorf("unexpected slots %v", s)
+	}
+}
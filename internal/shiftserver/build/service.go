@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,6 +20,7 @@ import (
 	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
 	itypes "github.com/elasticshift/elasticshift/internal/shiftserver/integration/types"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"gopkg.in/mgo.v2/bson"
 )
 
 var (
@@ -29,6 +31,7 @@ var (
 
 type service struct {
 	buildStore       store.Build
+	repositoryStore  store.Repository
 	teamStore        store.Team
 	sysconfStore     store.Sysconf
 	integrationStore store.Integration
@@ -40,6 +43,7 @@ type service struct {
 // Service ..
 type Service interface {
 	Viewlog(w http.ResponseWriter, r *http.Request)
+	Badge(w http.ResponseWriter, r *http.Request)
 }
 
 // NewService ..
@@ -48,6 +52,7 @@ func NewService(loggr logger.Loggr, s store.Shift) Service {
 	l := loggr.GetLogger("service/build")
 	return &service{
 		buildStore:       s.Build,
+		repositoryStore:  s.Repository,
 		teamStore:        s.Team,
 		sysconfStore:     s.Sysconf,
 		integrationStore: s.Integration,
@@ -74,6 +79,18 @@ func (s service) Viewlog(w http.ResponseWriter, r *http.Request) {
 	}
 
 	buildID := mux.Vars(r)["buildid"]
+
+	// the build is given by its number in the repository
+	if number := mux.Vars(r)["number"]; number != "" {
+
+		b, err := s.buildByNumber(mux.Vars(r)["team"], mux.Vars(r)["repo"], number)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		buildID = b.ID.Hex()
+	}
+
 	if buildID == "" {
 		http.Error(w, "URL doesn't container build identifier.", http.StatusBadRequest)
 		return
@@ -222,6 +239,43 @@ func (s service) Viewlog(w http.ResponseWriter, r *http.Request) {
 	//}
 }
 
+// buildByNumber ..
+// Returns the build of the repository by its number, the repository is
+// given by its identifier, or by its name in the team
+func (s service) buildByNumber(team, repo, number string) (types.Build, error) {
+
+	n, err := strconv.Atoi(strings.TrimPrefix(number, "#"))
+	if err != nil || n <= 0 {
+		return types.Build{}, fmt.Errorf("Invalid build number '%s'", number)
+	}
+
+	rp, err := s.repository(team, repo)
+	if err != nil {
+		return types.Build{}, err
+	}
+
+	b, err := s.buildStore.FetchBuildByNumber(rp.ID.Hex(), n)
+	if err != nil {
+		return types.Build{}, fmt.Errorf("Build #%d of repository '%s' is not found: %v", n, repo, err)
+	}
+	return b, nil
+}
+
+// repository ..
+// Returns the repository of the team by its identifier, or by its name
+func (s service) repository(team, repo string) (types.Repository, error) {
+
+	if !bson.IsObjectIdHex(repo) {
+		return findRepository(s.repositoryStore, team, repo)
+	}
+
+	rp, err := s.repositoryStore.GetRepositoryByID(repo)
+	if err != nil || rp.Team != team {
+		return types.Repository{}, fmt.Errorf("Repository '%s' is not found in the team", repo)
+	}
+	return rp, nil
+}
+
 func stream(w http.ResponseWriter, r io.ReadCloser) error {
 
 	done := make(chan bool, 1)
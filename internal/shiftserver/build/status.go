@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+// times the update is retried when the sub build
+// is changed by another update meanwhile
+const updateAttempts = 3
+
+// ErrTransitionRejected ..
+// The status of the sub build isn't allowed to move to the given status
+var ErrTransitionRejected = errors.New("The status of the sub build cannot be changed, it's either finished or not allowed to move to the status")
+
+// statuses a sub build can move to from its status, the
+// finished sub builds are not allowed to move anywhere
+var transitions = map[string][]string{
+
+	// the sub builds that fail to launch are failed right away
+	types.BuildStatusWaiting: {types.BuildStatusPreparing, types.BuildStatusFailed, types.BuildStatusCancel},
+
+	types.BuildStatusPreparing: {types.BuildStatusRunning, types.BuildStatusFailed, types.BuildStatusCancel, types.BuildStatusStuck},
+
+	types.BuildStatusRunning: {types.BuildStatusSuccess, types.BuildStatusFailed, types.BuildStatusCancel, types.BuildStatusStuck},
+}
+
+// Finished ..
+// True if the sub build in the status is finished
+func Finished(status string) bool {
+
+	switch status {
+	case types.BuildStatusSuccess, types.BuildStatusFailed, types.BuildStatusCancel, types.BuildStatusStuck:
+		return true
+	}
+	return false
+}
+
+// CanTransit ..
+// True if the sub build is allowed to move from the status to the other.
+// The sub build may stay in its status, such as when its graph is updated,
+// the finished sub build isn't allowed to move anywhere.
+func CanTransit(from, to string) bool {
+
+	if from == to {
+		return true
+	}
+
+	if Finished(from) {
+		return false
+	}
+
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateSubBuild ..
+// Updates the given fields of the sub build, as the store does, moving it
+// to the status given only if its current status is allowed to. A rejected
+// status change is logged for auditing, along with its source, the rest of
+// the fields, such as the graph, are still updated and ErrTransitionRejected
+// is returned.
+//
+// The sub build read by the caller is updated only at the version it was
+// read with, ErrConflict is returned when it's changed meanwhile and the
+// caller reads it again. The update without a version, of the fields that
+// don't depend on the sub build, is retried at the current version.
+func UpdateSubBuild(s store.Build, logger *logrus.Entry, source, buildID string, sb types.SubBuild) error {
+
+	for i := 0; i < updateAttempts; i++ {
+
+		cur, err := s.FetchSubBuild(buildID, sb.ID)
+		if err != nil {
+			return err
+		}
+
+		if sb.Version > 0 && sb.Version != cur.Version {
+			return store.ErrConflict
+		}
+
+		// the sub builds of the other images used to be launched without a status
+		from := cur.Status
+		if from == "" {
+			from = types.BuildStatusPreparing
+		}
+
+		to := sb.Status
+		if to == "" {
+			to = from
+		}
+
+		u := sb
+		u.Version = cur.Version
+
+		rejected := !CanTransit(from, to)
+		if rejected {
+
+			logger.WithFields(logrus.Fields{
+				"build_id":     buildID,
+				"sub_build_id": sb.ID,
+				"from":         from,
+				"to":           to,
+				"source":       source,
+			}).Warnf("Rejected the status transition of build %s-%s from %s to %s", buildID, sb.ID, from, to)
+
+			u.Status = ""
+			u.EndedAt = time.Time{}
+		}
+
+		err = s.UpdateSubBuild(buildID, u)
+		if err == store.ErrConflict && sb.Version == 0 {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if rejected {
+			return ErrTransitionRejected
+		}
+		return nil
+	}
+	return store.ErrConflict
+}
+
+// updateSubBuild ..
+// Updates the sub build through the state machine
+func (r *resolver) updateSubBuild(source, buildID string, sb types.SubBuild) error {
+	return UpdateSubBuild(r.store, r.logger, source, buildID, sb)
+}
+
+// finishSubBuild ..
+// Moves the sub build read to the finished status with the reason, unless
+// it's finished already. It's read again when it's changed meanwhile, such
+// as by a heartbeat.
+func (r *resolver) finishSubBuild(source, buildID string, sb types.SubBuild, status, reason string) error {
+
+	for i := 0; i < updateAttempts; i++ {
+
+		if Finished(sb.Status) {
+			return nil
+		}
+
+		err := r.updateSubBuild(source, buildID, types.SubBuild{
+			ID:      sb.ID,
+			Status:  status,
+			Reason:  reason,
+			EndedAt: time.Now(),
+			Version: sb.Version,
+		})
+		if err != store.ErrConflict {
+			return err
+		}
+
+		sb, err = r.store.FetchSubBuild(buildID, sb.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return store.ErrConflict
+}
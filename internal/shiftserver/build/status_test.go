@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package build
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFinished(t *testing.T) {
+
+	tests := []struct {
+		status   string
+		finished bool
+	}{
+		{types.BuildStatusWaiting, false},
+		{types.BuildStatusPreparing, false},
+		{types.BuildStatusRunning, false},
+		{types.BuildStatusSuccess, true},
+		{types.BuildStatusFailed, true},
+		{types.BuildStatusCancel, true},
+		{types.BuildStatusStuck, true},
+	}
+
+	for _, tt := range tests {
+		if f := Finished(tt.status); f != tt.finished {
+			t.Errorf("Finished(%s) = %v, expected %v", tt.status, f, tt.finished)
+		}
+	}
+}
+
+func TestCanTransit(t *testing.T) {
+
+	tests := []struct {
+		from string
+		to   string
+		can  bool
+	}{
+		{types.BuildStatusWaiting, types.BuildStatusWaiting, true},
+		{types.BuildStatusWaiting, types.BuildStatusPreparing, true},
+		{types.BuildStatusWaiting, types.BuildStatusFailed, true},
+		{types.BuildStatusWaiting, types.BuildStatusRunning, false},
+		{types.BuildStatusWaiting, types.BuildStatusSuccess, false},
+		{types.BuildStatusPreparing, types.BuildStatusRunning, true},
+		{types.BuildStatusPreparing, types.BuildStatusStuck, true},
+		{types.BuildStatusPreparing, types.BuildStatusSuccess, false},
+		{types.BuildStatusRunning, types.BuildStatusRunning, true},
+		{types.BuildStatusRunning, types.BuildStatusSuccess, true},
+		{types.BuildStatusRunning, types.BuildStatusCancel, true},
+		{types.BuildStatusRunning, types.BuildStatusWaiting, false},
+
+		// the finished sub build stays as it is, its graph is still updated
+		{types.BuildStatusSuccess, types.BuildStatusSuccess, true},
+		{types.BuildStatusCancel, types.BuildStatusCancel, true},
+		{types.BuildStatusCancel, types.BuildStatusFailed, false},
+		{types.BuildStatusStuck, types.BuildStatusSuccess, false},
+		{types.BuildStatusFailed, types.BuildStatusRunning, false},
+	}
+
+	for _, tt := range tests {
+		if c := CanTransit(tt.from, tt.to); c != tt.can {
+			t.Errorf("CanTransit(%s, %s) = %v, expected %v", tt.from, tt.to, c, tt.can)
+		}
+	}
+}
+
+// subBuildStore ..
+// Keeps a sub build the way the store does, the update is refused
+// when the sub build isn't at the version given
+type subBuildStore struct {
+	store.Build
+
+	sb types.SubBuild
+
+	// the updates made by the others before the next update
+	others int
+}
+
+func (s *subBuildStore) FetchSubBuild(buildID, subBuildID string) (types.SubBuild, error) {
+	return s.sb, nil
+}
+
+func (s *subBuildStore) UpdateSubBuild(buildID string, sb types.SubBuild) error {
+
+	if s.others > 0 {
+		s.others--
+		s.sb.Version++
+		return store.ErrConflict
+	}
+
+	if sb.Version != s.sb.Version {
+		return store.ErrConflict
+	}
+
+	if sb.Status != "" {
+		s.sb.Status = sb.Status
+	}
+
+	if sb.Graph != "" {
+		s.sb.Graph = sb.Graph
+	}
+
+	if sb.Reason != "" {
+		s.sb.Reason = sb.Reason
+	}
+
+	if !sb.EndedAt.IsZero() {
+		s.sb.EndedAt = sb.EndedAt
+	}
+	s.sb.Version++
+	return nil
+}
+
+func TestUpdateSubBuild(t *testing.T) {
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	tests := []struct {
+		name    string
+		cur     types.SubBuild
+		others  int
+		update  types.SubBuild
+		err     error
+		updated types.SubBuild
+	}{
+		{
+			"moved",
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 2},
+			0,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Graph: "g", Version: 2},
+			nil,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Graph: "g", Version: 3},
+		},
+		{
+			"changed since read",
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 3},
+			0,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Version: 2},
+			store.ErrConflict,
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 3},
+		},
+		{
+			"changed while updated",
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 2},
+			1,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Version: 2},
+			store.ErrConflict,
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 3},
+		},
+		{
+			"not read",
+			types.SubBuild{ID: "1", Status: types.BuildStatusRunning, Version: 2},
+			2,
+			types.SubBuild{ID: "1", Status: types.BuildStatusCancel, Reason: "cancelled"},
+			nil,
+			types.SubBuild{ID: "1", Status: types.BuildStatusCancel, Reason: "cancelled", Version: 5},
+		},
+		{
+			"graph of the finished",
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Version: 2},
+			0,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Graph: "g", Version: 2},
+			nil,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Graph: "g", Version: 3},
+		},
+		{
+			"status of the finished",
+			types.SubBuild{ID: "1", Status: types.BuildStatusCancel, Reason: "cancelled", Version: 2},
+			0,
+			types.SubBuild{ID: "1", Status: types.BuildStatusFailed, Graph: "g", Reason: "exit status 1", EndedAt: time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC), Version: 2},
+			ErrTransitionRejected,
+			types.SubBuild{ID: "1", Status: types.BuildStatusCancel, Graph: "g", Reason: "exit status 1", Version: 3},
+		},
+	}
+
+	for _, tt := range tests {
+
+		s := &subBuildStore{sb: tt.cur, others: tt.others}
+
+		err := UpdateSubBuild(s, logrus.NewEntry(logger), "test", "b1", tt.update)
+		if err != tt.err {
+			t.Errorf("%s: expected the error %v, got %v", tt.name, tt.err, err)
+		}
+
+		if s.sb != tt.updated {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.updated, s.sb)
+		}
+	}
+}
@@ -92,8 +92,13 @@ func (r *resolver) SetDefaults(params graphql.ResolveParams) (interface{}, error
 	storage_id, _ := params.Args["storage_id"].(string)
 	container_engine_id, _ := params.Args["container_engine_id"].(string)
 	languages, _ := params.Args["languages"].(string)
-	if storage_id == "" && container_engine_id == "" && languages == "" {
-		return nil, fmt.Errorf("No default value set, please set storage_id or container_engine_id or language.")
+	max_concurrent_builds, limited := params.Args["max_concurrent_builds"].(int)
+	if storage_id == "" && container_engine_id == "" && languages == "" && !limited {
+		return nil, fmt.Errorf("No default value set, please set storage_id or container_engine_id or language or max_concurrent_builds.")
+	}
+
+	if max_concurrent_builds < 0 {
+		return nil, fmt.Errorf("Maximum concurrent builds cannot be negative")
 	}
 
 	if kind == DK_Team {
@@ -152,6 +157,13 @@ func (r *resolver) SetDefaults(params graphql.ResolveParams) (interface{}, error
 		}
 	}
 
+	if limited {
+		def.MaxConcurrentBuilds = max_concurrent_builds
+		if upd {
+			updfields["max_concurrent_builds"] = max_concurrent_builds
+		}
+	}
+
 	if upd {
 		err = r.store.UpdateDefaults(reference_id, updfields)
 	} else {
@@ -1,6 +1,10 @@
 package providers
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
 	"github.com/sirupsen/logrus"
 
 	"net/url"
@@ -18,23 +22,39 @@ import (
 
 // Bitbucket URL ...
 const (
-	BitbucketProviderName   = "bitbucket"
-	BitbucketBaseURLV2      = "https://api.bitbucket.org/2.0"
-	BitbucketProfileURL     = BitbucketBaseURLV2 + "/user"
-	BitbucketGetUserRepoURL = BitbucketBaseURLV2 + "/repositories/:username"
+	BitbucketProviderName = "bitbucket"
+	BitbucketBaseURLV2    = "https://api.bitbucket.org/2.0"
+	BitbucketWebURL       = "https://bitbucket.org"
+
+	// path relative to the api url
+	BitbucketProfilePath     = "/user"
+	BitbucketGetUserRepoPath = "/repositories/:username"
+	BitbucketGetRepoPath     = "/repositories/:owner/:repo"
+	BitbucketCreateHookPath  = "/repositories/:owner/:repo/hooks"
 )
 
+// hook events that bitbucket should invoke eshift.
+var bitbucketHooks = []string{
+	"repo:push",
+	"pullrequest:created",
+	"pullrequest:updated",
+}
+
 // Bitbucket ...
 type Bitbucket struct {
 	CallbackURL string
 	HookURL     string
+	HookSecret  string
+	BaseURL     string
+	WebURL      string
 	Config      *oauth2.Config
+	name        string
 	logger      *logrus.Entry
 }
 
 // BitbucketProvider ...
 // Creates a new Github provider
-func BitbucketProvider(loggr logger.Loggr, clientID, secret, callbackURL, HookURL string) *Bitbucket {
+func BitbucketProvider(loggr logger.Loggr, clientID, secret, callbackURL, HookURL, hookSecret string) *Bitbucket {
 
 	l := loggr.GetLogger("oauth2/bitbucket")
 
@@ -48,14 +68,38 @@ func BitbucketProvider(loggr logger.Loggr, clientID, secret, callbackURL, HookUR
 	return &Bitbucket{
 		callbackURL,
 		HookURL,
+		hookSecret,
+		BitbucketBaseURLV2,
+		BitbucketWebURL,
 		conf,
+		BitbucketProviderName,
 		l,
 	}
 }
 
+// SetHost ..
+// Points the provider to the given bitbucket host, the host must
+// serve the bitbucket cloud (2.0) api and oauth2 endpoints.
+func (b *Bitbucket) SetHost(name, apiURL, webURL string) {
+
+	b.name = name
+	b.BaseURL = apiURL
+	b.WebURL = webURL
+	b.Config.Endpoint = oauth2.Endpoint{
+		AuthURL:  webURL + "/site/oauth2/authorize",
+		TokenURL: webURL + "/site/oauth2/access_token",
+	}
+}
+
 // Name of the provider
 func (b *Bitbucket) Name() string {
-	return BitbucketProviderName
+	return b.name
+}
+
+// Source ..
+// Returns the host serving the repositories
+func (b *Bitbucket) Source() string {
+	return hostOf(b.WebURL)
 }
 
 // Authorize ...
@@ -100,7 +144,7 @@ func (b *Bitbucket) Authorized(id, code string) (types.VCS, error) {
 		}
 	}{}
 
-	r := dispatch.NewGetRequestMaker(BitbucketProfileURL)
+	r := dispatch.NewGetRequestMaker(b.BaseURL + BitbucketProfilePath)
 	r.SetLogger(b.logger)
 
 	r.PathParams()
@@ -137,6 +181,7 @@ func (b *Bitbucket) RefreshToken(token string) (*oauth2.Token, error) {
 	var tok Token
 	err := r.Scan(&tok).Dispatch()
 
+	err = tokenStatus(r, tok, err)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +200,7 @@ func (b *Bitbucket) RefreshToken(token string) (*oauth2.Token, error) {
 // returns the list of repositories
 func (b *Bitbucket) GetRepos(token, accountName string, ownerType string) ([]types.Repository, error) {
 
-	r := dispatch.NewGetRequestMaker(BitbucketGetUserRepoURL)
+	r := dispatch.NewGetRequestMaker(b.BaseURL + BitbucketGetUserRepoPath)
 	r.SetLogger(b.logger)
 
 	r.Header("Accept", "application/json")
@@ -206,9 +251,75 @@ func (b *Bitbucket) GetRepos(token, accountName string, ownerType string) ([]typ
 	return repos, err
 }
 
-func (g *Bitbucket) Search(token, vcsName, repoName string) (types.Repository, error) {
+// Search ..
+// Fetches the repository by its owner and name, the repository
+// not found is reported as empty repository
+func (b *Bitbucket) Search(token, vcsName, repoName string) (types.Repository, error) {
+	return b.getRepository(token, vcsName, repoName)
+}
 
-	repo := types.Repository{}
+// GetRepo ..
+// Fetches the repository by its uuid, the repository
+// not found is reported as empty repository
+func (b *Bitbucket) GetRepo(token, owner, repoID string) (types.Repository, error) {
+	return b.getRepository(token, owner, url.PathEscape(repoID))
+}
+
+func (b *Bitbucket) getRepository(token, owner, name string) (types.Repository, error) {
+
+	r := dispatch.NewGetRequestMaker(b.BaseURL + BitbucketGetRepoPath)
+	r.SetLogger(b.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.PathParams(owner, name)
+	r.QueryParam("access_token", token)
+
+	rp := struct {
+		Name     string
+		UUID     string
+		Language string
+		Links    struct {
+			HTML struct {
+				Href string
+			}
+			Clone []struct {
+				Name string
+				Href string
+			}
+		}
+		MainBranch struct {
+			Name string
+		} `json:"mainbranch"`
+		Description string
+		Private     bool `json:"is_private"`
+	}{}
+
+	err := r.Scan(&rp).Dispatch()
+
+	if r.StatusCode() == http.StatusNotFound {
+		return types.Repository{}, nil
+	}
+
+	err = checkStatus(r, err)
+	if err != nil {
+		return types.Repository{}, err
+	}
+
+	repo := types.Repository{
+		RepoID:        rp.UUID,
+		Name:          rp.Name,
+		Language:      rp.Language,
+		Link:          rp.Links.HTML.Href,
+		Description:   rp.Description,
+		DefaultBranch: rp.MainBranch.Name,
+		Private:       rp.Private,
+	}
+
+	for _, c := range rp.Links.Clone {
+		if c.Name == "https" {
+			repo.CloneURL = c.Href
+		}
+	}
 	return repo, nil
 }
 
@@ -216,10 +327,128 @@ func (g *Bitbucket) Search(token, vcsName, repoName string) (types.Repository, e
 // Create a new hook
 func (b *Bitbucket) CreateHook(token, owner, repo string) error {
 
-	r := dispatch.NewPostRequestMaker(GithubCreateHookURL)
+	r := dispatch.NewPostRequestMaker(b.BaseURL + BitbucketCreateHookPath)
 	r.SetLogger(b.logger)
 
 	r.SetContentType(dispatch.JSON)
+	r.PathParams(owner, repo)
+
+	r.QueryParam("access_token", token)
+
+	body := struct {
+		Description string   `json:"description"`
+		URL         string   `json:"url"`
+		Active      bool     `json:"active"`
+		Secret      string   `json:"secret,omitempty"`
+		Events      []string `json:"events"`
+	}{}
+
+	body.Description = "elasticshift"
+	body.URL = b.HookURL
+	body.Active = true
+	body.Secret = b.HookSecret
+	body.Events = bitbucketHooks
+
+	r.Body(body)
+
+	return r.Dispatch()
+}
+
+// VerifyHook ..
+// Verifies the HMAC signature of the hook payload
+func (b *Bitbucket) VerifyHook(r *http.Request, payload []byte) error {
+	return verifySignature(b.HookSecret, r.Header.Get("X-Hub-Signature"), payload)
+}
+
+// ParseHook ..
+// Maps the push and pull request payloads to hook event
+func (b *Bitbucket) ParseHook(r *http.Request, payload []byte) (HookEvent, error) {
+
+	e := HookEvent{}
+	e.DeliveryID = deliveryID(r.Header.Get("X-Request-UUID"), payload)
+
+	type target struct {
+		Hash    string `json:"hash"`
+		Message string `json:"message"`
+	}
+
+	hook := struct {
+		Push struct {
+			Changes []struct {
+				New *struct {
+					Type   string `json:"type"`
+					Name   string `json:"name"`
+					Target target `json:"target"`
+				} `json:"new"`
+			} `json:"changes"`
+		} `json:"push"`
+		PullRequest struct {
+			Source struct {
+				Branch struct {
+					Name string `json:"name"`
+				} `json:"branch"`
+				Commit target `json:"commit"`
+			} `json:"source"`
+		} `json:"pullrequest"`
+		Repository struct {
+			UUID     string `json:"uuid"`
+			Name     string `json:"name"`
+			FullName string `json:"full_name"`
+			Private  bool   `json:"is_private"`
+			Links    struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		} `json:"repository"`
+		Actor struct {
+			Username string `json:"username"`
+			Nickname string `json:"nickname"`
+		} `json:"actor"`
+	}{}
+
+	err := json.Unmarshal(payload, &hook)
+	if err != nil {
+		return e, fmt.Errorf("Failed to decode bitbucket hook payload: %v", err)
+	}
+
+	switch r.Header.Get("X-Event-Key") {
+	case "repo:push":
+
+		// the last change holds the head of the push
+		changes := hook.Push.Changes
+		if len(changes) == 0 {
+			return e, nil
+		}
+
+		head := changes[len(changes)-1].New
+		if head == nil || head.Type != "branch" {
+			return e, nil
+		}
+		e.Kind = HookEventPush
+		e.Branch = head.Name
+		e.CommitID = head.Target.Hash
+		e.Message = head.Target.Message
+
+	case "pullrequest:created", "pullrequest:updated":
+
+		e.Kind = HookEventPullRequest
+		e.Branch = hook.PullRequest.Source.Branch.Name
+		e.CommitID = hook.PullRequest.Source.Commit.Hash
+	}
+
+	e.Sender = hook.Actor.Username
+	if e.Sender == "" {
+		e.Sender = hook.Actor.Nickname
+	}
+
+	e.Repository = types.Repository{
+		RepoID:   hook.Repository.UUID,
+		Name:     hook.Repository.Name,
+		Private:  hook.Repository.Private,
+		Link:     hook.Repository.Links.HTML.Href,
+		CloneURL: hook.Repository.Links.HTML.Href + ".git",
+	}
 
-	return nil
+	return e, nil
 }
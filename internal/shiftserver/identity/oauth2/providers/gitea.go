@@ -0,0 +1,447 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/pkg/dispatch"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// Gitea related properties, forgejo serves the same api
+const (
+	GiteaProviderName   = "gitea"
+	ForgejoProviderName = "forgejo"
+	GiteaWebURL         = "https://gitea.com"
+	GiteaBaseURL        = GiteaWebURL + GiteaAPIPath
+	ForgejoWebURL       = "https://codeberg.org"
+
+	// relative to the web url
+	GiteaAPIPath = "/api/v1"
+
+	// path relative to the api url
+	GiteaProfilePath      = "/user"
+	GiteaGetUserRepoPath  = "/user/repos"
+	GiteaGetOrgRepoPath   = "/orgs/:org/repos"
+	GiteaGetRepoPath      = "/repos/:owner/:repo"
+	GiteaGetRepoByIDPath  = "/repositories/:id"
+	GiteaCreateHookPath   = "/repos/:owner/:repo/hooks"
+	GiteaCommitStatusPath = "/repos/:owner/:repo/statuses/:sha"
+)
+
+// hook events that gitea should invoke eshift.
+var giteaHooks = []string{
+	"push",
+	"pull_request",
+}
+
+// Gitea ...
+type Gitea struct {
+	CallbackURL string
+	HookURL     string
+	HookSecret  string
+	BaseURL     string
+	WebURL      string
+	Config      *oauth2.Config
+	name        string
+	logger      *logrus.Entry
+}
+
+type giteaRepository struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Private       bool   `json:"private"`
+	Link          string `json:"html_url"`
+	Description   string `json:"description"`
+	Fork          bool   `json:"fork"`
+	DefaultBranch string `json:"default_branch"`
+	Language      string `json:"language"`
+	CloneURL      string `json:"clone_url"`
+}
+
+func (r giteaRepository) toRepository() types.Repository {
+
+	return types.Repository{
+		RepoID:        strconv.Itoa(r.ID),
+		Name:          r.Name,
+		Private:       r.Private,
+		Link:          r.Link,
+		Description:   r.Description,
+		Fork:          r.Fork,
+		DefaultBranch: r.DefaultBranch,
+		Language:      r.Language,
+		CloneURL:      r.CloneURL,
+	}
+}
+
+// GiteaProvider ...
+// Creates a new Gitea provider
+func GiteaProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL, hookSecret string) *Gitea {
+
+	l := loggr.GetLogger("oauth2/gitea")
+
+	conf := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: secret,
+		Scopes:       []string{},
+	}
+
+	g := &Gitea{
+		callbackURL,
+		hookURL,
+		hookSecret,
+		GiteaBaseURL,
+		GiteaWebURL,
+		conf,
+		GiteaProviderName,
+		l,
+	}
+	g.SetHost(GiteaProviderName, GiteaBaseURL, GiteaWebURL)
+	return g
+}
+
+// SetHost ..
+// Points the provider to the given gitea or forgejo host,
+// the name identifies the provider configuration.
+func (g *Gitea) SetHost(name, apiURL, webURL string) {
+
+	g.name = name
+	g.BaseURL = apiURL
+	g.WebURL = webURL
+	g.Config.Endpoint = oauth2.Endpoint{
+		AuthURL:  webURL + "/login/oauth/authorize",
+		TokenURL: webURL + "/login/oauth/access_token",
+	}
+}
+
+// Name of the provider
+func (g *Gitea) Name() string {
+	return g.name
+}
+
+// Source ..
+// Returns the host serving the repositories
+func (g *Gitea) Source() string {
+	return hostOf(g.WebURL)
+}
+
+// Authorize ...
+// Provide access to esh app on accessing the gitea user and repos.
+func (g *Gitea) Authorize(baseURL string) string {
+
+	opts := oauth2.SetAuthURLParam("redirect_uri", g.CallbackURL+"?id="+baseURL)
+	return g.Config.AuthCodeURL("state", oauth2.AccessTypeOffline, opts)
+}
+
+// Authorized ...
+// Finishes the authorize
+func (g *Gitea) Authorized(id, code string) (types.VCS, error) {
+
+	u := types.VCS{}
+
+	opts := oauth2.SetAuthURLParam("redirect_uri", g.CallbackURL+"?id="+id)
+	tok, err := g.Config.Exchange(context.Background(), code, opts)
+	if err != nil {
+		return u, fmt.Errorf("Exchange token after authorization failed: %v", err)
+	}
+
+	u.AccessCode = code
+	u.RefreshToken = tok.RefreshToken
+	u.AccessToken = tok.AccessToken
+	if !tok.Expiry.IsZero() { // zero never expires
+		u.TokenExpiry = tok.Expiry
+	}
+
+	u.Kind = g.Name()
+
+	us := struct {
+		ID        int    `json:"id"`
+		Login     string `json:"login"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}{}
+
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GiteaProfilePath)
+	r.SetLogger(g.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "token "+tok.AccessToken)
+
+	err = checkStatus(r, r.Scan(&us).Dispatch())
+	if err != nil {
+		return u, err
+	}
+
+	u.ID = strconv.Itoa(us.ID)
+	u.Name = us.Login
+	u.AvatarURL = us.AvatarURL
+	u.OwnerType = OwnerTypeUser
+	u.Link = g.WebURL + "/" + us.Login
+	return u, nil
+}
+
+// RefreshToken ..
+func (g *Gitea) RefreshToken(token string) (*oauth2.Token, error) {
+
+	r := dispatch.NewPostRequestMaker(g.Config.Endpoint.TokenURL)
+	r.SetLogger(g.logger)
+
+	r.SetContentType(dispatch.URLENCODED)
+	r.Header("Accept", dispatch.JSON)
+
+	params := make(url.Values)
+	params.Set("client_id", g.Config.ClientID)
+	params.Set("client_secret", g.Config.ClientSecret)
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", token)
+
+	r.Body(params)
+
+	var tok Token
+	err := r.Scan(&tok).Dispatch()
+	err = tokenStatus(r, tok, err)
+	if err != nil {
+		return nil, err
+	}
+
+	otok := &oauth2.Token{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenType:    tok.TokenType,
+	}
+
+	if tok.ExpiresIn > 0 {
+		otok.Expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	return otok, nil
+}
+
+// GetRepos ..
+// returns the list of repositories
+func (g *Gitea) GetRepos(token, accountName string, ownerType string) ([]types.Repository, error) {
+
+	var r *dispatch.RequestMaker
+	if OwnerTypeOrg == ownerType {
+		r = dispatch.NewGetRequestMaker(g.BaseURL + GiteaGetOrgRepoPath)
+		r.PathParams(accountName)
+	} else {
+		r = dispatch.NewGetRequestMaker(g.BaseURL + GiteaGetUserRepoPath)
+	}
+	r.SetLogger(g.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "token "+token)
+
+	var result []giteaRepository
+	err := checkStatus(r, r.Scan(&result).Dispatch())
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []types.Repository
+	for _, repo := range result {
+		repos = append(repos, repo.toRepository())
+	}
+	return repos, nil
+}
+
+// Search ..
+// Finds the repository of the account by name
+func (g *Gitea) Search(token, vcsName, repoName string) (types.Repository, error) {
+	return g.getRepository(token, GiteaGetRepoPath, vcsName, repoName)
+}
+
+// GetRepo ..
+// Fetches the repository by its id, the repository
+// not found is reported as empty repository
+func (g *Gitea) GetRepo(token, owner, repoID string) (types.Repository, error) {
+	return g.getRepository(token, GiteaGetRepoByIDPath, repoID)
+}
+
+func (g *Gitea) getRepository(token, path string, params ...string) (types.Repository, error) {
+
+	r := dispatch.NewGetRequestMaker(g.BaseURL + path)
+	r.SetLogger(g.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "token "+token)
+	r.PathParams(params...)
+
+	var result giteaRepository
+	err := r.Scan(&result).Dispatch()
+
+	// not found is reported as empty repository
+	if r.StatusCode() == http.StatusNotFound {
+		return types.Repository{}, nil
+	}
+
+	err = checkStatus(r, err)
+	if err != nil {
+		return types.Repository{}, err
+	}
+	return result.toRepository(), nil
+}
+
+// CreateHook ..
+// Create a new hook
+func (g *Gitea) CreateHook(token, owner, repo string) error {
+
+	r := dispatch.NewPostRequestMaker(g.BaseURL + GiteaCreateHookPath)
+	r.SetLogger(g.logger)
+
+	r.SetContentType(dispatch.JSON)
+	r.Header("Authorization", "token "+token)
+	r.PathParams(owner, repo)
+
+	body := struct {
+		Type   string   `json:"type"`
+		Active bool     `json:"active"`
+		Events []string `json:"events"`
+		Config struct {
+			URL         string `json:"url"`
+			ContentType string `json:"content_type"`
+			Secret      string `json:"secret,omitempty"`
+		} `json:"config"`
+	}{}
+
+	body.Type = "gitea"
+	body.Active = true
+	body.Events = giteaHooks
+	body.Config.URL = g.HookURL
+	body.Config.ContentType = "json"
+	body.Config.Secret = g.HookSecret
+
+	r.Body(body)
+
+	return checkStatus(r, r.Dispatch())
+}
+
+// VerifyHook ..
+// Verifies the HMAC (sha256) signature of the hook payload
+func (g *Gitea) VerifyHook(r *http.Request, payload []byte) error {
+
+	signature := r.Header.Get("X-Gitea-Signature")
+	if signature == "" {
+		signature = r.Header.Get("X-Forgejo-Signature")
+	}
+
+	if signature != "" {
+		signature = "sha256=" + signature
+	}
+	return verifySignature(g.HookSecret, signature, payload)
+}
+
+// ParseHook ..
+// Maps the push and pull request payloads to hook event
+func (g *Gitea) ParseHook(r *http.Request, payload []byte) (HookEvent, error) {
+
+	e := HookEvent{}
+
+	delivery := r.Header.Get("X-Gitea-Delivery")
+	if delivery == "" {
+		delivery = r.Header.Get("X-Forgejo-Delivery")
+	}
+	e.DeliveryID = deliveryID(delivery, payload)
+
+	hook := struct {
+		Ref         string          `json:"ref"`
+		After       string          `json:"after"`
+		Action      string          `json:"action"`
+		Repository  giteaRepository `json:"repository"`
+		PullRequest struct {
+			Head struct {
+				Ref string `json:"ref"`
+				Sha string `json:"sha"`
+			} `json:"head"`
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+		} `json:"pull_request"`
+		HeadCommit struct {
+			Message string `json:"message"`
+		} `json:"head_commit"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}{}
+
+	err := json.Unmarshal(payload, &hook)
+	if err != nil {
+		return e, fmt.Errorf("Failed to decode gitea hook payload: %v", err)
+	}
+
+	event := r.Header.Get("X-Gitea-Event")
+	if event == "" {
+		event = r.Header.Get("X-Forgejo-Event")
+	}
+
+	switch event {
+	case "push":
+
+		e.Branch = branchName(hook.Ref)
+		if e.Branch == "" || hook.After == emptyCommitID {
+			return e, nil
+		}
+		e.Kind = HookEventPush
+		e.CommitID = hook.After
+		e.Message = hook.HeadCommit.Message
+
+	case "pull_request":
+
+		if hook.Action != "opened" && hook.Action != "synchronized" && hook.Action != "reopened" {
+			return e, nil
+		}
+		e.Kind = HookEventPullRequest
+		e.Branch = hook.PullRequest.Head.Ref
+		e.CommitID = hook.PullRequest.Head.Sha
+		for _, l := range hook.PullRequest.Labels {
+			e.Labels = append(e.Labels, l.Name)
+		}
+	}
+
+	e.Sender = hook.Sender.Login
+	e.Repository = hook.Repository.toRepository()
+
+	return e, nil
+}
+
+// SetCommitStatus ..
+// Creates a commit status through gitea statuses api
+func (g *Gitea) SetCommitStatus(token, owner, repo, commitID string, status CommitStatus) error {
+
+	r := dispatch.NewPostRequestMaker(g.BaseURL + GiteaCommitStatusPath)
+	r.SetLogger(g.logger)
+
+	r.SetContentType(dispatch.JSON)
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "token "+token)
+	r.PathParams(owner, repo, commitID)
+
+	body := struct {
+		State       string `json:"state"`
+		TargetURL   string `json:"target_url,omitempty"`
+		Description string `json:"description,omitempty"`
+		Context     string `json:"context"`
+	}{}
+
+	// gitea states are same as the commit states
+	body.State = status.State
+	body.TargetURL = status.TargetURL
+	body.Description = status.Description
+	body.Context = status.Context
+
+	r.Body(body)
+
+	return checkStatus(r, r.Dispatch())
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+)
+
+const giteaRepo = `{
+  "id": 42,
+  "name": "tools",
+  "private": true,
+  "html_url": "%[1]s/infra/tools",
+  "clone_url": "%[1]s/infra/tools.git",
+  "default_branch": "main",
+  "language": "Go"
+}`
+
+// giteaServer emulates the gitea api & oauth2 endpoints
+func giteaServer(t *testing.T) (*httptest.Server, map[string][]byte) {
+
+	received := make(map[string][]byte)
+
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "token t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+
+		r.ParseForm()
+
+		// client credentials are sent either as basic auth or form values
+		id, _, ok := r.BasicAuth()
+		if !ok {
+			id = r.PostForm.Get("client_id")
+		}
+
+		if id != "client" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			if r.PostForm.Get("code") != "c0d3" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "r3fr3sh" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"t0k3n","token_type":"bearer","refresh_token":"r3fr3sh","expires_in":3600}`)
+	})
+
+	mux.HandleFunc("/api/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			fmt.Fprint(w, `{"id":7,"login":"octo","email":"octo@example.com","avatar_url":"http://avatar"}`)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			fmt.Fprintf(w, "["+giteaRepo+"]", srv.URL)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/repos/infra/tools", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			fmt.Fprintf(w, giteaRepo, srv.URL)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/repositories/42", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			fmt.Fprintf(w, giteaRepo, srv.URL)
+		}
+	})
+
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"not found"}`)
+	}
+	mux.HandleFunc("/api/v1/repos/infra/unknown", notFound)
+	mux.HandleFunc("/api/v1/repositories/43", notFound)
+
+	record := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+
+		if authorized(w, r) {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(r.Body)
+			received[r.URL.Path] = buf.Bytes()
+			w.WriteHeader(http.StatusCreated)
+		}
+	}
+	mux.HandleFunc("/api/v1/repos/infra/tools/hooks", record)
+	mux.HandleFunc("/api/v1/repos/infra/tools/statuses/abc123", record)
+
+	srv = httptest.NewServer(mux)
+	return srv, received
+}
+
+func newGitea(t *testing.T, srv *httptest.Server) *Gitea {
+
+	loggr, err := logger.New("info", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := GiteaProvider(loggr, "client", "secret", "http://shift.local/api/gitea/callback", "http://shift.local/api/hook/gitea", "s3cr3t")
+	g.SetHost("gitea-internal", srv.URL+GiteaAPIPath, srv.URL)
+	return g
+}
+
+func TestGiteaAuthorize(t *testing.T) {
+
+	srv, _ := giteaServer(t)
+	defer srv.Close()
+
+	g := newGitea(t, srv)
+
+	if u := g.Authorize("team"); !strings.HasPrefix(u, srv.URL+"/login/oauth/authorize?") {
+		t.Fatalf("unexpected authorize url %s", u)
+	}
+
+	u, err := g.Authorized("team", "c0d3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.AccessToken != "t0k3n" || u.RefreshToken != "r3fr3sh" || u.TokenExpiry.IsZero() {
+		t.Fatalf("unexpected token %+v", u)
+	}
+
+	if u.ID != "7" || u.Name != "octo" || u.Kind != "gitea-internal" || u.Link != srv.URL+"/octo" {
+		t.Fatalf("unexpected account %+v", u)
+	}
+
+	if g.Source() != "127.0.0.1" {
+		t.Fatalf("unexpected source %s", g.Source())
+	}
+}
+
+func TestGiteaRefreshToken(t *testing.T) {
+
+	srv, _ := giteaServer(t)
+	defer srv.Close()
+
+	tok, err := newGitea(t, srv).RefreshToken("r3fr3sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tok.AccessToken != "t0k3n" || tok.Expiry.IsZero() {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+
+	_, err = newGitea(t, srv).RefreshToken("expired")
+	if !Unauthorized(err) {
+		t.Fatalf("expected invalid refresh token to fail, got %v", err)
+	}
+}
+
+func TestGiteaRepositories(t *testing.T) {
+
+	srv, _ := giteaServer(t)
+	defer srv.Close()
+
+	g := newGitea(t, srv)
+
+	repos, err := g.GetRepos("t0k3n", "octo", OwnerTypeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repos) != 1 || repos[0].RepoID != "42" || !repos[0].Private || repos[0].CloneURL != srv.URL+"/infra/tools.git" {
+		t.Fatalf("unexpected repositories %+v", repos)
+	}
+
+	repo, err := g.Search("t0k3n", "infra", "tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.Name != "tools" || repo.DefaultBranch != "main" {
+		t.Fatalf("unexpected repository %+v", repo)
+	}
+
+	repo, err = g.Search("t0k3n", "infra", "unknown")
+	if err != nil || repo.RepoID != "" {
+		t.Fatalf("expected empty repository, got %+v (%v)", repo, err)
+	}
+
+	repo, err = g.GetRepo("t0k3n", "infra", "42")
+	if err != nil || repo.Name != "tools" {
+		t.Fatalf("unexpected repository %+v (%v)", repo, err)
+	}
+
+	repo, err = g.GetRepo("t0k3n", "infra", "43")
+	if err != nil || repo.RepoID != "" {
+		t.Fatalf("expected empty repository, got %+v (%v)", repo, err)
+	}
+
+	_, err = g.GetRepos("expired", "octo", OwnerTypeUser)
+	if !Unauthorized(err) {
+		t.Fatalf("expected unauthorized request to fail, got %v", err)
+	}
+}
+
+func TestGiteaCreateHookAndStatus(t *testing.T) {
+
+	srv, received := giteaServer(t)
+	defer srv.Close()
+
+	g := newGitea(t, srv)
+
+	err := g.CreateHook("t0k3n", "infra", "tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := struct {
+		Type   string
+		Events []string
+		Config map[string]string
+	}{}
+	json.Unmarshal(received["/api/v1/repos/infra/tools/hooks"], &hook)
+
+	if hook.Type != "gitea" || len(hook.Events) != 2 || hook.Config["url"] != g.HookURL || hook.Config["secret"] != "s3cr3t" {
+		t.Fatalf("unexpected hook %+v", hook)
+	}
+
+	err = ReportCommitStatus(g, "t0k3n", "infra", "tools", "abc123", status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := map[string]string{}
+	json.Unmarshal(received["/api/v1/repos/infra/tools/statuses/abc123"], &st)
+
+	if st["state"] != "success" || st["context"] != status.Context {
+		t.Fatalf("unexpected status %v", st)
+	}
+}
+
+func TestGiteaHook(t *testing.T) {
+
+	srv, _ := giteaServer(t)
+	defer srv.Close()
+
+	g := newGitea(t, srv)
+
+	payload := []byte(fmt.Sprintf(`{
+  "action": "synchronized",
+  "pull_request": {"head": {"ref": "feature", "sha": "def456"}},
+  "repository": `+giteaRepo+`,
+  "sender": {"login": "octo"}
+}`, srv.URL))
+
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(payload)
+
+	r := httptest.NewRequest("POST", "/api/hook/gitea-internal", bytes.NewBuffer(payload))
+	r.Header.Set("X-Gitea-Event", "pull_request")
+	r.Header.Set("X-Gitea-Delivery", "d1")
+	r.Header.Set("X-Gitea-Signature", hex.EncodeToString(mac.Sum(nil)))
+
+	if err := g.VerifyHook(r, payload); err != nil {
+		t.Fatalf("expected valid signature: %v", err)
+	}
+
+	e, err := g.ParseHook(r, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Kind != HookEventPullRequest || e.Branch != "feature" || e.CommitID != "def456" || e.DeliveryID != "d1" || e.Repository.RepoID != "42" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	r.Header.Set("X-Gitea-Signature", "00")
+	if err := g.VerifyHook(r, payload); err == nil {
+		t.Fatal("expected signature mismatch")
+	}
+}
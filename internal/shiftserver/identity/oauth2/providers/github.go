@@ -4,8 +4,11 @@ Copyright 2017 The Elasticshift Authors.
 package providers
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -18,13 +21,20 @@ import (
 
 // Github related properties
 const (
-	GithubProviderName        = "github"
-	GithubBaseURL             = "https://api.github.com"
-	GithubProfileURL          = GithubBaseURL + "/user"
-	GithubGetUserRepoURL      = GithubBaseURL + "/users/:user/repos"
-	GithubGetOrgRepoURL       = GithubBaseURL + "/orgs/:org/repos"
-	GithubCreateHookURL       = GithubBaseURL + "/repos/:owner/:repo/hooks"
-	GithubSearchRepositoryURL = GithubBaseURL + "/search/repositories"
+	GithubProviderName = "github"
+	GithubBaseURL      = "https://api.github.com"
+	GithubWebURL       = "https://github.com"
+
+	// relative to the api url of github enterprise
+	GithubEnterpriseAPIPath = "/api/v3"
+
+	// path relative to the api url
+	GithubProfilePath          = "/user"
+	GithubGetUserRepoPath      = "/users/:user/repos"
+	GithubGetOrgRepoPath       = "/orgs/:org/repos"
+	GithubCreateHookPath       = "/repos/:owner/:repo/hooks"
+	GithubSearchRepositoryPath = "/search/repositories"
+	GithubGetRepoPath          = "/repositories/:id"
 )
 
 // hook events that github should invoke eshift.
@@ -45,7 +55,11 @@ var hooks = []string{
 type Github struct {
 	CallbackURL string
 	HookURL     string
+	HookSecret  string
+	BaseURL     string
+	WebURL      string
 	Config      *oauth2.Config
+	name        string
 	logger      *logrus.Entry
 }
 
@@ -59,7 +73,7 @@ type githubUser struct {
 
 // GithubProvider ...
 // Creates a new Github provider
-func GithubProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL string) *Github {
+func GithubProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL, hookSecret string) *Github {
 
 	l := loggr.GetLogger("oauth2/github")
 
@@ -75,14 +89,38 @@ func GithubProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL s
 	return &Github{
 		callbackURL,
 		hookURL,
+		hookSecret,
+		GithubBaseURL,
+		GithubWebURL,
 		conf,
+		GithubProviderName,
 		l,
 	}
 }
 
+// SetHost ..
+// Points the provider to the given github (enterprise) host,
+// the name identifies the provider configuration.
+func (g *Github) SetHost(name, apiURL, webURL string) {
+
+	g.name = name
+	g.BaseURL = apiURL
+	g.WebURL = webURL
+	g.Config.Endpoint = oauth2.Endpoint{
+		AuthURL:  webURL + "/login/oauth/authorize",
+		TokenURL: webURL + "/login/oauth/access_token",
+	}
+}
+
 // Name of the provider
 func (g *Github) Name() string {
-	return GithubProviderName
+	return g.name
+}
+
+// Source ..
+// Returns the host serving the repositories
+func (g *Github) Source() string {
+	return hostOf(g.WebURL)
 }
 
 // Authorize ...
@@ -124,7 +162,7 @@ func (g *Github) Authorized(id, code string) (types.VCS, error) {
 		Type    string
 	}{}
 
-	r := dispatch.NewGetRequestMaker(GithubProfileURL)
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GithubProfilePath)
 	r.SetLogger(g.logger)
 
 	r.Header("Accept", "application/json")
@@ -162,13 +200,24 @@ func (g *Github) RefreshToken(token string) (*oauth2.Token, error) {
 	r.QueryParam("grant_type", "refresh_token")
 	r.QueryParam("refresh_token", token)
 
-	var tok oauth2.Token
+	var tok Token
 	err := r.Scan(&tok).Dispatch()
 
+	err = tokenStatus(r, tok, err)
 	if err != nil {
 		return nil, err
 	}
-	return &tok, nil
+
+	otok := &oauth2.Token{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenType:    tok.TokenType,
+	}
+
+	if tok.ExpiresIn > 0 {
+		otok.Expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	return otok, nil
 }
 
 // GetRepos ..
@@ -177,9 +226,9 @@ func (g *Github) GetRepos(token, accountName string, ownerType string) ([]types.
 
 	var url string
 	if OwnerTypeUser == ownerType {
-		url = GithubGetUserRepoURL
+		url = g.BaseURL + GithubGetUserRepoPath
 	} else if OwnerTypeOrg == ownerType {
-		url = GithubGetUserRepoURL
+		url = g.BaseURL + GithubGetUserRepoPath
 	}
 
 	r := dispatch.NewGetRequestMaker(url)
@@ -201,6 +250,7 @@ func (g *Github) GetRepos(token, accountName string, ownerType string) ([]types.
 		Fork          bool
 		DefaultBranch string `json:"default_branch"`
 		Language      string
+		CloneURL      string `json:"clone_url"`
 	}{}
 
 	err := r.Scan(&result).Dispatch()
@@ -219,6 +269,7 @@ func (g *Github) GetRepos(token, accountName string, ownerType string) ([]types.
 			Description:   repo.Description,
 			DefaultBranch: repo.DefaultBranch,
 			Language:      repo.Language,
+			CloneURL:      repo.CloneURL,
 		}
 
 		if repo.Private {
@@ -237,7 +288,7 @@ func (g *Github) Search(token, vcsName, repoName string) (types.Repository, erro
 
 	//https://api.github.com/search/repositories?q=user:nshahm+dotfiles
 
-	r := dispatch.NewGetRequestMaker(GithubSearchRepositoryURL)
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GithubSearchRepositoryPath)
 	r.SetLogger(g.logger)
 	r.SetContentType(dispatch.JSON)
 
@@ -283,11 +334,59 @@ func (g *Github) Search(token, vcsName, repoName string) (types.Repository, erro
 	return rp, nil
 }
 
+// GetRepo ..
+// Fetches the repository by its id, the repository
+// not found is reported as empty repository
+func (g *Github) GetRepo(token, owner, repoID string) (types.Repository, error) {
+
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GithubGetRepoPath)
+	r.SetLogger(g.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.PathParams(repoID)
+	r.QueryParam("access_token", token)
+
+	rp := struct {
+		RepoID        int    `json:"id"`
+		Name          string `json:"name"`
+		Private       bool   `json:"private"`
+		Link          string `json:"html_url"`
+		Description   string `json:"description"`
+		Fork          bool   `json:"fork"`
+		DefaultBranch string `json:"default_branch"`
+		Language      string `json:"language"`
+		CloneURL      string `json:"clone_url"`
+	}{}
+
+	err := r.Scan(&rp).Dispatch()
+
+	if r.StatusCode() == http.StatusNotFound {
+		return types.Repository{}, nil
+	}
+
+	err = checkStatus(r, err)
+	if err != nil {
+		return types.Repository{}, err
+	}
+
+	return types.Repository{
+		RepoID:        strconv.Itoa(rp.RepoID),
+		Name:          rp.Name,
+		Link:          rp.Link,
+		Description:   rp.Description,
+		DefaultBranch: rp.DefaultBranch,
+		Language:      rp.Language,
+		Private:       rp.Private,
+		Fork:          rp.Fork,
+		CloneURL:      rp.CloneURL,
+	}, nil
+}
+
 // CreateHook ..
 // Create a new hook
 func (g *Github) CreateHook(token, owner, repo string) error {
 
-	r := dispatch.NewPostRequestMaker(GithubCreateHookURL)
+	r := dispatch.NewPostRequestMaker(g.BaseURL + GithubCreateHookPath)
 	r.SetLogger(g.logger)
 
 	r.SetContentType(dispatch.JSON)
@@ -302,6 +401,7 @@ func (g *Github) CreateHook(token, owner, repo string) error {
 		Config struct {
 			URL         string `json:"url"`
 			ContentType string `json:"content_type"`
+			Secret      string `json:"secret,omitempty"`
 		} `json:"config"`
 	}{}
 
@@ -309,10 +409,109 @@ func (g *Github) CreateHook(token, owner, repo string) error {
 	body.Active = true
 	body.Events = hooks
 	body.Config.URL = g.HookURL
-	body.Config.ContentType = "JSON"
+	body.Config.ContentType = "json"
+	body.Config.Secret = g.HookSecret
 
 	r.Body(body)
 
 	err := r.Dispatch()
 	return err
 }
+
+// VerifyHook ..
+// Verifies the HMAC signature of the hook payload
+func (g *Github) VerifyHook(r *http.Request, payload []byte) error {
+
+	signature := r.Header.Get("X-Hub-Signature-256")
+	if signature == "" {
+		signature = r.Header.Get("X-Hub-Signature")
+	}
+	return verifySignature(g.HookSecret, signature, payload)
+}
+
+// ParseHook ..
+// Maps the push and pull request payloads to hook event
+func (g *Github) ParseHook(r *http.Request, payload []byte) (HookEvent, error) {
+
+	e := HookEvent{}
+	e.DeliveryID = deliveryID(r.Header.Get("X-GitHub-Delivery"), payload)
+
+	type repository struct {
+		ID            int    `json:"id"`
+		Name          string `json:"name"`
+		Private       bool   `json:"private"`
+		Link          string `json:"html_url"`
+		Description   string `json:"description"`
+		Fork          bool   `json:"fork"`
+		DefaultBranch string `json:"default_branch"`
+		Language      string `json:"language"`
+		CloneURL      string `json:"clone_url"`
+	}
+
+	hook := struct {
+		Ref         string     `json:"ref"`
+		After       string     `json:"after"`
+		Deleted     bool       `json:"deleted"`
+		Action      string     `json:"action"`
+		Repository  repository `json:"repository"`
+		PullRequest struct {
+			Head struct {
+				Ref string `json:"ref"`
+				Sha string `json:"sha"`
+			} `json:"head"`
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+		} `json:"pull_request"`
+		HeadCommit struct {
+			Message string `json:"message"`
+		} `json:"head_commit"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}{}
+
+	err := json.Unmarshal(payload, &hook)
+	if err != nil {
+		return e, fmt.Errorf("Failed to decode github hook payload: %v", err)
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "push":
+
+		e.Branch = branchName(hook.Ref)
+		if e.Branch == "" || hook.Deleted || hook.After == emptyCommitID {
+			return e, nil
+		}
+		e.Kind = HookEventPush
+		e.CommitID = hook.After
+		e.Message = hook.HeadCommit.Message
+
+	case "pull_request":
+
+		if hook.Action != "opened" && hook.Action != "synchronize" && hook.Action != "reopened" {
+			return e, nil
+		}
+		e.Kind = HookEventPullRequest
+		e.Branch = hook.PullRequest.Head.Ref
+		e.CommitID = hook.PullRequest.Head.Sha
+		for _, l := range hook.PullRequest.Labels {
+			e.Labels = append(e.Labels, l.Name)
+		}
+	}
+
+	e.Sender = hook.Sender.Login
+	e.Repository = types.Repository{
+		RepoID:        strconv.Itoa(hook.Repository.ID),
+		Name:          hook.Repository.Name,
+		Private:       hook.Repository.Private,
+		Link:          hook.Repository.Link,
+		Description:   hook.Repository.Description,
+		Fork:          hook.Repository.Fork,
+		DefaultBranch: hook.Repository.DefaultBranch,
+		Language:      hook.Repository.Language,
+		CloneURL:      hook.Repository.CloneURL,
+	}
+
+	return e, nil
+}
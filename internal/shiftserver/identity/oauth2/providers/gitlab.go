@@ -4,7 +4,9 @@ Copyright 2017 The Elasticshift Authors.
 package providers
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/sirupsen/logrus"
 
@@ -24,25 +26,37 @@ import (
 // Gitlab URL ...
 const (
 	GitlabProviderName = "gitlab"
-	GitlabAuthURL      = "https://gitlab.com/oauth/authorize"
-	GitlabTokenURL     = "https://gitlab.com/oauth/token"
+	GitlabWebURL       = "https://gitlab.com"
+	GitlabAuthURL      = GitlabWebURL + "/oauth/authorize"
+	GitlabTokenURL     = GitlabWebURL + "/oauth/token"
 
-	GitlabBaseURLV3      = "https://gitlab.com/api/v3"
-	GitlabProfileURL     = GitlabBaseURLV3 + "/user"
-	GitlabGetUserRepoURL = GitlabBaseURLV3 + "/projects"
+	GitlabBaseURLV4 = GitlabWebURL + GitlabAPIPathV4
+
+	// relative to the web url of self-managed gitlab
+	GitlabAPIPathV4 = "/api/v4"
+
+	// path relative to the api url
+	GitlabProfilePath     = "/user"
+	GitlabGetUserRepoPath = "/projects"
+	GitlabGetRepoPath     = "/projects/:id"
+	GitlabCreateHookPath  = "/projects/:id/hooks"
 )
 
 // Gitlab ...
 type Gitlab struct {
 	CallbackURL string
 	HookURL     string
+	HookSecret  string
+	BaseURL     string
+	WebURL      string
 	Config      *oauth2.Config
+	name        string
 	logger      *logrus.Entry
 }
 
 // GitlabProvider ...
 // Creates a new Gitlab provider
-func GitlabProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL string) *Gitlab {
+func GitlabProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL, hookSecret string) *Gitlab {
 
 	l := loggr.GetLogger("oauth2/gitlab")
 
@@ -59,14 +73,38 @@ func GitlabProvider(loggr logger.Loggr, clientID, secret, callbackURL, hookURL s
 	return &Gitlab{
 		callbackURL,
 		hookURL,
+		hookSecret,
+		GitlabBaseURLV4,
+		GitlabWebURL,
 		conf,
+		GitlabProviderName,
 		l,
 	}
 }
 
+// SetHost ..
+// Points the provider to the given gitlab (self-managed) host,
+// the name identifies the provider configuration.
+func (g *Gitlab) SetHost(name, apiURL, webURL string) {
+
+	g.name = name
+	g.BaseURL = apiURL
+	g.WebURL = webURL
+	g.Config.Endpoint = oauth2.Endpoint{
+		AuthURL:  webURL + "/oauth/authorize",
+		TokenURL: webURL + "/oauth/token",
+	}
+}
+
 // Name of the provider
 func (g *Gitlab) Name() string {
-	return GitlabProviderName
+	return g.name
+}
+
+// Source ..
+// Returns the host serving the repositories
+func (g *Gitlab) Source() string {
+	return hostOf(g.WebURL)
 }
 
 // Authorize ...
@@ -85,7 +123,7 @@ func (g *Gitlab) Authorized(id, code string) (types.VCS, error) {
 
 	//tok, err := g.Config.Exchange(oauth2.NoContext, code)
 	// Authorize request
-	r := dispatch.NewPostRequestMaker(GitlabTokenURL)
+	r := dispatch.NewPostRequestMaker(g.Config.Endpoint.TokenURL)
 	r.SetLogger(g.logger)
 	r.SetContentType(dispatch.URLENCODED)
 
@@ -125,7 +163,7 @@ func (g *Gitlab) Authorized(id, code string) (types.VCS, error) {
 		Link      string `json:"web_url"`
 	}{}
 
-	r = dispatch.NewGetRequestMaker(GitlabProfileURL)
+	r = dispatch.NewGetRequestMaker(g.BaseURL + GitlabProfilePath)
 	r.SetLogger(g.logger)
 
 	r.PathParams()
@@ -146,7 +184,7 @@ func (g *Gitlab) Authorized(id, code string) (types.VCS, error) {
 // RefreshToken ..
 func (g *Gitlab) RefreshToken(token string) (*oauth2.Token, error) {
 
-	r := dispatch.NewPostRequestMaker(GitlabTokenURL)
+	r := dispatch.NewPostRequestMaker(g.Config.Endpoint.TokenURL)
 	r.SetLogger(g.logger)
 
 	r.SetBasicAuth(g.Config.ClientID, g.Config.ClientSecret)
@@ -164,6 +202,7 @@ func (g *Gitlab) RefreshToken(token string) (*oauth2.Token, error) {
 	var tok Token
 	err := r.Scan(&tok).Dispatch()
 
+	err = tokenStatus(r, tok, err)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +227,7 @@ func (g *Gitlab) RefreshToken(token string) (*oauth2.Token, error) {
 // returns the list of repositories
 func (g *Gitlab) GetRepos(token, accountName string, ownerType string) ([]types.Repository, error) {
 
-	r := dispatch.NewGetRequestMaker(GitlabGetUserRepoURL)
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GitlabGetUserRepoPath)
 	r.SetLogger(g.logger)
 
 	r.Header("Accept", "application/json")
@@ -197,15 +236,17 @@ func (g *Gitlab) GetRepos(token, accountName string, ownerType string) ([]types.
 	//r.PathParams(accountName)
 
 	r.QueryParam("access_token", token)
+	r.QueryParam("membership", "true")
 
 	rp := []struct {
 		ID            int    `json:"id,omitempty"`
 		Name          string `json:"name,omitempty"`
 		Description   string `json:"description,omitempty"`
 		DefaultBranch string `json:"default_branch,omitempty"`
-		Public        bool   `json:"public,omitempty"`
+		Visibility    string `json:"visibility,omitempty"`
 		WebURL        string `json:"web_url"`
 		AvatarURL     string `json:"avatar_url"`
+		CloneURL      string `json:"http_url_to_repo"`
 	}{}
 	err := r.Scan(&rp).Dispatch()
 
@@ -218,9 +259,8 @@ func (g *Gitlab) GetRepos(token, accountName string, ownerType string) ([]types.
 			Link:          rpo.WebURL,
 			Description:   rpo.Description,
 			DefaultBranch: rpo.DefaultBranch,
-		}
-		if rpo.Public {
-			repo.Private = false
+			CloneURL:      rpo.CloneURL,
+			Private:       rpo.Visibility == "private",
 		}
 
 		repos = append(repos, *repo)
@@ -228,20 +268,183 @@ func (g *Gitlab) GetRepos(token, accountName string, ownerType string) ([]types.
 	return repos, err
 }
 
+// Search ..
+// Fetches the project by its path, the project not found
+// is reported as empty repository
 func (g *Gitlab) Search(token, vcsName, repoName string) (types.Repository, error) {
+	return g.getProject(token, url.PathEscape(vcsName+"/"+repoName))
+}
 
-	repo := types.Repository{}
-	return repo, nil
+// GetRepo ..
+// Fetches the project by its id, the project not found
+// is reported as empty repository
+func (g *Gitlab) GetRepo(token, owner, repoID string) (types.Repository, error) {
+	return g.getProject(token, repoID)
+}
+
+func (g *Gitlab) getProject(token, id string) (types.Repository, error) {
+
+	r := dispatch.NewGetRequestMaker(g.BaseURL + GitlabGetRepoPath)
+	r.SetLogger(g.logger)
+
+	r.Header("Accept", dispatch.JSON)
+	r.PathParams(id)
+	r.QueryParam("access_token", token)
+
+	rp := struct {
+		ID            int    `json:"id,omitempty"`
+		Name          string `json:"name,omitempty"`
+		Description   string `json:"description,omitempty"`
+		DefaultBranch string `json:"default_branch,omitempty"`
+		Visibility    string `json:"visibility,omitempty"`
+		WebURL        string `json:"web_url"`
+		CloneURL      string `json:"http_url_to_repo"`
+	}{}
+
+	err := r.Scan(&rp).Dispatch()
+
+	if r.StatusCode() == http.StatusNotFound {
+		return types.Repository{}, nil
+	}
+
+	err = checkStatus(r, err)
+	if err != nil {
+		return types.Repository{}, err
+	}
+
+	return types.Repository{
+		RepoID:        strconv.Itoa(rp.ID),
+		Name:          rp.Name,
+		Link:          rp.WebURL,
+		Description:   rp.Description,
+		DefaultBranch: rp.DefaultBranch,
+		CloneURL:      rp.CloneURL,
+		Private:       rp.Visibility == "private",
+	}, nil
 }
 
 // CreateHook ..
 // Create a new hook
 func (g *Gitlab) CreateHook(token, owner, repo string) error {
 
-	r := dispatch.NewPostRequestMaker(GithubCreateHookURL)
+	r := dispatch.NewPostRequestMaker(g.BaseURL + GitlabCreateHookPath)
 	r.SetLogger(g.logger)
 
 	r.SetContentType(dispatch.JSON)
+	r.PathParams(url.PathEscape(owner + "/" + repo))
+
+	r.QueryParam("access_token", token)
+
+	body := struct {
+		URL                 string `json:"url"`
+		Token               string `json:"token,omitempty"`
+		PushEvents          bool   `json:"push_events"`
+		MergeRequestsEvents bool   `json:"merge_requests_events"`
+	}{}
+
+	body.URL = g.HookURL
+	body.Token = g.HookSecret
+	body.PushEvents = true
+	body.MergeRequestsEvents = true
+
+	r.Body(body)
+
+	return r.Dispatch()
+}
+
+// VerifyHook ..
+// Verifies the secret token sent along with the hook
+func (g *Gitlab) VerifyHook(r *http.Request, payload []byte) error {
+	return verifyToken(g.HookSecret, r.Header.Get("X-Gitlab-Token"))
+}
+
+// ParseHook ..
+// Maps the push and merge request payloads to hook event
+func (g *Gitlab) ParseHook(r *http.Request, payload []byte) (HookEvent, error) {
+
+	e := HookEvent{}
+	e.DeliveryID = deliveryID(r.Header.Get("X-Gitlab-Event-UUID"), payload)
+
+	hook := struct {
+		ObjectKind  string `json:"object_kind"`
+		Ref         string `json:"ref"`
+		CheckoutSha string `json:"checkout_sha"`
+		UserName    string `json:"user_username"`
+		User        struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		Project struct {
+			ID            int    `json:"id"`
+			Name          string `json:"name"`
+			Description   string `json:"description"`
+			WebURL        string `json:"web_url"`
+			HTTPURL       string `json:"git_http_url"`
+			DefaultBranch string `json:"default_branch"`
+			Visibility    int    `json:"visibility_level"`
+		} `json:"project"`
+		Commits []struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		} `json:"commits"`
+		Labels []struct {
+			Title string `json:"title"`
+		} `json:"labels"`
+		ObjectAttributes struct {
+			Action       string `json:"action"`
+			SourceBranch string `json:"source_branch"`
+			LastCommit   struct {
+				ID      string `json:"id"`
+				Message string `json:"message"`
+			} `json:"last_commit"`
+		} `json:"object_attributes"`
+	}{}
+
+	err := json.Unmarshal(payload, &hook)
+	if err != nil {
+		return e, fmt.Errorf("Failed to decode gitlab hook payload: %v", err)
+	}
+
+	switch hook.ObjectKind {
+	case "push":
+
+		e.Branch = branchName(hook.Ref)
+		if e.Branch == "" || hook.CheckoutSha == "" {
+			return e, nil
+		}
+		e.Kind = HookEventPush
+		e.CommitID = hook.CheckoutSha
+		e.Sender = hook.UserName
+		for _, c := range hook.Commits {
+			if c.ID == hook.CheckoutSha {
+				e.Message = c.Message
+			}
+		}
+
+	case "merge_request":
+
+		action := hook.ObjectAttributes.Action
+		if action != "open" && action != "update" && action != "reopen" {
+			return e, nil
+		}
+		e.Kind = HookEventPullRequest
+		e.Branch = hook.ObjectAttributes.SourceBranch
+		e.CommitID = hook.ObjectAttributes.LastCommit.ID
+		e.Message = hook.ObjectAttributes.LastCommit.Message
+		e.Sender = hook.User.Username
+		for _, l := range hook.Labels {
+			e.Labels = append(e.Labels, l.Title)
+		}
+	}
+
+	e.Repository = types.Repository{
+		RepoID:        strconv.Itoa(hook.Project.ID),
+		Name:          hook.Project.Name,
+		Description:   hook.Project.Description,
+		Link:          hook.Project.WebURL,
+		CloneURL:      hook.Project.HTTPURL,
+		DefaultBranch: hook.Project.DefaultBranch,
+		Private:       hook.Project.Visibility == 0,
+	}
 
-	return nil
+	return e, nil
 }
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+)
+
+const (
+	refHeadsPrefix = "refs/heads/"
+	emptyCommitID  = "0000000000000000000000000000000000000000"
+)
+
+var (
+	errHookSecretNotSet      = errors.New("Hook secret is not configured for the provider")
+	errHookSignatureMissing  = errors.New("Hook request is not signed")
+	errHookSignatureMismatch = errors.New("Hook signature doesn't match")
+	errHookSignatureUnknown  = errors.New("Unknown hook signature algorithm")
+)
+
+// verifySignature ..
+// Verifies the HMAC signature sent in the form of <algorithm>=<hex digest>
+func verifySignature(secret, signature string, payload []byte) error {
+
+	if secret == "" {
+		return errHookSecretNotSet
+	}
+
+	if signature == "" {
+		return errHookSignatureMissing
+	}
+
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return errHookSignatureUnknown
+	}
+
+	var h func() hash.Hash
+	switch parts[0] {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	default:
+		return errHookSignatureUnknown
+	}
+
+	actual, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return errHookSignatureMismatch
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(payload)
+
+	if !hmac.Equal(mac.Sum(nil), actual) {
+		return errHookSignatureMismatch
+	}
+	return nil
+}
+
+// verifyToken ..
+// Verifies the plain shared token sent along with the hook
+func verifyToken(secret, token string) error {
+
+	if secret == "" {
+		return errHookSecretNotSet
+	}
+
+	if token == "" {
+		return errHookSignatureMissing
+	}
+
+	if !hmac.Equal([]byte(secret), []byte(token)) {
+		return errHookSignatureMismatch
+	}
+	return nil
+}
+
+// deliveryID ..
+// Returns the delivery id sent by the provider, when the provider
+// doesn't send one the digest of the payload is used instead.
+func deliveryID(id string, payload []byte) string {
+
+	if id != "" {
+		return id
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// branchName ..
+// Returns the branch name out of git reference, empty if the
+// reference doesn't point to a branch (tags etc)
+func branchName(ref string) string {
+
+	if !strings.HasPrefix(ref, refHeadsPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, refHeadsPrefix)
+}
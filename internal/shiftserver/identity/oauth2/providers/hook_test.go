@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
+)
+
+var pushPayload = []byte(`{
+  "ref": "refs/heads/master",
+  "after": "3f2b9e1c0a8d7f6e5d4c3b2a1f0e9d8c7b6a5f4e",
+  "repository": {
+    "id": 1296269,
+    "name": "hello-world",
+    "clone_url": "https://github.com/octocat/hello-world.git",
+    "default_branch": "master"
+  },
+  "head_commit": {
+    "message": "Update README [skip ci]"
+  },
+  "sender": {
+    "login": "octocat"
+  }
+}`)
+
+var pullRequestPayload = []byte(`{
+  "action": "synchronize",
+  "pull_request": {
+    "head": {
+      "ref": "feature",
+      "sha": "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+    },
+    "labels": [
+      { "name": "ci rebuild-cache" },
+      { "name": "enhancement" }
+    ]
+  },
+  "repository": {
+    "id": 1296269,
+    "name": "hello-world"
+  },
+  "sender": {
+    "login": "octocat"
+  }
+}`)
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newGithub(t *testing.T, secret string) *providers.Github {
+
+	loggr, err := logger.New("info", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return providers.GithubProvider(loggr, "key", "secret", "", "", secret)
+}
+
+func TestGithubVerifyHook(t *testing.T) {
+
+	g := newGithub(t, "s3cr3t")
+
+	r := httptest.NewRequest("POST", "/api/hook/github", bytes.NewBuffer(pushPayload))
+	r.Header.Set("X-Hub-Signature-256", sign("s3cr3t", pushPayload))
+	if err := g.VerifyHook(r, pushPayload); err != nil {
+		t.Fatalf("expected valid signature: %v", err)
+	}
+
+	r.Header.Set("X-Hub-Signature-256", sign("other", pushPayload))
+	if err := g.VerifyHook(r, pushPayload); err == nil {
+		t.Fatal("expected signature mismatch")
+	}
+
+	r.Header.Del("X-Hub-Signature-256")
+	if err := g.VerifyHook(r, pushPayload); err == nil {
+		t.Fatal("expected unsigned request to be rejected")
+	}
+
+	g = newGithub(t, "")
+	r.Header.Set("X-Hub-Signature-256", sign("", pushPayload))
+	if err := g.VerifyHook(r, pushPayload); err == nil {
+		t.Fatal("expected failure when hook secret is not configured")
+	}
+}
+
+func TestGithubParsePushHook(t *testing.T) {
+
+	g := newGithub(t, "s3cr3t")
+
+	r := httptest.NewRequest("POST", "/api/hook/github", bytes.NewBuffer(pushPayload))
+	r.Header.Set("X-GitHub-Event", "push")
+	r.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+
+	e, err := g.ParseHook(r, pushPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Kind != providers.HookEventPush {
+		t.Fatalf("expected push event, got '%s'", e.Kind)
+	}
+
+	if e.Branch != "master" || e.CommitID != "3f2b9e1c0a8d7f6e5d4c3b2a1f0e9d8c7b6a5f4e" {
+		t.Fatalf("unexpected branch/commit: %s/%s", e.Branch, e.CommitID)
+	}
+
+	if e.Repository.RepoID != "1296269" || e.DeliveryID != "72d3162e-cc78-11e3-81ab-4c9367dc0958" || e.Sender != "octocat" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	if e.Message != "Update README [skip ci]" {
+		t.Fatalf("unexpected commit message '%s'", e.Message)
+	}
+
+	r.Header.Set("X-GitHub-Event", "fork")
+	e, err = g.ParseHook(r, pushPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Kind != "" {
+		t.Fatalf("expected fork event to be ignored, got '%s'", e.Kind)
+	}
+}
+
+func TestGithubParsePullRequestHook(t *testing.T) {
+
+	g := newGithub(t, "s3cr3t")
+
+	r := httptest.NewRequest("POST", "/api/hook/github", bytes.NewBuffer(pullRequestPayload))
+	r.Header.Set("X-GitHub-Event", "pull_request")
+
+	e, err := g.ParseHook(r, pullRequestPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Kind != providers.HookEventPullRequest || e.Branch != "feature" || e.CommitID != "6dcb09b5b57875f334f61aebed695e2e4193db5e" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	if len(e.Labels) != 2 || e.Labels[0] != "ci rebuild-cache" || e.Labels[1] != "enhancement" {
+		t.Fatalf("unexpected labels %v", e.Labels)
+	}
+}
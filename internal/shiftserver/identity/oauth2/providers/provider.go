@@ -5,6 +5,9 @@ package providers
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -36,6 +39,12 @@ const (
 	False = 0
 )
 
+// Hook event kinds
+const (
+	HookEventPush        = "push"
+	HookEventPullRequest = "pull_request"
+)
+
 var (
 	errNoProviderFound = "No provider found for %s : %v"
 )
@@ -58,12 +67,17 @@ type Token struct {
 
 	CreatedAt int64 `json:"created_at"`
 	Scope     string
+
+	// reported by the token endpoint, instead of the token
+	Error string `json:"error"`
 }
 
 // Provider ..
 type Provider interface {
 	Name() string
 
+	Source() string
+
 	Authorize(baseURL string) string
 
 	Authorized(id, code string) (types.VCS, error)
@@ -74,39 +88,135 @@ type Provider interface {
 
 	Search(token, vcsName, repoName string) (types.Repository, error)
 
+	GetRepo(token, owner, repoID string) (types.Repository, error)
+
 	CreateHook(token, owner, repo string) error
+
+	VerifyHook(r *http.Request, payload []byte) error
+
+	ParseHook(r *http.Request, payload []byte) (HookEvent, error)
+
+	SetCommitStatus(token, owner, repo, commitID string, status CommitStatus) error
+}
+
+// HookEvent ..
+// Provider neutral representation of a webhook delivery
+type HookEvent struct {
+	DeliveryID string
+	Kind       string
+	Repository types.Repository
+	Branch     string
+	CommitID   string
+	Sender     string
+
+	// head commit message and the pull request labels, carry the build directives
+	Message string
+	Labels  []string
 }
 
 // Providers type
 type Providers struct {
-	logger *logrus.Entry
-	loggr  logger.Loggr
-	store  store.Sysconf
+	logger    *logrus.Entry
+	loggr     logger.Loggr
+	store     store.Sysconf
+	teamStore store.Team
 }
 
 func New(loggr logger.Loggr, s store.Shift) Providers {
-	return Providers{loggr: loggr, logger: loggr.GetLogger("oauth2/providers"), store: s.Sysconf}
+	return Providers{loggr: loggr, logger: loggr.GetLogger("oauth2/providers"), store: s.Sysconf, teamStore: s.Team}
 }
 
-// Get the provider by namee
-func (p Providers) Get(name string) (Provider, error) {
+// Conf ..
+// Returns the provider configuration by name, the api and web url
+// default to the public hosts of the provider when not configured.
+func (p Providers) Conf(name string) (types.VCSSysConf, error) {
 
 	var conf types.VCSSysConf
 	err := p.store.GetSysConf(sysconf.VcsKind, name, &conf)
 	if err != nil {
-		return nil, fmt.Errorf(errNoProviderFound, name, err)
+		return conf, fmt.Errorf(errNoProviderFound, name, err)
+	}
+
+	if conf.Provider == "" {
+		conf.Provider = conf.Name
+	}
+
+	conf.APIURL = strings.TrimSuffix(conf.APIURL, "/")
+	conf.WebURL = strings.TrimSuffix(conf.WebURL, "/")
+
+	switch conf.Provider {
+	case GithubProviderName:
+		conf.WebURL, conf.APIURL = hostURLs(conf, GithubWebURL, GithubBaseURL, GithubEnterpriseAPIPath)
+	case GitlabProviderName:
+		conf.WebURL, conf.APIURL = hostURLs(conf, GitlabWebURL, GitlabBaseURLV4, GitlabAPIPathV4)
+	case GiteaProviderName:
+		conf.WebURL, conf.APIURL = hostURLs(conf, GiteaWebURL, GiteaBaseURL, GiteaAPIPath)
+	case ForgejoProviderName:
+		conf.WebURL, conf.APIURL = hostURLs(conf, ForgejoWebURL, ForgejoWebURL+GiteaAPIPath, GiteaAPIPath)
+	case BitbucketProviderName:
+		conf.WebURL, conf.APIURL = hostURLs(conf, BitbucketWebURL, BitbucketBaseURLV2, "")
+		if conf.APIURL == "" {
+			return conf, fmt.Errorf("API url must be configured for self-hosted bitbucket %s", name)
+		}
 	}
+	return conf, nil
+}
+
+// Get the provider by namee
+func (p Providers) Get(name string) (Provider, error) {
 
-	fmt.Println("Providers.Get(): ", conf.Name)
+	conf, err := p.Conf(name)
+	if err != nil {
+		return nil, err
+	}
 
-	switch conf.Name {
+	switch conf.Provider {
 	case GithubProviderName:
-		return GithubProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL), nil
+		g := GithubProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL, conf.HookSecret)
+		g.SetHost(conf.Name, conf.APIURL, conf.WebURL)
+		return g, nil
 	case GitlabProviderName:
-		return GitlabProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL), nil
+		g := GitlabProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL, conf.HookSecret)
+		g.SetHost(conf.Name, conf.APIURL, conf.WebURL)
+		return g, nil
 	case BitbucketProviderName:
-		return BitbucketProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL), nil
+		b := BitbucketProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL, conf.HookSecret)
+		b.SetHost(conf.Name, conf.APIURL, conf.WebURL)
+		return b, nil
+	case GiteaProviderName, ForgejoProviderName:
+		g := GiteaProvider(p.loggr, conf.Key, conf.Secret, conf.CallbackURL, conf.HookURL, conf.HookSecret)
+		g.SetHost(conf.Name, conf.APIURL, conf.WebURL)
+		return g, nil
 	}
 
-	return nil, fmt.Errorf("No provider found for ", name)
+	return nil, fmt.Errorf("No provider found for %s", name)
+}
+
+// hostURLs ..
+// Returns the web and api url of the provider host, the api url of
+// self-hosted systems is derived from the web url when not configured.
+func hostURLs(conf types.VCSSysConf, webURL, apiURL, apiPath string) (string, string) {
+
+	if conf.WebURL == "" || conf.WebURL == webURL {
+		if conf.APIURL == "" {
+			conf.APIURL = apiURL
+		}
+		return webURL, conf.APIURL
+	}
+
+	if conf.APIURL == "" && apiPath != "" {
+		conf.APIURL = conf.WebURL + apiPath
+	}
+	return conf.WebURL, conf.APIURL
+}
+
+// hostOf ..
+// Returns the host name of the url, used as source of the repositories
+func hostOf(uri string) string {
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
 }
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+func TestHostURLs(t *testing.T) {
+
+	tests := []struct {
+		conf           types.VCSSysConf
+		web, api       string
+		defWeb, defAPI string
+		apiPath        string
+	}{
+		// public host
+		{types.VCSSysConf{}, GithubWebURL, GithubBaseURL, GithubWebURL, GithubBaseURL, GithubEnterpriseAPIPath},
+
+		// github enterprise, api derived from the web url
+		{types.VCSSysConf{WebURL: "https://github.example.com"}, "https://github.example.com", "https://github.example.com/api/v3", GithubWebURL, GithubBaseURL, GithubEnterpriseAPIPath},
+
+		// gitlab self-managed, api configured
+		{types.VCSSysConf{WebURL: "https://git.example.com", APIURL: "https://api.git.example.com/v4"}, "https://git.example.com", "https://api.git.example.com/v4", GitlabWebURL, GitlabBaseURLV4, GitlabAPIPathV4},
+
+		// bitbucket self-hosted requires the api url
+		{types.VCSSysConf{WebURL: "https://bitbucket.example.com"}, "https://bitbucket.example.com", "", BitbucketWebURL, BitbucketBaseURLV2, ""},
+	}
+
+	for _, tt := range tests {
+
+		web, api := hostURLs(tt.conf, tt.defWeb, tt.defAPI, tt.apiPath)
+		if web != tt.web || api != tt.api {
+			t.Errorf("hostURLs(%+v) = %s, %s, expected %s, %s", tt.conf, web, api, tt.web, tt.api)
+		}
+	}
+
+	if source := hostOf("https://github.example.com:8443"); source != "github.example.com" {
+		t.Errorf("unexpected source %s", source)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+)
+
+// searchServer emulates the repository lookup of github, gitlab & bitbucket apis
+func searchServer(t *testing.T) *httptest.Server {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Query().Get("access_token") != "t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.EscapedPath() != "/api/v4/projects/infra%2Ftools" && r.URL.Path != "/api/v4/projects/42" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"404 Project Not Found"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"id":42,"name":"tools","default_branch":"main","visibility":"private","web_url":"https://gitlab.local/infra/tools","http_url_to_repo":"https://gitlab.local/infra/tools.git"}`)
+	})
+
+	mux.HandleFunc("/2.0/repositories/", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Query().Get("access_token") != "t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/2.0/repositories/infra/tools" && r.URL.Path != "/2.0/repositories/infra/{42}" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"type":"error","error":{"message":"Repository infra/unknown not found"}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"uuid":"{42}","name":"tools","is_private":true,"mainbranch":{"name":"main"},"links":{"html":{"href":"https://bitbucket.local/infra/tools"},"clone":[{"name":"ssh","href":"git@bitbucket.local:infra/tools.git"},{"name":"https","href":"https://bitbucket.local/infra/tools.git"}]}}`)
+	})
+
+	mux.HandleFunc("/api/v3/repositories/", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Query().Get("access_token") != "t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/api/v3/repositories/42" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"id":42,"name":"tools","private":true,"default_branch":"main","html_url":"https://github.local/infra/tools","clone_url":"https://github.local/infra/tools.git"}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestSearch(t *testing.T) {
+
+	srv := searchServer(t)
+	defer srv.Close()
+
+	loggr, err := logger.New("info", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := GitlabProvider(loggr, "client", "secret", "http://shift.local/api/gitlab/callback", "http://shift.local/api/hook/gitlab", "s3cr3t")
+	g.SetHost("gitlab-internal", srv.URL+GitlabAPIPathV4, srv.URL)
+
+	b := BitbucketProvider(loggr, "client", "secret", "http://shift.local/api/bitbucket/callback", "http://shift.local/api/hook/bitbucket", "s3cr3t")
+	b.SetHost("bitbucket-internal", srv.URL+"/2.0", srv.URL)
+
+	tests := []struct {
+		p             Provider
+		repoID, clone string
+	}{
+		{g, "42", "https://gitlab.local/infra/tools.git"},
+		{b, "{42}", "https://bitbucket.local/infra/tools.git"},
+	}
+
+	for _, tt := range tests {
+
+		repo, err := tt.p.Search("t0k3n", "infra", "tools")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if repo.RepoID != tt.repoID || repo.Name != "tools" || repo.DefaultBranch != "main" || !repo.Private || repo.CloneURL != tt.clone {
+			t.Errorf("unexpected repository %+v", repo)
+		}
+
+		repo, err = tt.p.Search("t0k3n", "infra", "unknown")
+		if err != nil || repo.RepoID != "" {
+			t.Errorf("expected empty repository, got %+v (%v)", repo, err)
+		}
+
+		_, err = tt.p.Search("expired", "infra", "tools")
+		if !Unauthorized(err) {
+			t.Errorf("expected unauthorized request to fail, got %v", err)
+		}
+	}
+}
+
+func TestGetRepo(t *testing.T) {
+
+	srv := searchServer(t)
+	defer srv.Close()
+
+	loggr, err := logger.New("info", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gh := GithubProvider(loggr, "client", "secret", "http://shift.local/api/github/callback", "http://shift.local/api/hook/github", "s3cr3t")
+	gh.SetHost("github-internal", srv.URL+GithubEnterpriseAPIPath, srv.URL)
+
+	g := GitlabProvider(loggr, "client", "secret", "http://shift.local/api/gitlab/callback", "http://shift.local/api/hook/gitlab", "s3cr3t")
+	g.SetHost("gitlab-internal", srv.URL+GitlabAPIPathV4, srv.URL)
+
+	b := BitbucketProvider(loggr, "client", "secret", "http://shift.local/api/bitbucket/callback", "http://shift.local/api/hook/bitbucket", "s3cr3t")
+	b.SetHost("bitbucket-internal", srv.URL+"/2.0", srv.URL)
+
+	tests := []struct {
+		p               Provider
+		repoID, unknown string
+		clone           string
+	}{
+		{gh, "42", "43", "https://github.local/infra/tools.git"},
+		{g, "42", "43", "https://gitlab.local/infra/tools.git"},
+		{b, "{42}", "{43}", "https://bitbucket.local/infra/tools.git"},
+	}
+
+	for _, tt := range tests {
+
+		repo, err := tt.p.GetRepo("t0k3n", "infra", tt.repoID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if repo.RepoID != tt.repoID || repo.Name != "tools" || repo.DefaultBranch != "main" || !repo.Private || repo.CloneURL != tt.clone {
+			t.Errorf("unexpected repository %+v", repo)
+		}
+
+		repo, err = tt.p.GetRepo("t0k3n", "infra", tt.unknown)
+		if err != nil || repo.RepoID != "" {
+			t.Errorf("expected empty repository, got %+v (%v)", repo, err)
+		}
+
+		_, err = tt.p.GetRepo("expired", "infra", tt.repoID)
+		if !Unauthorized(err) {
+			t.Errorf("expected unauthorized request to fail, got %v", err)
+		}
+	}
+}
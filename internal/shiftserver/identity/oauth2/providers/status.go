@@ -0,0 +1,208 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/elasticshift/elasticshift/pkg/dispatch"
+)
+
+// Commit status states
+const (
+	CommitStatePending = "pending"
+	CommitStateSuccess = "success"
+	CommitStateFailure = "failure"
+	CommitStateError   = "error"
+)
+
+// Commit status path (relative to provider base url)
+const (
+	githubStatusPath    = "/repos/:owner/:repo/statuses/:sha"
+	gitlabStatusPath    = "/projects/:id/statuses/:sha"
+	bitbucketStatusPath = "/repositories/:owner/:repo/commit/:sha/statuses/build"
+)
+
+var (
+	statusAttempts = 5
+	statusBackoff  = 1 * time.Second
+)
+
+// CommitStatus ..
+// Represents the build status reported against a commit
+type CommitStatus struct {
+	State       string
+	TargetURL   string
+	Description string
+	Context     string
+}
+
+// ReportCommitStatus ..
+// Sets the commit status on the provider, retries with backoff
+// when the provider is unreachable or fails with server errors.
+func ReportCommitStatus(p Provider, token, owner, repo, commitID string, status CommitStatus) error {
+
+	return utils.Retry(statusAttempts, statusBackoff, func() error {
+		return p.SetCommitStatus(token, owner, repo, commitID, status)
+	})
+}
+
+// checkStatus ..
+// Converts the failed http response to error, client errors are
+// marked permanent as the retry wouldn't succeed. The failed status
+// takes precedence over the error of decoding the response.
+func checkStatus(r *dispatch.RequestMaker, err error) error {
+
+	code := r.StatusCode()
+	if code >= http.StatusInternalServerError {
+		return fmt.Errorf("Provider responded with status %d", code)
+	}
+
+	if code == http.StatusUnauthorized || code == http.StatusForbidden {
+		return utils.Permanent(unauthorizedError{fmt.Errorf("Provider rejected the credentials with status %d", code)})
+	}
+
+	if code >= http.StatusBadRequest {
+		return utils.Permanent(fmt.Errorf("Provider rejected the request with status %d", code))
+	}
+	return err
+}
+
+// unauthorizedError ..
+// The provider rejected the token, it's revoked or expired
+type unauthorizedError struct {
+	err error
+}
+
+func (e unauthorizedError) Error() string {
+	return e.err.Error()
+}
+
+// Unauthorized ..
+// True if the provider rejected the credentials of the account (401/403,
+// or the refresh token isn't granted), the timeouts and the server errors
+// don't tell that the access is lost.
+func Unauthorized(err error) bool {
+
+	var u unauthorizedError
+	return errors.As(err, &u)
+}
+
+// SetCommitStatus ..
+// Creates a commit status through github statuses api
+func (g *Github) SetCommitStatus(token, owner, repo, commitID string, status CommitStatus) error {
+
+	r := dispatch.NewPostRequestMaker(g.BaseURL + githubStatusPath)
+	r.SetLogger(g.logger)
+
+	r.SetContentType(dispatch.JSON)
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "token "+token)
+	r.PathParams(owner, repo, commitID)
+
+	body := struct {
+		State       string `json:"state"`
+		TargetURL   string `json:"target_url,omitempty"`
+		Description string `json:"description,omitempty"`
+		Context     string `json:"context"`
+	}{}
+
+	body.State = status.State
+	body.TargetURL = status.TargetURL
+	body.Description = status.Description
+	body.Context = status.Context
+
+	r.Body(body)
+
+	return checkStatus(r, r.Dispatch())
+}
+
+// SetCommitStatus ..
+// Creates a commit status through gitlab commit status api
+func (g *Gitlab) SetCommitStatus(token, owner, repo, commitID string, status CommitStatus) error {
+
+	r := dispatch.NewPostRequestMaker(g.BaseURL + gitlabStatusPath)
+	r.SetLogger(g.logger)
+
+	r.SetContentType(dispatch.JSON)
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "Bearer "+token)
+	r.PathParams(url.PathEscape(owner+"/"+repo), commitID)
+
+	var state string
+	switch status.State {
+	case CommitStatePending:
+		state = "running"
+	case CommitStateSuccess:
+		state = "success"
+	case CommitStateError:
+		state = "canceled"
+	default:
+		state = "failed"
+	}
+
+	body := struct {
+		State       string `json:"state"`
+		TargetURL   string `json:"target_url,omitempty"`
+		Description string `json:"description,omitempty"`
+		Name        string `json:"name"`
+	}{}
+
+	body.State = state
+	body.TargetURL = status.TargetURL
+	body.Description = status.Description
+	body.Name = status.Context
+
+	r.Body(body)
+
+	return checkStatus(r, r.Dispatch())
+}
+
+// SetCommitStatus ..
+// Creates a build status through bitbucket commit status api
+func (b *Bitbucket) SetCommitStatus(token, owner, repo, commitID string, status CommitStatus) error {
+
+	r := dispatch.NewPostRequestMaker(b.BaseURL + bitbucketStatusPath)
+	r.SetLogger(b.logger)
+
+	r.SetContentType(dispatch.JSON)
+	r.Header("Accept", dispatch.JSON)
+	r.Header("Authorization", "Bearer "+token)
+	r.PathParams(owner, repo, commitID)
+
+	var state string
+	switch status.State {
+	case CommitStatePending:
+		state = "INPROGRESS"
+	case CommitStateSuccess:
+		state = "SUCCESSFUL"
+	case CommitStateError:
+		state = "STOPPED"
+	default:
+		state = "FAILED"
+	}
+
+	body := struct {
+		State       string `json:"state"`
+		Key         string `json:"key"`
+		Name        string `json:"name"`
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}{}
+
+	body.State = state
+	body.Key = status.Context
+	body.Name = status.Context
+	body.URL = status.TargetURL
+	body.Description = status.Description
+
+	r.Body(body)
+
+	return checkStatus(r, r.Dispatch())
+}
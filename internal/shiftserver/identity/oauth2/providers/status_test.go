@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type statusRequest struct {
+	Path          string
+	Authorization string
+	Body          map[string]string
+}
+
+// fakeProvider emulates the commit status endpoint of a provider,
+// fails with the given status codes before succeeding.
+func fakeProvider(t *testing.T, failures ...int) (*httptest.Server, *[]statusRequest) {
+
+	var requests []statusRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+
+		req := statusRequest{Path: r.URL.EscapedPath(), Authorization: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&req.Body)
+		requests = append(requests, req)
+
+		if len(requests) <= len(failures) {
+			w.WriteHeader(failures[len(requests)-1])
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	return srv, &requests
+}
+
+var status = CommitStatus{
+	State:       CommitStateSuccess,
+	TargetURL:   "http://shift.local/builds/5b3f",
+	Description: "Build succeeded",
+	Context:     "elasticshift/1",
+}
+
+func TestGithubSetCommitStatus(t *testing.T) {
+
+	srv, requests := fakeProvider(t)
+	defer srv.Close()
+
+	g := &Github{BaseURL: srv.URL}
+	err := g.SetCommitStatus("t0k3n", "octocat", "hello-world", "abc123", status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := (*requests)[0]
+	if req.Path != "/repos/octocat/hello-world/statuses/abc123" {
+		t.Fatalf("unexpected path %s", req.Path)
+	}
+
+	if req.Authorization != "token t0k3n" {
+		t.Fatalf("unexpected authorization %s", req.Authorization)
+	}
+
+	if req.Body["state"] != "success" || req.Body["context"] != "elasticshift/1" || req.Body["target_url"] != status.TargetURL {
+		t.Fatalf("unexpected body %v", req.Body)
+	}
+}
+
+func TestGitlabSetCommitStatus(t *testing.T) {
+
+	srv, requests := fakeProvider(t)
+	defer srv.Close()
+
+	s := status
+	s.State = CommitStateFailure
+
+	g := &Gitlab{BaseURL: srv.URL}
+	err := g.SetCommitStatus("t0k3n", "group", "project", "abc123", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := (*requests)[0]
+	if req.Path != "/projects/group%2Fproject/statuses/abc123" {
+		t.Fatalf("unexpected path %s", req.Path)
+	}
+
+	if req.Authorization != "Bearer t0k3n" {
+		t.Fatalf("unexpected authorization %s", req.Authorization)
+	}
+
+	if req.Body["state"] != "failed" || req.Body["name"] != "elasticshift/1" {
+		t.Fatalf("unexpected body %v", req.Body)
+	}
+}
+
+func TestBitbucketSetCommitStatus(t *testing.T) {
+
+	srv, requests := fakeProvider(t)
+	defer srv.Close()
+
+	s := status
+	s.State = CommitStatePending
+
+	b := &Bitbucket{BaseURL: srv.URL}
+	err := b.SetCommitStatus("t0k3n", "team", "repo", "abc123", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := (*requests)[0]
+	if req.Path != "/repositories/team/repo/commit/abc123/statuses/build" {
+		t.Fatalf("unexpected path %s", req.Path)
+	}
+
+	if req.Body["state"] != "INPROGRESS" || req.Body["key"] != "elasticshift/1" || req.Body["url"] != status.TargetURL {
+		t.Fatalf("unexpected body %v", req.Body)
+	}
+}
+
+func TestReportCommitStatusRetries(t *testing.T) {
+
+	statusBackoff = time.Millisecond
+
+	srv, requests := fakeProvider(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	err := ReportCommitStatus(&Github{BaseURL: srv.URL}, "t0k3n", "octocat", "hello-world", "abc123", status)
+	if err != nil {
+		t.Fatalf("expected the status to be reported after retries: %v", err)
+	}
+
+	if len(*requests) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(*requests))
+	}
+}
+
+func TestReportCommitStatusGivesUpOnClientError(t *testing.T) {
+
+	statusBackoff = time.Millisecond
+
+	srv, requests := fakeProvider(t, http.StatusUnprocessableEntity)
+	defer srv.Close()
+
+	err := ReportCommitStatus(&Github{BaseURL: srv.URL}, "t0k3n", "octocat", "hello-world", "abc123", status)
+	if err == nil {
+		t.Fatal("expected the client error to be returned")
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected no retry on client error, got %d attempts", len(*requests))
+	}
+}
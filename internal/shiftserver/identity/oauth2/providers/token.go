@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/pkg/dispatch"
+)
+
+// expiryDelta determines how earlier a token should be considered
+const expiryDelta = 10 * time.Second
+
+// GetToken ..
+// Returns the valid access token of the account linked to the team,
+// the expired token gets refreshed and persisted.
+func (p Providers) GetToken(team string, a types.VCS) (string, error) {
+
+	// Never expire type token
+	if a.RefreshToken == "" {
+		return a.AccessToken, nil
+	}
+
+	// Token that requires frequent refresh
+	// check if the token is expired
+	if !a.TokenExpiry.Add(-expiryDelta).Before(time.Now()) {
+		return a.AccessToken, nil
+	}
+
+	a, err := p.RefreshToken(team, a)
+	if err != nil {
+		return "", err
+	}
+	return a.AccessToken, nil
+}
+
+// RefreshToken ..
+// Refreshes the access token of the account and persists it,
+// returns the account with the refreshed token.
+func (p Providers) RefreshToken(team string, a types.VCS) (types.VCS, error) {
+
+	pr, err := p.Get(a.Kind)
+	if err != nil {
+		return a, err
+	}
+
+	// Refresh the token
+	tok, err := pr.RefreshToken(a.RefreshToken)
+	if err != nil {
+		return a, fmt.Errorf("Failed to refresh the token: %w", err)
+	}
+
+	a.AccessToken = tok.AccessToken
+	a.TokenExpiry = tok.Expiry
+
+	// the refresh token is rotated only by some of the providers
+	if tok.RefreshToken != "" {
+		a.RefreshToken = tok.RefreshToken
+	}
+
+	// persist the updated token information
+	err = p.teamStore.UpdateVCS(team, a)
+	if err != nil {
+		return a, fmt.Errorf("Failed to update VCS after token refreshed: %v", err)
+	}
+	return a, nil
+}
+
+// tokenStatus ..
+// Checks the response of the token endpoint, the revoked or expired refresh
+// token is reported as invalid_grant (bad_refresh_token by github).
+func tokenStatus(r *dispatch.RequestMaker, tok Token, err error) error {
+
+	if tok.Error == "invalid_grant" || tok.Error == "bad_refresh_token" {
+		return unauthorizedError{fmt.Errorf("Provider rejected the refresh token: %s", tok.Error)}
+	}
+	return checkStatus(r, err)
+}
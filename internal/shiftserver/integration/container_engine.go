@@ -7,10 +7,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
 	itypes "github.com/elasticshift/elasticshift/internal/shiftserver/integration/types"
+	"gopkg.in/mgo.v2/bson"
 )
 
 //container engine
@@ -30,6 +32,7 @@ type ContainerEngineInterface interface {
 	CreateContainerWithVolume(opts *itypes.CreateContainerOptions) (*itypes.ContainerInfo, error)
 	CreatePersistentVolume(opts *itypes.CreatePersistentVolumeOptions) (*itypes.PersistentVolumeInfo, error)
 	DeleteContainer(id string) error
+	ListContainers() ([]itypes.ContainerInfo, error)
 	StreamLog(opts *itypes.StreamLogOptions) (io.ReadCloser, error)
 }
 
@@ -63,3 +66,15 @@ func NewContainerEngine(loggr logger.Loggr, i types.ContainerEngine, s types.Sto
 
 	return nil, fmt.Errorf("No container engine to connect.")
 }
+
+// BuildContainer ..
+// Returns the build and the sub build the container is launched for,
+// from the name of the container given as <buildid>-<subbuildid>
+func BuildContainer(name string) (string, string, bool) {
+
+	parts := strings.SplitN(name, "-", 2)
+	if len(parts) != 2 || parts[1] == "" || !bson.IsObjectIdHex(parts[0]) {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	dtypes "github.com/docker/docker/api/types"
@@ -94,6 +95,10 @@ func (c *dockerClient) CreateContainer(opts *itypes.CreateContainerOptions) (*it
 	}
 
 	name := opts.BuildID + "-" + opts.SubBuildID
+	cfg.Labels = map[string]string{
+		KW_CREATEDBY: DefaultContext,
+		KW_BUILDID:   name,
+	}
 	containerResult, err := c.cli.ContainerCreate(c.ctx, cfg, hc, nil, name)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create container %s:%v", opts.Image, err)
@@ -146,6 +151,43 @@ func (c *dockerClient) DeleteContainer(id string) error {
 	return nil
 }
 
+// ListContainers ..
+// Returns the containers created by elasticshift, including the stopped ones.
+// The containers launched before they were labelled are known by the name.
+func (c *dockerClient) ListContainers() ([]itypes.ContainerInfo, error) {
+
+	list, err := c.cli.ContainerList(c.ctx, dtypes.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the containers: %v", err)
+	}
+
+	var result []itypes.ContainerInfo
+	for _, ct := range list {
+
+		var name string
+		if len(ct.Names) > 0 {
+			name = strings.TrimPrefix(ct.Names[0], "/")
+		}
+
+		if ct.Labels[KW_CREATEDBY] != DefaultContext {
+
+			if _, _, ok := BuildContainer(name); !ok {
+				continue
+			}
+		}
+
+		result = append(result, itypes.ContainerInfo{
+			Name:      name,
+			UID:       ct.ID,
+			Image:     ct.Image,
+			Status:    ct.State,
+			Labels:    ct.Labels,
+			CreatedAt: time.Unix(ct.Created, 0),
+		})
+	}
+	return result, nil
+}
+
 func (c *dockerClient) StreamLog(opts *itypes.StreamLogOptions) (io.ReadCloser, error) {
 
 	options := dtypes.ContainerLogsOptions{ShowStdout: true}
@@ -428,6 +428,38 @@ func (c *kubernetesClient) DeleteContainer(id string) error {
 	return err
 }
 
+// ListContainers ..
+// Returns the pods created by elasticshift in the namespace
+func (c *kubernetesClient) ListContainers() ([]itypes.ContainerInfo, error) {
+
+	lo := metav1.ListOptions{LabelSelector: KW_CREATEDBY + "=" + DefaultContext}
+	pods, err := c.Kube.CoreV1().Pods(c.opts.Namespace).List(lo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the pods: %v", err)
+	}
+
+	var result []itypes.ContainerInfo
+	for _, pod := range pods.Items {
+
+		var image string
+		if len(pod.Spec.Containers) > 0 {
+			image = pod.Spec.Containers[0].Image
+		}
+
+		result = append(result, itypes.ContainerInfo{
+			Name:      pod.Name,
+			UID:       string(pod.UID),
+			Image:     image,
+			Status:    string(pod.Status.Phase),
+			ShiftID:   pod.Labels[KW_SHIFTID],
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+			CreatedAt: pod.CreationTimestamp.Time,
+		})
+	}
+	return result, nil
+}
+
 func (c *kubernetesClient) StreamLog(opts *itypes.StreamLogOptions) (io.ReadCloser, error) {
 
 	c.logger.Infoln("pod=", opts.Pod)
@@ -188,3 +188,33 @@ func (m minioClient) PutFObject(bucketName, objectName, filepath, contentType st
 func (m minioClient) GetFObject(bucketName, objectName, filepath string) error {
 	return m.cli.FGetObject(bucketName, objectName, filepath, minio.GetObjectOptions{})
 }
+
+// RemoveObjects ..
+// Removes the objects whose names start with the prefix, the objects
+// nested under the prefix are left as is unless it's recursive.
+// Returns the number of objects removed.
+func (m minioClient) RemoveObjects(bucketName, prefix string, recursive bool) (int, error) {
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var count int
+	for obj := range m.cli.ListObjectsV2(bucketName, prefix, recursive, done) {
+
+		if obj.Err != nil {
+			return count, obj.Err
+		}
+
+		// the nested prefixes are listed when it's not recursive
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+
+		err := m.cli.RemoveObject(bucketName, obj.Key)
+		if err != nil {
+			return count, fmt.Errorf("Failed to remove the object %s: %v", obj.Key, err)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -130,6 +130,10 @@ func (r *resolver) AddContainerEngine(params graphql.ResolveParams) (interface{}
 	certificate, _ := params.Args["certificate"].(string)
 	token, _ := params.Args["token"].(string)
 	version, _ := params.Args["version"].(string)
+	maxConcurrentBuilds, _ := params.Args["max_concurrent_builds"].(int)
+	if maxConcurrentBuilds < 0 {
+		return nil, fmt.Errorf("Maximum concurrent builds cannot be negative")
+	}
 
 	i := types.ContainerEngine{}
 	i.Name = name
@@ -141,6 +145,7 @@ func (r *resolver) AddContainerEngine(params graphql.ResolveParams) (interface{}
 	i.Provider = provider
 	i.InternalType = INT_ContainerEngine
 	i.Version = version
+	i.MaxConcurrentBuilds = maxConcurrentBuilds
 
 	err = r.store.Save(&i)
 	if err != nil {
@@ -31,6 +31,7 @@ type StorageInterface interface {
 	GetObject(bucketName, objectName string) (io.ReadCloser, error)
 	PutFObject(bucketName, objectName, filepath, contentType string) (int64, error)
 	GetFObject(bucketName, objectName, filepath string) error
+	RemoveObjects(bucketName, prefix string, recursive bool) (int, error)
 	SetupStorage(bucketName, workerURL string) (string, error)
 }
 
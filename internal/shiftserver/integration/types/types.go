@@ -45,6 +45,10 @@ type ContainerInfo struct {
 	ShiftID           string
 	Namespace         string
 	Name              string
+
+	// labels set on the container, and the time it was created
+	Labels    map[string]string
+	CreatedAt time.Time
 }
 
 type PersistentVolumeClaimOptions struct {
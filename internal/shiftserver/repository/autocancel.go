@@ -0,0 +1,39 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/graphql-go/graphql"
+)
+
+// SetAutoCancel ..
+// Configures the repository to cancel the older builds of a branch,
+// when a new commit is pushed to it.
+func (r resolver) SetAutoCancel(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	enabled, _ := params.Args["enabled"].(bool)
+	defaultBranch, _ := params.Args["default_branch"].(bool)
+
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	ac := types.AutoCancel{Enabled: enabled, DefaultBranch: defaultBranch}
+	err = r.store.UpdateAutoCancel(repo.ID, ac)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the auto cancel: %v", err)
+	}
+
+	repo.AutoCancel = ac
+	return repo, nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// length of the badge token in bytes
+const badgeTokenSize = 20
+
+// ResetBadgeToken ..
+// Generates a new token to view the status badge of the private
+// repository, given as the token query parameter of the badge URL
+func (r resolver) ResetBadgeToken(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	b := make([]byte, badgeTokenSize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate the token: %v", err)
+	}
+	token := hex.EncodeToString(b)
+
+	err = r.store.UpdateBadgeToken(repo.ID, token)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the badge token: %v", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultPollInterval ..
+// Interval (in seconds) the plain git repositories are polled for changes
+const DefaultPollInterval = 60
+
+// addGitRepository ..
+// Registers the repository hosted on a bare git server by its url, the
+// credentials are vaulted. For ssh, a deploy key pair is generated and the
+// public key is returned, which has to be added to the git server.
+func (r resolver) addGitRepository(params graphql.ResolveParams, uri, teamName, auth string) (interface{}, error) {
+
+	// the uri is passed to git, it must not be taken for an option
+	if !vcs.ValidGitUrl(uri) {
+		return nil, fmt.Errorf("Invalid repository uri '%s', must be either https://, ssh:// or user@host:path", uri)
+	}
+
+	source, account, repoName := vcs.ParseGitUrl(uri)
+	if source == "" || repoName == "" {
+		return nil, fmt.Errorf("Invalid repository uri '%s'", uri)
+	}
+
+	var currentRepo types.Repository
+	err := r.store.FindOne(bson.M{"team": teamName, "clone_url": uri}, &currentRepo)
+	if err != nil && err.Error() != "not found" {
+		return nil, fmt.Errorf("Failed to check the existance of the repository :%v", err)
+	}
+
+	if currentRepo.ID != "" {
+		return nil, fmt.Errorf("URI '%s' already added as a repository to your team", uri)
+	}
+
+	interval, ok := params.Args["poll_interval"].(int)
+	if !ok {
+		interval = DefaultPollInterval
+	}
+
+	if interval < 0 {
+		return nil, fmt.Errorf("Poll interval must be positive, or zero to disable polling")
+	}
+
+	repo := types.Repository{}
+	repo.ID = bson.NewObjectId()
+	repo.Team = teamName
+	repo.Name = repoName
+	repo.Source = source
+	repo.CloneURL = uri
+	repo.Identifier = strings.Join([]string{source, account}, "/")
+	repo.RepoID = repo.Identifier + "/" + repoName
+	repo.Auth = auth
+	repo.Private = auth != types.RepoAuthNone
+	repo.PollInterval = interval
+	repo.DefaultBranch, _ = params.Args["default_branch"].(string)
+	repo.Language, _ = params.Args["language"].(string)
+
+	if strings.HasPrefix(uri, "http") {
+		repo.Link = strings.TrimSuffix(uri, ".git")
+	}
+
+	c := vcs.Credentials{}
+	switch auth {
+	case types.RepoAuthNone:
+
+	case types.RepoAuthBasic:
+
+		if !strings.HasPrefix(uri, "http") {
+			return nil, fmt.Errorf("Username and token are supported only for http(s) repository uri")
+		}
+
+		c.Username, _ = params.Args["username"].(string)
+		c.Token, _ = params.Args["token"].(string)
+		if c.Username == "" || c.Token == "" {
+			return nil, fmt.Errorf("Username and token are required to access the repository")
+		}
+
+	case types.RepoAuthSSHKey:
+
+		if strings.HasPrefix(uri, "http") {
+			return nil, fmt.Errorf("Deploy key is supported only for ssh repository uri")
+		}
+
+		// the key is used only against the git server known by its keys
+		repo.HostKeys, _ = params.Args["host_keys"].(string)
+		if !vcs.KnownHost(source) && repo.HostKeys == "" {
+			return nil, fmt.Errorf("Host keys of %s are required to access the repository through ssh, ex: the output of ssh-keyscan %s", source, source)
+		}
+
+		if !validHostKeys(repo.HostKeys) {
+			return nil, fmt.Errorf("Invalid host keys, must be in the known_hosts format")
+		}
+		c.KnownHosts = repo.HostKeys
+
+		c.SSHKey, repo.PublicKey, err = secret.GenerateDeployKey()
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("Unknown authentication '%s', must be one of %s, %s or %s", auth, types.RepoAuthNone, types.RepoAuthBasic, types.RepoAuthSSHKey)
+	}
+
+	// the deploy key grants access only once it's added to the git server,
+	// so the heads are recorded on the first poll.
+	if auth != types.RepoAuthSSHKey {
+
+		heads, defaultBranch, err := vcs.LsRemote(uri, c)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to access the repository: %v", err)
+		}
+
+		repo.Heads = toHeads(heads)
+		repo.PolledAt = time.Now()
+		if repo.DefaultBranch == "" {
+			repo.DefaultBranch = defaultBranch
+		}
+	}
+
+	if auth != types.RepoAuthNone {
+
+		repo.SecretID, err = secret.SaveGitCredentials(r.vault, repo, c)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to vault the credentials: %v", err)
+		}
+	}
+
+	err = r.store.Save(&repo)
+	if err != nil {
+		if repo.SecretID != "" {
+			r.vault.Del(repo.SecretID)
+		}
+		return nil, fmt.Errorf("Failed to save the repository: %v", err)
+	}
+
+	return repo, nil
+}
+
+// toHeads ..
+// Converts the branch heads listed from the remote, ordered by branch
+func toHeads(heads map[string]string) []types.Head {
+
+	result := make([]types.Head, 0, len(heads))
+	for branch, commitID := range heads {
+		result = append(result, types.Head{Branch: branch, CommitID: commitID})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Branch < result[j].Branch
+	})
+	return result
+}
+
+// SetHostKeys ..
+// Sets the host keys the git server of the plain git repository is
+// verified by, when it's accessed through the deploy key
+func (r resolver) SetHostKeys(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	keys, _ := params.Args["host_keys"].(string)
+	if !validHostKeys(keys) {
+		return nil, fmt.Errorf("Invalid host keys, must be in the known_hosts format")
+	}
+
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	if repo.Auth != types.RepoAuthSSHKey {
+		return nil, fmt.Errorf("Host keys are used only by the plain git repository accessed through the deploy key")
+	}
+
+	err = r.store.UpdateId(repo.ID, bson.M{"$set": bson.M{"host_keys": keys}})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the host keys: %v", err)
+	}
+
+	repo.HostKeys = keys
+	return repo, nil
+}
+
+// validHostKeys ..
+// True if the host keys are in the known_hosts format, the blank
+// lines and the comments are ignored
+func validHostKeys(keys string) bool {
+
+	rest := []byte(keys)
+	for len(rest) > 0 {
+
+		var err error
+		_, _, _, _, rest, err = ssh.ParseKnownHosts(rest)
+		if err == io.EOF {
+			return true
+		}
+
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
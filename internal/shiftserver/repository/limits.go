@@ -0,0 +1,41 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SetBuildLimits ..
+// Limits the builds of the repository run at a time, and sets the
+// priority its builds are launched with, ahead of the other queued builds.
+func (r resolver) SetBuildLimits(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	maxConcurrentBuilds, _ := params.Args["max_concurrent_builds"].(int)
+	if maxConcurrentBuilds < 0 {
+		return nil, fmt.Errorf("Maximum concurrent builds cannot be negative")
+	}
+	priority, _ := params.Args["priority"].(int)
+
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	err = r.store.UpdateBuildLimits(repo.ID, maxConcurrentBuilds, priority)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the build limits: %v", err)
+	}
+
+	repo.MaxConcurrentBuilds = maxConcurrentBuilds
+	repo.Priority = priority
+	return repo, nil
+}
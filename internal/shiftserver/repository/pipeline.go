@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/graphql-go/graphql"
+)
+
+// SetPipelines ..
+// Configures the repository (monorepo) with pipelines, each built through
+// its own Shiftfile when the changed files match its paths. An empty list
+// turns the repository back to a single build.
+func (r resolver) SetPipelines(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	args, _ := params.Args["pipelines"].([]interface{})
+
+	names := make(map[string]bool)
+	pipelines := make([]types.Pipeline, 0, len(args))
+	for _, arg := range args {
+
+		val, _ := arg.(map[string]interface{})
+
+		p := types.Pipeline{}
+		p.Name, _ = val["name"].(string)
+		p.Shiftfile, _ = val["shiftfile"].(string)
+		p.Include = toStrings(val["include"])
+		p.Exclude = toStrings(val["exclude"])
+
+		if p.Name == "" {
+			return nil, fmt.Errorf("Pipeline name cannot be empty")
+		}
+
+		if names[p.Name] {
+			return nil, fmt.Errorf("Pipeline '%s' is configured more than once", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Shiftfile == "" {
+			p.Shiftfile = vcs.SHIFTFILE
+		}
+
+		pipelines = append(pipelines, p)
+	}
+
+	err = r.store.UpdatePipelines(repo.ID, pipelines)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the pipelines: %v", err)
+	}
+
+	repo.Pipelines = pipelines
+	return repo, nil
+}
+
+func toStrings(val interface{}) []string {
+
+	list, _ := val.([]interface{})
+
+	var result []string
+	for _, v := range list {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/resolver"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// triggered by, for the builds started on a branch move
+	pollTriggeredBy = "poller"
+
+	// maximum repositories polled at the same time
+	maxConcurrentPolls = 8
+)
+
+// interval the poller looks for the repositories that are due
+var pollTick = 15 * time.Second
+
+// Poller ..
+// Detects the changes on the plain git repositories by listing
+// the heads of the remote and triggers the build on the branches
+// that have moved since the last poll.
+type Poller struct {
+	store  store.Repository
+	vault  secret.Vault
+	rs     *resolver.Shift
+	logger *logrus.Entry
+}
+
+// NewPoller ..
+func NewPoller(loggr logger.Loggr, s store.Shift, vault secret.Vault, rs *resolver.Shift) *Poller {
+
+	return &Poller{
+		store:  s.Repository,
+		vault:  vault,
+		rs:     rs,
+		logger: loggr.GetLogger("repository/poller"),
+	}
+}
+
+// Run ..
+// Polls the repositories that are due, until the context is done
+func (p *Poller) Run(ctx context.Context) {
+
+	t := time.NewTicker(pollTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.poll()
+		}
+	}
+}
+
+func (p *Poller) poll() {
+
+	repos, err := p.store.GetPolledRepositories()
+	if err != nil {
+		p.logger.Errorf("Failed to fetch the repositories to poll: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, maxConcurrentPolls)
+	now := time.Now()
+
+	for _, repo := range repos {
+
+		if now.Sub(repo.PolledAt) < time.Duration(repo.PollInterval)*time.Second {
+			continue
+		}
+
+		// other servers may poll the same repository
+		claimed, err := p.store.ClaimPoll(repo.ID, repo.PolledAt, now)
+		if err != nil {
+			p.logger.Errorf("Failed to claim the poll of repository %s: %v", repo.ID.Hex(), err)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		sem <- struct{}{}
+		go func(repo types.Repository) {
+			defer func() { <-sem }()
+			p.pollRepository(repo)
+		}(repo)
+	}
+}
+
+func (p *Poller) pollRepository(repo types.Repository) {
+
+	c, err := secret.GitCredentials(p.vault, repo)
+	if err != nil {
+		p.logger.Errorf("Failed to poll the repository %s: %v", repo.ID.Hex(), err)
+		return
+	}
+
+	heads, defaultBranch, err := vcs.LsRemote(repo.CloneURL, c)
+	if err != nil {
+
+		// unreachable remotes are polled again, until the credentials are rejected
+		p.logger.Warnf("Failed to poll the repository %s: %v", repo.ID.Hex(), err)
+		if vcs.AccessDenied(err) {
+			p.flag(repo, types.RepoSyncAccessLost)
+		}
+		return
+	}
+	p.flag(repo, types.RepoSyncOK)
+
+	// the heads of the first poll are the base to detect the changes
+	if repo.Heads != nil {
+
+		prev := make(map[string]string)
+		for _, h := range repo.Heads {
+			prev[h.Branch] = h.CommitID
+		}
+
+		branches := make([]string, 0, len(heads))
+		for branch := range heads {
+			branches = append(branches, branch)
+		}
+		sort.Strings(branches)
+
+		for _, branch := range branches {
+
+			if prev[branch] == heads[branch] {
+				continue
+			}
+
+			opts := build.TriggerOptions{}
+			opts.Repository = repo
+			opts.Branch = branch
+			opts.CommitID = heads[branch]
+			opts.TriggeredBy = pollTriggeredBy
+
+			// the build directives are given through the commit message
+			opts.Message, err = vcs.CommitMessage(repo.CloneURL, branch, c)
+			if err != nil {
+				p.logger.Warnf("Failed to read the commit message of repository %s: %v", repo.ID.Hex(), err)
+			}
+
+			builds, err := p.rs.Build.TriggerChanged(opts)
+			if err == nil {
+				continue
+			}
+
+			p.logger.Errorf("Failed to trigger the build for repository %s: %v", repo.ID.Hex(), err)
+
+			// the pipelines triggered are not triggered again
+			if len(builds) > 0 {
+				continue
+			}
+
+			// retried on the next poll
+			if commitID, ok := prev[branch]; ok {
+				heads[branch] = commitID
+			} else {
+				delete(heads, branch)
+			}
+		}
+	}
+
+	// branch configured by the user is kept
+	if repo.DefaultBranch != "" {
+		defaultBranch = ""
+	}
+
+	err = p.store.UpdateHeads(repo.ID, toHeads(heads), defaultBranch)
+	if err != nil {
+		p.logger.Errorf("Failed to update the heads of repository %s: %v", repo.ID.Hex(), err)
+	}
+}
+
+// flag ..
+// Records whether the repository is still accessible
+func (p *Poller) flag(repo types.Repository, status string) {
+
+	if repo.SyncStatus == status {
+		return
+	}
+
+	err := p.store.UpdateSyncStatus(repo.ID, status, time.Now())
+	if err != nil {
+		p.logger.Errorf("Failed to flag the repository %s: %v", repo.ID.Hex(), err)
+	}
+}
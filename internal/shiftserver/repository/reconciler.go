@@ -0,0 +1,215 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// interval the reconciler looks for the accounts that are due
+	syncTick = time.Minute
+
+	// interval the repositories of an account are synced with the vcs
+	syncInterval = 30 * time.Minute
+
+	// tokens expiring within the window are refreshed ahead
+	tokenRefreshWindow = 5 * time.Minute
+)
+
+// Reconciler ..
+// Keeps the linked vcs accounts and their repositories in sync in background,
+// the expiring tokens are refreshed and vaulted, the repository details are
+// synced and the repositories that disappeared or lost access are flagged.
+type Reconciler struct {
+	store     store.Repository
+	teamStore store.Team
+	providers providers.Providers
+	vault     secret.Vault
+	logger    *logrus.Entry
+}
+
+// NewReconciler ..
+func NewReconciler(loggr logger.Loggr, s store.Shift, providers providers.Providers, vault secret.Vault) *Reconciler {
+
+	return &Reconciler{
+		store:     s.Repository,
+		teamStore: s.Team,
+		providers: providers,
+		vault:     vault,
+		logger:    loggr.GetLogger("repository/reconciler"),
+	}
+}
+
+// Run ..
+// Reconciles the accounts that are due, until the context is done
+func (rc *Reconciler) Run(ctx context.Context) {
+
+	t := time.NewTicker(syncTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			rc.reconcile()
+		}
+	}
+}
+
+func (rc *Reconciler) reconcile() {
+
+	teams, err := rc.teamStore.GetLinkedTeams()
+	if err != nil {
+		rc.logger.Errorf("Failed to fetch the linked accounts: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, t := range teams {
+
+		for _, a := range t.Accounts {
+
+			if !rc.due(a, now) {
+				continue
+			}
+
+			// other servers may reconcile the same account
+			claimed, err := rc.teamStore.ClaimSync(t.Name, a.ID, a.SyncedAt, now)
+			if err != nil {
+				rc.logger.Errorf("Failed to claim the sync of account %s: %v", a.Name, err)
+				continue
+			}
+
+			if claimed {
+				rc.reconcileAccount(t.Name, a, now)
+			}
+		}
+	}
+}
+
+// due ..
+// The account is due when its token is about to expire, or
+// its repositories are not synced for the sync interval.
+func (rc *Reconciler) due(a types.VCS, now time.Time) bool {
+
+	if expiring(a, now) {
+		return true
+	}
+	return now.Sub(a.SyncedAt) >= syncInterval
+}
+
+func expiring(a types.VCS, now time.Time) bool {
+	return a.RefreshToken != "" && a.TokenExpiry.Before(now.Add(tokenRefreshWindow))
+}
+
+func (rc *Reconciler) reconcileAccount(team string, a types.VCS, now time.Time) {
+
+	repos, err := rc.store.GetRepository(team, a.ID)
+	if err != nil {
+		rc.logger.Errorf("Failed to fetch the repositories of account %s: %v", a.Name, err)
+		return
+	}
+
+	if expiring(a, now) {
+
+		a, err = rc.providers.RefreshToken(team, a)
+		if err != nil {
+
+			rc.logger.Warnf("Failed to refresh the token of account %s: %v", a.Name, err)
+			if !providers.Unauthorized(err) {
+				return
+			}
+
+			// the authorization is revoked, the account has to be linked again
+			for _, repo := range repos {
+				rc.flag(repo, types.RepoSyncAccessLost, now)
+			}
+			return
+		}
+
+		err = secret.UpdateVCSToken(rc.vault, a)
+		if err != nil {
+			rc.logger.Errorf("Failed to vault the refreshed token of account %s: %v", a.Name, err)
+		}
+	}
+
+	if len(repos) == 0 {
+		return
+	}
+
+	p, err := rc.providers.Get(a.Kind)
+	if err != nil {
+		rc.logger.Errorf("No provider found for account %s: %v", a.Name, err)
+		return
+	}
+
+	remote, err := p.GetRepos(a.AccessToken, a.Name, a.OwnerType)
+	if err != nil {
+		rc.logger.Warnf("Failed to fetch the repositories of account %s: %v", a.Name, err)
+		return
+	}
+
+	byID := make(map[string]types.Repository, len(remote))
+	for _, r := range remote {
+		byID[r.RepoID] = r
+	}
+
+	for _, repo := range repos {
+
+		r, ok := byID[repo.RepoID]
+		if !ok {
+
+			// the listing isn't paged, so the repository is looked up by
+			// its id, before it's considered as disappeared. The providers
+			// report the repository not found as empty repository.
+			r, err = p.GetRepo(a.AccessToken, a.Name, repo.RepoID)
+			if err != nil {
+
+				rc.logger.Warnf("Failed to look up the repository %s: %v", repo.ID.Hex(), err)
+				if providers.Unauthorized(err) {
+					rc.flag(repo, types.RepoSyncAccessLost, now)
+				}
+				continue
+			}
+
+			if r.RepoID != repo.RepoID {
+				rc.flag(repo, types.RepoSyncNotFound, now)
+				continue
+			}
+		}
+
+		r.ID = repo.ID
+		r.SyncStatus = types.RepoSyncOK
+		r.SyncedAt = now
+
+		err = rc.store.UpdateMetadata(r)
+		if err != nil {
+			rc.logger.Errorf("Failed to update the repository %s: %v", repo.ID.Hex(), err)
+		}
+	}
+}
+
+func (rc *Reconciler) flag(repo types.Repository, status string, now time.Time) {
+
+	if repo.SyncStatus == status {
+		return
+	}
+
+	rc.logger.Warnf("Flagging the repository %s as %s", repo.ID.Hex(), status)
+
+	err := rc.store.UpdateSyncStatus(repo.ID, status, now)
+	if err != nil {
+		rc.logger.Errorf("Failed to flag the repository %s: %v", repo.ID.Hex(), err)
+	}
+}
@@ -8,24 +8,24 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/sirupsen/logrus"
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/team"
 	"gopkg.in/mgo.v2/bson"
 )
 
-// expiryDelta determines how earlier a token should be considered
-const expiryDelta = 10 * time.Second
-
 var (
 	// repository errors
-	errNoURIProvided = errors.New("URI is empty")
+	errNoURIProvided           = errors.New("URI is empty")
+	errRepositoryIDCantBeEmpty = errors.New("Repository ID cannot be empty")
+	errHookNotSupported        = errors.New("Hooks are not supported for plain git repositories, the changes are detected by polling")
 )
 
 // Resolver ...
@@ -33,6 +33,12 @@ type Resolver interface {
 	FetchRepository(params graphql.ResolveParams) (interface{}, error)
 	FetchBuild(params graphql.ResolveParams) (interface{}, error)
 	AddRepository(params graphql.ResolveParams) (interface{}, error)
+	EnableHook(params graphql.ResolveParams) (interface{}, error)
+	SetPipelines(params graphql.ResolveParams) (interface{}, error)
+	SetBuildLimits(params graphql.ResolveParams) (interface{}, error)
+	SetAutoCancel(params graphql.ResolveParams) (interface{}, error)
+	SetHostKeys(params graphql.ResolveParams) (interface{}, error)
+	ResetBadgeToken(params graphql.ResolveParams) (interface{}, error)
 }
 
 type resolver struct {
@@ -41,16 +47,19 @@ type resolver struct {
 	buildStore store.Build
 	logger     *logrus.Entry
 	providers  providers.Providers
+	vault      secret.Vault
 }
 
 // NewResolver ...
-func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift) (Resolver, error) {
+func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift, providers providers.Providers, vault secret.Vault) (Resolver, error) {
 
 	r := &resolver{
 		store:      s.Repository,
 		teamStore:  s.Team,
 		buildStore: s.Build,
 		logger:     loggr.GetLogger("graphql/repository"),
+		providers:  providers,
+		vault:      vault,
 	}
 	return r, nil
 }
@@ -101,33 +110,18 @@ func (r resolver) AddRepository(params graphql.ResolveParams) (interface{}, erro
 		return nil, team.ErrTeamNameIsEmpty
 	}
 
+	// repositories on bare git servers are registered with the credentials
+	if auth, _ := params.Args["auth"].(string); auth != "" {
+		return r.addGitRepository(params, uri, teamName, auth)
+	}
+
 	// parse uri and identify the VCS
 	// git@github.com:nshahm/hybrid.test.runner.git
-	protoGit := strings.HasPrefix(uri, "git@")
-	protoHttps := strings.HasPrefix(uri, "http")
-
-	eIdx := strings.LastIndex(uri, "/")
-	var sIdx int
-	var source, vcsName, repoName string
-	if protoGit {
-
-		sIdx = strings.Index(uri, "@")
-		val := uri[sIdx+1 : eIdx]
-		valArr := strings.Split(val, ":")
-		source = valArr[0]
-		vcsName = valArr[1]
-		repoName = uri[eIdx+1:]
-
-	} else if protoHttps {
-
-		valArr := strings.Split(uri, "/")
-		source = valArr[2]
-		vcsName = valArr[3]
-		repoName = valArr[4]
+	source, vcsName, repoName := vcs.ParseGitUrl(uri)
+	if vcsName == "" || repoName == "" {
+		return nil, fmt.Errorf("Invalid repository uri '%s'", uri)
 	}
 
-	// TODO add source to repository such as github.com
-
 	// parse the repository name
 	account, err := r.teamStore.GetVCSByName(teamName, vcsName, source)
 	if err != nil {
@@ -143,14 +137,12 @@ func (r resolver) AddRepository(params graphql.ResolveParams) (interface{}, erro
 		return nil, fmt.Errorf("No account named %s from %s linked: %v", vcsName, source, err)
 	}
 
-	token, err := r.getToken(teamName, *account, p)
+	token, err := r.providers.GetToken(teamName, *account)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to fetch/refresh the token for account %s from %s: %v", vcsName, source, err)
 	}
 
 	// fetch the repository from VCSServer
-	repoName = strings.TrimSuffix(repoName, ".git")
-
 	repo, err := p.Search(token, vcsName, repoName)
 	if err != nil {
 		return nil, fmt.Errorf("Repository fetch from %s for %s failed: %v", source, vcsName, err)
@@ -162,6 +154,7 @@ func (r resolver) AddRepository(params graphql.ResolveParams) (interface{}, erro
 
 	repo.Team = teamName
 	repo.VcsID = account.ID
+	repo.Source = source
 	repo.Identifier = strings.Join([]string{source, vcsName}, "/")
 
 	var currentRepo types.Repository
@@ -180,34 +173,56 @@ func (r resolver) AddRepository(params graphql.ResolveParams) (interface{}, erro
 	return repo, err
 }
 
-// Gets the valid token
-// Checks whether the token is expired.
-// Expired token will get refreshed.
-func (r resolver) getToken(team string, a types.VCS, p providers.Provider) (string, error) {
+func (r resolver) EnableHook(params graphql.ResolveParams) (interface{}, error) {
 
-	// Never expire type token
-	if a.RefreshToken == "" {
-		return a.AccessToken, nil
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errRepositoryIDCantBeEmpty
 	}
 
-	// Token that requires frequent refresh
-	// check if the token is expired
-	if !a.TokenExpiry.Add(-expiryDelta).Before(time.Now()) {
-		return a.AccessToken, nil
+	repo, err := r.store.GetRepositoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
 	}
 
-	// Refresh the token
-	tok, err := p.RefreshToken(a.RefreshToken)
+	if repo.HookEnabled {
+		return repo, nil
+	}
 
-	a.AccessToken = tok.AccessToken
-	a.TokenExpiry = tok.Expiry
-	a.RefreshToken = tok.RefreshToken
+	if repo.Plain() {
+		return nil, errHookNotSupported
+	}
 
-	// persist the updated token information
-	err = r.teamStore.UpdateVCS(team, a)
+	account, err := r.teamStore.GetVCSByID(repo.Team, repo.VcsID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the vcs account linked to the repository: %v", err)
+	}
 
+	p, err := r.providers.Get(account.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("No provider found for %s: %v", account.Kind, err)
+	}
+
+	token, err := r.providers.GetToken(repo.Team, account)
 	if err != nil {
-		return "", fmt.Errorf("Failed to update VCS after token refreshed: %v", err)
+		return nil, fmt.Errorf("Failed to fetch/refresh the token for account %s: %v", account.Name, err)
 	}
-	return tok.AccessToken, nil
+
+	owner := repo.Owner()
+	if owner == "" {
+		owner = account.Name
+	}
+
+	err = p.CreateHook(token, owner, repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the hook on %s: %v", repo.Source, err)
+	}
+
+	repo.HookEnabled = true
+	err = r.store.UpdateRepository(repo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the repository: %v", err)
+	}
+
+	return repo, nil
 }
@@ -0,0 +1,225 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/pkg/storage"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// interval the expired builds are removed
+	cleanTick = time.Hour
+
+	// job the runs of the cleaner are claimed by
+	cleanJob = "retention"
+)
+
+// Cleaner ..
+// Removes the builds expired by the retention of their repositories, along
+// with their logs, archives and snapshots. The objects are removed before the
+// build, so a build that failed to be removed is retried on the next run.
+type Cleaner struct {
+	store            store.Build
+	teamStore        store.Team
+	repositoryStore  store.Repository
+	integrationStore store.Integration
+	jobStore         store.Job
+	logger           *logrus.Entry
+}
+
+// NewCleaner ..
+func NewCleaner(loggr logger.Loggr, s store.Shift) *Cleaner {
+
+	return &Cleaner{
+		store:            s.Build,
+		teamStore:        s.Team,
+		repositoryStore:  s.Repository,
+		integrationStore: s.Integration,
+		jobStore:         s.Job,
+		logger:           loggr.GetLogger("retention/cleaner"),
+	}
+}
+
+// Run ..
+// Removes the expired builds periodically, until the context is done
+func (cl *Cleaner) Run(ctx context.Context) {
+
+	t := time.NewTicker(cleanTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+
+			now := time.Now()
+
+			// one of the servers cleans at a time, the claim falls short
+			// of the tick so the server claiming ticks in time for the next
+			claimed, err := cl.jobStore.ClaimRun(cleanJob, now.Add(cleanTick-cleanTick/10), now)
+			if err != nil {
+				cl.logger.Errorf("Failed to claim the run of the cleaner: %v", err)
+				continue
+			}
+
+			if claimed {
+				cl.clean(now)
+			}
+		}
+	}
+}
+
+func (cl *Cleaner) clean(now time.Time) {
+
+	teams, err := cl.teamStore.GetRetainedTeams()
+	if err != nil {
+		cl.logger.Errorf("Failed to fetch the teams with retention: %v", err)
+		return
+	}
+
+	byName := map[string]types.Team{}
+	names := []string{}
+	for _, t := range teams {
+		byName[t.Name] = t
+		names = append(names, t.Name)
+	}
+
+	repos, err := cl.repositoryStore.GetRetainedRepositories(names)
+	if err != nil {
+		cl.logger.Errorf("Failed to fetch the repositories with retention: %v", err)
+		return
+	}
+
+	stors := map[string]*storage.ShiftStorage{}
+	for _, repo := range repos {
+
+		p := Policy(byName[repo.Team], repo)
+		if p == nil {
+			continue
+		}
+
+		builds, err := cl.store.FetchBuildHistory(repo.ID.Hex())
+		if err != nil {
+			cl.logger.Errorf("Failed to fetch the builds of repository %s: %v", repo.ID.Hex(), err)
+			continue
+		}
+
+		expired := Expire(*p, builds, now)
+		if len(expired) == 0 {
+			continue
+		}
+
+		cl.logger.Infof("Removing %d builds of repository %s (%s) expired by the retention", len(expired), repo.Name, repo.ID.Hex())
+
+		cl.remove(repo, builds, expired, stors)
+	}
+}
+
+func (cl *Cleaner) remove(repo types.Repository, builds []types.Build, expired []types.ExpiredBuild, stors map[string]*storage.ShiftStorage) {
+
+	byID := map[string]types.Build{}
+	for _, b := range builds {
+		byID[b.ID.Hex()] = b
+	}
+
+	// the builds failed to be removed are retried on the next run,
+	// the caches of their branches are kept until then
+	var removed []types.ExpiredBuild
+	for _, e := range expired {
+
+		b := byID[e.BuildID]
+
+		ss, err := cl.storage(b.StorageID, stors)
+		if err != nil {
+			cl.logger.Warnf("Failed to connect to the storage of build %s: %v", e.BuildID, err)
+			continue
+		}
+
+		if ss != nil && b.StoragePath != "" {
+
+			n, err := ss.RemoveBuild(&types.StorageMetadata{TeamID: b.Team, RepositoryID: b.RepositoryID, BuildID: e.BuildID, Path: b.StoragePath})
+			if err != nil {
+				cl.logger.Warnf("Failed to remove the objects of build %s: %v", e.BuildID, err)
+				continue
+			}
+			cl.logger.Debugf("Removed %d objects of build %s", n, e.BuildID)
+		}
+
+		err = cl.store.Remove(b.ID)
+		if err != nil && err != mgo.ErrNotFound {
+			cl.logger.Warnf("Failed to remove build %s: %v", e.BuildID, err)
+			continue
+		}
+
+		cl.logger.Infof("Removed build %s of branch %s: %s", e.BuildID, e.Branch, e.Reason)
+		removed = append(removed, e)
+	}
+
+	// any build of the branch tells the storage of the cache
+	paths := map[string]types.Build{}
+	for _, b := range builds {
+		if _, ok := paths[b.StoragePath]; !ok {
+			paths[b.StoragePath] = b
+		}
+	}
+
+	for _, path := range ExpiredCaches(builds, removed) {
+
+		b := paths[path]
+		ss, err := cl.storage(b.StorageID, stors)
+		if err != nil || ss == nil {
+			continue
+		}
+
+		_, err = ss.RemoveCache(&types.StorageMetadata{TeamID: b.Team, RepositoryID: b.RepositoryID, Branch: b.Branch, Path: path})
+		if err != nil {
+			cl.logger.Warnf("Failed to remove the cache of %s: %v", path, err)
+			continue
+		}
+		cl.logger.Infof("Removed the cache of branch %s of repository %s", b.Branch, repo.Name)
+	}
+}
+
+// storage ..
+// Connects to the storage of the build once per run, nil when the build
+// wasn't stored anywhere, or the storage is no longer integrated
+func (cl *Cleaner) storage(id string, stors map[string]*storage.ShiftStorage) (*storage.ShiftStorage, error) {
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, nil
+	}
+
+	if ss, ok := stors[id]; ok {
+		return ss, nil
+	}
+
+	var stor types.Storage
+	err := cl.integrationStore.FindByID(id, &stor)
+	if err == mgo.ErrNotFound {
+		stors[id] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := storage.New(cl.logger, &stor)
+	if err != nil {
+		return nil, err
+	}
+
+	stors[id] = ss
+	return ss, nil
+}
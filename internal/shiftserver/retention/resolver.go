@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/team"
+	"github.com/graphql-go/graphql"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	errInvalidRepositoryID = errors.New("Repository ID is invalid")
+	errInvalidRetention    = errors.New("Builds and days to keep cannot be negative")
+)
+
+// Resolver ...
+type Resolver interface {
+	FetchRetention(params graphql.ResolveParams) (interface{}, error)
+	SetRetention(params graphql.ResolveParams) (interface{}, error)
+	RemoveRetention(params graphql.ResolveParams) (interface{}, error)
+}
+
+type resolver struct {
+	store           store.Build
+	teamStore       store.Team
+	repositoryStore store.Repository
+	logger          *logrus.Entry
+	Ctx             context.Context
+}
+
+// NewResolver ...
+func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift) (Resolver, error) {
+
+	r := &resolver{
+		store:           s.Build,
+		teamStore:       s.Team,
+		repositoryStore: s.Repository,
+		logger:          loggr.GetLogger("graphql/retention"),
+		Ctx:             ctx,
+	}
+	return r, nil
+}
+
+// FetchRetention ..
+// Reports the builds and the caches removed by the retention on its next
+// run, of the repository or of all the repositories of the team. Nothing
+// is removed, it's a dry run.
+func (r *resolver) FetchRetention(params graphql.ResolveParams) (interface{}, error) {
+
+	t, repo, err := r.target(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []types.Repository
+	if repo != nil {
+		repos = append(repos, *repo)
+	} else {
+
+		err = r.repositoryStore.FindAll(bson.M{"team": t.Name}, &repos)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch the repositories: %v", err)
+		}
+	}
+
+	now := time.Now()
+
+	res := types.RetentionReport{}
+	for _, repo := range repos {
+
+		p := Policy(t, repo)
+		if p == nil {
+			continue
+		}
+
+		builds, err := r.store.FetchBuildHistory(repo.ID.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch the builds of repository %s: %v", repo.Name, err)
+		}
+
+		expired := Expire(*p, builds, now)
+		res.Builds = append(res.Builds, expired...)
+		res.Caches = append(res.Caches, ExpiredCaches(builds, expired)...)
+	}
+	res.Count = len(res.Builds)
+
+	return res, nil
+}
+
+// SetRetention ..
+// Sets the build history kept for the repository, or for
+// the repositories of the team when the repository isn't given
+func (r *resolver) SetRetention(params graphql.ResolveParams) (interface{}, error) {
+
+	t, repo, err := r.target(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &types.Retention{}
+	ret.KeepBuilds, _ = params.Args["keep_builds"].(int)
+	ret.KeepDays, _ = params.Args["keep_days"].(int)
+
+	if ret.KeepBuilds < 0 || ret.KeepDays < 0 {
+		return nil, errInvalidRetention
+	}
+
+	if repo != nil {
+		err = r.repositoryStore.UpdateRetention(repo.ID, ret)
+	} else {
+		err = r.teamStore.UpdateRetention(t.Name, ret)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the retention: %v", err)
+	}
+	return *ret, nil
+}
+
+// RemoveRetention ..
+// Removes the retention of the repository, the retention of the team
+// applies to it. The builds of the team are kept forever when the
+// repository isn't given.
+func (r *resolver) RemoveRetention(params graphql.ResolveParams) (interface{}, error) {
+
+	t, repo, err := r.target(params)
+	if err != nil {
+		return false, err
+	}
+
+	if repo != nil {
+		err = r.repositoryStore.UpdateRetention(repo.ID, nil)
+	} else {
+		err = r.teamStore.UpdateRetention(t.Name, nil)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to remove the retention: %v", err)
+	}
+	return true, nil
+}
+
+// target ..
+// Returns the team, and the repository of the team if it's given
+func (r *resolver) target(params graphql.ResolveParams) (types.Team, *types.Repository, error) {
+
+	teamName, _ := params.Args["team"].(string)
+	if teamName == "" {
+		return types.Team{}, nil, team.ErrTeamNameIsEmpty
+	}
+
+	t, err := r.teamStore.GetTeam("", teamName)
+	if err != nil {
+		return types.Team{}, nil, fmt.Errorf("Failed to fetch the team: %v", err)
+	}
+
+	id, _ := params.Args["repository_id"].(string)
+	if id == "" {
+		return t, nil, nil
+	}
+
+	if !bson.IsObjectIdHex(id) {
+		return t, nil, errInvalidRepositoryID
+	}
+
+	repo, err := r.repositoryStore.GetRepositoryByID(id)
+	if err != nil || repo.Team != t.Name {
+		return t, nil, errInvalidRepositoryID
+	}
+	return t, &repo, nil
+}
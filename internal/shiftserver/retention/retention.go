@@ -0,0 +1,133 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package retention
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+)
+
+type branchKey struct {
+	branch   string
+	pipeline string
+}
+
+// Policy ..
+// Returns the retention of the repository, or the retention of the team
+// when the repository isn't configured with one. Nil if neither is.
+func Policy(t types.Team, repo types.Repository) *types.Retention {
+
+	if repo.Retention != nil {
+		return repo.Retention
+	}
+	return t.Retention
+}
+
+// Expire ..
+// Returns the builds expired by the retention, the builds of the repository
+// are given from the newest to the oldest. The builds in progress, the last
+// successful build of each branch, and the builds whose snapshots are
+// restored by the kept reruns are never expired.
+func Expire(p types.Retention, builds []types.Build, now time.Time) []types.ExpiredBuild {
+
+	if p.KeepBuilds <= 0 && p.KeepDays <= 0 {
+		return nil
+	}
+
+	maxAge := time.Duration(p.KeepDays) * 24 * time.Hour
+
+	var expired []types.ExpiredBuild
+	successful := map[branchKey]bool{}
+	snapshots := map[string]bool{}
+	for i, b := range builds {
+
+		status := build.Status(b)
+		switch status {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+			keep(b, snapshots)
+			continue
+		}
+
+		key := branchKey{b.Branch, b.Pipeline}
+		if status == types.BuildStatusSuccess && !successful[key] {
+			successful[key] = true
+			keep(b, snapshots)
+			continue
+		}
+
+		created := b.ID.Time()
+
+		var reason string
+		switch {
+		case p.KeepBuilds > 0 && i >= p.KeepBuilds:
+			reason = fmt.Sprintf("Beyond the latest %d builds", p.KeepBuilds)
+		case p.KeepDays > 0 && now.Sub(created) > maxAge:
+			reason = fmt.Sprintf("Older than %d days", p.KeepDays)
+		}
+
+		if reason == "" {
+			keep(b, snapshots)
+			continue
+		}
+
+		expired = append(expired, types.ExpiredBuild{
+			BuildID:      b.ID.Hex(),
+			RepositoryID: b.RepositoryID,
+			Branch:       b.Branch,
+			Pipeline:     b.Pipeline,
+			Status:       status,
+			CreatedAt:    created,
+			Reason:       reason,
+		})
+	}
+
+	// the reruns are newer than the builds they restore the snapshots from
+	var result []types.ExpiredBuild
+	for _, e := range expired {
+		if !snapshots[e.BuildID] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func keep(b types.Build, snapshots map[string]bool) {
+
+	if b.SnapshotOf != "" {
+		snapshots[b.SnapshotOf] = true
+	}
+}
+
+// ExpiredCaches ..
+// Returns the storage paths of the branches whose builds are all expired,
+// their caches are no longer restored by any build
+func ExpiredCaches(builds []types.Build, expired []types.ExpiredBuild) []string {
+
+	ids := map[string]bool{}
+	for _, e := range expired {
+		ids[e.BuildID] = true
+	}
+
+	kept := map[string]bool{}
+	for _, b := range builds {
+		if !ids[b.ID.Hex()] {
+			kept[b.StoragePath] = true
+		}
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, b := range builds {
+
+		if b.StoragePath == "" || kept[b.StoragePath] || seen[b.StoragePath] {
+			continue
+		}
+		seen[b.StoragePath] = true
+		paths = append(paths, b.StoragePath)
+	}
+	return paths
+}
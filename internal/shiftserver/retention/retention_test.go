@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var now = time.Date(2018, 6, 30, 10, 0, 0, 0, time.UTC)
+
+func testBuild(days int, branch, status string) types.Build {
+	return types.Build{
+		ID:          bson.NewObjectIdWithTime(now.Add(-time.Duration(days) * 24 * time.Hour)),
+		Branch:      branch,
+		StoragePath: "team/repo/" + branch,
+		SubBuilds:   []types.SubBuild{{ID: "1", Status: status}},
+	}
+}
+
+func expiredIDs(expired []types.ExpiredBuild) string {
+
+	var ids []string
+	for _, e := range expired {
+		ids = append(ids, e.BuildID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestPolicy(t *testing.T) {
+
+	team := &types.Retention{KeepBuilds: 10}
+	repo := &types.Retention{KeepDays: 7}
+
+	if p := Policy(types.Team{Retention: team}, types.Repository{Retention: repo}); p != repo {
+		t.Errorf("expected the retention of the repository, got %+v", p)
+	}
+
+	if p := Policy(types.Team{Retention: team}, types.Repository{}); p != team {
+		t.Errorf("expected the retention of the team, got %+v", p)
+	}
+
+	if p := Policy(types.Team{}, types.Repository{}); p != nil {
+		t.Errorf("expected no retention, got %+v", p)
+	}
+}
+
+func TestExpire(t *testing.T) {
+
+	// newest first
+	running := testBuild(0, "master", types.BuildStatusRunning)
+	failed := testBuild(1, "master", types.BuildStatusFailed)
+	success := testBuild(2, "master", types.BuildStatusSuccess)
+	older := testBuild(10, "master", types.BuildStatusSuccess)
+	feature := testBuild(20, "feature", types.BuildStatusFailed)
+	featureSuccess := testBuild(30, "feature", types.BuildStatusSuccess)
+
+	builds := []types.Build{running, failed, success, older, feature, featureSuccess}
+
+	tests := []struct {
+		name    string
+		policy  types.Retention
+		builds  []types.Build
+		expired []types.Build
+	}{
+		{"no retention", types.Retention{}, builds, nil},
+		{"keep builds", types.Retention{KeepBuilds: 2}, builds, []types.Build{older, feature}},
+		{"keep days", types.Retention{KeepDays: 5}, builds, []types.Build{older, feature}},
+		{"within both", types.Retention{KeepBuilds: 10, KeepDays: 60}, builds, nil},
+
+		// the last successful build of each branch is kept
+		{"keep one", types.Retention{KeepBuilds: 1}, builds, []types.Build{failed, older, feature}},
+	}
+
+	for _, tt := range tests {
+
+		expired := Expire(tt.policy, tt.builds, now)
+		if ids := expiredIDs(expired); ids != expiredIDs(toExpired(tt.expired)) {
+			t.Errorf("%s: unexpected expired builds %s", tt.name, ids)
+		}
+
+		for _, e := range expired {
+			if e.Reason == "" || e.Status == "" {
+				t.Errorf("%s: expected the reason and the status of %s", tt.name, e.BuildID)
+			}
+		}
+	}
+}
+
+func TestExpireSnapshot(t *testing.T) {
+
+	failed := testBuild(10, "master", types.BuildStatusFailed)
+	success := testBuild(1, "master", types.BuildStatusSuccess)
+
+	// the rerun resumed from the failed build restores its snapshot
+	rerun := testBuild(0, "master", types.BuildStatusFailed)
+	rerun.SnapshotOf = failed.ID.Hex()
+
+	expired := Expire(types.Retention{KeepDays: 5}, []types.Build{rerun, success, failed}, now)
+	if len(expired) != 0 {
+		t.Fatalf("expected the build holding the snapshot kept, got %s", expiredIDs(expired))
+	}
+}
+
+func TestExpiredCaches(t *testing.T) {
+
+	master := testBuild(0, "master", types.BuildStatusSuccess)
+	feature := testBuild(1, "feature", types.BuildStatusFailed)
+	featureOld := testBuild(2, "feature", types.BuildStatusFailed)
+	unstored := testBuild(3, "", types.BuildStatusFailed)
+	unstored.StoragePath = ""
+
+	builds := []types.Build{master, feature, featureOld, unstored}
+
+	tests := []struct {
+		name    string
+		expired []types.Build
+		caches  []string
+	}{
+		{"none expired", nil, nil},
+		{"branch partly expired", []types.Build{featureOld}, nil},
+		{"branch expired", []types.Build{feature, featureOld, unstored}, []string{"team/repo/feature"}},
+	}
+
+	for _, tt := range tests {
+
+		caches := ExpiredCaches(builds, toExpired(tt.expired))
+		if strings.Join(caches, ",") != strings.Join(tt.caches, ",") {
+			t.Errorf("%s: expected the caches %v, got %v", tt.name, tt.caches, caches)
+		}
+	}
+}
+
+func toExpired(builds []types.Build) []types.ExpiredBuild {
+
+	var expired []types.ExpiredBuild
+	for _, b := range builds {
+		expired = append(expired, types.ExpiredBuild{BuildID: b.ID.Hex()})
+	}
+	return expired
+}
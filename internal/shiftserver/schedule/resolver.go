@@ -0,0 +1,303 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/cron"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/team"
+	"github.com/graphql-go/graphql"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	errIDCantBeEmpty           = errors.New("Schedule ID cannot be empty or invalid")
+	errRepositoryIDCantBeEmpty = errors.New("Repository ID cannot be empty")
+	errCronCantBeEmpty         = errors.New("Cron expression cannot be empty")
+)
+
+// Resolver ...
+type Resolver interface {
+	FetchSchedules(params graphql.ResolveParams) (interface{}, error)
+	AddSchedule(params graphql.ResolveParams) (interface{}, error)
+	UpdateSchedule(params graphql.ResolveParams) (interface{}, error)
+	DeleteSchedule(params graphql.ResolveParams) (interface{}, error)
+}
+
+type resolver struct {
+	store           store.Schedule
+	repositoryStore store.Repository
+	logger          *logrus.Entry
+	Ctx             context.Context
+}
+
+// NewResolver ...
+func NewResolver(ctx context.Context, loggr logger.Loggr, s store.Shift) (Resolver, error) {
+
+	r := &resolver{
+		store:           s.Schedule,
+		repositoryStore: s.Repository,
+		logger:          loggr.GetLogger("graphql/schedule"),
+		Ctx:             ctx,
+	}
+	return r, nil
+}
+
+func (r *resolver) FetchSchedules(params graphql.ResolveParams) (interface{}, error) {
+
+	teamName, _ := params.Args["team"].(string)
+	if teamName == "" {
+		return nil, team.ErrTeamNameIsEmpty
+	}
+	repositoryID, _ := params.Args["repository_id"].(string)
+
+	result, err := r.store.FetchSchedules(teamName, repositoryID)
+
+	var res types.ScheduleList
+	res.Nodes = result
+	res.Count = len(res.Nodes)
+
+	return &res, err
+}
+
+func (r *resolver) AddSchedule(params graphql.ResolveParams) (interface{}, error) {
+
+	repositoryID, _ := params.Args["repository_id"].(string)
+	if repositoryID == "" {
+		return nil, errRepositoryIDCantBeEmpty
+	}
+
+	repo, err := r.repositoryStore.GetRepositoryByID(repositoryID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	s := types.Schedule{}
+	s.ID = bson.NewObjectId()
+	s.Team = repo.Team
+	s.RepositoryID = repositoryID
+	s.Enabled = true
+	s.CreatedBy = "Anonymous" //TODO fill in with logged-in user
+	s.CreatedAt = time.Now()
+
+	err = r.apply(&s, repo, params.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Cron == "" {
+		return nil, errCronCantBeEmpty
+	}
+
+	err = r.store.Save(&s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save the schedule: %v", err)
+	}
+	return s, nil
+}
+
+func (r *resolver) UpdateSchedule(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if id == "" {
+		return nil, errIDCantBeEmpty
+	}
+
+	var s types.Schedule
+	err := r.store.FindByID(id, &s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the schedule: %v", err)
+	}
+
+	repo, err := r.repositoryStore.GetRepositoryByID(s.RepositoryID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the repository: %v", err)
+	}
+
+	err = r.apply(&s, repo, params.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.UpdateId(s.ID, s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update the schedule: %v", err)
+	}
+	return s, nil
+}
+
+func (r *resolver) DeleteSchedule(params graphql.ResolveParams) (interface{}, error) {
+
+	id, _ := params.Args["id"].(string)
+	if !bson.IsObjectIdHex(id) {
+		return nil, errIDCantBeEmpty
+	}
+
+	err := r.store.Remove(bson.ObjectIdHex(id))
+	if err != nil {
+		return false, fmt.Errorf("Failed to delete the schedule: %v", err)
+	}
+	return true, nil
+}
+
+// apply ..
+// Sets the arguments given on the schedule, and computes the next run
+func (r *resolver) apply(s *types.Schedule, repo types.Repository, args map[string]interface{}) error {
+
+	if v, ok := args["cron"].(string); ok {
+		s.Cron = v
+	}
+
+	if v, ok := args["timezone"].(string); ok {
+		s.Timezone = v
+	}
+
+	if v, ok := args["branch"].(string); ok {
+		s.Branch = v
+	}
+
+	if v, ok := args["pipeline"].(string); ok {
+		s.Pipeline = v
+	}
+
+	if v, ok := args["priority"].(int); ok {
+		s.Priority = v
+	}
+
+	if v, ok := args["enabled"].(bool); ok {
+		s.Enabled = v
+	}
+
+	if v, ok := args["rebuild_cache"].(bool); ok {
+		s.Directives.RebuildCache = v
+	}
+
+	if v, ok := args["debug"].(bool); ok {
+		s.Directives.Debug = v
+	}
+
+	if v, ok := args["only"].([]interface{}); ok {
+
+		s.Directives.Only = nil
+		for _, name := range v {
+			if n, _ := name.(string); n != "" {
+				s.Directives.Only = append(s.Directives.Only, n)
+			}
+		}
+	}
+
+	if v, ok := args["parameters"].([]interface{}); ok {
+
+		params, err := toParameters(v)
+		if err != nil {
+			return err
+		}
+		s.Parameters = params
+	}
+
+	if s.Pipeline == "" && len(repo.Pipelines) > 0 {
+		return fmt.Errorf("Pipeline is required to schedule the build, the repository is configured with pipelines")
+	}
+
+	if s.Pipeline != "" && !hasPipeline(repo, s.Pipeline) {
+		return fmt.Errorf("Pipeline '%s' is not configured for the repository", s.Pipeline)
+	}
+
+	if s.Cron == "" {
+		return nil
+	}
+
+	next, err := NextRun(*s, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if s.Enabled {
+		s.NextRunAt = next
+	}
+	return nil
+}
+
+// NextRun ..
+// Returns the time the schedule runs next, after the given time
+func NextRun(s types.Schedule, after time.Time) (time.Time, error) {
+
+	loc := time.UTC
+	if s.Timezone != "" {
+
+		var err error
+		loc, err = time.LoadLocation(s.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid time zone '%s': %v", s.Timezone, err)
+		}
+	}
+
+	c, err := cron.Parse(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := c.Next(after.In(loc))
+	if next.IsZero() {
+		return next, fmt.Errorf("The schedule '%s' never runs", s.Cron)
+	}
+	return next.UTC(), nil
+}
+
+// toParameters ..
+// Converts the name and value pairs given to the parameters of the builds.
+// The names of the environment set by the server are refused.
+func toParameters(args []interface{}) (map[string]string, error) {
+
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+
+		val, _ := arg.(map[string]interface{})
+		name, _ := val["name"].(string)
+		value, _ := val["value"].(string)
+
+		if name == "" {
+			return nil, fmt.Errorf("Parameter name cannot be empty")
+		}
+
+		if build.ReservedParameter(name) {
+			return nil, fmt.Errorf("Parameter '%s' is reserved for the environment set by the server", name)
+		}
+
+		if _, ok := params[name]; ok {
+			return nil, fmt.Errorf("Parameter '%s' is given more than once", name)
+		}
+		params[name] = value
+	}
+	return params, nil
+}
+
+// hasPipeline ..
+// A pipeline is either configured by the name, or discovered from the
+// shiftfiles matching the glob, named by the directory of the shiftfile.
+func hasPipeline(repo types.Repository, name string) bool {
+
+	for _, p := range repo.Pipelines {
+
+		if p.Name == name {
+			return true
+		}
+
+		if utils.IsGlob(p.Shiftfile) && utils.MatchGlob(path.Dir(p.Shiftfile), name) {
+			return true
+		}
+	}
+	return false
+}
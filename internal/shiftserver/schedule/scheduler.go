@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/resolver"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/sirupsen/logrus"
+)
+
+// triggered by, for the builds started by a schedule
+const scheduleTriggeredBy = "scheduler"
+
+// interval the scheduler looks for the schedules that are due
+var scheduleTick = 20 * time.Second
+
+// Scheduler ..
+// Triggers the builds of the schedules that are due. Every server runs
+// the scheduler, a run is fired by the server that claims it first by
+// moving the next run, so it's fired once even across the restarts.
+type Scheduler struct {
+	store           store.Schedule
+	repositoryStore store.Repository
+	rs              *resolver.Shift
+	logger          *logrus.Entry
+}
+
+// NewScheduler ..
+func NewScheduler(loggr logger.Loggr, s store.Shift, rs *resolver.Shift) *Scheduler {
+
+	return &Scheduler{
+		store:           s.Schedule,
+		repositoryStore: s.Repository,
+		rs:              rs,
+		logger:          loggr.GetLogger("schedule/scheduler"),
+	}
+}
+
+// Run ..
+// Fires the schedules that are due, until the context is done
+func (sc *Scheduler) Run(ctx context.Context) {
+
+	t := time.NewTicker(scheduleTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			sc.fire(time.Now())
+		}
+	}
+}
+
+func (sc *Scheduler) fire(now time.Time) {
+
+	due, err := sc.store.FetchDueSchedules(now)
+	if err != nil {
+		sc.logger.Errorf("Failed to fetch the schedules that are due: %v", err)
+		return
+	}
+
+	for _, s := range due {
+
+		// the runs missed while the servers were down are fired once
+		next, err := NextRun(s, now)
+		if err != nil {
+			sc.logger.Errorf("Failed to compute the next run of schedule %s: %v", s.ID.Hex(), err)
+			continue
+		}
+
+		// other servers may fire the same schedule
+		claimed, err := sc.store.ClaimRun(s.ID, s.NextRunAt, next, now)
+		if err != nil {
+			sc.logger.Errorf("Failed to claim the run of schedule %s: %v", s.ID.Hex(), err)
+			continue
+		}
+
+		if claimed {
+			sc.trigger(s)
+		}
+	}
+}
+
+// trigger ..
+// Triggers the build of the schedule, the same way it's triggered through the api
+func (sc *Scheduler) trigger(s types.Schedule) {
+
+	var buildID, reason string
+
+	repo, err := sc.repositoryStore.GetRepositoryByID(s.RepositoryID)
+	if err == nil {
+
+		opts := build.TriggerOptions{}
+		opts.Repository = repo
+		opts.Branch = s.Branch
+		opts.Pipeline = s.Pipeline
+		opts.Priority = s.Priority
+		opts.Directives = s.Directives
+		opts.Parameters = s.Parameters
+		opts.TriggeredBy = scheduleTriggeredBy
+
+		var b types.Build
+		b, err = sc.rs.Build.Trigger(opts)
+		buildID = b.ID.Hex()
+	}
+
+	if err != nil {
+		sc.logger.Errorf("Failed to trigger the build of schedule %s: %v", s.ID.Hex(), err)
+		reason = err.Error()
+	}
+
+	err = sc.store.UpdateLastRun(s.ID, buildID, reason)
+	if err != nil {
+		sc.logger.Errorf("Failed to record the last run of schedule %s: %v", s.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package schema
+
+import (
+	"context"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/analytics"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/graphql-go/graphql"
+)
+
+func newAnalyticsSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (queries graphql.Fields, mutations graphql.Fields) {
+
+	r, _ := analytics.NewResolver(ctx, loggr, s)
+
+	dayType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "AnalyticsDay",
+			Fields: graphql.Fields{
+
+				"day": &graphql.Field{
+					Type:        graphql.DateTime,
+					Description: "Day the builds were triggered, in UTC",
+				},
+
+				"builds": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of finished builds",
+				},
+
+				"succeeded": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of successful builds",
+				},
+
+				"failed": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of failed builds",
+				},
+
+				"cancelled": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of cancelled builds",
+				},
+
+				"stuck": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of builds reaped as stuck",
+				},
+
+				"success_rate": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Ratio of the successful builds, the cancelled builds aren't counted",
+				},
+			},
+			Description: "The builds of a day",
+		},
+	)
+
+	nodeType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "NodeAnalytics",
+			Fields: graphql.Fields{
+
+				"node": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Name of the node",
+				},
+
+				"runs": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of times the node ran",
+				},
+
+				"failures": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of times the node failed",
+				},
+
+				"failure_rate": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Ratio of the failed runs",
+				},
+
+				"average_duration": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Average time the node took, in seconds",
+				},
+
+				"max_duration": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Longest time the node took, in seconds",
+				},
+			},
+			Description: "The runs of a node of the builds",
+		},
+	)
+
+	analyticsType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Analytics",
+			Fields: graphql.Fields{
+
+				"from": &graphql.Field{
+					Type:        graphql.DateTime,
+					Description: "Start of the first day",
+				},
+
+				"to": &graphql.Field{
+					Type:        graphql.DateTime,
+					Description: "End of the last day",
+				},
+
+				"builds": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of finished builds",
+				},
+
+				"success_rate": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Ratio of the successful builds, the cancelled builds aren't counted",
+				},
+
+				"duration_p50": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Median time the builds took, in seconds",
+				},
+
+				"duration_p95": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "95th percentile of the time the builds took, in seconds",
+				},
+
+				"wait_p50": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Median time the builds waited in the queue, in seconds",
+				},
+
+				"wait_p95": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "95th percentile of the time the builds waited in the queue, in seconds",
+				},
+
+				"series": &graphql.Field{
+					Type:        graphql.NewList(dayType),
+					Description: "The builds of each day, from the oldest",
+				},
+
+				"slowest_nodes": &graphql.Field{
+					Type:        graphql.NewList(nodeType),
+					Description: "Nodes taking the longest on average",
+				},
+
+				"failing_nodes": &graphql.Field{
+					Type:        graphql.NewList(nodeType),
+					Description: "Nodes failing the most",
+				},
+			},
+			Description: "Metrics of the finished builds over the days",
+		},
+	)
+
+	flakyNodeType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "FlakyNode",
+			Fields: graphql.Fields{
+
+				"node": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Description of the block",
+				},
+
+				"runs": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of times the node ran",
+				},
+
+				"failures": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of times the node failed",
+				},
+
+				"reruns": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of times the node ran again on the same commit",
+				},
+
+				"flips": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of reruns on the same commit that passed after a failure, or failed after a pass",
+				},
+
+				"commits": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of commits the node both failed and passed on",
+				},
+
+				"score": &graphql.Field{
+					Type:        graphql.Float,
+					Description: "Flakiness of the node, the ratio of the reruns that flipped",
+				},
+
+				"last_build_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "The latest build the node flipped in",
+				},
+
+				"last_flaked_at": &graphql.Field{
+					Type:        graphql.DateTime,
+					Description: "Time the node last flipped",
+				},
+
+				"quarantined": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "Whether the node is allowed to fail without failing the build",
+				},
+			},
+			Description: "A node that both failed and passed on the same commit",
+		},
+	)
+
+	// the repository of the team
+	repoArgs := func(more graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+
+		a := graphql.FieldConfigArgument{
+			"team": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Name of the team",
+			},
+			"repository_id": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Repository identifier",
+			},
+		}
+
+		for k, v := range more {
+			a[k] = v
+		}
+		return a
+	}
+
+	nodeArg := graphql.FieldConfigArgument{
+		"node": &graphql.ArgumentConfig{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Description of the block",
+		},
+	}
+
+	queries = graphql.Fields{
+
+		"flakyNodes": &graphql.Field{
+			Type:        graphql.NewList(flakyNodeType),
+			Description: "Finds the nodes of the repository that both failed and passed on the same commit, from the flakiest",
+			Args: repoArgs(graphql.FieldConfigArgument{
+				"days": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Number of days up to today, 30 by default",
+				},
+			}),
+			Resolve: r.FetchFlakyNodes,
+		},
+
+		"analytics": &graphql.Field{
+			Type:        analyticsType,
+			Description: "Computes the metrics of the builds of the team, the repository or the branch, over the days up to today",
+			Args: graphql.FieldConfigArgument{
+				"team": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Name of the team",
+				},
+				"repository_id": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Repository identifier, all the repositories of the team if empty",
+				},
+				"branch": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Branch of the repository, all the branches if empty",
+				},
+				"days": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Number of days up to today, 30 by default",
+				},
+				"nodes": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Number of the slowest and the most failing nodes, 10 by default",
+				},
+			},
+			Resolve: r.FetchAnalytics,
+		},
+	}
+
+	mutations = graphql.Fields{
+
+		"quarantineNode": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Allows the block to fail without failing the builds of the repository, returns the quarantined blocks",
+			Args:        repoArgs(nodeArg),
+			Resolve:     r.QuarantineNode,
+		},
+
+		"unquarantineNode": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Fails the builds of the repository again when the block fails, returns the quarantined blocks",
+			Args:        repoArgs(nodeArg),
+			Resolve:     r.UnquarantineNode,
+		},
+	}
+
+	return queries, mutations
+}
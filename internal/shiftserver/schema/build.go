@@ -5,14 +5,17 @@ package schema
 
 import (
 	"context"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
 	"github.com/elasticshift/elasticshift/internal/pkg/utils"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/resolver"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
 )
 
@@ -67,6 +70,11 @@ var (
 			Description: "Time when the build completed",
 		},
 
+		"heartbeat_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Last time the worker reported alive, the build is stuck when the heartbeats are missed",
+		},
+
 		"status": &graphql.Field{
 			Type:        buildStatusEnum,
 			Description: "The status of the build",
@@ -112,6 +120,41 @@ var (
 			Type:        graphql.String,
 			Description: "Duration of the actual build time",
 		},
+
+		"estimate": &graphql.Field{
+			Type:        graphql.Float,
+			Description: "Seconds the build is expected to take, from the recent runs of its nodes on the branch",
+		},
+
+		"progress": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Percentage of the build done, from the estimates of its nodes",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+
+				if t, ok := p.Source.(types.SubBuild); ok {
+					progress, _ := build.Progress(t, time.Now())
+					return progress, nil
+				}
+				return nil, nil
+			},
+		},
+
+		"eta": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the build is expected to end, the time it ended once it's finished",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+
+				if t, ok := p.Source.(types.SubBuild); ok {
+
+					_, eta := build.Progress(t, time.Now())
+					if eta.IsZero() {
+						return nil, nil
+					}
+					return eta, nil
+				}
+				return nil, nil
+			},
+		},
 	}
 
 	subBuildType = graphql.NewObject(
@@ -122,6 +165,74 @@ var (
 		},
 	)
 
+	directivesType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "BuildDirectives",
+			Fields: graphql.Fields{
+
+				"skip": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "True if the commit directed to skip the build",
+				},
+
+				"rebuild_cache": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "True if the cache is rebuilt, instead of restored",
+				},
+
+				"only": &graphql.Field{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Names of the blocks to run, the rest of the blocks are skipped",
+				},
+
+				"debug": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "True if the build runs with verbose logging",
+				},
+			},
+			Description: "Directives given to the build through the commit message or the pull request labels",
+		},
+	)
+
+	buildLinkType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "BuildLink",
+			Fields: graphql.Fields{
+
+				"build_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Build identifier",
+				},
+
+				"repository_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Repository identifier",
+				},
+
+				"branch": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Branch of the build",
+				},
+
+				"pipeline": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Pipeline of the build",
+				},
+
+				"node": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Trigger block of the upstream build",
+				},
+
+				"wait": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "True if the upstream build waits for the downstream build to finish",
+				},
+			},
+			Description: "Links the builds triggered one from another",
+		},
+	)
+
 	fields = graphql.Fields{
 		"id": &graphql.Field{
 			Type:        graphql.ID,
@@ -134,6 +245,11 @@ var (
 			},
 		},
 
+		"number": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Sequential number of the build in the repository, such as 512 of build #512",
+		},
+
 		"repository_id": &graphql.Field{
 			Type:        graphql.String,
 			Description: "Repository identifier",
@@ -164,6 +280,78 @@ var (
 			Description: "The branch to which the build is/was triggered",
 		},
 
+		"pipeline": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The repository pipeline the build is/was triggered for",
+		},
+
+		"shiftfile": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Path of the Shiftfile of the pipeline in the repository",
+		},
+
+		"directives": &graphql.Field{
+			Type:        directivesType,
+			Description: "Directives given through the commit message or the pull request labels, such as [skip ci]",
+		},
+
+		"priority": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Priority of the build in the queue, higher is launched first",
+		},
+
+		"concurrency": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Concurrency group of the build given in the Shiftfile, the builds of a group run one at a time",
+		},
+
+		"held_by": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Concurrency group the queued build is waiting for, held by another build",
+		},
+
+		"superseded_by": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Build of a newer commit on the branch that cancelled this build",
+		},
+
+		"rerun_of": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Build that this build reruns",
+		},
+
+		"from_node": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Node the rerun is resumed from, the nodes before are reused",
+		},
+
+		"upstream": &graphql.Field{
+			Type:        buildLinkType,
+			Description: "Build that triggered this build through its trigger block",
+		},
+
+		"downstream": &graphql.Field{
+			Type:        graphql.NewList(buildLinkType),
+			Description: "Builds triggered by this build",
+		},
+
+		"queue_position": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Position of the build in the launch queue, zero when not queued",
+		},
+
+		"estimated_start": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Estimated time the queued build is launched",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+
+				if t, ok := p.Source.(types.Build); ok && t.QueuePosition > 0 {
+					return t.EstimatedStart, nil
+				}
+				return nil, nil
+			},
+		},
+
 		"sub_builds": &graphql.Field{
 			Type:        graphql.NewList(subBuildType),
 			Description: "Build status and other info",
@@ -192,9 +380,11 @@ func newBuildSchema(
 	s store.Shift,
 	ps pubsub.Engine,
 	rs *resolver.Shift,
+	providers providers.Providers,
+	vault secret.Vault,
 ) (queries graphql.Fields, mutations graphql.Fields, subscriptions graphql.Fields) {
 
-	r, _ := build.NewResolver(ctx, loggr, s, ps)
+	r, _ := build.NewResolver(ctx, loggr, s, ps, providers, vault)
 	rs.Build = r
 
 	buildArgs := graphql.FieldConfigArgument{
@@ -212,19 +402,124 @@ func newBuildSchema(
 			Type:        graphql.String,
 			Description: "Build Identifier",
 		},
+
+		"number": &graphql.ArgumentConfig{
+			Type:        graphql.Int,
+			Description: "Sequential number of the build in the repository, such as 512 of build #512",
+		},
+
 		"branch": &graphql.ArgumentConfig{
 			Type:        graphql.String,
 			Description: "Status of the build",
 		},
 
+		"pipeline": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Pipeline of the repository",
+		},
+
 		"status": &graphql.ArgumentConfig{
 			Type:        buildStatusEnum,
 			Description: "Status of the build",
 		},
 	}
 
+	pipelineNodeType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "PipelineNode",
+			Fields: graphql.Fields{
+				"build_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Build identifier",
+				},
+				"number": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Sequential number of the build in the repository",
+				},
+				"repository_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Repository identifier",
+				},
+				"repository": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Name of the repository",
+				},
+				"branch": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Branch of the build",
+				},
+				"pipeline": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Pipeline of the build",
+				},
+				"status": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Status of the build",
+				},
+				"triggered_by": &graphql.Field{
+					Type:        graphql.String,
+					Description: "User, or the build triggered the build",
+				},
+			},
+			Description: "A build of the pipeline",
+		},
+	)
+
+	pipelineEdgeType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "PipelineEdge",
+			Fields: graphql.Fields{
+				"from": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Upstream build identifier",
+				},
+				"to": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Downstream build identifier",
+				},
+				"node": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Trigger block of the upstream build",
+				},
+				"wait": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "True if the upstream build waits for the downstream build to finish",
+				},
+			},
+			Description: "An upstream build triggering a downstream build",
+		},
+	)
+
+	pipelineGraphType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "PipelineGraph",
+			Fields: graphql.Fields{
+				"nodes": &graphql.Field{
+					Type:        graphql.NewList(pipelineNodeType),
+					Description: "Builds of the pipeline",
+				},
+				"edges": &graphql.Field{
+					Type:        graphql.NewList(pipelineEdgeType),
+					Description: "Builds triggered one from another",
+				},
+			},
+			Description: "The builds linked across the repositories through the trigger blocks",
+		},
+	)
+
 	queries = graphql.Fields{
 		"build": utils.MakeListType("BuildList", BuildType, r.FetchBuild, buildArgs),
+
+		"pipeline": &graphql.Field{
+			Type: pipelineGraphType,
+			Args: graphql.FieldConfigArgument{
+				"build_id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Any of the builds of the pipeline",
+				},
+			},
+			Resolve: r.FetchPipelineGraph,
+		},
 	}
 
 	mutations = graphql.Fields{
@@ -239,6 +534,14 @@ func newBuildSchema(
 					Type:        graphql.String,
 					Description: "Key for the elasticshift oauth application",
 				},
+				"pipeline": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Pipeline to build, required when the repository is configured with pipelines",
+				},
+				"priority": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Priority of the build in the queue, defaults to the priority of the repository",
+				},
 			},
 			Resolve: r.TriggerBuild,
 		},
@@ -253,6 +556,21 @@ func newBuildSchema(
 			},
 			Resolve: r.CancelBuild,
 		},
+
+		"rerunBuild": &graphql.Field{
+			Type: BuildType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Identifier of the build to rerun",
+				},
+				"from_node": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Node to resume the build from, the nodes before are reused. Reruns from the start if empty",
+				},
+			},
+			Resolve: r.RerunBuild,
+		},
 	}
 
 	subscriptions = graphql.Fields{
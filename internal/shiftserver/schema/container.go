@@ -112,6 +112,16 @@ func newContainerSchema(ctx context.Context, loggr logger.Loggr, s store.Shift)
 				return nil, nil
 			},
 		},
+
+		"team": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Team of the container engine",
+		},
+
+		"reason": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The reason the container was removed by the garbage collector",
+		},
 	}
 
 	containerType := graphql.NewObject(
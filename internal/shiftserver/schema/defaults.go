@@ -92,6 +92,11 @@ func newDefaultsSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (
 			Description: "Storage identifier",
 		},
 
+		"max_concurrent_builds": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Maximum builds of the team run at a time, unlimited when zero",
+		},
+
 		"languages": &graphql.Field{
 			Type:        graphql.String,
 			Description: "Default Language specification (shiftfile)",
@@ -175,6 +180,10 @@ func newDefaultsSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (
 					Type:        graphql.String,
 					Description: "Default language description file (shift file), this should be in json format with key value pair",
 				},
+				"max_concurrent_builds": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum builds of the team run at a time, zero is unlimited",
+				},
 			},
 			Resolve: r.SetDefaults,
 		},
@@ -146,6 +146,11 @@ func newIntegrationSchema(ctx context.Context, loggr logger.Loggr, s store.Shift
 			Type:        graphql.String,
 			Description: "Team Identifier",
 		},
+
+		"max_concurrent_builds": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Maximum builds run on the cluster at a time, unlimited when zero",
+		},
 	}
 
 	minioFields := graphql.Fields{
@@ -470,6 +475,10 @@ func newIntegrationSchema(ctx context.Context, loggr logger.Loggr, s store.Shift
 					Type:        graphql.NewNonNull(graphql.String),
 					Description: "Team identifier",
 				},
+				"max_concurrent_builds": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum builds run on the cluster at a time, zero (default) is unlimited",
+				},
 			},
 			Resolve: r.AddContainerEngine,
 		},
@@ -10,17 +10,97 @@ import (
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
 	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/repository"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
 )
 
 func newRepositorySchema(
 	ctx context.Context,
 	loggr logger.Loggr,
+	providers providers.Providers,
 	s store.Shift,
+	vault secret.Vault,
 ) (queries graphql.Fields, mutations graphql.Fields) {
 
-	r, _ := repository.NewResolver(ctx, loggr, s)
+	r, _ := repository.NewResolver(ctx, loggr, s, providers, vault)
+
+	pipelineType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Pipeline",
+			Fields: graphql.Fields{
+
+				"name": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Name of the pipeline",
+				},
+
+				"shiftfile": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Path of the Shiftfile in the repository that builds the pipeline, or the glob that discovers a pipeline for each Shiftfile",
+				},
+
+				"include": &graphql.Field{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Globs of the paths that trigger the pipeline when changed, all the paths if empty",
+				},
+
+				"exclude": &graphql.Field{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Globs of the paths that never trigger the pipeline",
+				},
+			},
+			Description: "A part of the repository (monorepo) built through its own Shiftfile",
+		},
+	)
+
+	autoCancelType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "AutoCancel",
+			Fields: graphql.Fields{
+
+				"enabled": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "Cancels the waiting and running builds of a branch when a new commit is pushed to it",
+				},
+
+				"default_branch": &graphql.Field{
+					Type:        graphql.Boolean,
+					Description: "Cancels the superseded builds of the default branch too",
+				},
+			},
+			Description: "Auto cancellation of the builds superseded by a newer commit",
+		},
+	)
+
+	pipelineInputType := graphql.NewInputObject(
+		graphql.InputObjectConfig{
+			Name: "PipelineInput",
+			Fields: graphql.InputObjectConfigFieldMap{
+
+				"name": &graphql.InputObjectFieldConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Name of the pipeline, unique within the repository",
+				},
+
+				"shiftfile": &graphql.InputObjectFieldConfig{
+					Type:        graphql.String,
+					Description: "Path of the Shiftfile in the repository, defaults to Shiftfile. A glob such as services/*/Shiftfile discovers a pipeline for each Shiftfile, named by its directory",
+				},
+
+				"include": &graphql.InputObjectFieldConfig{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Globs of the paths that trigger the pipeline when changed. Ex: services/api/**",
+				},
+
+				"exclude": &graphql.InputObjectFieldConfig{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Globs of the paths that never trigger the pipeline. Ex: **/*.md",
+				},
+			},
+		},
+	)
 
 	fields := graphql.Fields{
 		"id": &graphql.Field{
@@ -84,6 +164,76 @@ func newRepositorySchema(
 			Description: "The source of the repository such as github.com, gitlab.com etc",
 		},
 
+		"hook_enabled": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "True if the push and pull request events trigger the build",
+		},
+
+		"auth": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Authentication of the plain git repository (none, basic or ssh_key), empty if the repository is linked through the vcs account",
+		},
+
+		"public_key": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Public key of the generated deploy key, that has to be added to the git server",
+		},
+
+		"host_keys": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Host keys (known_hosts format) the git server of the plain git repository is verified by over ssh",
+		},
+
+		"poll_interval": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Interval in seconds the plain git repository is polled for changes, zero if polling is disabled",
+		},
+
+		"polled_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the plain git repository was last polled",
+		},
+
+		"sync_status": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Result of the last background sync: ok, not_found (disappeared from the vcs) or access_lost",
+		},
+
+		"synced_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the repository was last synced",
+		},
+
+		"pipelines": &graphql.Field{
+			Type:        graphql.NewList(pipelineType),
+			Description: "Pipelines of the repository, triggered by the changed paths",
+		},
+
+		"max_concurrent_builds": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Maximum builds of the repository run at a time, unlimited when zero",
+		},
+
+		"priority": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Default priority of the builds, the queued builds of a higher priority are launched first",
+		},
+
+		"auto_cancel": &graphql.Field{
+			Type:        autoCancelType,
+			Description: "Auto cancellation of the builds superseded by a newer commit",
+		},
+
+		"retention": &graphql.Field{
+			Type:        retentionType,
+			Description: "Build history kept, the retention of the team applies when it's empty",
+		},
+
+		"quarantine": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Blocks allowed to fail without failing the build",
+		},
+
 		"build": &graphql.Field{
 			Type: graphql.NewObject(graphql.ObjectConfig{
 				Name: "builds",
@@ -133,9 +283,128 @@ func newRepositorySchema(
 					Type:        graphql.NewNonNull(graphql.String),
 					Description: "Represent the team name or ID",
 				},
+				"auth": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Registers a plain git repository without vcs account, authenticated through none, basic (username and token) or ssh_key (generated deploy key)",
+				},
+				"username": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Username to access the plain git repository over http(s)",
+				},
+				"token": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Token or password to access the plain git repository over http(s)",
+				},
+				"host_keys": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Host keys (known_hosts format) of the self-hosted git server accessed through the deploy key, ex: the output of ssh-keyscan <host>. Not required for github.com, gitlab.com and bitbucket.org",
+				},
+				"poll_interval": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Interval in seconds to poll the plain git repository for changes, defaults to 60, zero disables polling",
+				},
+				"default_branch": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Default branch of the plain git repository, detected from the remote if not provided",
+				},
+				"language": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Language of the plain git repository, used to find the default Shiftfile",
+				},
 			},
 			Resolve: r.AddRepository,
 		},
+
+		"enableHook": &graphql.Field{
+			Type: repositoryType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+			},
+			Resolve: r.EnableHook,
+		},
+
+		"setPipelines": &graphql.Field{
+			Type: repositoryType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+				"pipelines": &graphql.ArgumentConfig{
+					Type:        graphql.NewList(pipelineInputType),
+					Description: "Pipelines of the repository, replaces the configured pipelines. Empty builds the repository as a whole",
+				},
+			},
+			Resolve: r.SetPipelines,
+		},
+
+		"setBuildLimits": &graphql.Field{
+			Type: repositoryType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+				"max_concurrent_builds": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum builds of the repository run at a time, zero (default) is unlimited",
+				},
+				"priority": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Default priority of the builds, zero (default) is normal, negative is lower",
+				},
+			},
+			Resolve: r.SetBuildLimits,
+		},
+
+		"setAutoCancel": &graphql.Field{
+			Type: repositoryType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+				"enabled": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.Boolean),
+					Description: "Cancels the waiting and running builds of a branch when a new commit is pushed to it",
+				},
+				"default_branch": &graphql.ArgumentConfig{
+					Type:        graphql.Boolean,
+					Description: "Cancels the superseded builds of the default branch too, false (default) exempts it",
+				},
+			},
+			Resolve: r.SetAutoCancel,
+		},
+
+		"setHostKeys": &graphql.Field{
+			Type: repositoryType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+				"host_keys": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Host keys (known_hosts format) of the git server accessed through the deploy key, ex: the output of ssh-keyscan <host>",
+				},
+			},
+			Resolve: r.SetHostKeys,
+		},
+
+		"resetBadgeToken": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Generates a new token to view the status badge of the private repository, the old token stops working",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+			},
+			Resolve: r.ResetBadgeToken,
+		},
 	}
 
 	return queries, mutations
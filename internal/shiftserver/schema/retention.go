@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package schema
+
+import (
+	"context"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/retention"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	retentionType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Retention",
+			Fields: graphql.Fields{
+
+				"keep_builds": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Latest builds kept, unlimited when zero",
+				},
+
+				"keep_days": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Days the builds are kept, unlimited when zero",
+				},
+			},
+			Description: "Build history kept, the last successful build of each branch is always kept",
+		},
+	)
+)
+
+func newRetentionSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (queries graphql.Fields, mutations graphql.Fields) {
+
+	r, _ := retention.NewResolver(ctx, loggr, s)
+
+	expiredBuildType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "ExpiredBuild",
+			Fields: graphql.Fields{
+
+				"build_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Build identifier",
+				},
+
+				"repository_id": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Repository identifier",
+				},
+
+				"branch": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Branch of the build",
+				},
+
+				"pipeline": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Pipeline of the build",
+				},
+
+				"status": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Status of the build",
+				},
+
+				"created_at": &graphql.Field{
+					Type:        graphql.DateTime,
+					Description: "Time the build was triggered",
+				},
+
+				"reason": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Reason the build is expired",
+				},
+			},
+			Description: "A build removed by the retention",
+		},
+	)
+
+	reportType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "RetentionReport",
+			Fields: graphql.Fields{
+
+				"builds": &graphql.Field{
+					Type:        graphql.NewList(expiredBuildType),
+					Description: "Builds removed along with their logs, archives and snapshots",
+				},
+
+				"caches": &graphql.Field{
+					Type:        graphql.NewList(graphql.String),
+					Description: "Storage paths of the branches whose caches are removed, as none of their builds are kept",
+				},
+
+				"count": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Number of builds removed",
+				},
+			},
+			Description: "Builds and caches removed by the retention on its next run",
+		},
+	)
+
+	// the repository of the team, the team itself if it's not given
+	args := func(more graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+
+		a := graphql.FieldConfigArgument{
+			"team": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Name of the team",
+			},
+			"repository_id": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Repository identifier, the team if empty",
+			},
+		}
+
+		for k, v := range more {
+			a[k] = v
+		}
+		return a
+	}
+
+	queries = graphql.Fields{
+		"retention": &graphql.Field{
+			Type:        reportType,
+			Description: "Reports the builds and the caches the retention removes on its next run, without removing them",
+			Args:        args(nil),
+			Resolve:     r.FetchRetention,
+		},
+	}
+
+	mutations = graphql.Fields{
+
+		"setRetention": &graphql.Field{
+			Type: retentionType,
+			Args: args(graphql.FieldConfigArgument{
+				"keep_builds": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Latest builds of the repository kept, unlimited when zero (default)",
+				},
+				"keep_days": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Days the builds are kept, unlimited when zero (default)",
+				},
+			}),
+			Resolve: r.SetRetention,
+		},
+
+		"removeRetention": &graphql.Field{
+			Type:    graphql.Boolean,
+			Args:    args(nil),
+			Resolve: r.RemoveRetention,
+		},
+	}
+
+	return queries, mutations
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package schema
+
+import (
+	"context"
+	"sort"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/schedule"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
+	"github.com/graphql-go/graphql"
+)
+
+func newScheduleSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (queries graphql.Fields, mutations graphql.Fields) {
+
+	r, _ := schedule.NewResolver(ctx, loggr, s)
+
+	parameterType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "BuildParameter",
+			Fields: graphql.Fields{
+
+				"name": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Name of the environment variable",
+				},
+
+				"value": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Value of the environment variable",
+				},
+			},
+			Description: "Parameter given to the build, set as its environment",
+		},
+	)
+
+	parameterInputType := graphql.NewInputObject(
+		graphql.InputObjectConfig{
+			Name: "BuildParameterInput",
+			Fields: graphql.InputObjectConfigFieldMap{
+
+				"name": &graphql.InputObjectFieldConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Name of the environment variable, unique within the parameters",
+				},
+
+				"value": &graphql.InputObjectFieldConfig{
+					Type:        graphql.String,
+					Description: "Value of the environment variable",
+				},
+			},
+			Description: "Parameter given to the build, set as its environment",
+		},
+	)
+
+	fields := graphql.Fields{
+		"id": &graphql.Field{
+			Type:        graphql.ID,
+			Description: "Schedule identifier",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if t, ok := p.Source.(types.Schedule); ok {
+					return t.ID.Hex(), nil
+				}
+				return nil, nil
+			},
+		},
+
+		"team": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Team the repository belongs to",
+		},
+
+		"repository_id": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Repository identifier",
+		},
+
+		"branch": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Branch to build, the default branch of the repository if empty",
+		},
+
+		"cron": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Cron expression of the times the build is triggered, such as '0 2 * * 1-5'",
+		},
+
+		"timezone": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Time zone the cron expression is evaluated in, UTC if empty",
+		},
+
+		"enabled": &graphql.Field{
+			Type:        graphql.Boolean,
+			Description: "True if the schedule triggers the builds",
+		},
+
+		"pipeline": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Pipeline to build, required when the repository is configured with pipelines",
+		},
+
+		"priority": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Priority of the builds in the queue, defaults to the priority of the repository",
+		},
+
+		"directives": &graphql.Field{
+			Type:        directivesType,
+			Description: "Directives given to the builds",
+		},
+
+		"parameters": &graphql.Field{
+			Type:        graphql.NewList(parameterType),
+			Description: "Parameters given to the builds, ordered by name",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+
+				t, ok := p.Source.(types.Schedule)
+				if !ok {
+					return nil, nil
+				}
+
+				names := make([]string, 0, len(t.Parameters))
+				for name := range t.Parameters {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				params := make([]map[string]string, 0, len(names))
+				for _, name := range names {
+					params = append(params, map[string]string{"name": name, "value": t.Parameters[name]})
+				}
+				return params, nil
+			},
+		},
+
+		"last_run_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the schedule last triggered a build",
+		},
+
+		"next_run_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the schedule triggers the next build",
+		},
+
+		"last_build_id": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Build triggered by the last run",
+		},
+
+		"last_error": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Reason the last run failed to trigger the build",
+		},
+
+		"created_by": &graphql.Field{
+			Type:        graphql.String,
+			Description: "User created the schedule",
+		},
+
+		"created_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time the schedule was created",
+		},
+	}
+
+	scheduleType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:        "Schedule",
+			Fields:      fields,
+			Description: "Triggers the builds of a repository branch periodically",
+		},
+	)
+
+	scheduleArgs := graphql.FieldConfigArgument{
+
+		"team": &graphql.ArgumentConfig{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Name of the team",
+		},
+
+		"repository_id": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Repository identifier, all the schedules of the team if empty",
+		},
+	}
+
+	queries = graphql.Fields{
+		"schedule": utils.MakeListType("ScheduleList", scheduleType, r.FetchSchedules, scheduleArgs),
+	}
+
+	// arguments shared by add and update, the ones not given are left as is on update
+	args := func(more graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+
+		a := graphql.FieldConfigArgument{
+			"branch": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Branch to build, the default branch of the repository if empty",
+			},
+			"timezone": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Time zone the cron expression is evaluated in, such as 'Asia/Kolkata', UTC (default) if empty",
+			},
+			"enabled": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "True (default) if the schedule triggers the builds",
+			},
+			"pipeline": &graphql.ArgumentConfig{
+				Type:        graphql.String,
+				Description: "Pipeline to build, required when the repository is configured with pipelines",
+			},
+			"priority": &graphql.ArgumentConfig{
+				Type:        graphql.Int,
+				Description: "Priority of the builds in the queue, defaults to the priority of the repository",
+			},
+			"rebuild_cache": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "True if the cache is rebuilt, instead of restored",
+			},
+			"only": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "Names of the blocks to run, the rest of the blocks are skipped",
+			},
+			"debug": &graphql.ArgumentConfig{
+				Type:        graphql.Boolean,
+				Description: "True if the builds run with verbose logging",
+			},
+			"parameters": &graphql.ArgumentConfig{
+				Type:        graphql.NewList(parameterInputType),
+				Description: "Parameters given to the builds, set as their environment. Replaces the parameters on update",
+			},
+		}
+
+		for k, v := range more {
+			a[k] = v
+		}
+		return a
+	}
+
+	mutations = graphql.Fields{
+
+		"addSchedule": &graphql.Field{
+			Type: scheduleType,
+			Args: args(graphql.FieldConfigArgument{
+				"repository_id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Repository identifier",
+				},
+				"cron": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Cron expression of the times the build is triggered, such as '0 2 * * 1-5' or '@daily'",
+				},
+			}),
+			Resolve: r.AddSchedule,
+		},
+
+		"updateSchedule": &graphql.Field{
+			Type: scheduleType,
+			Args: args(graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Schedule identifier",
+				},
+				"cron": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Cron expression of the times the build is triggered",
+				},
+			}),
+			Resolve: r.UpdateSchedule,
+		},
+
+		"deleteSchedule": &graphql.Field{
+			Type: graphql.Boolean,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Schedule identifier",
+				},
+			},
+			Resolve: r.DeleteSchedule,
+		},
+	}
+
+	return queries, mutations
+}
@@ -44,7 +44,7 @@ func Construct(
 	appendFields(mutations, vcsM)
 
 	// repository fields
-	repositoryQ, repositoryM := newRepositorySchema(ctx, loggr, s)
+	repositoryQ, repositoryM := newRepositorySchema(ctx, loggr, providers, s, vault)
 	appendFields(queries, repositoryQ)
 	appendFields(mutations, repositoryM)
 
@@ -54,7 +54,7 @@ func Construct(
 	appendFields(mutations, sysconfM)
 
 	// build fields
-	buildQ, buildM, buildS := newBuildSchema(ctx, loggr, s, pb, r)
+	buildQ, buildM, buildS := newBuildSchema(ctx, loggr, s, pb, r, providers, vault)
 	appendFields(queries, buildQ)
 	appendFields(mutations, buildM)
 	appendFields(subscriptions, buildS)
@@ -94,6 +94,21 @@ func Construct(
 	appendFields(queries, shiftfileQ)
 	appendFields(mutations, shiftfileM)
 
+	// schedule fields
+	scheduleQ, scheduleM := newScheduleSchema(ctx, loggr, s)
+	appendFields(queries, scheduleQ)
+	appendFields(mutations, scheduleM)
+
+	// retention fields
+	retentionQ, retentionM := newRetentionSchema(ctx, loggr, s)
+	appendFields(queries, retentionQ)
+	appendFields(mutations, retentionM)
+
+	// analytics fields
+	analyticsQ, analyticsM := newAnalyticsSchema(ctx, loggr, s)
+	appendFields(queries, analyticsQ)
+	appendFields(mutations, analyticsM)
+
 	rootQuery := graphql.ObjectConfig{Name: "RootQuery", Fields: queries}
 	rootMutation := graphql.ObjectConfig{Name: "RootMutation", Fields: mutations}
 	rootSubscription := graphql.ObjectConfig{Name: "RootSubscription", Fields: subscriptions}
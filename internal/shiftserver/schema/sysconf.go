@@ -53,6 +53,26 @@ func newSysconfSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (q
 			Type:        graphql.String,
 			Description: "The callback url for the elasticshift application",
 		},
+
+		"hook_url": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The url invoked by the vcs on push and pull request events",
+		},
+
+		"provider": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Kind of the provider such as github, gitlab, bitbucket, gitea or forgejo",
+		},
+
+		"api_url": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The api url of the self-hosted version control system",
+		},
+
+		"web_url": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The web url of the self-hosted version control system",
+		},
 	}
 
 	genericSysconfFields := graphql.Fields{
@@ -205,6 +225,26 @@ func newSysconfSchema(ctx context.Context, loggr logger.Loggr, s store.Shift) (q
 					Type:        graphql.NewNonNull(graphql.String),
 					Description: "callback url for the elasticshift oauth application",
 				},
+				"hookURL": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "hook url for the push and pull request events, Ex: https://<host>/api/hook/github",
+				},
+				"hookSecret": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "secret used to sign or verify the hook requests",
+				},
+				"provider": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "kind of the provider (github, gitlab, bitbucket, gitea, forgejo), defaults to the name",
+				},
+				"apiURL": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "api url of the self-hosted system, Ex: https://github.example.com/api/v3",
+				},
+				"webURL": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "web url of the self-hosted system, Ex: https://github.example.com",
+				},
 			},
 			Resolve: r.CreateVCSSysConf,
 		},
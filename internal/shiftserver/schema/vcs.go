@@ -63,6 +63,11 @@ func newVcsSchema(
 			Type:        graphql.DateTime,
 			Description: "Time when the token will be expired",
 		},
+
+		"synced_at": &graphql.Field{
+			Type:        graphql.DateTime,
+			Description: "Time when the token and the repositories of the account were last synced",
+		},
 	}
 
 	vcsType := graphql.NewObject(
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
+	"golang.org/x/crypto/ssh"
+)
+
+// size of the generated deploy keys
+const deployKeyBitSize = 4096
+
+// GenerateDeployKey ..
+// Generates the ssh key pair used to access the plain git repository,
+// returns the private key (PEM) and the public key in authorized_keys format.
+func GenerateDeployKey() (string, string, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, deployKeyBitSize)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to generate rsa keys: %v", err)
+	}
+
+	private := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	public, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to convert the public key: %v", err)
+	}
+
+	return string(private), strings.TrimSpace(string(ssh.MarshalAuthorizedKey(public))), nil
+}
+
+// SaveGitCredentials ..
+// Vaults the credentials of the plain git repository and returns the secret id
+func SaveGitCredentials(v Vault, repo types.Repository, c vcs.Credentials) (string, error) {
+
+	sec := types.Secret{}
+	sec.Name = repo.Identifier + "/" + repo.Name
+	sec.Kind = TYPE_SECRET
+	if c.SSHKey != "" {
+		sec.Kind = TYPE_SSHKEY
+	}
+	sec.ReferenceKind = RefType_REPO
+	sec.ReferenceID = repo.ID.Hex()
+	sec.TeamID = repo.Team
+
+	p := NewPair()
+	p.Put("username", c.Username)
+	p.Put("token", c.Token)
+	p.Put("ssh_key", c.SSHKey)
+
+	value, err := p.Json()
+	if err != nil {
+		return "", err
+	}
+	sec.Value = value
+
+	return v.Put(sec)
+}
+
+// GitCredentials ..
+// Returns the credentials of the plain git repository from the vault
+func GitCredentials(v Vault, repo types.Repository) (vcs.Credentials, error) {
+
+	c := vcs.Credentials{KnownHosts: repo.HostKeys}
+	if repo.SecretID == "" {
+		return c, nil
+	}
+
+	sec, err := v.Get(repo.SecretID)
+	if err != nil {
+		return c, fmt.Errorf("Failed to fetch the credentials of the repository: %v", err)
+	}
+
+	value, err := v.Decrypt(sec.Value)
+	if err != nil {
+		return c, fmt.Errorf("Failed to decrypt the credentials of the repository: %v", err)
+	}
+
+	p, err := FillPair(value)
+	if err != nil {
+		return c, fmt.Errorf("Failed to read the credentials of the repository: %v", err)
+	}
+
+	c.Username = p.Get("username")
+	c.Token = p.Get("token")
+	c.SSHKey = p.Get("ssh_key")
+	return c, nil
+}
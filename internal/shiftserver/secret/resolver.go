@@ -34,6 +34,7 @@ const (
 	RefType_VCS  string = "vcs"
 	RefType_TEAM string = "team"
 	RefType_USER string = "user"
+	RefType_REPO string = "repository"
 )
 
 // Resolver ..
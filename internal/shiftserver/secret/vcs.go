@@ -0,0 +1,32 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package secret
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api/types"
+)
+
+// UpdateVCSToken ..
+// Updates the vaulted token of the vcs account, once it's refreshed
+func UpdateVCSToken(v Vault, a types.VCS) error {
+
+	sec, err := v.GetByReferenceID(a.ID, RefType_VCS)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch the secret of account %s: %v", a.Name, err)
+	}
+
+	p := NewPair()
+	p.Put("access_token", a.AccessToken)
+	p.Put("refresh_token", a.RefreshToken)
+
+	sec.Value, err = p.Json()
+	if err != nil {
+		return err
+	}
+
+	_, err = v.Put(sec)
+	return err
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package shift
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Heartbeat ..
+// Records the worker of the build is alive. The worker is asked to stop
+// when the build is no longer in progress, such as when it's cancelled
+// or marked as stuck after the heartbeats were missed.
+func (s *shift) Heartbeat(ctx context.Context, req *api.HeartbeatReq) (*api.HeartbeatRes, error) {
+
+	if req == nil || !bson.IsObjectIdHex(req.GetBuildId()) {
+		return nil, fmt.Errorf("BuildID is empty or invalid")
+	}
+
+	if req.GetSubBuildId() == "" {
+		return nil, fmt.Errorf("Sub BuildID is empty")
+	}
+
+	res := &api.HeartbeatRes{}
+
+	alive, err := s.buildStore.Heartbeat(req.GetBuildId(), req.GetSubBuildId(), time.Now())
+	if err != nil {
+		return res, fmt.Errorf("Failed to record the heartbeat: %v", err)
+	}
+
+	if alive {
+		return res, nil
+	}
+
+	sb, err := s.buildStore.FetchSubBuild(req.GetBuildId(), req.GetSubBuildId())
+	if err != nil {
+		return res, fmt.Errorf("Failed to fetch build by id : %v", err)
+	}
+
+	res.Stop = true
+	res.Status = sb.Status
+
+	return res, nil
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/elasticshift/elasticshift/api"
 	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/integration"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/pubsub"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/resolver"
@@ -21,6 +24,10 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// times the report of the worker is applied again when the
+// sub build is changed by another update meanwhile
+const updateAttempts = 3
+
 type shift struct {
 	loggr            logger.Loggr
 	logger           *logrus.Entry
@@ -30,15 +37,21 @@ type shift struct {
 	repositoryStore  store.Repository
 	defaultStore     store.Defaults
 	integrationStore store.Integration
+	teamStore        store.Team
 	vault            secret.Vault
 	ps               pubsub.Engine
+	providers        providers.Providers
+	webURL           string
+
+	// the commit statuses are reported in order per build
+	statuses *statusQueue
 
 	rs *resolver.Shift
 }
 
-func NewServer(loggr logger.Loggr, ctx context.Context, s store.Shift, vault secret.Vault, ps pubsub.Engine, rs *resolver.Shift) api.ShiftServer {
+func NewServer(loggr logger.Loggr, ctx context.Context, s store.Shift, vault secret.Vault, ps pubsub.Engine, rs *resolver.Shift, p providers.Providers, webURL string) api.ShiftServer {
 	l := loggr.GetLogger("shiftserver/grpc")
-	return &shift{loggr, l, ctx, s.Build, s.Container, s.Repository, s.Defaults, s.Integration, vault, ps, rs}
+	return &shift{loggr, l, ctx, s.Build, s.Container, s.Repository, s.Defaults, s.Integration, s.Team, vault, ps, p, webURL, newStatusQueue(), rs}
 }
 
 func (s *shift) Register(ctx context.Context, req *api.RegisterReq) (*api.RegisterRes, error) {
@@ -85,18 +98,72 @@ func (s *shift) UpdateBuildStatus(ctx context.Context, req *api.UpdateBuildStatu
 	res := &api.UpdateBuildStatusRes{}
 
 	var b types.SubBuild
+	var prevStatus string
+	var stopContainer bool
 	var err error
-	b, err = s.buildStore.FetchSubBuild(req.GetBuildId(), req.GetSubBuildId())
+
+	// the sub build changed meanwhile, such as by a heartbeat,
+	// is read again and the report is applied again
+	for i := 0; i < updateAttempts; i++ {
+
+		b, prevStatus, stopContainer, err = s.applyReport(req)
+		if err != store.ErrConflict {
+			break
+		}
+	}
+
+	// a late status, such as of the sub build cancelled or marked as stuck
+	// meanwhile, is rejected and not reported, the graph is still updated
+	rejected := err == build.ErrTransitionRejected
+	if err != nil && !rejected {
+		return res, err
+	}
+
+	// publish pubsub to fetch latest update to subscribers
+	s.ps.Publish(pubsub.SubscribeBuildUpdate, req.GetBuildId())
+
+	if rejected {
+		return res, nil
+	}
+
+	// report the status change back to the version control system
+	if b.Status != prevStatus {
+		s.statuses.push(req.GetBuildId(), b, s.reportCommitStatus)
+	}
+
+	if stopContainer {
+
+		// kick off the next waiting build
+		s.rs.Build.TriggerNextIfAny(req.GetBuildId(), req.GetTeamId(), req.GetRepositoryId(), req.GetBranch())
+
+		// the container is left for the logs to be looked into,
+		// it's removed by the garbage collector of the build resolver
+	}
+
+	return res, nil
+}
+
+// applyReport ..
+// Updates the sub build with the graph and the status reported by the
+// worker, at the version the sub build is read with. Returns the sub build
+// as reported, its status before and whether it's finished by the report.
+func (s *shift) applyReport(req *api.UpdateBuildStatusReq) (types.SubBuild, string, bool, error) {
+
+	b, err := s.buildStore.FetchSubBuild(req.GetBuildId(), req.GetSubBuildId())
 	if err != nil {
-		return res, fmt.Errorf("Failed to fetch build by id : %v", err)
+		return b, "", false, fmt.Errorf("Failed to fetch build by id : %v", err)
 	}
 
+	prevStatus := b.Status
+	prevGraph := b.Graph
+
 	b.Graph = req.GetGraph()
 	status := req.GetStatus()
 	cp := req.GetCheckpoint()
 
 	if b.Status == types.BuildStatusPreparing {
 		b.Status = types.BuildStatusRunning
+		b.StartedAt = time.Now()
 	}
 
 	if req.GetReason() != "" {
@@ -108,52 +175,39 @@ func (s *shift) UpdateBuildStatus(ctx context.Context, req *api.UpdateBuildStatu
 	}
 
 	var stopContainer bool
-	if status != "" {
-
-		if status == "F" {
-			b.Status = types.BuildStatusFailed
-			stopContainer = true
-		} else if status == "S" && cp == "END" {
-			b.Status = types.BuildStatusSuccess
-			stopContainer = true
-		} else if status == "C" {
-			b.Status = types.BuildStatusCancel
-		}
+	if st := subBuildStatus(status, cp); st != "" {
+
+		b.Status = st
 		b.EndedAt = time.Now()
-	}
 
-	err = s.buildStore.UpdateSubBuild(req.GetBuildId(), b)
-	if err != nil {
-		return res, fmt.Errorf("Failed to update the graph : %v", err)
+		// the cancelled build triggers the next waiting build by itself
+		stopContainer = st != types.BuildStatusCancel
 	}
 
-	// publish pubsub to fetch latest update to subscribers
-	s.ps.Publish(pubsub.SubscribeBuildUpdate, req.GetBuildId())
-
-	if stopContainer {
-
-		// kick off the next waiting build
-		s.rs.Build.TriggerNextIfAny(req.GetBuildId(), req.GetTeamId(), req.GetRepositoryId(), req.GetBranch())
-
-		// fmt.Println("-------------------------------------------------------------")
-		// fmt.Println("Stopping the container..... ")
-		// fmt.Println("Status = ", status)
-		// fmt.Println("Checkpoint = ", cp)
-		// fmt.Println("-------------------------------------------------------------")
-		// request container engine to stop the live container
+	// the nodes are estimated when the worker reports the graph first
+	s.rs.Build.Estimate(req.GetBuildId(), &b, prevGraph, prevStatus == types.BuildStatusPreparing)
 
-		/*ce, err := s.getContainerEngine(req.GetTeamId())
-		if err != nil {
-			return res, errors.Wrap(err, "Failed to get the default container engine: %v")
-		}
-
-		err = ce.DeleteContainer(req.GetBuildId() + "-" + req.GetSubBuildId())
-		if err != nil {
-			return res, fmt.Errorf("Failed to stop the container: %v", err)
-		}*/
+	err = build.UpdateSubBuild(s.buildStore, s.logger, "worker", req.GetBuildId(), b)
+	if err != nil && err != store.ErrConflict && err != build.ErrTransitionRejected {
+		return b, prevStatus, stopContainer, fmt.Errorf("Failed to update the graph : %v", err)
 	}
+	return b, prevStatus, stopContainer, err
+}
 
-	return res, nil
+// subBuildStatus ..
+// Returns the status of the sub build the worker reported the node status
+// at the checkpoint for, empty when the sub build is still in progress
+func subBuildStatus(status, checkpoint string) string {
+
+	switch {
+	case status == graph.StatusFailed:
+		return types.BuildStatusFailed
+	case status == graph.StatusCancelled:
+		return types.BuildStatusCancel
+	case status == graph.StatusSuccess && checkpoint == graph.END:
+		return types.BuildStatusSuccess
+	}
+	return ""
 }
 
 func (s *shift) getContainerEngine(team string) (integration.ContainerEngineInterface, error) {
@@ -243,8 +297,26 @@ func (s *shift) GetProject(ctx context.Context, req *api.GetProjectReq) (*api.Ge
 	res.StoragePath = b.StoragePath
 	res.Source = b.Source
 	res.RepositoryId = b.RepositoryID
+	res.CommitId = b.CommitID
+
+	// the token of the vcs account isn't handed to the build, the
+	// container runs the shiftfile, that could act as the account
+	// on all its repositories, the shiftfile is fetched by the server
+
+	if req.GetIncludeShiftfile() && b.ResolvedShiftfile != "" {
+
+		// pinned when the build was launched
+		res.Shiftfile = b.ResolvedShiftfile
+	} else if req.GetIncludeShiftfile() {
+
+		// fetched by the server through the api of the vcs account,
+		// self-hosted ones included, or through git for the plain ones
+		f, err := s.rs.Build.Shiftfile(b)
+		if err != nil {
+			s.logger.Warnf("Failed to fetch the shiftfile of build %s: %v", req.BuildId, err)
+		}
+		res.Shiftfile = string(f)
 
-	if req.GetIncludeShiftfile() {
 		// TODO fetch shiftfile from registry
 	}
 
@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package shift
+
+import (
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+)
+
+func TestSubBuildStatus(t *testing.T) {
+
+	tests := []struct {
+		status     string
+		checkpoint string
+		subBuild   string
+	}{
+		{graph.StatusFailed, "test", types.BuildStatusFailed},
+		{graph.StatusFailed, graph.END, types.BuildStatusFailed},
+		{graph.StatusCancelled, "test", types.BuildStatusCancel},
+		{graph.StatusSuccess, graph.END, types.BuildStatusSuccess},
+
+		// still in progress
+		{graph.StatusSuccess, "test", ""},
+		{graph.StatusRunning, "test", ""},
+		{graph.StatusAllowedFailure, "test", ""},
+		{graph.StatusSkipped, "deploy", ""},
+	}
+
+	for _, tt := range tests {
+		if s := subBuildStatus(tt.status, tt.checkpoint); s != tt.subBuild {
+			t.Errorf("subBuildStatus(%s, %s) = %q, expected %q", tt.status, tt.checkpoint, s, tt.subBuild)
+		}
+	}
+}
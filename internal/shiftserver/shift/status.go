@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package shift
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
+)
+
+const commitStatusContext = "elasticshift/"
+
+// statusQueue ..
+// Reports the statuses of a build one after the other, in the order the
+// worker reported them, so that a slow report of the pending status can't
+// land after and overwrite the status the build finished with. The builds
+// are reported independent of each other.
+type statusQueue struct {
+	mu      sync.Mutex
+	pending map[string][]types.SubBuild
+}
+
+func newStatusQueue() *statusQueue {
+	return &statusQueue{pending: make(map[string][]types.SubBuild)}
+}
+
+// push ..
+// Queues the status of the sub build to be reported, the reports of the
+// build are drained by a single goroutine, started by the first of them.
+func (q *statusQueue) push(buildID string, sb types.SubBuild, report func(string, types.SubBuild)) {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, draining := q.pending[buildID]
+	q.pending[buildID] = append(queued, sb)
+
+	if !draining {
+		go q.drain(buildID, report)
+	}
+}
+
+func (q *statusQueue) drain(buildID string, report func(string, types.SubBuild)) {
+
+	for {
+
+		q.mu.Lock()
+		queued := q.pending[buildID]
+		if len(queued) == 0 {
+			delete(q.pending, buildID)
+			q.mu.Unlock()
+			return
+		}
+		sb := queued[0]
+		q.pending[buildID] = queued[1:]
+		q.mu.Unlock()
+
+		report(buildID, sb)
+	}
+}
+
+// reportCommitStatus ..
+// Reports the sub build status against the commit on the version
+// control system the build was triggered for.
+func (s *shift) reportCommitStatus(buildID string, sb types.SubBuild) {
+
+	b, err := s.buildStore.FetchBuildByID(buildID)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch the build %s to report commit status: %v", buildID, err)
+		return
+	}
+
+	// builds triggered without a commit (manual) or for the plain
+	// git repositories (no vcs account) can't be reported
+	if b.CommitID == "" || b.VcsID == "" {
+		return
+	}
+
+	repo, err := s.repositoryStore.GetRepositoryByID(b.RepositoryID)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch the repository %s to report commit status: %v", b.RepositoryID, err)
+		return
+	}
+
+	account, err := s.teamStore.GetVCSByID(b.Team, b.VcsID)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch the vcs account %s to report commit status: %v", b.VcsID, err)
+		return
+	}
+
+	p, err := s.providers.Get(account.Kind)
+	if err != nil {
+		s.logger.Errorf("No provider found for %s: %v", account.Kind, err)
+		return
+	}
+
+	token, err := s.providers.GetToken(b.Team, account)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch/refresh the token for account %s: %v", account.Name, err)
+		return
+	}
+
+	owner := repo.Owner()
+	if owner == "" {
+		owner = account.Name
+	}
+
+	status := providers.CommitStatus{
+		State:       commitState(sb.Status),
+		Description: fmt.Sprintf("Build %s", strings.ToLower(sb.Status)),
+		Context:     commitStatusContext + sb.ID,
+	}
+
+	if s.webURL != "" {
+		status.TargetURL = fmt.Sprintf("%s/builds/%s", strings.TrimSuffix(s.webURL, "/"), buildID)
+	}
+
+	err = providers.ReportCommitStatus(p, token, owner, repo.Name, b.CommitID, status)
+	if err != nil {
+		s.logger.Errorf("Failed to report commit status for build %s: %v", buildID, err)
+	}
+}
+
+// commitState ..
+// Maps the build status to commit status state
+func commitState(status string) string {
+
+	switch status {
+	case types.BuildStatusSuccess:
+		return providers.CommitStateSuccess
+	case types.BuildStatusFailed:
+		return providers.CommitStateFailure
+	case types.BuildStatusCancel, types.BuildStatusStuck:
+		return providers.CommitStateError
+	}
+	return providers.CommitStatePending
+}
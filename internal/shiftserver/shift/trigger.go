@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package shift
+
+import (
+	"fmt"
+
+	"github.com/elasticshift/elasticshift/api"
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TriggerBuild ..
+// Triggers the build of another repository of the team, requested by the
+// trigger block of a running build
+func (s *shift) TriggerBuild(ctx context.Context, req *api.TriggerBuildReq) (*api.TriggerBuildRes, error) {
+
+	if req == nil {
+		return nil, fmt.Errorf("TriggerBuildReq cannot be nil")
+	}
+
+	if !bson.IsObjectIdHex(req.GetBuildId()) {
+		return nil, fmt.Errorf("BuildID is empty or invalid")
+	}
+
+	upstream, err := s.buildStore.FetchBuildByID(req.GetBuildId())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch build by id : %v", err)
+	}
+
+	params, err := build.ParseParameters(req.GetParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := build.DownstreamOptions{}
+	opts.Repository = req.GetRepository()
+	opts.Branch = req.GetBranch()
+	opts.Pipeline = req.GetPipeline()
+	opts.Parameters = params
+	opts.Node = req.GetNode()
+	opts.Wait = req.GetWait()
+
+	b, err := s.rs.Build.TriggerDownstream(upstream, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Build %s triggered the build %s of repository %s", req.GetBuildId(), b.ID.Hex(), b.RepositoryID)
+
+	res := &api.TriggerBuildRes{}
+	res.BuildId = b.ID.Hex()
+	res.RepositoryId = b.RepositoryID
+	res.Status = build.Status(b)
+
+	return res, nil
+}
+
+// GetBuildStatus ..
+// Returns the status of the build, polled by the trigger block
+// that waits for the build to finish
+func (s *shift) GetBuildStatus(ctx context.Context, req *api.GetBuildStatusReq) (*api.GetBuildStatusRes, error) {
+
+	if req == nil || !bson.IsObjectIdHex(req.GetBuildId()) {
+		return nil, fmt.Errorf("BuildID is empty or invalid")
+	}
+
+	b, err := s.buildStore.FetchBuildByID(req.GetBuildId())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch build by id : %v", err)
+	}
+
+	res := &api.GetBuildStatusRes{}
+	res.Status = build.Status(b)
+
+	// the upstream build goes on with the finished build
+	switch res.Status {
+	case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+	default:
+		s.rs.Build.WaitOver(b)
+	}
+
+	for _, sb := range b.SubBuilds {
+		if sb.Reason != "" {
+			res.Reason = sb.Reason
+			break
+		}
+	}
+
+	return res, nil
+}
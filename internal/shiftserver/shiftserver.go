@@ -70,6 +70,7 @@ type ServerConfig struct {
 	NSQ      NSQ
 	Session  *mgo.Session
 	Identity Identity
+	WebURL   string
 }
 
 // Identity ..
@@ -151,12 +152,15 @@ func New(ctx context.Context, c ServerConfig) (*Server, error) {
 func (s *Server) registerEndpointServices() {
 
 	// VCS service to link repositories.
-	vcsServ := vcs.NewService(s.Loggr, s.DB, s.Providers, s.Shift, s.Vault)
+	vcsServ := vcs.NewService(s.Loggr, s.DB, s.Providers, s.Shift, s.Vault, s.Resolver)
 
 	// Oauth2 providers
 	s.Router.HandleFunc("/api/{team}/link/{provider}", vcsServ.Authorize)
 	s.Router.HandleFunc("/api/link/{provider}/callback", vcsServ.Authorized)
 
+	// VCS webhook (push, pull request events)
+	s.Router.HandleFunc("/api/hook/{provider}", vcsServ.Hook).Methods("POST")
+
 	// TODO the directory is only applicable for dev testing
 	// s.Router.Handle("/download/", http.StripPrefix("/download/", http.FileServer(http.Dir("/Users/ghazni/.elasticshift/cloud"))))
 
@@ -173,6 +177,9 @@ func (s *Server) registerEndpointServices() {
 	buildServ := build.NewService(s.Loggr, s.Shift)
 	s.Router.HandleFunc("/api/log/{buildid}/{subbuildid}", buildServ.Viewlog)
 	s.Router.HandleFunc("/api/log/{buildid}/{subbuildid}/{nodeid}", buildServ.Viewlog)
+	s.Router.HandleFunc("/api/log/{team}/{repo}/{number}/{subbuildid}", buildServ.Viewlog)
+	s.Router.HandleFunc("/api/log/{team}/{repo}/{number}/{subbuildid}/{nodeid}", buildServ.Viewlog)
+	s.Router.HandleFunc("/api/badge/{team}/{repo}.svg", buildServ.Badge).Methods("GET")
 }
 
 func (s *Server) registerGraphQLServices() error {
@@ -230,7 +237,7 @@ func (s *Server) registerWebSocketServices() {
 
 // Registers the GRPC services ...
 func (s *Server) registerGRPCServices(grpcServer *grpc.Server) {
-	api.RegisterShiftServer(grpcServer, shift.NewServer(s.Loggr, s.Ctx, s.Shift, s.Vault, s.Pubsub, s.Resolver))
+	api.RegisterShiftServer(grpcServer, shift.NewServer(s.Loggr, s.Ctx, s.Shift, s.Vault, s.Pubsub, s.Resolver, s.Providers, s.Config.WebURL))
 }
 
 // Registers the exposed http services
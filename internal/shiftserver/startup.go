@@ -60,6 +60,9 @@ type NSQ struct {
 type Web struct {
 	HTTP string `json:"http"`
 	GRPC string `json:"grpc"`
+
+	// public url of the server, used to link builds from external systems
+	URL string `json:"url"`
 }
 
 // Dex ..
@@ -135,6 +138,7 @@ func Run() error {
 			Web: Web{
 				HTTP: "0.0.0.0:9100",
 				GRPC: "0.0.0.0:9101",
+				URL:  "http://127.0.0.1:9100",
 			},
 
 			Identity: Identity{
@@ -207,6 +211,13 @@ func Run() error {
 	}
 	sc.Store.AutoReconnect = true
 
+	// override the public url of the server
+	if webURL := os.Getenv("SHIFT_WEB_URL"); webURL != "" {
+		sc.WebURL = webURL
+	} else {
+		sc.WebURL = c.Web.URL
+	}
+
 	ctx := context.Background()
 
 	// set rest of databse properties to server config
@@ -4,13 +4,19 @@ Copyright 2017 The Elasticshift Authors.
 package store
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/elasticshift/elasticshift/api/types"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
+// ErrConflict ..
+// The sub build is changed by another update since it was read
+var ErrConflict = errors.New("The sub build is changed by another update")
+
 type build struct {
 	Store
 }
@@ -20,17 +26,39 @@ type build struct {
 type Build interface {
 	Interface
 
-	FetchBuild(team, repositoryID, branch, id string, status []string) ([]types.Build, error)
+	FetchBuild(team, repositoryID, branch, pipeline, id string, status []string, limit int) ([]types.Build, error)
 	FetchBuildByID(id string) (types.Build, error)
+	FetchBuildByNumber(repositoryID string, number int) (types.Build, error)
+	FetchLastSuccessfulBuild(repositoryID, branch, pipeline string) (types.Build, error)
 	FetchBuildByRepositoryID(id string) ([]types.Build, error)
+	FetchBuildsByStatus(status ...string) ([]types.Build, error)
+	CountActiveBuilds() ([]types.ActiveBuilds, error)
+	FetchRecentBuilds(repositoryID, status string, limit int) ([]types.Build, error)
+	FetchBuildHistory(repositoryID string) ([]types.Build, error)
+	FetchUnrolledBuilds(limit int) ([]types.Build, error)
+	FetchCommitBuilds(repositoryID string, since time.Time) ([]types.Build, error)
+	FetchRecentGraphs(repositoryID, branch, pipeline string, limit int) ([]types.Build, error)
+	FetchUnnumberedBuilds(limit int) ([]types.Build, error)
+	FetchLastBuildNumber(repositoryID string) (int, error)
 
 	UpdateBuildLog(id bson.ObjectId, log string) error
 	UpdateBuildStatus(id bson.ObjectId, s string) error
 	UpdateContainerID(id bson.ObjectId, containerID string) error
+	UpdateConcurrency(id bson.ObjectId, group, heldBy string) error
+	AddDownstream(id bson.ObjectId, link types.BuildLink) error
+	SetNumber(id bson.ObjectId, number int) error
+	SetWaitingFor(id bson.ObjectId, downstreamID string) error
+	ClearWaitingFor(id bson.ObjectId, downstreamID string) error
 
 	SaveSubBuild(buildID string, sb *types.SubBuild) error
 	UpdateSubBuild(buildID string, sb types.SubBuild) error
 	FetchSubBuild(buildID, subBuildID string) (types.SubBuild, error)
+	TransitSubBuild(buildID, subBuildID, from, to string) (bool, error)
+	Heartbeat(buildID, subBuildID string, at time.Time) (bool, error)
+	ClaimRollup(id bson.ObjectId) (bool, error)
+	ReleaseRollup(id bson.ObjectId) error
+
+	EnsureIndexes() error
 }
 
 // NewStore ..
@@ -41,7 +69,10 @@ func newBuildStore(d Database) Build {
 	return s
 }
 
-func (s *build) FetchBuild(team, repositoryID, branch, id string, status []string) ([]types.Build, error) {
+// FetchBuild ..
+// Returns the builds matching the given filters, all of them unless
+// limited, the latest builds are returned first when limited
+func (s *build) FetchBuild(team, repositoryID, branch, pipeline, id string, status []string, limit int) ([]types.Build, error) {
 
 	q := bson.M{"team": team}
 	if repositoryID != "" {
@@ -52,6 +83,10 @@ func (s *build) FetchBuild(team, repositoryID, branch, id string, status []strin
 		q["branch"] = branch
 	}
 
+	if pipeline != "" {
+		q["pipeline"] = pipeline
+	}
+
 	if statusLen := len(status); statusLen > 0 {
 		if statusLen == 1 {
 			q["sub_builds.status"] = status[0]
@@ -67,7 +102,11 @@ func (s *build) FetchBuild(team, repositoryID, branch, id string, status []strin
 	var err error
 	var result []types.Build
 	s.Execute(func(c *mgo.Collection) {
-		err = c.Find(q).All(&result)
+		query := c.Find(q)
+		if limit > 0 {
+			query = query.Sort("-_id").Limit(limit)
+		}
+		err = query.All(&result)
 	})
 
 	return result, err
@@ -86,12 +125,278 @@ func (s *build) FetchBuildByRepositoryID(id string) ([]types.Build, error) {
 	return result, err
 }
 
+// FetchBuildsByStatus ..
+// Returns the builds of all the teams having a sub build in any of
+// the status, the oldest first.
+func (s *build) FetchBuildsByStatus(status ...string) ([]types.Build, error) {
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"sub_builds.status": bson.M{"$in": status}}).Sort("_id").All(&result)
+	})
+
+	return result, err
+}
+
+// CountActiveBuilds ..
+// Counts the builds having a container launched, that are preparing or
+// running, by the team, repository and container engine. The builds
+// waiting for a downstream build are left out, having released their limits.
+func (s *build) CountActiveBuilds() ([]types.ActiveBuilds, error) {
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"sub_builds": bson.M{"$elemMatch": bson.M{"$or": []bson.M{
+				{"status": types.BuildStatusRunning},
+				{"status": types.BuildStatusPreparing, "metadata.container_id": bson.M{"$exists": true, "$ne": ""}},
+			}}},
+			"waiting_for": bson.M{"$exists": false},
+		}},
+		{"$group": bson.M{
+			"_id":   bson.M{"team": "$team", "repository_id": "$repository_id", "container_engine_id": "$container_engine_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"_id":                 0,
+			"team":                "$_id.team",
+			"repository_id":       "$_id.repository_id",
+			"container_engine_id": "$_id.container_engine_id",
+			"count":               1,
+		}},
+	}
+
+	var err error
+	var result []types.ActiveBuilds
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Pipe(pipeline).All(&result)
+	})
+
+	return result, err
+}
+
+// FetchRecentBuilds ..
+// Returns the latest builds of the repository in the status, the newest first
+func (s *build) FetchRecentBuilds(repositoryID, status string, limit int) ([]types.Build, error) {
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"repository_id": repositoryID, "sub_builds.status": status}).Sort("-_id").Limit(limit).All(&result)
+	})
+
+	return result, err
+}
+
+// FetchBuildHistory ..
+// Returns the builds of the repository from the newest to the oldest,
+// without the logs and the shiftfiles
+func (s *build) FetchBuildHistory(repositoryID string) ([]types.Build, error) {
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"repository_id": repositoryID}).
+			Select(bson.M{"log": 0, "shiftfile": 0, "resolved_shiftfile": 0}).
+			Sort("-_id").
+			All(&result)
+	})
+
+	return result, err
+}
+
+// FetchUnrolledBuilds ..
+// Returns the oldest finished builds that are not counted in the daily
+// rollups of the analytics yet, without their logs and shiftfiles
+func (s *build) FetchUnrolledBuilds(limit int) ([]types.Build, error) {
+
+	active := []string{types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning}
+
+	q := bson.M{
+		"rolled_up":         bson.M{"$exists": false},
+		"sub_builds.0":      bson.M{"$exists": true},
+		"sub_builds.status": bson.M{"$nin": active},
+	}
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).
+			Select(bson.M{"log": 0, "shiftfile": 0, "resolved_shiftfile": 0}).
+			Sort("_id").
+			Limit(limit).
+			All(&result)
+	})
+
+	return result, err
+}
+
+// FetchUnnumberedBuilds ..
+// Returns the builds triggered before they were numbered, from the oldest,
+// with only their repository
+func (s *build) FetchUnnumberedBuilds(limit int) ([]types.Build, error) {
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"number": bson.M{"$exists": false}}).
+			Select(bson.M{"repository_id": 1}).
+			Sort("_id").
+			Limit(limit).
+			All(&result)
+	})
+
+	return result, err
+}
+
+// FetchLastBuildNumber ..
+// Returns the highest number of the builds of the repository, 0 if none
+// is numbered
+func (s *build) FetchLastBuildNumber(repositoryID string) (int, error) {
+
+	var err error
+	var b types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"repository_id": repositoryID, "number": bson.M{"$exists": true}}).
+			Select(bson.M{"number": 1}).
+			Sort("-number").
+			One(&b)
+	})
+
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return b.Number, err
+}
+
+// FetchCommitBuilds ..
+// Returns the builds of the repository triggered since the given time for
+// a commit, from the oldest, without their logs and shiftfiles
+func (s *build) FetchCommitBuilds(repositoryID string, since time.Time) ([]types.Build, error) {
+
+	q := bson.M{
+		"repository_id": repositoryID,
+		"commit_id":     bson.M{"$exists": true},
+		"_id":           bson.M{"$gte": bson.NewObjectIdWithTime(since)},
+	}
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).
+			Select(bson.M{"log": 0, "shiftfile": 0, "resolved_shiftfile": 0}).
+			Sort("_id").
+			All(&result)
+	})
+
+	return result, err
+}
+
+// FetchRecentGraphs ..
+// Returns the latest finished builds of the pipeline on the branch, the
+// newest first, with only the graphs and the statuses of their sub builds
+func (s *build) FetchRecentGraphs(repositoryID, branch, pipeline string, limit int) ([]types.Build, error) {
+
+	active := []string{types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning}
+
+	q := bson.M{
+		"repository_id":     repositoryID,
+		"branch":            branch,
+		"sub_builds.0":      bson.M{"$exists": true},
+		"sub_builds.status": bson.M{"$nin": active},
+	}
+
+	if pipeline != "" {
+		q["pipeline"] = pipeline
+	} else {
+		q["pipeline"] = bson.M{"$exists": false}
+	}
+
+	var err error
+	var result []types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).
+			Select(bson.M{"sub_builds.id": 1, "sub_builds.status": 1, "sub_builds.graph": 1}).
+			Sort("-_id").
+			Limit(limit).
+			All(&result)
+	})
+
+	return result, err
+}
+
+// FetchLastSuccessfulBuild ..
+// Returns the latest build of the pipeline on the branch that has
+// succeeded all of its sub builds and was built for a commit.
+func (s *build) FetchLastSuccessfulBuild(repositoryID, branch, pipeline string) (types.Build, error) {
+
+	q := bson.M{
+		"repository_id":     repositoryID,
+		"branch":            branch,
+		"commit_id":         bson.M{"$exists": true},
+		"sub_builds.status": types.BuildStatusSuccess,
+		"sub_builds":        bson.M{"$not": bson.M{"$elemMatch": bson.M{"status": bson.M{"$ne": types.BuildStatusSuccess}}}},
+	}
+
+	if pipeline != "" {
+		q["pipeline"] = pipeline
+	} else {
+		q["pipeline"] = bson.M{"$exists": false}
+	}
+
+	var err error
+	var b types.Build
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).Sort("-_id").One(&b)
+	})
+	return b, err
+}
+
 func (s *build) FetchBuildByID(id string) (types.Build, error) {
 	var b types.Build
 	err := s.FindOne(bson.M{"_id": bson.ObjectIdHex(id)}, &b)
 	return b, err
 }
 
+// EnsureIndexes ..
+// Creates the indexes the builds are looked up by, if they don't exist
+func (s *build) EnsureIndexes() error {
+
+	indexes := []mgo.Index{
+
+		// the latest build of a branch, such as for the status badge
+		{Key: []string{"repository_id", "branch", "-_id"}, Background: true},
+
+		// the build by its number in the repository, the builds are
+		// numbered before the index is created
+		{Key: []string{"repository_id", "number"}, Unique: true, Background: true},
+
+		// the finished builds yet to be counted in the analytics
+		{Key: []string{"rolled_up"}, Background: true},
+
+		// the active builds counted against the build limits
+		{Key: []string{"sub_builds.status"}, Background: true},
+	}
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		for _, i := range indexes {
+			if err = c.EnsureIndex(i); err != nil {
+				return
+			}
+		}
+	})
+	return err
+}
+
+// FetchBuildByNumber ..
+// Returns the build of the repository by its sequential number
+func (s *build) FetchBuildByNumber(repositoryID string, number int) (types.Build, error) {
+	var b types.Build
+	err := s.FindOne(bson.M{"repository_id": repositoryID, "number": number}, &b)
+	return b, err
+}
+
 func (s *build) UpdateBuildLog(id bson.ObjectId, log string) error {
 
 	var err error
@@ -114,6 +419,51 @@ func (s *build) UpdateContainerID(id bson.ObjectId, containerID string) error {
 	return s.UpdateId(id, bson.M{"$set": bson.M{"container_id": containerID}})
 }
 
+// UpdateConcurrency ..
+// Sets the concurrency group of the build, and the group it waits for
+func (s *build) UpdateConcurrency(id bson.ObjectId, group, heldBy string) error {
+
+	if heldBy == "" {
+		return s.UpdateId(id, bson.M{"$set": bson.M{"concurrency": group}, "$unset": bson.M{"held_by": ""}})
+	}
+	return s.UpdateId(id, bson.M{"$set": bson.M{"concurrency": group, "held_by": heldBy}})
+}
+
+// AddDownstream ..
+// Links the build triggered by the build
+func (s *build) AddDownstream(id bson.ObjectId, link types.BuildLink) error {
+	return s.UpdateId(id, bson.M{"$push": bson.M{"downstream": link}})
+}
+
+// SetNumber ..
+// Numbers the build, unless it's numbered already
+func (s *build) SetNumber(id bson.ObjectId, number int) error {
+
+	err := s.Update(bson.M{"_id": id, "number": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"number": number}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// SetWaitingFor ..
+// Sets the downstream build the build waits for
+func (s *build) SetWaitingFor(id bson.ObjectId, downstreamID string) error {
+	return s.UpdateId(id, bson.M{"$set": bson.M{"waiting_for": downstreamID}})
+}
+
+// ClearWaitingFor ..
+// Clears the downstream build the build waited for, unless it
+// waits for another build since
+func (s *build) ClearWaitingFor(id bson.ObjectId, downstreamID string) error {
+
+	err := s.Update(bson.M{"_id": id, "waiting_for": downstreamID}, bson.M{"$unset": bson.M{"waiting_for": ""}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
 func (s *build) SaveSubBuild(buildID string, sb *types.SubBuild) error {
 
 	var err error
@@ -126,6 +476,9 @@ func (s *build) SaveSubBuild(buildID string, sb *types.SubBuild) error {
 	return err
 }
 
+// UpdateSubBuild ..
+// Sets the given fields of the sub build, only if it's still in the version
+// it was read with. Returns ErrConflict if it's changed by another update.
 func (s *build) UpdateSubBuild(buildID string, sb types.SubBuild) error {
 
 	u := bson.M{}
@@ -151,12 +504,50 @@ func (s *build) UpdateSubBuild(buildID string, sb types.SubBuild) error {
 		u["sub_builds.$.duration"] = sb.Duration
 	}
 
+	if sb.Metadata != nil {
+		u["sub_builds.$.metadata"] = sb.Metadata
+	}
+
+	if !sb.StartedAt.IsZero() {
+		u["sub_builds.$.started_at"] = sb.StartedAt
+	}
+
+	if !sb.EndedAt.IsZero() {
+		u["sub_builds.$.ended_at"] = sb.EndedAt
+	}
+
+	if !sb.LaunchedAt.IsZero() {
+		u["sub_builds.$.launched_at"] = sb.LaunchedAt
+	}
+
+	if sb.Estimate > 0 {
+		u["sub_builds.$.estimate"] = sb.Estimate
+	}
+
+	// the sub builds saved before the versions were introduced have none
+	var version interface{} = bson.M{"$exists": false}
+	if sb.Version > 0 {
+		version = sb.Version
+	}
+
+	q := bson.M{
+		"_id":        bson.ObjectIdHex(buildID),
+		"sub_builds": bson.M{"$elemMatch": bson.M{"id": sb.ID, "version": version}},
+	}
+
+	change := bson.M{"$inc": bson.M{"sub_builds.$.version": 1}}
+	if len(u) > 0 {
+		change["$set"] = u
+	}
+
 	var err error
 	s.Execute(func(c *mgo.Collection) {
-		err = c.Update(
-			bson.M{"_id": bson.ObjectIdHex(buildID), "sub_builds.id": sb.ID},
-			bson.M{"$set": u})
+		err = c.Update(q, change)
 	})
+
+	if err == mgo.ErrNotFound {
+		return ErrConflict
+	}
 	return err
 }
 
@@ -179,3 +570,66 @@ func (s *build) FetchSubBuild(buildID, subBuildID string) (types.SubBuild, error
 	fmt.Printf("Inside FetchSubBuild: ID = %s, Image = %s \n", sb.ID, sb.Image)
 	return sb, err
 }
+
+// TransitSubBuild ..
+// Moves the sub build to the status, only if it's still in the status
+// it was read with. Returns false if another server has moved it already.
+func (s *build) TransitSubBuild(buildID, subBuildID, from, to string) (bool, error) {
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Update(
+			bson.M{"_id": bson.ObjectIdHex(buildID), "sub_builds": bson.M{"$elemMatch": bson.M{"id": subBuildID, "status": from}}},
+			bson.M{"$set": bson.M{"sub_builds.$.status": to}, "$inc": bson.M{"sub_builds.$.version": 1}},
+		)
+	})
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Heartbeat ..
+// Records the worker of the sub build is alive, only while the sub build
+// is preparing or running. Returns false if the sub build is finished. The
+// version is incremented, so the sub build found expired before the
+// heartbeat isn't marked as stuck.
+func (s *build) Heartbeat(buildID, subBuildID string, at time.Time) (bool, error) {
+
+	active := []string{types.BuildStatusPreparing, types.BuildStatusRunning}
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Update(
+			bson.M{"_id": bson.ObjectIdHex(buildID), "sub_builds": bson.M{"$elemMatch": bson.M{"id": subBuildID, "status": bson.M{"$in": active}}}},
+			bson.M{"$set": bson.M{"sub_builds.$.heartbeat_at": at}, "$inc": bson.M{"sub_builds.$.version": 1}},
+		)
+	})
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ClaimRollup ..
+// Marks the build as counted in the analytics, only if it's not marked by
+// another server. Returns false if the claim is lost.
+func (s *build) ClaimRollup(id bson.ObjectId) (bool, error) {
+
+	err := s.Update(
+		bson.M{"_id": id, "rolled_up": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"rolled_up": true}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseRollup ..
+// Releases the build claimed for the rollup, for it to be rolled up again
+func (s *build) ReleaseRollup(id bson.ObjectId) error {
+	return s.UpdateId(id, bson.M{"$unset": bson.M{"rolled_up": ""}})
+}
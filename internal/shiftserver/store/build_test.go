@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCountActiveBuilds(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newBuildStore(d)
+
+	err := s.EnsureIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(team, repositoryID, status, containerID string) types.Build {
+
+		sb := types.SubBuild{ID: "1", Status: status}
+		if containerID != "" {
+			sb.Metadata = &types.Metadata{ContainerID: containerID}
+		}
+		return types.Build{ID: bson.NewObjectId(), Team: team, RepositoryID: repositoryID, ContainerEngineID: "e1", SubBuilds: []types.SubBuild{sb}}
+	}
+
+	// the build waiting for a downstream build is left out
+	waiting := build("a", "r1", types.BuildStatusRunning, "c5")
+	waiting.WaitingFor = "d1"
+
+	builds := []types.Build{
+		waiting,
+		build("a", "r1", types.BuildStatusRunning, "c1"),
+		build("a", "r1", types.BuildStatusPreparing, "c2"),
+		build("a", "r1", types.BuildStatusPreparing, ""),
+		build("a", "r1", types.BuildStatusSuccess, "c3"),
+		build("b", "r2", types.BuildStatusRunning, "c4"),
+	}
+
+	for _, b := range builds {
+		if err = s.Save(&b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active, err := s.CountActiveBuilds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(active, func(i, j int) bool { return active[i].Team < active[j].Team })
+
+	expected := []types.ActiveBuilds{
+		{Team: "a", RepositoryID: "r1", EngineID: "e1", Count: 2},
+		{Team: "b", RepositoryID: "r2", EngineID: "e1", Count: 1},
+	}
+
+	if len(active) != len(expected) || active[0] != expected[0] || active[1] != expected[1] {
+		t.Fatalf("unexpected active builds %+v", active)
+	}
+
+	// the build waits for another build
+	err = s.ClearWaitingFor(waiting.ID, "d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := s.FetchBuildByID(waiting.ID.Hex())
+	if err != nil || b.WaitingFor != "d1" {
+		t.Fatalf("expected the build waiting for d1, got %q: %v", b.WaitingFor, err)
+	}
+
+	err = s.ClearWaitingFor(waiting.ID, "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err = s.CountActiveBuilds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(active, func(i, j int) bool { return active[i].Team < active[j].Team })
+	if len(active) != 2 || active[0].Count != 3 {
+		t.Fatalf("expected the build counted once the wait is over, got %+v", active)
+	}
+}
+
+func TestNumberBuilds(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newBuildStore(d)
+
+	numbered := types.Build{ID: bson.NewObjectId(), RepositoryID: "r1", Number: 4}
+	first := types.Build{ID: bson.NewObjectId(), RepositoryID: "r1"}
+	second := types.Build{ID: bson.NewObjectId(), RepositoryID: "r2"}
+
+	for _, b := range []types.Build{numbered, first, second} {
+		if err := s.Save(&b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builds, err := s.FetchUnnumberedBuilds(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(builds) != 2 || builds[0].ID != first.ID || builds[1].ID != second.ID {
+		t.Fatalf("expected the unnumbered builds from the oldest, got %+v", builds)
+	}
+
+	last, err := s.FetchLastBuildNumber("r1")
+	if err != nil || last != 4 {
+		t.Fatalf("expected the last number 4, got %d, %v", last, err)
+	}
+
+	last, err = s.FetchLastBuildNumber("r3")
+	if err != nil || last != 0 {
+		t.Fatalf("expected no last number, got %d, %v", last, err)
+	}
+
+	err = s.SetNumber(first.ID, 5)
+	if err == nil {
+		err = s.SetNumber(second.ID, 1)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a numbered build keeps its number
+	err = s.SetNumber(first.ID, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := s.FetchBuildByNumber("r1", 5)
+	if err != nil || b.ID != first.ID {
+		t.Fatalf("expected the build numbered 5, got %+v, %v", b, err)
+	}
+
+	err = s.EnsureIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := types.Build{ID: bson.NewObjectId(), RepositoryID: "r1", Number: 5}
+	if err = s.Save(&duplicate); err == nil {
+		t.Fatal("expected the number to be unique in the repository")
+	}
+}
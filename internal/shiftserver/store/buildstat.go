@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type buildStat struct {
+	Store
+}
+
+// BuildStat ..
+// Store keeps the daily rollups of the finished builds of the branches
+type BuildStat interface {
+	Interface
+
+	Record(st types.BuildStat) error
+	FetchDays(q bson.M) ([]types.AnalyticsDay, error)
+
+	EnsureIndexes() error
+}
+
+// NewStore ..
+func newBuildStatStore(d Database) BuildStat {
+	s := &buildStat{}
+	s.Database = d
+	s.CollectionName = "build_stat"
+	return s
+}
+
+// Record ..
+// Adds the counts to the rollup of the day of the branch
+func (s *buildStat) Record(st types.BuildStat) error {
+
+	selector := bson.M{
+		"team":          st.Team,
+		"repository_id": st.RepositoryID,
+		"branch":        st.Branch,
+		"day":           st.Day,
+	}
+
+	u := bson.M{
+		"$inc": bson.M{
+			"builds":    st.Builds,
+			"succeeded": st.Succeeded,
+			"failed":    st.Failed,
+			"cancelled": st.Cancelled,
+			"stuck":     st.Stuck,
+		},
+	}
+
+	_, err := s.Upsert(selector, u)
+	return err
+}
+
+// FetchDays ..
+// Sums the rollups matching the query by the day, from the oldest day
+func (s *buildStat) FetchDays(q bson.M) ([]types.AnalyticsDay, error) {
+
+	pipeline := []bson.M{
+		{"$match": q},
+		{"$group": bson.M{
+			"_id":       "$day",
+			"builds":    bson.M{"$sum": "$builds"},
+			"succeeded": bson.M{"$sum": "$succeeded"},
+			"failed":    bson.M{"$sum": "$failed"},
+			"cancelled": bson.M{"$sum": "$cancelled"},
+			"stuck":     bson.M{"$sum": "$stuck"},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	var err error
+	var result []types.AnalyticsDay
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Pipe(pipeline).All(&result)
+	})
+	return result, err
+}
+
+// EnsureIndexes ..
+// Creates the indexes the rollups are recorded and queried by
+func (s *buildStat) EnsureIndexes() error {
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		err = c.EnsureIndex(mgo.Index{Key: []string{"team", "repository_id", "branch", "day"}, Unique: true, Background: true})
+	})
+	return err
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type buildTime struct {
+	Store
+}
+
+// BuildTime ..
+// Store keeps the durations and the queue waits of the finished builds
+type BuildTime interface {
+	Interface
+
+	Record(bt types.BuildTime) error
+	CountValues(q bson.M, field string) (int, error)
+	FetchValueAt(q bson.M, field string, i int) (float64, error)
+
+	EnsureIndexes() error
+}
+
+// NewStore ..
+func newBuildTimeStore(d Database) BuildTime {
+	s := &buildTime{}
+	s.Database = d
+	s.CollectionName = "build_time"
+	return s
+}
+
+// Record ..
+// Keeps the times of the build, once even if it's rolled up again
+func (s *buildTime) Record(bt types.BuildTime) error {
+
+	err := s.Save(&bt)
+	if mgo.IsDup(err) {
+		return nil
+	}
+	return err
+}
+
+// CountValues ..
+// Returns the number of the builds matching the query with the field,
+// such as the duration
+func (s *buildTime) CountValues(q bson.M, field string) (int, error) {
+
+	var n int
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		n, err = c.Find(withField(q, field)).Count()
+	})
+	return n, err
+}
+
+// FetchValueAt ..
+// Returns the value of the field at the index, of the builds matching the
+// query sorted by the field from the smallest, without reading the rest
+func (s *buildTime) FetchValueAt(q bson.M, field string, i int) (float64, error) {
+
+	pipeline := []bson.M{
+		{"$match": withField(q, field)},
+		{"$sort": bson.M{field: 1}},
+		{"$skip": i},
+		{"$limit": 1},
+		{"$project": bson.M{"v": "$" + field}},
+	}
+
+	var err error
+	var result []struct {
+		Value float64 `bson:"v"`
+	}
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Pipe(pipeline).AllowDiskUse().All(&result)
+	})
+
+	if err != nil || len(result) == 0 {
+		return 0, err
+	}
+	return result[0].Value, nil
+}
+
+// EnsureIndexes ..
+// Creates the index the times are queried by
+func (s *buildTime) EnsureIndexes() error {
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		err = c.EnsureIndex(mgo.Index{Key: []string{"team", "repository_id", "branch", "day"}, Background: true})
+	})
+	return err
+}
+
+func withField(q bson.M, field string) bson.M {
+
+	m := bson.M{field: bson.M{"$exists": true}}
+	for k, v := range q {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestBuildTime(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newBuildTimeStore(d)
+
+	err := s.EnsureIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	times := []types.BuildTime{
+		{ID: bson.NewObjectId(), Team: "a", Day: day, Duration: 30, Wait: 5},
+		{ID: bson.NewObjectId(), Team: "a", Day: day, Duration: 10},
+		{ID: bson.NewObjectId(), Team: "a", Day: day, Duration: 20, Wait: 1},
+		{ID: bson.NewObjectId(), Team: "b", Day: day, Duration: 1},
+	}
+
+	for _, bt := range times {
+		if err = s.Record(bt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the build rolled up again is kept once
+	err = s.Record(times[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := bson.M{"team": "a"}
+
+	n, err := s.CountValues(q, "duration")
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 durations, got %d, %v", n, err)
+	}
+
+	n, err = s.CountValues(q, "wait")
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 waits, got %d, %v", n, err)
+	}
+
+	for i, expected := range []float64{10, 20, 30} {
+
+		v, err := s.FetchValueAt(q, "duration", i)
+		if err != nil || v != expected {
+			t.Errorf("expected the duration %v at %d, got %v, %v", expected, i, v, err)
+		}
+	}
+
+	v, err := s.FetchValueAt(q, "wait", 0)
+	if err != nil || v != 1 {
+		t.Errorf("expected the smallest wait 1, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type concurrency struct {
+	Store
+}
+
+// Concurrency ..
+// Store keeps the locks of the concurrency groups, a group is held by
+// one build at a time across the repositories of the team.
+type Concurrency interface {
+	Interface
+
+	Acquire(team, group, buildID string) (types.ConcurrencyLock, bool, error)
+	Takeover(team, group, prevBuildID, buildID string) (bool, error)
+	Release(team, group, buildID string) error
+}
+
+// NewStore ..
+func newConcurrencyStore(d Database) Concurrency {
+	s := &concurrency{}
+	s.Database = d
+	s.CollectionName = "concurrency_group"
+	return s
+}
+
+// Acquire ..
+// Locks the group for the build. Returns false with the lock
+// if the group is held by another build.
+func (s *concurrency) Acquire(team, group, buildID string) (types.ConcurrencyLock, bool, error) {
+
+	l := types.ConcurrencyLock{ID: lockID(team, group), Team: team, Group: group, BuildID: buildID, AcquiredAt: time.Now()}
+
+	err := s.Save(&l)
+	if err == nil {
+		return l, true, nil
+	}
+
+	if !mgo.IsDup(err) {
+		return l, false, err
+	}
+
+	err = s.FindOne(bson.M{"_id": l.ID}, &l)
+	if err != nil {
+		return l, false, err
+	}
+	return l, l.BuildID == buildID, nil
+}
+
+// Takeover ..
+// Moves the lock to the build, only if it's still held by the previous
+// build. Returns false if another build has taken it over meanwhile.
+func (s *concurrency) Takeover(team, group, prevBuildID, buildID string) (bool, error) {
+
+	err := s.Update(
+		bson.M{"_id": lockID(team, group), "build_id": prevBuildID},
+		bson.M{"$set": bson.M{"build_id": buildID, "acquired_at": time.Now()}},
+	)
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release ..
+// Unlocks the group, provided it's held by the build
+func (s *concurrency) Release(team, group, buildID string) error {
+
+	err := s.RemoveBySelector(bson.M{"_id": lockID(team, group), "build_id": buildID})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// lockID ..
+// The groups of the teams are apart, even when named the same
+func lockID(team, group string) string {
+	return team + "/" + group
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import "testing"
+
+func TestConcurrencyGroupsOfTeams(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newConcurrencyStore(d)
+
+	// the teams hold the group of the same name apart
+	_, acquired, err := s.Acquire("a", "deploy", "b1")
+	if err != nil || !acquired {
+		t.Fatalf("expected team a to acquire the group, got %v (%v)", acquired, err)
+	}
+
+	_, acquired, err = s.Acquire("b", "deploy", "b2")
+	if err != nil || !acquired {
+		t.Fatalf("expected team b to acquire the group, got %v (%v)", acquired, err)
+	}
+
+	// a build of the team waits for the one holding the group
+	lock, acquired, err := s.Acquire("a", "deploy", "b3")
+	if err != nil || acquired || lock.BuildID != "b1" || lock.Team != "a" {
+		t.Fatalf("expected the group to be held by b1, got %+v %v (%v)", lock, acquired, err)
+	}
+
+	// the group of the other team isn't taken over, nor released
+	taken, err := s.Takeover("b", "deploy", "b1", "b3")
+	if err != nil || taken {
+		t.Fatalf("expected the group of team b not to be taken over, got %v (%v)", taken, err)
+	}
+
+	err = s.Release("b", "deploy", "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, acquired, err = s.Acquire("a", "deploy", "b3")
+	if err != nil || acquired {
+		t.Fatalf("expected the group of team a to be still held, got %v (%v)", acquired, err)
+	}
+
+	err = s.Release("a", "deploy", "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, acquired, err = s.Acquire("a", "deploy", "b3")
+	if err != nil || !acquired {
+		t.Fatalf("expected the released group to be acquired, got %v (%v)", acquired, err)
+	}
+
+	lock, acquired, err = s.Acquire("b", "deploy", "b4")
+	if err != nil || acquired || lock.BuildID != "b2" {
+		t.Fatalf("expected the group of team b to be held by b2, got %+v %v (%v)", lock, acquired, err)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+)
+
+type hook struct {
+	Store
+}
+
+// Hook ..
+// Store keeps track of the received webhook deliveries
+type Hook interface {
+	Interface
+
+	SaveDelivery(d *types.HookDelivery) (bool, error)
+}
+
+// NewStore ..
+func newHookStore(d Database) Hook {
+	s := &hook{}
+	s.Database = d
+	s.CollectionName = "hook_delivery"
+	return s
+}
+
+// SaveDelivery ..
+// Records the delivery and returns false if it was already received.
+func (s *hook) SaveDelivery(d *types.HookDelivery) (bool, error) {
+
+	err := s.Save(d)
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
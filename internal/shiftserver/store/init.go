@@ -18,6 +18,15 @@ type Shift struct {
 	Defaults       Defaults
 	Secret         Secret
 	Shiftfile      Shiftfile
+	Hook           Hook
+	BuildQueue     BuildQueue
+	Concurrency    Concurrency
+	BuildSlot      BuildSlot
+	Schedule       Schedule
+	BuildStat      BuildStat
+	BuildTime      BuildTime
+	NodeStat       NodeStat
+	Job            Job
 }
 
 type Database struct {
@@ -45,5 +54,14 @@ func (db Database) InitShiftStore() Shift {
 		Defaults:    newDefaultsStore(db),
 		Secret:      newSecretStore(db),
 		Shiftfile:   newShiftfileStore(db),
+		Hook:        newHookStore(db),
+		BuildQueue:  newBuildQueueStore(db),
+		Concurrency: newConcurrencyStore(db),
+		BuildSlot:   newBuildSlotStore(db),
+		Schedule:    newScheduleStore(db),
+		BuildStat:   newBuildStatStore(db),
+		BuildTime:   newBuildTimeStore(db),
+		NodeStat:    newNodeStatStore(db),
+		Job:         newJobStore(db),
 	}
 }
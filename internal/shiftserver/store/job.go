@@ -0,0 +1,53 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type job struct {
+	Store
+}
+
+// Job ..
+// Store keeps the next run of the periodic jobs every server runs, such as
+// the garbage collector, so a run is done by only one of the servers.
+type Job interface {
+	Interface
+
+	ClaimRun(name string, next, now time.Time) (bool, error)
+}
+
+// NewStore ..
+func newJobStore(d Database) Job {
+	s := &job{}
+	s.Database = d
+	s.CollectionName = "job"
+	return s
+}
+
+// ClaimRun ..
+// Moves the next run of the job, only if it's due. Returns false if the run
+// isn't due, or it's claimed by another server.
+func (s *job) ClaimRun(name string, next, now time.Time) (bool, error) {
+
+	// the job document is created by the first run
+	_, err := s.Upsert(
+		bson.M{"_id": name, "next_run_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_run_at": next, "last_run_at": now}},
+	)
+
+	if err == nil {
+		return true, nil
+	}
+
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	return false, err
+}
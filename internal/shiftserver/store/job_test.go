@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimRun(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newJobStore(d)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	tick := 10 * time.Minute
+
+	claims := []struct {
+		name    string
+		at      time.Time
+		claimed bool
+	}{
+		{"gc", now, true},
+
+		// another server ticking before the next run
+		{"gc", now.Add(time.Minute), false},
+		{"gc", now.Add(time.Minute), false},
+
+		// the jobs are claimed apart
+		{"cleaner", now.Add(time.Minute), true},
+
+		{"gc", now.Add(tick), true},
+		{"gc", now.Add(tick + time.Minute), false},
+	}
+
+	for i, c := range claims {
+
+		claimed, err := s.ClaimRun(c.name, c.at.Add(tick), c.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claimed != c.claimed {
+			t.Errorf("claim %d of %s at %v: expected %v, got %v", i, c.name, c.at, c.claimed, claimed)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type nodeStat struct {
+	Store
+}
+
+// NodeStat ..
+// Store keeps the daily rollups of the runs of the nodes of the builds
+type NodeStat interface {
+	Interface
+
+	Record(st types.NodeStat) error
+	FetchNodes(q bson.M, sortBy string, limit int) ([]types.NodeAnalytics, error)
+
+	EnsureIndexes() error
+}
+
+// NewStore ..
+func newNodeStatStore(d Database) NodeStat {
+	s := &nodeStat{}
+	s.Database = d
+	s.CollectionName = "node_stat"
+	return s
+}
+
+// Record ..
+// Adds the runs of the node to the rollup of the day of the branch
+func (s *nodeStat) Record(st types.NodeStat) error {
+
+	selector := bson.M{
+		"team":          st.Team,
+		"repository_id": st.RepositoryID,
+		"branch":        st.Branch,
+		"day":           st.Day,
+		"node":          st.Node,
+	}
+
+	u := bson.M{
+		"$inc": bson.M{
+			"runs":     st.Runs,
+			"failures": st.Failures,
+			"duration": st.Duration,
+		},
+		"$max": bson.M{"max_duration": st.MaxDuration},
+	}
+
+	_, err := s.Upsert(selector, u)
+	return err
+}
+
+// FetchNodes ..
+// Sums the rollups matching the query by the node, the nodes are sorted by
+// the given field of types.NodeAnalytics from the largest, such as
+// "average_duration" or "failures". The nodes never failed are left out
+// when sorted by the failures.
+func (s *nodeStat) FetchNodes(q bson.M, sortBy string, limit int) ([]types.NodeAnalytics, error) {
+
+	pipeline := []bson.M{
+		{"$match": q},
+		{"$group": bson.M{
+			"_id":          "$node",
+			"runs":         bson.M{"$sum": "$runs"},
+			"failures":     bson.M{"$sum": "$failures"},
+			"duration":     bson.M{"$sum": "$duration"},
+			"max_duration": bson.M{"$max": "$max_duration"},
+		}},
+		{"$match": bson.M{"runs": bson.M{"$gt": 0}}},
+		{"$project": bson.M{
+			"runs":             1,
+			"failures":         1,
+			"max_duration":     1,
+			"failure_rate":     bson.M{"$divide": []interface{}{"$failures", "$runs"}},
+			"average_duration": bson.M{"$divide": []interface{}{"$duration", "$runs"}},
+		}},
+	}
+
+	if sortBy == "failures" || sortBy == "failure_rate" {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"failures": bson.M{"$gt": 0}}})
+	}
+
+	// the ties are broken by the node, for the same order on every query
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Name: sortBy, Value: -1}, {Name: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	)
+
+	var err error
+	var result []types.NodeAnalytics
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Pipe(pipeline).All(&result)
+	})
+	return result, err
+}
+
+// EnsureIndexes ..
+// Creates the indexes the rollups are recorded and queried by
+func (s *nodeStat) EnsureIndexes() error {
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		err = c.EnsureIndex(mgo.Index{Key: []string{"team", "repository_id", "branch", "day", "node"}, Unique: true, Background: true})
+	})
+	return err
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type buildQueue struct {
+	Store
+}
+
+// BuildQueue ..
+// Store persists the builds to be launched. A build is leased by one
+// launcher until it's acknowledged, if the launcher dies the lease
+// expires and the build becomes visible to the other launchers.
+type BuildQueue interface {
+	Interface
+
+	Enqueue(q types.QueuedBuild) error
+	FetchQueued() ([]types.QueuedBuild, error)
+	FetchLeasable(now time.Time) ([]types.QueuedBuild, error)
+	Lease(id bson.ObjectId, owner string, timeout time.Duration) (types.QueuedBuild, error)
+	Extend(id bson.ObjectId, owner string, timeout time.Duration) error
+	Release(id bson.ObjectId, owner string, delay time.Duration) error
+	Postpone(id bson.ObjectId, owner string, delay time.Duration) error
+	Ack(id bson.ObjectId, owner string) error
+	EnsureIndexes() error
+}
+
+// NewStore ..
+func newBuildQueueStore(d Database) BuildQueue {
+	s := &buildQueue{}
+	s.Database = d
+	s.CollectionName = "build_queue"
+	return s
+}
+
+// Enqueue ..
+// Queues the build, a build that is already queued is left as is,
+// so the lease held by a launcher isn't lost.
+func (s *buildQueue) Enqueue(q types.QueuedBuild) error {
+
+	_, err := s.Upsert(
+		bson.M{"_id": q.ID},
+		bson.M{"$setOnInsert": bson.M{
+			"team":                q.Team,
+			"repository_id":       q.RepositoryID,
+			"container_engine_id": q.EngineID,
+			"priority":            q.Priority,
+			"enqueued_at":         q.EnqueuedAt,
+			"visible_at":          q.VisibleAt,
+			"attempts":            0,
+		}},
+	)
+	return err
+}
+
+// FetchQueued ..
+// Returns all the queued builds, including the leased ones, the oldest first
+func (s *buildQueue) FetchQueued() ([]types.QueuedBuild, error) {
+
+	var err error
+	var result []types.QueuedBuild
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(nil).Sort("enqueued_at").All(&result)
+	})
+	return result, err
+}
+
+// FetchLeasable ..
+// Returns the builds visible to the launchers, along with the ones leased
+// at the moment, leaving out the builds put back in the queue for a delay
+func (s *buildQueue) FetchLeasable(now time.Time) ([]types.QueuedBuild, error) {
+
+	q := bson.M{"$or": []bson.M{
+		{"visible_at": bson.M{"$lte": now}},
+		{"leased_by": bson.M{"$exists": true}},
+	}}
+
+	var err error
+	var result []types.QueuedBuild
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).Sort("enqueued_at").All(&result)
+	})
+	return result, err
+}
+
+// Lease ..
+// Leases the build to the owner if it's visible, the build stays hidden
+// from the other launchers until the timeout. Returns mgo.ErrNotFound
+// when the build is leased by another launcher.
+func (s *buildQueue) Lease(id bson.ObjectId, owner string, timeout time.Duration) (types.QueuedBuild, error) {
+
+	now := time.Now()
+
+	var q types.QueuedBuild
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		_, err = c.Find(bson.M{"_id": id, "visible_at": bson.M{"$lte": now}}).Apply(mgo.Change{
+			Update: bson.M{
+				"$set": bson.M{"visible_at": now.Add(timeout), "leased_by": owner},
+				"$inc": bson.M{"attempts": 1},
+			},
+			ReturnNew: true,
+		}, &q)
+	})
+	return q, err
+}
+
+// Extend ..
+// Extends the lease held by the owner
+func (s *buildQueue) Extend(id bson.ObjectId, owner string, timeout time.Duration) error {
+
+	return s.Update(
+		bson.M{"_id": id, "leased_by": owner},
+		bson.M{"$set": bson.M{"visible_at": time.Now().Add(timeout)}},
+	)
+}
+
+// Release ..
+// Gives up the lease held by the owner, the build becomes
+// visible again after the delay
+func (s *buildQueue) Release(id bson.ObjectId, owner string, delay time.Duration) error {
+
+	return s.Update(
+		bson.M{"_id": id, "leased_by": owner},
+		bson.M{
+			"$set":   bson.M{"visible_at": time.Now().Add(delay)},
+			"$unset": bson.M{"leased_by": ""},
+		},
+	)
+}
+
+// Postpone ..
+// Gives up the lease like Release, without counting it as an attempt
+// to launch, as the build has to wait for something else to finish.
+func (s *buildQueue) Postpone(id bson.ObjectId, owner string, delay time.Duration) error {
+
+	return s.Update(
+		bson.M{"_id": id, "leased_by": owner},
+		bson.M{
+			"$set":   bson.M{"visible_at": time.Now().Add(delay)},
+			"$unset": bson.M{"leased_by": ""},
+			"$inc":   bson.M{"attempts": -1},
+		},
+	)
+}
+
+// Ack ..
+// Removes the launched build from the queue, provided the owner still holds the lease
+func (s *buildQueue) Ack(id bson.ObjectId, owner string) error {
+	return s.RemoveBySelector(bson.M{"_id": id, "leased_by": owner})
+}
+
+// EnsureIndexes ..
+// Creates the indexes the queued builds are listed by, if they don't exist
+func (s *buildQueue) EnsureIndexes() error {
+
+	indexes := []mgo.Index{
+		{Key: []string{"enqueued_at"}, Background: true},
+
+		// the leasable builds
+		{Key: []string{"visible_at"}, Background: true},
+		{Key: []string{"leased_by"}, Sparse: true, Background: true},
+	}
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		for _, i := range indexes {
+			if err = c.EnsureIndex(i); err != nil {
+				return
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestBuildQueue(t *testing.T) {
+
+	d, cleanup := testDatabase(t)
+	defer cleanup()
+
+	s := newBuildQueueStore(d)
+
+	err := s.EnsureIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	first := types.QueuedBuild{ID: bson.NewObjectId(), Team: "a", EnqueuedAt: now.Add(-time.Minute), VisibleAt: now.Add(-time.Minute)}
+	second := types.QueuedBuild{ID: bson.NewObjectId(), Team: "b", EnqueuedAt: now, VisibleAt: now}
+
+	for _, q := range []types.QueuedBuild{second, first} {
+		if err = s.Enqueue(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// leased by launcher l1
+	q, err := s.Lease(first.ID, "l1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.LeasedBy != "l1" || q.Attempts != 1 || !q.VisibleAt.After(now) {
+		t.Fatalf("unexpected lease %+v", q)
+	}
+
+	// queuing again doesn't lose the lease
+	err = s.Enqueue(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := s.FetchQueued()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queued) != 2 || queued[0].ID != first.ID || queued[0].LeasedBy != "l1" || queued[1].ID != second.ID {
+		t.Fatalf("unexpected queue %+v", queued)
+	}
+
+	// hidden from the other launchers until the lease expires
+	_, err = s.Lease(first.ID, "l2", time.Minute)
+	if err != mgo.ErrNotFound {
+		t.Fatalf("expected the leased build to be hidden, got %v", err)
+	}
+
+	if err = s.Extend(first.ID, "l2", time.Minute); err != mgo.ErrNotFound {
+		t.Fatalf("expected only the owner to extend the lease, got %v", err)
+	}
+
+	if err = s.Ack(first.ID, "l2"); err != mgo.ErrNotFound {
+		t.Fatalf("expected only the owner to ack, got %v", err)
+	}
+
+	// postponed isn't counted as an attempt to launch
+	err = s.Postpone(first.ID, "l1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = s.Lease(first.ID, "l2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.LeasedBy != "l2" || q.Attempts != 1 {
+		t.Fatalf("unexpected lease after postpone %+v", q)
+	}
+
+	// released is counted, and hidden for the delay
+	err = s.Release(first.ID, "l2", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Lease(first.ID, "l1", time.Minute)
+	if err != mgo.ErrNotFound {
+		t.Fatalf("expected the released build to be hidden for the delay, got %v", err)
+	}
+
+	// the expired lease is taken over by another launcher
+	_, err = s.Lease(second.ID, "l1", -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = s.Lease(second.ID, "l2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.LeasedBy != "l2" || q.Attempts != 2 {
+		t.Fatalf("unexpected lease after expiry %+v", q)
+	}
+
+	if err = s.Ack(second.ID, "l1"); err != mgo.ErrNotFound {
+		t.Fatalf("expected the expired owner not to ack, got %v", err)
+	}
+
+	err = s.Ack(second.ID, "l2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err = s.FetchQueued()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queued) != 1 || queued[0].ID != first.ID || queued[0].Attempts != 1 {
+		t.Fatalf("unexpected queue after ack %+v", queued)
+	}
+	// the leased and the visible builds are leasable, not the ones put back for a delay
+	third := types.QueuedBuild{ID: bson.NewObjectId(), Team: "c", EnqueuedAt: now, VisibleAt: now}
+	fourth := types.QueuedBuild{ID: bson.NewObjectId(), Team: "d", EnqueuedAt: now, VisibleAt: now}
+
+	for _, q := range []types.QueuedBuild{third, fourth} {
+		if err = s.Enqueue(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = s.Lease(third.ID, "l1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leasable, err := s.FetchLeasable(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(leasable) != 2 || leasable[0].ID != third.ID || leasable[1].ID != fourth.ID {
+		t.Fatalf("unexpected leasable builds %+v", leasable)
+	}
+}
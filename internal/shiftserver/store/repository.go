@@ -4,6 +4,8 @@ Copyright 2018 The Elasticshift Authors.
 package store
 
 import (
+	"time"
+
 	"github.com/elasticshift/elasticshift/api/types"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -21,6 +23,33 @@ type Repository interface {
 	UpdateRepository(repo types.Repository) error
 	GetRepositoryByID(id string) (types.Repository, error)
 	GetRepository(team, vcsID string) ([]types.Repository, error)
+	GetRepositoryByName(team, name string) ([]types.Repository, error)
+
+	// Polling of plain git repositories
+	GetPolledRepositories() ([]types.Repository, error)
+	ClaimPoll(id bson.ObjectId, prev, now time.Time) (bool, error)
+	UpdateHeads(id bson.ObjectId, heads []types.Head, defaultBranch string) error
+	UpdatePipelines(id bson.ObjectId, pipelines []types.Pipeline) error
+	UpdateBuildLimits(id bson.ObjectId, maxConcurrentBuilds, priority int) error
+	UpdateAutoCancel(id bson.ObjectId, ac types.AutoCancel) error
+
+	// Sequential build numbers
+	NextBuildNumber(id bson.ObjectId) (int, error)
+
+	// Status badge
+	UpdateBadgeToken(id bson.ObjectId, token string) error
+
+	// Blocks allowed to fail
+	AddQuarantine(id bson.ObjectId, node string) error
+	RemoveQuarantine(id bson.ObjectId, node string) error
+
+	// Retention of the build history
+	UpdateRetention(id bson.ObjectId, r *types.Retention) error
+	GetRetainedRepositories(teams []string) ([]types.Repository, error)
+
+	// Sync with the vcs
+	UpdateMetadata(repo types.Repository) error
+	UpdateSyncStatus(id bson.ObjectId, status string, at time.Time) error
 }
 
 // NewStore related database operations
@@ -58,3 +87,181 @@ func (s *repository) GetRepository(team, vcsID string) ([]types.Repository, erro
 	})
 	return result, err
 }
+
+// GetRepositoryByName ..
+// Returns the repositories of the team by name, across the vcs accounts
+func (s *repository) GetRepositoryByName(team, name string) ([]types.Repository, error) {
+
+	q := bson.M{"team": team, "name": name}
+
+	var err error
+	var result []types.Repository
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).All(&result)
+	})
+	return result, err
+}
+
+// GetPolledRepositories ..
+// Returns the plain git repositories that have polling enabled
+func (s *repository) GetPolledRepositories() ([]types.Repository, error) {
+
+	q := bson.M{"auth": bson.M{"$exists": true}, "poll_interval": bson.M{"$gt": 0}}
+
+	var err error
+	var result []types.Repository
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).All(&result)
+	})
+	return result, err
+}
+
+// ClaimPoll ..
+// Moves the poll time of the repository, only if it's not moved by
+// another server since it was read. Returns false if the claim is lost.
+func (s *repository) ClaimPoll(id bson.ObjectId, prev, now time.Time) (bool, error) {
+
+	q := bson.M{"_id": id}
+	if prev.IsZero() {
+		q["polled_at"] = bson.M{"$exists": false}
+	} else {
+		q["polled_at"] = prev
+	}
+
+	err := s.Update(q, bson.M{"$set": bson.M{"polled_at": now}})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UpdateHeads ..
+// Records the branch heads seen on the last poll
+func (s *repository) UpdateHeads(id bson.ObjectId, heads []types.Head, defaultBranch string) error {
+
+	set := bson.M{"heads": heads}
+	if defaultBranch != "" {
+		set["default_branch"] = defaultBranch
+	}
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": set})
+}
+
+// UpdatePipelines ..
+// Replaces the pipelines configured for the repository
+func (s *repository) UpdatePipelines(id bson.ObjectId, pipelines []types.Pipeline) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"pipelines": pipelines}})
+}
+
+// UpdateBuildLimits ..
+// Sets the concurrent builds allowed for the repository and the priority of its builds
+func (s *repository) UpdateBuildLimits(id bson.ObjectId, maxConcurrentBuilds, priority int) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"max_concurrent_builds": maxConcurrentBuilds, "priority": priority}})
+}
+
+// UpdateAutoCancel ..
+// Sets whether the superseded builds of the repository are cancelled
+func (s *repository) UpdateAutoCancel(id bson.ObjectId, ac types.AutoCancel) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"auto_cancel": ac}})
+}
+
+// NextBuildNumber ..
+// Increments the build number of the repository and returns it,
+// the number is unique even when the builds are triggered at once
+func (s *repository) NextBuildNumber(id bson.ObjectId) (int, error) {
+
+	var err error
+	var result types.Repository
+	s.Execute(func(c *mgo.Collection) {
+		_, err = c.FindId(id).Select(bson.M{"build_number": 1}).Apply(mgo.Change{
+			Update:    bson.M{"$inc": bson.M{"build_number": 1}},
+			ReturnNew: true,
+		}, &result)
+	})
+	return result.BuildNumber, err
+}
+
+// UpdateBadgeToken ..
+// Sets the token required to view the status badge of the private repository
+func (s *repository) UpdateBadgeToken(id bson.ObjectId, token string) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"badge_token": token}})
+}
+
+// AddQuarantine ..
+// Allows the block of the repository to fail without failing the build
+func (s *repository) AddQuarantine(id bson.ObjectId, node string) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"quarantine": node}})
+}
+
+// RemoveQuarantine ..
+// Fails the build again when the block of the repository fails
+func (s *repository) RemoveQuarantine(id bson.ObjectId, node string) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$pull": bson.M{"quarantine": node}})
+}
+
+// UpdateRetention ..
+// Sets the build history kept for the repository, the retention
+// of the team applies when it's nil
+func (s *repository) UpdateRetention(id bson.ObjectId, r *types.Retention) error {
+
+	if r == nil {
+		return s.Update(bson.M{"_id": id}, bson.M{"$unset": bson.M{"retention": ""}})
+	}
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"retention": r}})
+}
+
+// GetRetainedRepositories ..
+// Returns the repositories configured with a retention,
+// and the repositories of the given teams
+func (s *repository) GetRetainedRepositories(teams []string) ([]types.Repository, error) {
+
+	q := bson.M{"$or": []bson.M{
+		{"retention": bson.M{"$exists": true}},
+		{"team": bson.M{"$in": teams}},
+	}}
+
+	var err error
+	var result []types.Repository
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).Select(bson.M{"name": 1, "team": 1, "retention": 1}).All(&result)
+	})
+	return result, err
+}
+
+// UpdateMetadata ..
+// Updates the repository details synced from the vcs, the details
+// the vcs didn't return are kept as is.
+func (s *repository) UpdateMetadata(repo types.Repository) error {
+
+	set := bson.M{
+		"name":        repo.Name,
+		"private":     repo.Private,
+		"fork":        repo.Fork,
+		"description": repo.Description,
+		"sync_status": repo.SyncStatus,
+		"synced_at":   repo.SyncedAt,
+	}
+
+	if repo.Link != "" {
+		set["link"] = repo.Link
+	}
+
+	if repo.DefaultBranch != "" {
+		set["default_branch"] = repo.DefaultBranch
+	}
+
+	if repo.Language != "" {
+		set["language"] = repo.Language
+	}
+
+	if repo.CloneURL != "" {
+		set["clone_url"] = repo.CloneURL
+	}
+
+	return s.Update(bson.M{"_id": repo.ID}, bson.M{"$set": set})
+}
+
+// UpdateSyncStatus ..
+// Flags whether the repository is still accessible
+func (s *repository) UpdateSyncStatus(id bson.ObjectId, status string, at time.Time) error {
+	return s.Update(bson.M{"_id": id}, bson.M{"$set": bson.M{"sync_status": status, "synced_at": at}})
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type schedule struct {
+	Store
+}
+
+// Schedule ..
+// Store persists the schedules of the periodic builds
+type Schedule interface {
+	Interface
+
+	FetchSchedules(team, repositoryID string) ([]types.Schedule, error)
+	FetchDueSchedules(now time.Time) ([]types.Schedule, error)
+	ClaimRun(id bson.ObjectId, prev, next, now time.Time) (bool, error)
+	UpdateLastRun(id bson.ObjectId, buildID, reason string) error
+}
+
+// NewStore ..
+func newScheduleStore(d Database) Schedule {
+	s := &schedule{}
+	s.Database = d
+	s.CollectionName = "schedule"
+	return s
+}
+
+// FetchSchedules ..
+// Returns the schedules of the team, of the repository when given
+func (s *schedule) FetchSchedules(team, repositoryID string) ([]types.Schedule, error) {
+
+	q := bson.M{"team": team}
+	if repositoryID != "" {
+		q["repository_id"] = repositoryID
+	}
+
+	var err error
+	var result []types.Schedule
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(q).Sort("_id").All(&result)
+	})
+	return result, err
+}
+
+// FetchDueSchedules ..
+// Returns the enabled schedules whose next run is due
+func (s *schedule) FetchDueSchedules(now time.Time) ([]types.Schedule, error) {
+
+	var err error
+	var result []types.Schedule
+	s.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"enabled": true, "next_run_at": bson.M{"$lte": now}}).Sort("next_run_at").All(&result)
+	})
+	return result, err
+}
+
+// ClaimRun ..
+// Moves the next run of the schedule, only if it's not moved by another
+// server since it was read. Returns false if the claim is lost.
+func (s *schedule) ClaimRun(id bson.ObjectId, prev, next, now time.Time) (bool, error) {
+
+	err := s.Update(
+		bson.M{"_id": id, "enabled": true, "next_run_at": prev},
+		bson.M{"$set": bson.M{"next_run_at": next, "last_run_at": now}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UpdateLastRun ..
+// Records the build triggered by the last run, or the reason it failed
+func (s *schedule) UpdateLastRun(id bson.ObjectId, buildID, reason string) error {
+
+	return s.UpdateId(id, bson.M{"$set": bson.M{"last_build_id": buildID, "last_error": reason}})
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"strconv"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type buildSlot struct {
+	Store
+}
+
+// BuildSlot ..
+// Store keeps the slots of the concurrent build limits, a slot is taken
+// by a build atomically, so the launchers of all the servers together
+// never exceed the limit.
+type BuildSlot interface {
+	Interface
+
+	Acquire(key string, limit int, buildID string) (bool, error)
+	FetchHolders(key string) ([]string, error)
+	RemoveHolders(key string, buildIDs []string) error
+	Release(buildID string) error
+}
+
+// NewStore ..
+func newBuildSlotStore(d Database) BuildSlot {
+	s := &buildSlot{}
+	s.Database = d
+	s.CollectionName = "build_slot"
+	return s
+}
+
+// Acquire ..
+// Takes a slot of the limit for the build, only if the slots held are
+// fewer than the limit. Returns true if the build holds the slot already.
+func (s *buildSlot) Acquire(key string, limit int, buildID string) (bool, error) {
+
+	// the slot limit-1 doesn't exist while the slots held are fewer than
+	// the limit, the slots document is created by the first build
+	_, err := s.Upsert(
+		bson.M{
+			"_id":                              key,
+			"holders":                          bson.M{"$ne": buildID},
+			"holders." + strconv.Itoa(limit-1): bson.M{"$exists": false},
+		},
+		bson.M{"$push": bson.M{"holders": buildID}},
+	)
+
+	if err == nil {
+		return true, nil
+	}
+
+	if !mgo.IsDup(err) {
+		return false, err
+	}
+
+	// either held by the build or the slots are taken
+	holders, err := s.FetchHolders(key)
+	if err != nil {
+		return false, err
+	}
+
+	for _, h := range holders {
+		if h == buildID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FetchHolders ..
+// Returns the builds holding the slots of the limit
+func (s *buildSlot) FetchHolders(key string) ([]string, error) {
+
+	var slot types.BuildSlot
+	err := s.FindOne(bson.M{"_id": key}, &slot)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return slot.Holders, err
+}
+
+// RemoveHolders ..
+// Frees the slots of the limit held by the builds
+func (s *buildSlot) RemoveHolders(key string, buildIDs []string) error {
+
+	err := s.Update(
+		bson.M{"_id": key},
+		bson.M{"$pull": bson.M{"holders": bson.M{"$in": buildIDs}}},
+	)
+
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Release ..
+// Frees all the slots held by the build
+func (s *buildSlot) Release(buildID string) error {
+
+	var err error
+	s.Execute(func(c *mgo.Collection) {
+		_, err = c.UpdateAll(
+			bson.M{"holders": buildID},
+			bson.M{"$pull": bson.M{"holders": buildID}},
+		)
+	})
+	return err
+}
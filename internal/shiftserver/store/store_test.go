@@ -0,0 +1,35 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package store
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// testDatabase ..
+// Connects to the mongodb given by SHIFT_TEST_MONGO (host:port), the test is
+// skipped without it. The cleanup drops the database.
+func testDatabase(t *testing.T) (Database, func()) {
+
+	server := os.Getenv("SHIFT_TEST_MONGO")
+	if server == "" {
+		t.Skip("SHIFT_TEST_MONGO is not set")
+	}
+
+	session, err := mgo.DialWithTimeout(server, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", server, err)
+	}
+
+	d := Database{Session: session, Name: "shifttest_" + bson.NewObjectId().Hex()}
+	return d, func() {
+		session.DB(d.Name).DropDatabase()
+		session.Close()
+	}
+}
@@ -5,6 +5,7 @@ package store
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/elasticshift/elasticshift/api/types"
 	mgo "gopkg.in/mgo.v2"
@@ -30,6 +31,14 @@ type Team interface {
 	UpdateVCS(team string, vcs types.VCS) error
 	GetVCSByID(team, id string) (types.VCS, error)
 	GetVCSByName(team, name, source string) (*types.VCS, error)
+
+	// Background sync of the linked accounts
+	GetLinkedTeams() ([]types.Team, error)
+	ClaimSync(team, id string, prev, now time.Time) (bool, error)
+
+	// Retention of the build history
+	UpdateRetention(team string, r *types.Retention) error
+	GetRetainedTeams() ([]types.Team, error)
 }
 
 // NewStore related database operations
@@ -125,8 +134,10 @@ func (r *team) UpdateVCS(team string, vcs types.VCS) error {
 	r.Execute(func(c *mgo.Collection) {
 		err = c.Update(bson.M{"name": team, "accounts.id": vcs.ID},
 			bson.M{"$set": bson.M{
-				"accounts.$.owner_type":   vcs.OwnerType,
-				"accounts.$.token_expiry": vcs.TokenExpiry}})
+				"accounts.$.owner_type":    vcs.OwnerType,
+				"accounts.$.access_token":  vcs.AccessToken,
+				"accounts.$.refresh_token": vcs.RefreshToken,
+				"accounts.$.token_expiry":  vcs.TokenExpiry}})
 	})
 	return err
 }
@@ -144,3 +155,64 @@ func (s *team) GetVCSByName(team, name, source string) (*types.VCS, error) {
 	}
 	return &t.Accounts[0], err
 }
+
+// GetLinkedTeams ..
+// Returns the teams that have linked any vcs account
+func (r *team) GetLinkedTeams() ([]types.Team, error) {
+
+	var err error
+	var result []types.Team
+	r.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"accounts.0": bson.M{"$exists": true}}).Select(bson.M{"name": 1, "accounts": 1}).All(&result)
+	})
+	return result, err
+}
+
+// ClaimSync ..
+// Moves the sync time of the account, only if it's not moved by
+// another server since it was read. Returns false if the claim is lost.
+func (r *team) ClaimSync(team, id string, prev, now time.Time) (bool, error) {
+
+	match := bson.M{"id": id}
+	if prev.IsZero() {
+		match["synced_at"] = bson.M{"$exists": false}
+	} else {
+		match["synced_at"] = prev
+	}
+
+	var err error
+	r.Execute(func(c *mgo.Collection) {
+		err = c.Update(
+			bson.M{"name": team, "accounts": bson.M{"$elemMatch": match}},
+			bson.M{"$set": bson.M{"accounts.$.synced_at": now}},
+		)
+	})
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UpdateRetention ..
+// Sets the build history kept for the repositories of the team,
+// removed when it's nil
+func (r *team) UpdateRetention(team string, ret *types.Retention) error {
+
+	if ret == nil {
+		return r.Update(bson.M{"name": team}, bson.M{"$unset": bson.M{"retention": ""}})
+	}
+	return r.Update(bson.M{"name": team}, bson.M{"$set": bson.M{"retention": ret}})
+}
+
+// GetRetainedTeams ..
+// Returns the teams configured with a retention
+func (r *team) GetRetainedTeams() ([]types.Team, error) {
+
+	var err error
+	var result []types.Team
+	r.Execute(func(c *mgo.Collection) {
+		err = c.Find(bson.M{"retention": bson.M{"$exists": true}}).Select(bson.M{"name": 1, "retention": 1}).All(&result)
+	})
+	return result, err
+}
@@ -65,12 +65,22 @@ func (r *resolver) CreateVCSSysConf(params graphql.ResolveParams) (interface{},
 	key, _ := params.Args["key"].(string)
 	secret, _ := params.Args["secret"].(string)
 	callbackURL, _ := params.Args["callbackURL"].(string)
+	hookURL, _ := params.Args["hookURL"].(string)
+	hookSecret, _ := params.Args["hookSecret"].(string)
+	provider, _ := params.Args["provider"].(string)
+	apiURL, _ := params.Args["apiURL"].(string)
+	webURL, _ := params.Args["webURL"].(string)
 
 	res := &types.VCSSysConf{}
 	res.Name = name
 	res.Key = key
 	res.Secret = secret
 	res.CallbackURL = callbackURL
+	res.HookURL = hookURL
+	res.HookSecret = hookSecret
+	res.Provider = provider
+	res.APIURL = apiURL
+	res.WebURL = webURL
 	res.Kind = VcsKind
 
 	result, err := r.FetchVCSSysConfByName(params)
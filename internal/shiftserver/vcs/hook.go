@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package vcs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/build"
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maximum size of the hook payload accepted
+const maxHookPayload = 5 << 20
+
+// Hook ..
+// Invoked by the version control system on push and pull request events,
+// triggers the build for every repository that has the hook enabled.
+func (s service) Hook(w http.ResponseWriter, r *http.Request) {
+
+	provider := mux.Vars(r)["provider"]
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Getting provider %s failed: %v", provider, err), http.StatusNotFound)
+		return
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxHookPayload))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read the hook payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = p.VerifyHook(r, payload)
+	if err != nil {
+		s.logger.Warnf("Hook verification failed for %s: %v", provider, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	e, err := p.ParseHook(r, payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if e.Kind == "" {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Event ignored")
+		return
+	}
+
+	d := &types.HookDelivery{}
+	d.ID = provider + ":" + e.DeliveryID
+	d.Provider = provider
+	d.Event = e.Kind
+	d.ReceivedAt = time.Now()
+
+	fresh, err := s.hookStore.SaveDelivery(d)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record the hook delivery: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !fresh {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Delivery already processed")
+		return
+	}
+
+	var repos []types.Repository
+	q := bson.M{"repo_id": e.Repository.RepoID, "source": p.Source(), "hook_enabled": true}
+	err = s.repositoryStore.FindAll(q, &repos)
+	if err != nil {
+		s.hookStore.Remove(d.ID)
+		http.Error(w, fmt.Sprintf("Failed to fetch the repositories: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var handled, triggered int
+	for _, repo := range repos {
+
+		opts := build.TriggerOptions{}
+		opts.Repository = repo
+		opts.Branch = e.Branch
+		opts.CommitID = e.CommitID
+		opts.TriggeredBy = e.Sender
+		opts.Message = e.Message
+		opts.Labels = e.Labels
+
+		// pipelines of the repository are triggered by the changed paths,
+		// the repository is handled when any of them is triggered
+		builds, err := s.rs.Build.TriggerChanged(opts)
+		triggered += len(builds)
+		if err != nil {
+			s.logger.Errorf("Failed to trigger the build for repository %s: %v", repo.ID.Hex(), err)
+			if len(builds) == 0 {
+				continue
+			}
+		}
+		handled++
+	}
+
+	// let the provider redeliver, if none of the repositories are handled
+	if len(repos) > 0 && handled == 0 {
+		s.hookStore.Remove(d.ID)
+		http.Error(w, "Failed to trigger the build", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "Triggered %d build(s)", triggered)
+}
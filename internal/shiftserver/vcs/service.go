@@ -16,8 +16,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/elasticshift/elasticshift/api/types"
 	"github.com/elasticshift/elasticshift/internal/pkg/logger"
-	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/identity/oauth2/providers"
+	"github.com/elasticshift/elasticshift/internal/shiftserver/resolver"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/secret"
 	"github.com/elasticshift/elasticshift/internal/shiftserver/store"
 )
@@ -53,29 +53,36 @@ const (
 )
 
 type service struct {
-	store     store.Vcs
-	teamStore store.Team
-	vault     secret.Vault
-	logger    *logrus.Entry
-	providers providers.Providers
+	store           store.Vcs
+	teamStore       store.Team
+	repositoryStore store.Repository
+	hookStore       store.Hook
+	vault           secret.Vault
+	logger          *logrus.Entry
+	providers       providers.Providers
+	rs              *resolver.Shift
 }
 
 // Service ..
 type Service interface {
 	Authorize(w http.ResponseWriter, r *http.Request)
 	Authorized(w http.ResponseWriter, r *http.Request)
+	Hook(w http.ResponseWriter, r *http.Request)
 }
 
 // NewVCSService ..
-func NewService(loggr logger.Loggr, d store.Database, providers providers.Providers, s store.Shift, vault secret.Vault) Service {
+func NewService(loggr logger.Loggr, d store.Database, providers providers.Providers, s store.Shift, vault secret.Vault, rs *resolver.Shift) Service {
 
 	l := loggr.GetLogger("service/vcs")
 	return &service{
-		store:     s.Vcs,
-		teamStore: s.Team,
-		vault:     vault,
-		logger:    l,
-		providers: providers,
+		store:           s.Vcs,
+		teamStore:       s.Team,
+		repositoryStore: s.Repository,
+		hookStore:       s.Hook,
+		vault:           vault,
+		logger:          l,
+		providers:       providers,
+		rs:              rs,
 	}
 }
 
@@ -101,7 +108,7 @@ func (s service) Authorize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	source := vcs.GetSource(provider)
+	source := p.Source()
 
 	var buf bytes.Buffer
 	buf.WriteString(source)
@@ -163,6 +170,8 @@ func (s service) Authorized(w http.ResponseWriter, r *http.Request) {
 
 		// updvcs.UpdatedDt = time.Now()
 		acc.OwnerType = u.OwnerType
+		acc.AccessToken = u.AccessToken
+		acc.RefreshToken = u.RefreshToken
 		acc.TokenExpiry = u.TokenExpiry
 
 		// update the key id
@@ -176,11 +185,8 @@ func (s service) Authorized(w http.ResponseWriter, r *http.Request) {
 	var sec types.Secret
 	secretID := s.saveSecret(sec, u, team, w)
 
-	if err == nil {
-
-		// TODO sync the repo and setup hook reqeust for the repo
-		// go p.CreateHook(u.AccessCode, u.Name, u.OwnerType)
-	}
+	// hooks are created per repository through enableHook mutation
+	// TODO sync the repo
 
 	// u.ID = utils.NewUUID()
 	// u.CreatedDt = time.Now()
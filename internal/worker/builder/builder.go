@@ -15,7 +15,6 @@ import (
 	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/ast"
 	"github.com/elasticshift/elasticshift/internal/pkg/shiftfile/parser"
 	"github.com/elasticshift/elasticshift/internal/pkg/storage"
-	"github.com/elasticshift/elasticshift/internal/pkg/vcs"
 	"github.com/elasticshift/elasticshift/internal/worker/logshipper"
 	wtypes "github.com/elasticshift/elasticshift/internal/worker/types"
 	"google.golang.org/grpc"
@@ -34,6 +33,12 @@ type builder struct {
 	f *ast.File
 	g *graph.Graph
 
+	// checkpoint the workspace is archived at, stored if the build fails
+	snapshot string
+
+	// the commit of the build is checked out in the workspace
+	checkedOut bool
+
 	done chan int
 
 	writer io.Writer
@@ -56,7 +61,7 @@ func New(ctx wtypes.Context, shiftconn *grpc.ClientConn, writer io.Writer, done
 func (b *builder) run() error {
 
 	// Get the project information
-	proj, err := b.shiftclient.GetProject(b.ctx, &api.GetProjectReq{BuildId: b.config.BuildID, IncludeShiftfile: !b.config.RepoBasedShiftFile})
+	proj, err := b.shiftclient.GetProject(b.ctx, &api.GetProjectReq{BuildId: b.config.BuildID, IncludeShiftfile: true})
 	if err != nil {
 		return fmt.Errorf("Failed to get the project/repository detail from shift server: %v\n", err)
 	}
@@ -71,14 +76,11 @@ func (b *builder) run() error {
 	// 3. Fetch the shiftfile
 
 	// 4. otherwise use the global language spec defined by elasticshift
-	var f []byte
-	if b.config.RepoBasedShiftFile {
-		f, err = vcs.GetShiftFile(proj.Source, proj.CloneUrl, proj.Branch)
-		if err != nil {
-			return errors.Errorf("Failed to get shift file (source: %s, CloneUrl: %s, branch : %s): %v\n", proj.Source, proj.CloneUrl, proj.Branch, err)
-		}
-	} else {
-		f = []byte(proj.GetShiftfile())
+	// the shiftfile of the repository is fetched by the server, that knows
+	// the api endpoint of the vcs account the repository is linked to
+	f := []byte(proj.GetShiftfile())
+	if len(f) == 0 && b.config.RepoBasedShiftFile {
+		return errors.Errorf("Failed to get shift file (source: %s, CloneUrl: %s, branch : %s): not received from the server\n", proj.Source, proj.CloneUrl, proj.Branch)
 	}
 
 	// 5. Parse the shiftfile
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+)
+
+// checkoutFailed ..
+// Checks out the commit of the build once the checkpoint cloned the source
+// into the workspace, the shiftfile clones the branch that may have moved
+// since. The checkpoint is failed when the commit can't be checked out.
+func (b *builder) checkoutFailed(c *graph.Checkpoint) bool {
+
+	err := b.checkout()
+	if err == nil {
+		return false
+	}
+
+	msg := fmt.Sprintf("Failed to check out commit %s: %v", b.config.Commit, err)
+	b.wctx.EnvLogger.Println(msg)
+	c.Node.SetStatus(graph.StatusFailed)
+	c.Node.Message = msg
+	b.UpdateBuildGraphToShiftServer(graph.StatusFailed, c.Node.Name, msg, b.wctx.EnvLogger)
+
+	return true
+}
+
+// checkout ..
+// Checks out the commit in the workspace, if the workspace is a clone of
+// the repository. It's done once, the nodes after build the commit.
+func (b *builder) checkout() error {
+
+	if b.config.Commit == "" || b.checkedOut {
+		return nil
+	}
+
+	wdir, err := b.workspace()
+	if err != nil {
+		return err
+	}
+
+	// the source isn't cloned yet
+	if _, err := os.Stat(filepath.Join(wdir, ".git")); err != nil {
+		return nil
+	}
+	b.checkedOut = true
+
+	err = checkoutCommit(wdir, b.config.Commit)
+	if err != nil {
+		return err
+	}
+
+	b.wctx.EnvLogger.Printf("Checked out commit %s in %s\n", b.config.Commit, wdir)
+	return nil
+}
+
+// checkoutCommit ..
+// Checks out the commit in the clone, fetched first if the shallow clone
+// doesn't hold it
+func checkoutCommit(dir, commit string) error {
+
+	head, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err == nil && head == commit {
+		return nil
+	}
+
+	if _, err := gitOutput(dir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+
+		_, err = gitOutput(dir, "fetch", "-q", "origin", commit)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = gitOutput(dir, "checkout", "-q", "--detach", commit)
+	return err
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutCommit(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "checkout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	origin := filepath.Join(dir, "origin")
+	err = os.Mkdir(origin, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(dir string, args ...string) string {
+		out, err := gitOutput(dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	commit := func(msg string) string {
+		run(origin, "-c", "user.name=shift", "-c", "user.email=shift@localhost", "commit", "--quiet", "--allow-empty", "-m", msg)
+		return run(origin, "rev-parse", "HEAD")
+	}
+
+	run(origin, "init", "--quiet")
+	run(origin, "checkout", "--quiet", "-b", "main")
+	run(origin, "config", "uploadpack.allowAnySHA1InWant", "true")
+	first := commit("First")
+	second := commit("Second")
+	commit("Third")
+
+	full := filepath.Join(dir, "full")
+	run(dir, "clone", "--quiet", "file://"+origin, full)
+
+	err = checkoutCommit(full, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if head := run(full, "rev-parse", "HEAD"); head != second {
+		t.Fatalf("expected %s to be checked out, got %s", second, head)
+	}
+
+	// the commit isn't in the shallow clone, it's fetched
+	shallow := filepath.Join(dir, "shallow")
+	run(dir, "clone", "--quiet", "--depth", "1", "file://"+origin, shallow)
+
+	err = checkoutCommit(shallow, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if head := run(shallow, "rev-parse", "HEAD"); head != first {
+		t.Fatalf("expected %s to be checked out, got %s", first, head)
+	}
+
+	err = checkoutCommit(shallow, "0000000000000000000000000000000000000001")
+	if err == nil {
+		t.Fatal("expected failure for unknown commit")
+	}
+}
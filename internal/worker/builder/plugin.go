@@ -25,9 +25,17 @@ func (b *builder) invokePlugin(n *graph.N) (string, error) {
 	if SHELL == n.Name {
 		msg, err = b.invokeShell(n)
 	} else if graph.RESTORE_CACHE == n.Name {
+
+		// the cache is saved afresh at the end of the build
+		if b.config.RebuildCache {
+			n.Logger.Printf("Skipping the cache restore, as the cache is directed to be rebuilt\n")
+			return "", nil
+		}
 		err = b.restoreCache(n.Logger)
 	} else if graph.SAVE_CACHE == n.Name {
 		err = b.saveCache(n.Logger)
+	} else if TRIGGER == n.Name {
+		msg, err = b.invokeTrigger(n)
 	}
 
 	if err != nil {
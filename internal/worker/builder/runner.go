@@ -45,6 +45,10 @@ func (b *builder) build(g *graph.Graph) error {
 
 	var failed bool
 
+	// a rerun resumes from the checkpoint of the node,
+	// the blocks before are reused from the earlier build
+	resume := b.resumeAt(g)
+
 	// walk through the checkpoints and execute them
 	checkpoints := g.Checkpoints()
 	for i := 0; i < len(checkpoints); i++ {
@@ -55,6 +59,26 @@ func (b *builder) build(g *graph.Graph) error {
 		}
 
 		c := checkpoints[i]
+
+		if i < resume && snapshotPoint(c) {
+			b.reuse(c)
+			continue
+		}
+
+		if i == resume {
+
+			err := b.restoreSnapshot(checkpoints, i)
+			if err != nil {
+
+				msg := fmt.Sprintf("Failed to restore the workspace: %v", err)
+				b.wctx.EnvLogger.Println(msg)
+				c.Node.End(graph.StatusFailed, msg)
+				b.UpdateBuildGraphToShiftServer(graph.StatusFailed, c.Node.Name, msg, b.wctx.EnvLogger)
+
+				b.done <- 1
+				return nil
+			}
+		}
 		// s += fmt.Sprintf("(%d) %s\n", i+1, c.node.Name())
 
 		// run block if it is a sequential task
@@ -99,6 +123,11 @@ func (b *builder) build(g *graph.Graph) error {
 
 					defer b.ShipLog(n.ID, n.Name)
 					defer wg.Done()
+
+					if !b.selected(n) {
+						b.skipNode(n)
+						return
+					}
 					parallelCh <- 1
 
 					n.Start()
@@ -106,7 +135,13 @@ func (b *builder) build(g *graph.Graph) error {
 
 					msg, err := b.invokePlugin(n)
 
-					if err != nil {
+					if err != nil && b.allowedToFail(n) {
+
+						nodelogger.Printf("Failed when executing %s, the failure is allowed : %v\n", n.Name, err)
+						n.End(graph.StatusAllowedFailure, msg)
+						b.UpdateBuildGraphToShiftServer(graph.StatusAllowedFailure, n.Name, msg, nodelogger)
+
+					} else if err != nil {
 
 						errMutex.Lock()
 						defer errMutex.Unlock()
@@ -141,8 +176,21 @@ func (b *builder) build(g *graph.Graph) error {
 		} else {
 			failed = b.runNode(c.Node)
 		}
+
+		if !failed {
+			failed = b.checkoutFailed(c)
+		}
+
+		// the rerun of the failed build is resumed from the failed node
+		if failed {
+			b.saveSnapshot()
+		} else if snapshotNeeded(checkpoints, i) {
+			b.takeSnapshot(c)
+		}
 	}
 
+	b.dropSnapshot()
+
 	return nil
 }
 
@@ -158,12 +206,25 @@ func (b *builder) runNode(n *graph.N) bool {
 
 	// defer b.ShipLog(n.ID, n.Name)
 
+	if !b.selected(n) {
+		b.skipNode(n)
+		return false
+	}
+
 	n.Start()
 	b.UpdateBuildGraphToShiftServer(graph.StatusRunning, n.Name, "", nodelogger)
 
 	// sequential checkpoint execution
 	msg, err := b.invokePlugin(n)
-	if err != nil {
+	if err != nil && b.allowedToFail(n) {
+
+		nodelogger.Printf("Failed when executing %s, the failure is allowed : %v\n", n.Name, err)
+		n.End(graph.StatusAllowedFailure, msg)
+
+		b.ShipLog(n.ID, n.Name)
+		b.UpdateBuildGraphToShiftServer(graph.StatusAllowedFailure, n.Name, msg, nodelogger)
+
+	} else if err != nil {
 		n.End(graph.StatusFailed, msg)
 
 		b.ShipLog(n.ID, n.Name)
@@ -181,6 +242,57 @@ func (b *builder) runNode(n *graph.N) bool {
 	return failed
 }
 
+// selected ..
+// True if the node has to be run, when the build is directed to run only
+// the named blocks, the rest of the blocks are skipped. The nodes added by
+// the system (such as cache, fanout) are always run.
+func (b *builder) selected(n *graph.N) bool {
+
+	if len(b.config.Only) == 0 || systemNode(n.Name) {
+		return true
+	}
+
+	for _, name := range b.config.Only {
+		if graph.SameKey(name, n.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedToFail ..
+// True if the node is quarantined in the repository, its failure doesn't
+// fail the build. The nodes added by the system are never allowed to fail.
+func (b *builder) allowedToFail(n *graph.N) bool {
+
+	if systemNode(n.Name) {
+		return false
+	}
+
+	for _, name := range b.config.AllowFailure {
+		if graph.SameKey(name, n.Key()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) skipNode(n *graph.N) {
+
+	n.Logger.Printf("Skipping '%s', the build is directed to run only %s\n", n.Description, strings.Join(b.config.Only, ", "))
+	n.SetStatus(graph.StatusSkipped)
+	b.UpdateBuildGraphToShiftServer(graph.StatusSkipped, n.Name, "", n.Logger)
+}
+
+func systemNode(name string) bool {
+
+	switch name {
+	case graph.START, graph.END, graph.ENV, graph.RESTORE_CACHE, graph.SAVE_CACHE:
+		return true
+	}
+	return strings.HasPrefix(name, graph.FANOUT) || strings.HasPrefix(name, graph.FANIN)
+}
+
 func (b *builder) ShipLog(nodeid, name string) {
 
 	if name == graph.START || name == graph.END || strings.HasPrefix(name, graph.FANOUT) || strings.HasPrefix(name, graph.FANIN) {
@@ -0,0 +1,190 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+	"github.com/mholt/archiver"
+	homedir "github.com/minio/go-homedir"
+)
+
+var (
+	DIR_SNAPSHOT = "/tmp/shiftsnapshot"
+)
+
+// snapshotPoint ..
+// The workspace is snapshotted after the checkpoints running the blocks of
+// the shiftfile, a rerun is resumed from any of these checkpoints.
+func snapshotPoint(c *graph.Checkpoint) bool {
+	return len(c.Edges) > 0 || !systemNode(c.Node.Name)
+}
+
+// snapshotNeeded ..
+// A snapshot is taken only if there is a checkpoint after, to resume from
+func snapshotNeeded(checkpoints []*graph.Checkpoint, i int) bool {
+
+	if !snapshotPoint(checkpoints[i]) {
+		return false
+	}
+
+	for _, c := range checkpoints[i+1:] {
+		if snapshotPoint(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeAt ..
+// Index of the checkpoint the rerun resumes from, -1 if the build runs from the start
+func (b *builder) resumeAt(g *graph.Graph) int {
+
+	if b.config.FromNode == "" {
+		return -1
+	}
+
+	idx, _ := g.CheckpointOf(b.config.FromNode)
+	if idx < 0 {
+		b.wctx.EnvLogger.Printf("Node %s is not found in the graph, running the build from the start\n", b.config.FromNode)
+	}
+	return idx
+}
+
+// reuse ..
+// Marks the checkpoint as reused from the build rerun
+func (b *builder) reuse(c *graph.Checkpoint) {
+
+	c.Node.SetStatus(graph.StatusReused)
+	for _, e := range c.Edges {
+		e.SetStatus(graph.StatusReused)
+	}
+
+	b.UpdateBuildGraphToShiftServer(graph.StatusReused, c.Node.Name, "", b.wctx.EnvLogger)
+}
+
+// workspace ..
+// The working directory of the build, snapshotted after the checkpoints
+func (b *builder) workspace() (string, error) {
+
+	if wdir := b.f.WorkDir(); wdir != "" {
+		return homedir.Expand(wdir)
+	}
+	return os.Getwd()
+}
+
+// takeSnapshot ..
+// Archives the workspace at the checkpoint, replacing the archive taken at
+// the checkpoint before. It's stored only if the build fails after, as the
+// rerun is resumed from the node the build failed at. A failure is only
+// logged as it's needed only when the build is rerun.
+func (b *builder) takeSnapshot(c *graph.Checkpoint) {
+
+	log1 := b.wctx.EnvLogger
+
+	b.dropSnapshot()
+
+	wdir, err := b.workspace()
+	if err == nil {
+		err = utils.Mkdir(DIR_SNAPSHOT)
+	}
+
+	file := filepath.Join(DIR_SNAPSHOT, c.Node.ID+".tar.gz")
+	if err == nil {
+		err = archiver.Archive([]string{wdir}, file)
+	}
+
+	if err != nil {
+		os.Remove(file)
+		log1.Printf("Failed to snapshot the workspace at checkpoint %s: %v\n", c.Node.ID, err)
+		return
+	}
+	b.snapshot = c.Node.ID
+}
+
+// saveSnapshot ..
+// Stores the archive taken at the checkpoint before the failed one
+func (b *builder) saveSnapshot() {
+
+	if b.snapshot == "" {
+		return
+	}
+	defer b.dropSnapshot()
+
+	err := b.storage.PutSnapshot(b.snapshot, filepath.Join(DIR_SNAPSHOT, b.snapshot+".tar.gz"))
+	if err != nil {
+		b.wctx.EnvLogger.Printf("Failed to snapshot the workspace at checkpoint %s: %v\n", b.snapshot, err)
+	}
+}
+
+// dropSnapshot ..
+// Removes the archive taken, if any
+func (b *builder) dropSnapshot() {
+
+	if b.snapshot != "" {
+		os.Remove(filepath.Join(DIR_SNAPSHOT, b.snapshot+".tar.gz"))
+		b.snapshot = ""
+	}
+}
+
+// restoreSnapshot ..
+// Restores the workspace from the snapshot taken by the earlier build,
+// at the last checkpoint before the one the build is resumed from.
+func (b *builder) restoreSnapshot(checkpoints []*graph.Checkpoint, resume int) error {
+
+	at := -1
+	for i := resume - 1; i >= 0; i-- {
+		if snapshotPoint(checkpoints[i]) {
+			at = i
+			break
+		}
+	}
+
+	// nothing had run before the checkpoint
+	if at < 0 {
+		return nil
+	}
+
+	if b.config.SnapshotBuildID == "" {
+		return fmt.Errorf("The build to restore the snapshot from is not given")
+	}
+
+	wdir, err := b.workspace()
+	if err != nil {
+		return err
+	}
+
+	err = utils.Mkdir(DIR_SNAPSHOT)
+	if err != nil {
+		return err
+	}
+
+	id := checkpoints[at].Node.ID
+	m := &types.StorageMetadata{
+		TeamID:       b.config.TeamID,
+		RepositoryID: b.project.GetRepositoryId(),
+		BuildID:      b.config.SnapshotBuildID,
+		SubBuildID:   b.config.SubBuildID,
+		Branch:       b.project.GetBranch(),
+		Path:         b.project.GetStoragePath(),
+	}
+
+	file := filepath.Join(DIR_SNAPSHOT, id+".tar.gz")
+	defer os.Remove(file)
+
+	err = b.storage.GetSnapshotWithMetadata(id, file, m)
+	if err != nil {
+		return err
+	}
+
+	b.wctx.EnvLogger.Printf("Restoring the workspace %s from checkpoint %s of build %s\n", wdir, id, b.config.SnapshotBuildID)
+
+	// the archive holds the workspace directory itself
+	return archiver.Unarchive(file, filepath.Dir(filepath.Clean(wdir)))
+}
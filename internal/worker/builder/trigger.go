@@ -0,0 +1,178 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elasticshift/elasticshift/api"
+	"github.com/elasticshift/elasticshift/api/types"
+	"github.com/elasticshift/elasticshift/internal/pkg/graph"
+	"github.com/elasticshift/elasticshift/internal/pkg/utils"
+)
+
+var (
+	TRIGGER = "elasticshift/trigger"
+
+	// properties of the trigger block
+	TRIGGER_REPO      = "repo"
+	TRIGGER_BRANCH    = "branch"
+	TRIGGER_PIPELINE  = "pipeline"
+	TRIGGER_PARAMS    = "params"
+	TRIGGER_WAIT      = "wait"
+	TRIGGER_PROPAGATE = "propagate"
+
+	// interval the status of the downstream build is polled
+	triggerPollInterval = 10 * time.Second
+
+	// the status is polled again on failure, waiting twice as long each time
+	triggerStatusAttempts = 5
+	triggerStatusBackoff  = 2 * time.Second
+)
+
+// invokeTrigger ..
+// Triggers the build of another repository of the team, such as
+//
+//	"elasticshift/trigger", "Build the dependent services" {
+//		repo "acme/payments"
+//		branch "master"
+//		params ["LIB_VERSION=1.2.0", "DEPLOY=false"]
+//		wait "true"
+//	}
+//
+// The parameters are set as the environment of the build. When it waits,
+// the block fails if the build isn't successful, unless propagate is "false".
+func (b *builder) invokeTrigger(n *graph.N) (string, error) {
+
+	item := n.Item()
+
+	req := &api.TriggerBuildReq{}
+	req.BuildId = b.config.BuildID
+	req.SubBuildId = b.config.SubBuildID
+	req.Node = n.Description
+	req.Repository, _ = item[TRIGGER_REPO].(string)
+	req.Branch, _ = item[TRIGGER_BRANCH].(string)
+	req.Pipeline, _ = item[TRIGGER_PIPELINE].(string)
+
+	switch params := item[TRIGGER_PARAMS].(type) {
+	case []string:
+		req.Parameters = params
+	case string:
+		req.Parameters = []string{params}
+	}
+
+	wait, err := boolProperty(item, TRIGGER_WAIT, false)
+	if err != nil {
+		return err.Error(), err
+	}
+	req.Wait = wait
+
+	propagate, err := boolProperty(item, TRIGGER_PROPAGATE, true)
+	if err != nil {
+		return err.Error(), err
+	}
+
+	if req.Repository == "" {
+		err := fmt.Errorf("The repository to trigger is not given, expected '%s' property", TRIGGER_REPO)
+		return err.Error(), err
+	}
+
+	res, err := b.shiftclient.TriggerBuild(b.ctx, req)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to trigger the build of %s: %v", req.Repository, err)
+		return msg, errors.New(msg)
+	}
+
+	n.Logger.Printf("Triggered the build %s of %s\n", res.GetBuildId(), req.Repository)
+
+	if !wait {
+		return "", nil
+	}
+
+	status, reason, err := b.waitForBuild(n, res.GetBuildId())
+	if err != nil {
+		msg := fmt.Sprintf("Failed to wait for the build %s of %s: %v", res.GetBuildId(), req.Repository, err)
+		return msg, errors.New(msg)
+	}
+
+	n.Logger.Printf("The build %s of %s finished as %s\n", res.GetBuildId(), req.Repository, status)
+
+	if status != types.BuildStatusSuccess && propagate {
+
+		msg := fmt.Sprintf("The build %s of %s finished as %s", res.GetBuildId(), req.Repository, status)
+		if reason != "" {
+			msg += ": " + reason
+		}
+		return msg, errors.New(msg)
+	}
+
+	return "", nil
+}
+
+// waitForBuild ..
+// Polls the status of the build until it's finished, the server going away
+// for a while doesn't fail the wait
+func (b *builder) waitForBuild(n *graph.N, buildID string) (string, string, error) {
+
+	t := time.NewTicker(triggerPollInterval)
+	defer t.Stop()
+
+	var last string
+	for {
+
+		var res *api.GetBuildStatusRes
+		err := utils.Retry(triggerStatusAttempts, triggerStatusBackoff, func() error {
+
+			var err error
+			res, err = b.shiftclient.GetBuildStatus(b.ctx, &api.GetBuildStatusReq{BuildId: buildID})
+			if err != nil && b.ctx.Err() != nil {
+				return utils.Permanent(err)
+			}
+
+			if err != nil {
+				n.Logger.Printf("Failed to get the status of the build %s, retrying: %v\n", buildID, err)
+			}
+			return err
+		})
+
+		if err != nil {
+			return "", "", err
+		}
+
+		status := res.GetStatus()
+		switch status {
+		case types.BuildStatusWaiting, types.BuildStatusPreparing, types.BuildStatusRunning:
+		default:
+			return status, res.GetReason(), nil
+		}
+
+		if status != last {
+			n.Logger.Printf("Waiting for the build %s, it's %s\n", buildID, status)
+			last = status
+		}
+
+		select {
+		case <-b.ctx.Done():
+			return "", "", b.ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func boolProperty(item map[string]interface{}, name string, def bool) (bool, error) {
+
+	v, _ := item[name].(string)
+	if v == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("Invalid value '%s' of '%s', expected true or false", v, name)
+	}
+	return b, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Elasticshift Authors.
+*/
+package worker
+
+import (
+	"time"
+
+	"github.com/elasticshift/elasticshift/api"
+)
+
+// interval the worker reports alive to the shift server, the server
+// marks the build as stuck when the heartbeats are missed for a while
+var heartbeatInterval = 30 * time.Second
+
+// Heartbeat ..
+// Reports the worker is alive to the shift server, until the build is
+// done. The worker is halted when the server no longer expects the build
+// to run, such as when it's cancelled or marked as stuck.
+func (w *W) Heartbeat() {
+
+	log1 := w.Context.EnvLogger
+
+	t := time.NewTicker(heartbeatInterval)
+	defer t.Stop()
+
+	req := &api.HeartbeatReq{}
+	req.BuildId = w.Config.BuildID
+	req.SubBuildId = w.Config.SubBuildID
+
+	for {
+
+		select {
+		case <-w.Context.Context.Done():
+			return
+		case <-t.C:
+		}
+
+		res, err := w.Context.Client.Heartbeat(w.Context.Context, req)
+		if err != nil {
+			log1.Printf("Failed to send the heartbeat: %v\n", err)
+			continue
+		}
+
+		if res.GetStop() {
+			w.stopped <- res.GetStatus()
+			return
+		}
+	}
+}
@@ -38,4 +38,19 @@ type Config struct {
 	SubBuildID         string
 	TeamID             string
 	RepoBasedShiftFile bool
+
+	// directives given through the commit message or the labels
+	RebuildCache bool
+	Only         []string
+
+	// blocks allowed to fail without failing the build
+	AllowFailure []string
+
+	// the commit built, checked out once the source is in the workspace
+	Commit string
+
+	// a rerun resumes from the node, the workspace is restored
+	// from the snapshots taken by the build
+	FromNode        string
+	SnapshotBuildID string
 }
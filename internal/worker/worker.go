@@ -5,12 +5,14 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/elasticshift/elasticshift/api"
@@ -145,6 +147,36 @@ func Run() error {
 	repoBasedShiftFile := os.Getenv("SHIFT_REPOFILE")
 	cfg.RepoBasedShiftFile, _ = strconv.ParseBool(repoBasedShiftFile)
 
+	cfg.RebuildCache, _ = strconv.ParseBool(os.Getenv("SHIFT_REBUILD_CACHE"))
+	if cfg.RebuildCache {
+		log1.Print("SHIFT_REBUILD_CACHE=true, the cache is not restored \n")
+	}
+
+	if only := os.Getenv("SHIFT_ONLY"); only != "" {
+
+		err := json.Unmarshal([]byte(only), &cfg.Only)
+		if err != nil {
+			return fmt.Errorf("Invalid SHIFT_ONLY %s: %v", only, err)
+		}
+		log1.Printf("SHIFT_ONLY=%s\n", only)
+	}
+
+	if allow := os.Getenv("SHIFT_ALLOW_FAILURE"); allow != "" {
+		cfg.AllowFailure = strings.Split(allow, ",")
+		log1.Printf("SHIFT_ALLOW_FAILURE=%s\n", allow)
+	}
+
+	if commit := os.Getenv("SHIFT_COMMIT"); commit != "" {
+		cfg.Commit = commit
+		log1.Printf("SHIFT_COMMIT=%s\n", commit)
+	}
+
+	if fromNode := os.Getenv("SHIFT_FROM_NODE"); fromNode != "" {
+		cfg.FromNode = fromNode
+		cfg.SnapshotBuildID = os.Getenv("SHIFT_SNAPSHOT_BUILDID")
+		log1.Printf("SHIFT_FROM_NODE=%s, SHIFT_SNAPSHOT_BUILDID=%s\n", fromNode, cfg.SnapshotBuildID)
+	}
+
 	ctx := types.Context{}
 	ctx.Context = bctx
 	ctx.Config = cfg
@@ -170,6 +202,7 @@ type W struct {
 	GRPCServer *grpc.Server
 	errch      chan error
 	done       chan int
+	stopped    chan string
 
 	// logger      logshipper.Logger
 	ShiftServer *grpc.ClientConn
@@ -190,6 +223,7 @@ func Start(ctx types.Context) error {
 	w.Context = ctx
 	w.errch = make(chan error)
 	w.done = make(chan int)
+	w.stopped = make(chan string, 1)
 
 	log1 := ctx.EnvLogger
 
@@ -228,6 +262,9 @@ func Start(ctx types.Context) error {
 			return
 		}
 
+		// Reports alive to the shift server, while the build runs
+		go w.Heartbeat()
+
 		// Listener on worker to receive command from shift server.
 		w.StartGRPCServer()
 
@@ -252,6 +289,9 @@ func Start(ctx types.Context) error {
 		msg := fmt.Sprintf("Worker has been timed-out after running for about %s minutes, and all the process have been halted", ctx.Config.Timeout)
 		w.UpdateShiftServer(statusFailed, "")
 		log.Print(msg + "\n")
+	case status := <-w.stopped:
+		w.Halt()
+		log.Printf("The build is %s on the shift server, and all the process have been halted\n", status)
 	case <-w.done:
 	}
 
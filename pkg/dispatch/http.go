@@ -55,6 +55,7 @@ type RequestMaker struct {
 	contentType         string
 	logger              *logrus.Entry
 	verbose             bool
+	statusCode          int
 }
 
 // NewGetRequestMaker ..
@@ -119,7 +120,8 @@ func (r *RequestMaker) Body(request interface{}) *RequestMaker {
 }
 
 // Scan ..
-// Maps the response to response struct
+// Maps the response to response struct, the raw response
+// body is set as is when the response is of type *[]byte
 func (r *RequestMaker) Scan(response interface{}) *RequestMaker {
 	r.response = response
 	return r
@@ -154,6 +156,12 @@ func (r *RequestMaker) Verbose(verbose bool) *RequestMaker {
 	return r
 }
 
+// StatusCode ..
+// Returns the http status code of the response, set after the dispatch
+func (r *RequestMaker) StatusCode() int {
+	return r.statusCode
+}
+
 // Dispatch ..
 // This is where actuall request made to destination
 func (r *RequestMaker) Dispatch() error {
@@ -273,6 +281,8 @@ func (r *RequestMaker) Dispatch() error {
 	}
 	defer res.Body.Close()
 
+	r.statusCode = res.StatusCode
+
 	if r.response != nil {
 
 		// read the response body
@@ -282,6 +292,11 @@ func (r *RequestMaker) Dispatch() error {
 		}
 
 		// r.logger.Infoln("Response = ", string(bits[:]))
+		if raw, ok := r.response.(*[]byte); ok {
+			*raw = bits
+			return nil
+		}
+
 		// decode to response type
 		err = json.NewDecoder(bytes.NewBuffer(bits)).Decode(r.response)
 		if err != nil {